@@ -13,7 +13,7 @@ const bodyParser = require("body-parser");
 const {SESSION_OPTS, UI_PROXY_OPTIONS} = require("./config/config");
 const connectDB = require("./config/db");
 const {createProxyMiddleware} = require("http-proxy-middleware");
-const {ensureAuth, ensureApiAuth} = require("./middleware/auth");
+const {ensureAuth, ensureApiAuth, ensurePasswordChanged} = require("./middleware/auth");
 const flash = require("connect-flash");
 const User = require("./models/User");
 
@@ -48,8 +48,8 @@ if (process.env.NODE_ENV === "development") {
 }
 
 //proxy router
-app.use("/v2", ensureApiAuth, emcoRouter);
-app.use("/middleend", ensureApiAuth, emcoRouter);
+app.use("/v2", ensureApiAuth, ensurePasswordChanged, emcoRouter);
+app.use("/middleend", ensureApiAuth, ensurePasswordChanged, emcoRouter);
 
 // view engine setup
 app.set("views", path.join(__dirname, "views"));
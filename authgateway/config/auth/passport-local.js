@@ -16,7 +16,7 @@ module.exports = function (passport) {
                 if (err) {
                     return done(err);
                 }
-                if (!user) {
+                if (!user || user.disabled) {
                     return done(null, false);
                 }
 
@@ -3,7 +3,7 @@ const mongoose = require("mongoose");
 
 //return the currently logged-in user
 exports.getCurrentUser = (req, res) => {
-    UserModel.findById(req.user._id, '_id provider displayName tenant role createdAt email image').exec((err, user) => {
+    UserModel.findById(req.user._id, '_id provider displayName tenant role createdAt email image mustChangePassword').exec((err, user) => {
         res.status(200).json(user);
     });
 }
@@ -63,6 +63,7 @@ exports.updatePassword = async (req, res) => {
                 res.status(400).send("invalid current password");
             } else {
                 user.password = newPassword;
+                user.mustChangePassword = false;
                 await user.save();
                 res.send("password changed");
             }
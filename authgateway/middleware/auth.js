@@ -19,4 +19,11 @@ module.exports = {
             res.status(401).send("unauthorized");
         }
     },
+    //a user who must change the password can only do that until it is changed
+    ensurePasswordChanged: function (req, res, next) {
+        if (req.user && req.user.mustChangePassword) {
+            return res.status(403).send("password change required");
+        }
+        return next();
+    },
 };
@@ -45,6 +45,17 @@ const UserSchema = new mongoose.Schema({
         type: String,
         select: false,
         default: null
+    },
+    // set by the admin CLI: a disabled user cannot log in, and a user with
+    // mustChangePassword set can only change the password, which the UI
+    // asks for, until it is changed
+    disabled: {
+        type: Boolean,
+        default: false
+    },
+    mustChangePassword: {
+        type: Boolean,
+        default: false
     }
 })
 
@@ -94,6 +105,7 @@ UserSchema.methods.getUserFields = function () {
     userDetails.provider = this.provider;
     userDetails.firstName = this.firstName;
     userDetails.lastName = this.lastName;
+    userDetails.mustChangePassword = this.mustChangePassword;
     return userDetails;
 }
 module.exports = mongoose.model('User', UserSchema)
\ No newline at end of file
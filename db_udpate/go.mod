@@ -3,20 +3,29 @@ module example.com/m
 go 1.17
 
 require (
+	example.com/middleend v0.0.0
+	github.com/ghodss/yaml v0.0.0-20150909031657-73d445a93680
 	github.com/pkg/errors v0.9.1
-	go.mongodb.org/mongo-driver v1.0.3
-	golang.org/x/net v0.0.0-20211112202133-69e39bad7dc2
+	go.mongodb.org/mongo-driver v1.8.3
+	golang.org/x/crypto v0.0.0-20201216223049-8b5274cf687f
 )
 
 require (
 	github.com/go-stack/stack v1.8.1 // indirect
-	github.com/golang/snappy v0.0.4 // indirect
-	github.com/google/go-cmp v0.5.7 // indirect
-	github.com/stretchr/testify v1.7.1 // indirect
-	github.com/tidwall/pretty v1.2.0 // indirect
-	github.com/xdg/scram v1.0.5 // indirect
-	github.com/xdg/stringprep v1.0.3 // indirect
-	golang.org/x/crypto v0.0.0-20220321153916-2c7772ba3064 // indirect
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/gorilla/mux v1.8.0 // indirect
+	github.com/klauspost/compress v1.13.6 // indirect
+	github.com/sirupsen/logrus v1.4.2 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.0.2 // indirect
+	github.com/xdg-go/stringprep v1.0.2 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
+	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e // indirect
 	golang.org/x/text v0.3.7 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+// the CLI shares the Store of the middleend
+replace example.com/middleend => ../guimiddleend
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+
+	"github.com/ghodss/yaml"
+	pkgerrors "github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// seedFile lists the users to create, e.g.
+//
+//	users:
+//	- email: admin@enterprise.com
+//	  firstName: Admin
+//	  tenant: default
+//	  role: admin
+//	  password: changeme1
+//	  mustChangePassword: true
+type seedFile struct {
+	Users []seedUser `json:"users"`
+}
+
+// seedUser is a user of the seed file. The password is hashed, or
+// passwordHash is a bcrypt hash already. The password of an existing user
+// is left as is, so that seeding again does not undo the changes of the
+// users.
+type seedUser struct {
+	Email              string `json:"email"`
+	Provider           string `json:"provider"`
+	FirstName          string `json:"firstName"`
+	LastName           string `json:"lastName"`
+	DisplayName        string `json:"displayName"`
+	Tenant             string `json:"tenant"`
+	Role               string `json:"role"`
+	Password           string `json:"password"`
+	PasswordHash       string `json:"passwordHash"`
+	MustChangePassword bool   `json:"mustChangePassword"`
+	Disabled           bool   `json:"disabled"`
+}
+
+// readSeedFile loads the seed file at path
+func readSeedFile(path string) (*seedFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed := &seedFile{}
+	if err := yaml.Unmarshal(data, seed); err != nil {
+		return nil, pkgerrors.Wrapf(err, "reading %s", path)
+	}
+	return seed, nil
+}
+
+// seedUsers creates the users of seed which are missing and updates the
+// profile of the others. It saves only the users which differ, so it can
+// run any number of times.
+func seedUsers(ctx context.Context, seed *seedFile, cost int) error {
+	for _, s := range seed.Users {
+		if s.Email == "" {
+			return pkgerrors.New("a user of the seed file has no email")
+		}
+		existing, err := findUser(ctx, s.Email)
+		if err != nil {
+			return err
+		}
+		var u User
+		if existing != nil {
+			u = *existing
+		} else {
+			u = User{ID: s.Email, Email: s.Email, Provider: "amcop", MustChangePassword: s.MustChangePassword}
+			if u.Password, err = seedPassword(s, cost); err != nil {
+				return pkgerrors.Wrap(err, s.Email)
+			}
+		}
+		if s.Provider != "" {
+			u.Provider = s.Provider
+		}
+		u.FirstName, u.LastName, u.Tenant, u.Role, u.Disabled = s.FirstName, s.LastName, s.Tenant, s.Role, s.Disabled
+		u.DisplayName = s.DisplayName
+		if u.DisplayName == "" {
+			u.DisplayName = s.FirstName
+		}
+		if existing != nil && u == *existing {
+			log.Printf("User %s is up to date", s.Email)
+			continue
+		}
+		if err := saveUser(ctx, u); err != nil {
+			return err
+		}
+		if existing != nil {
+			log.Printf("Updated user %s", s.Email)
+		} else {
+			log.Printf("Created user %s", s.Email)
+		}
+	}
+	return nil
+}
+
+func seedPassword(s seedUser, cost int) (string, error) {
+	if s.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(s.PasswordHash)); err != nil {
+			return "", pkgerrors.Wrap(err, "invalid passwordHash")
+		}
+		return s.PasswordHash, nil
+	}
+	return hashPassword(s.Password, cost)
+}
@@ -1,36 +1,42 @@
 package main
 
 import (
-	context2 "context"
-	"encoding/json"
+	"bufio"
+	"context"
+	"flag"
 	"fmt"
-	pkgerrors "github.com/pkg/errors"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/net/context"
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
-)
+	"text/tabwriter"
 
-type DefaultUserEntry struct {
-	Provider    string `json:"provider"`
-	ID          string `json:"id"`
-	DisplayName string `json:"displayName"`
-	FirstName   string `json:"firstName"`
-	Tenant      string `json:"tenant"`
-	Role        string `json:"role"`
-	Email       string `json:"email"`
-	Password    string `json:"password"`
-}
+	"example.com/middleend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
 
 const (
 	DBName = "rbac_userdb"
 )
 
+// bootstrapAdmin is created on install. Its password is the one of the
+// earlier releases, it has to be changed on first login.
+var bootstrapAdmin = User{
+	Provider:           "amcop",
+	ID:                 "admin@enterprise.com",
+	DisplayName:        "Admin",
+	FirstName:          "Admin",
+	Tenant:             "default",
+	Role:               "admin",
+	Email:              "admin@enterprise.com",
+	Password:           "$2a$10$SaNe/etlslsSqW3adzO9Zuzic8okeEZxaS6/6oACjQFzO9CU8IRfW",
+	MustChangePassword: true,
+}
+
 func CreateUser(dbName string, ip string) error {
 	clientOptions := options.Client()
 	clientOptions.ApplyURI(ip)
@@ -49,7 +55,7 @@ func CreateUser(dbName string, ip string) error {
 		log.Printf("Failed to connect to mongo client err %s", err.Error())
 		return err
 	}
-	defer func(mongoClient *mongo.Client, ctx context2.Context) {
+	defer func(mongoClient *mongo.Client, ctx context.Context) {
 		err := mongoClient.Disconnect(ctx)
 		if err != nil {
 			log.Printf("Failed to connect to mongo client err %s", err.Error())
@@ -58,244 +64,367 @@ func CreateUser(dbName string, ip string) error {
 	}(mongoClient, context.Background())
 
 	dbLocal := mongoClient.Database("rbac_userdb")
-	r := dbLocal.RunCommand(context.Background(), bson.D{{"createUser", os.Getenv("DB_EMCOUI_USERNAME")},
-		{"pwd", os.Getenv("DB_EMCOUI_PASSWORD")},
-		{"roles", []bson.M{{"role": "readWrite", "db": "rbac_userdb"},
+	r := dbLocal.RunCommand(context.Background(), bson.D{{Key: "createUser", Value: os.Getenv("DB_EMCOUI_USERNAME")},
+		{Key: "pwd", Value: os.Getenv("DB_EMCOUI_PASSWORD")},
+		{Key: "roles", Value: []bson.M{{"role": "readWrite", "db": "rbac_userdb"},
 			{"role": "readWrite", "db": "middleend"}}},
 	})
 	// UPdate the user entry: If we helm delete and install the app
 	// the secret will get refreshed, hence the user needs to be updated
 	// with new secret.
-	if (r.Err() != nil && strings.Contains(r.Err().Error(), "already exists")) {
-		r := dbLocal.RunCommand(context.Background(), bson.D{{"updateUser", os.Getenv("DB_EMCOUI_USERNAME")},
-			{"pwd", os.Getenv("DB_EMCOUI_PASSWORD")},
-			{"roles", []bson.M{{"role": "readWrite", "db": "rbac_userdb"},
+	if r.Err() != nil && strings.Contains(r.Err().Error(), "already exists") {
+		r := dbLocal.RunCommand(context.Background(), bson.D{{Key: "updateUser", Value: os.Getenv("DB_EMCOUI_USERNAME")},
+			{Key: "pwd", Value: os.Getenv("DB_EMCOUI_PASSWORD")},
+			{Key: "roles", Value: []bson.M{{"role": "readWrite", "db": "rbac_userdb"},
 				{"role": "readWrite", "db": "middleend"}}},
 		})
 		if r.Err() != nil {
-			log.Printf("Failed to Update User EMCOUI %s", err.Error())
-			return err
-
+			log.Printf("Failed to Update User EMCOUI %s", r.Err().Error())
+			return r.Err()
 		}
 		return nil
 	}
 	if r.Err() != nil {
-		log.Printf("Failed to create User EMCOUI %s", err.Error())
-		return err
+		log.Printf("Failed to create User EMCOUI %s", r.Err().Error())
+		return r.Err()
 	}
 	return nil
 }
 
+// command is a subcommand of the CLI. run gets the arguments following
+// its name.
+type command struct {
+	usage string
+	run   func(a *admin, args []string) error
+}
+
+// admin holds the settings shared by the commands
+type admin struct {
+	ctx  context.Context
+	cost int
+}
+
+// commands are the subcommands by name
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"bootstrap": {"create the mongo user of the GUI and the default admin, the helm hook", (*admin).bootstrap},
+		"create":    {"create a user", (*admin).create},
+		"list":      {"list the users", (*admin).list},
+		"update":    {"change the names of a user", (*admin).update},
+		"assign":    {"change the role and the tenant of a user", (*admin).assign},
+		"disable":   {"prevent a user from logging in", (*admin).disable},
+		"enable":    {"allow a disabled user to log in again", (*admin).enable},
+		"passwd":    {"set the password of a user", (*admin).passwd},
+		"seed":      {"create or update the users of a YAML file", (*admin).seed},
+	}
+}
+
 func main() {
-	URI := os.ExpandEnv("mongodb://${MONGODB_HOST}")
-	// Create DB users for middleend and authservice databases,
-	// if the env variable dbauthEnable is set to true
-	if len(os.Getenv("MONGO_INITDB_ROOT_USERNAME")) > 0 && len(os.Getenv("MONGO_INITDB_ROOT_PASSWORD")) > 0 {
-		err := CreateUser("admin", URI)
+	cost := bcrypt.DefaultCost
+	if v := os.Getenv("BCRYPT_COST"); v != "" {
+		n, err := strconv.Atoi(v)
 		if err != nil {
-			fmt.Println("Failed to connect to mongo", err)
-			return
+			log.Fatalf("Invalid BCRYPT_COST %q", v)
 		}
-		log.Printf("Created user for emcoui")
+		cost = n
 	}
-	log.Printf("Connect to DB client without auth")
-
-	err := CreateDBClient("mongo", DBName, URI)
-	if err != nil {
-		fmt.Println("Failed to connect to mongo", err)
-		return
+	host := flag.String("db", os.Getenv("MONGODB_HOST"), "host:port of mongo, $MONGODB_HOST by default")
+	flag.IntVar(&cost, "bcrypt-cost", cost, "bcrypt cost of the password hashes, $BCRYPT_COST by default")
+	flag.Usage = usage
+	flag.Parse()
+	if err := validateCost(cost); err != nil {
+		log.Fatal(err)
 	}
 
-	userData := DefaultUserEntry{
-		Provider:    "amcop",
-		ID:          "admin@enterprise.com",
-		DisplayName: "Admin",
-		FirstName:   "Admin",
-		Tenant:      "default",
-		Role:        "admin",
-		Email:       "admin@enterprise.com",
-		Password:    "$2a$10$SaNe/etlslsSqW3adzO9Zuzic8okeEZxaS6/6oACjQFzO9CU8IRfW",
+	// without a command the helm hook bootstraps the database
+	name, args := "bootstrap", []string(nil)
+	if flag.NArg() > 0 {
+		name, args = flag.Arg(0), flag.Args()[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
 	}
 
-	err = DBconn.Insert("users", userData, nil, "userData", userData)
+	if name == "bootstrap" {
+		// Create DB users for middleend and authservice databases,
+		// if the env variable dbauthEnable is set to true
+		if len(os.Getenv("MONGO_INITDB_ROOT_USERNAME")) > 0 && len(os.Getenv("MONGO_INITDB_ROOT_PASSWORD")) > 0 {
+			if err := CreateUser("admin", "mongodb://"+*host); err != nil {
+				log.Fatalf("Failed to connect to mongo: %s", err)
+			}
+			log.Printf("Created user for emcoui")
+		}
+	}
+	if err := db.CreateDBClient("mongo", DBName, *host); err != nil {
+		log.Fatalf("Failed to connect to mongo: %s", err)
+	}
+	a := &admin{ctx: context.Background(), cost: cost}
+	err := cmd.run(a, args)
+	if cerr := db.DBconn.Close(a.ctx); cerr != nil {
+		log.Printf("Failed to disconnect from mongo: %s", cerr)
+	}
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
 	if err != nil {
-		fmt.Println(err)
-		return
+		log.Fatal(err)
 	}
-	log.Printf("Created default Admin user in rbac_userdb")
-	return
 }
 
-// MongoStore is the interface which implements the db.Store interface
-type MongoStore struct {
-	db *mongo.Database
-}
-
-// Key interface
-type Key interface {
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [command flags]\n\nManages the users of %s, bootstrap is the default command.\n\nCommands:\n", os.Args[0], DBName)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-10s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
 }
 
-// DBconn variable of type Store
-var DBconn Store
-
-// Store Interface which implements the data store functions
-type Store interface {
-	Insert(coll string, key Key, query interface{}, tag string, data interface{}) error
+// flags returns the flag set of a command, -h prints its flags
+func flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", os.Args[0], name, commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
 }
 
-// NewMongoStore Return mongo client
-func NewMongoStore(name string, store *mongo.Database, svcEp string) (Store, error) {
-	if store == nil {
-		ip := svcEp
-		clientOptions := options.Client()
-		clientOptions.ApplyURI(ip)
-		if len(os.Getenv("DB_EMCOUI_USERNAME")) > 0 && len(os.Getenv("DB_EMCOUI_PASSWORD")) > 0 {
-			clientOptions.SetAuth(options.Credential{
-				AuthMechanism: "SCRAM-SHA-256",
-				AuthSource:    "rbac_userdb",
-				Username:      os.Getenv("DB_EMCOUI_USERNAME"),
-				Password:      os.Getenv("DB_EMCOUI_PASSWORD")})
+// passwordFlags adds the ways to give a password to fs
+func passwordFlags(fs *flag.FlagSet) func() (string, error) {
+	password := fs.String("password", "", "the password, visible to the other processes; prefer -password-stdin")
+	stdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	return func() (string, error) {
+		if !*stdin {
+			return *password, nil
 		}
-		mongoClient, err := mongo.NewClient(clientOptions)
-		if err != nil {
-			return nil, err
-		}
-
-		err = mongoClient.Connect(context.Background())
-		if err != nil {
-			return nil, err
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading the password: %v", err)
 		}
-		store = mongoClient.Database(name)
+		return strings.TrimRight(line, "\r\n"), nil
 	}
-	return &MongoStore{
-		db: store,
-	}, nil
-}
-
-// CreateDBClient creates the DB client, currently only mongo
-func CreateDBClient(dbType string, dbName string, svcEp string) error {
-	var err error
-	switch dbType {
-	case "mongo":
-		DBconn, err = NewMongoStore(dbName, nil, svcEp)
-	default:
-		fmt.Println(dbType + "DB not supported")
-	}
-	return err
 }
 
-func (m *MongoStore) updateFilter(key interface{}) (primitive.M, error) {
-
-	var n map[string]string
-	st, err := json.Marshal(key)
-	if err != nil {
-		return primitive.M{}, pkgerrors.Errorf("Error Marshalling key: %s", err.Error())
+// bootstrap creates the default admin unless it exists
+func (a *admin) bootstrap(args []string) error {
+	if err := flags("bootstrap").Parse(args); err != nil {
+		return err
 	}
-	err = bson.UnmarshalExtJSON(st, true, &n)
+	u, err := findUser(a.ctx, bootstrapAdmin.Email)
 	if err != nil {
-		return primitive.M{}, pkgerrors.Errorf("Error Unmarshalling key to Bson Map: %s", err.Error())
+		return err
 	}
-	p := make(bson.M, len(n))
-	for k, v := range n {
-		p[k] = v
+	if u != nil {
+		log.Printf("Admin user %s exists already", u.Email)
+		return nil
 	}
-	filter := bson.M{
-		"$set": p,
+	if err := saveUser(a.ctx, bootstrapAdmin); err != nil {
+		return err
 	}
-	return filter, nil
+	log.Printf("Created default Admin user in rbac_userdb, its password must be changed on first login")
+	return nil
 }
 
-func (m *MongoStore) createKeyField(key interface{}) (string, error) {
-
-	var n map[string]string
-	st, err := json.Marshal(key)
+func (a *admin) create(args []string) error {
+	fs := flags("create")
+	email := fs.String("email", "", "email of the user, which logs in with it")
+	first := fs.String("first", "", "first name")
+	last := fs.String("last", "", "last name")
+	display := fs.String("display", "", "display name, the first name by default")
+	tenant := fs.String("tenant", "default", "tenant of the user")
+	role := fs.String("role", "tenant", "role of the user: "+strings.Join(roles, ", "))
+	mustChange := fs.Bool("must-change", true, "require a password change on first login")
+	password := passwordFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("the email is required")
+	}
+	existing, err := findUser(a.ctx, *email)
 	if err != nil {
-		return "", pkgerrors.Errorf("Error Marshalling key: %s", err.Error())
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("user %s exists already", *email)
+	}
+	u := User{
+		Provider:           "amcop",
+		ID:                 *email,
+		Email:              *email,
+		FirstName:          *first,
+		LastName:           *last,
+		DisplayName:        *display,
+		Tenant:             *tenant,
+		Role:               *role,
+		MustChangePassword: *mustChange,
 	}
-	err = bson.UnmarshalExtJSON(st, true, &n)
+	if u.DisplayName == "" {
+		u.DisplayName = u.FirstName
+	}
+	p, err := password()
 	if err != nil {
-		return "", pkgerrors.Errorf("Error Unmarshalling key to Bson Map: %s", err.Error())
+		return err
 	}
-	var keys []string
-	for k := range n {
-		keys = append(keys, k)
+	if u.Password, err = hashPassword(p, a.cost); err != nil {
+		return err
 	}
-	sort.Strings(keys)
-	s := "{"
-	for _, k := range keys {
-		s = s + k + ","
+	if err := saveUser(a.ctx, u); err != nil {
+		return err
 	}
-	s = s + "}"
-	return s, nil
+	log.Printf("Created user %s", u.Email)
+	return nil
 }
 
-func (m *MongoStore) findFilter(key Key) (primitive.M, error) {
-
-	var bsonMap bson.M
-	st, err := json.Marshal(key)
-	if err != nil {
-		return primitive.M{}, pkgerrors.Errorf("Error Marshalling key: %s", err.Error())
+func (a *admin) list(args []string) error {
+	fs := flags("list")
+	tenant := fs.String("tenant", "", "list the users of the tenant only")
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
-	err = bson.UnmarshalExtJSON(st, true, &bsonMap)
+	users, err := loadUsers(a.ctx)
 	if err != nil {
-		return primitive.M{}, pkgerrors.Errorf("Error Unmarshalling key to Bson Map: %s", err.Error())
+		return err
 	}
-	filter := bson.M{
-		"$and": []bson.M{bsonMap},
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tNAME\tROLE\tTENANT\tSTATUS")
+	for _, u := range users {
+		if *tenant != "" && u.Tenant != *tenant {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", u.Email, u.DisplayName, u.Role, u.Tenant, u.status())
 	}
-	return filter, nil
+	return w.Flush()
 }
 
-var decodeBytes = func(sr *mongo.SingleResult) (bson.Raw, error) {
-	return sr.DecodeBytes()
+func (a *admin) update(args []string) error {
+	fs := flags("update")
+	email := fs.String("email", "", "email of the user")
+	first := fs.String("first", "", "first name")
+	last := fs.String("last", "", "last name")
+	display := fs.String("display", "", "display name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return a.change(*email, func(u *User) error {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "first":
+				u.FirstName = *first
+			case "last":
+				u.LastName = *last
+			case "display":
+				u.DisplayName = *display
+			}
+		})
+		return nil
+	})
 }
 
-// Insert is used to insert/add element to a document
-func (m *MongoStore) Insert(coll string, key Key, query interface{}, tag string, data interface{}) error {
+func (a *admin) assign(args []string) error {
+	fs := flags("assign")
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "", "new role: "+strings.Join(roles, ", "))
+	tenant := fs.String("tenant", "", "new tenant")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *role == "" && *tenant == "" {
+		return fmt.Errorf("give a -role, a -tenant or both")
+	}
+	return a.change(*email, func(u *User) error {
+		if *role != "" {
+			u.Role = *role
+		}
+		if *tenant != "" {
+			u.Tenant = *tenant
+		}
+		return nil
+	})
+}
 
-	c := m.db.Collection(coll)
-	ctx := context.Background()
+func (a *admin) disable(args []string) error {
+	return a.setDisabled("disable", args, true)
+}
 
-	filter, err := m.findFilter(key)
-	if err != nil {
+func (a *admin) enable(args []string) error {
+	return a.setDisabled("enable", args, false)
+}
+
+func (a *admin) setDisabled(name string, args []string, disabled bool) error {
+	fs := flags(name)
+	email := fs.String("email", "", "email of the user")
+	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	// Create and add key tag
-	s, err := m.createKeyField(key)
+	return a.change(*email, func(u *User) error {
+		u.Disabled = disabled
+		return nil
+	})
+}
+
+func (a *admin) passwd(args []string) error {
+	fs := flags("passwd")
+	email := fs.String("email", "", "email of the user")
+	mustChange := fs.Bool("must-change", false, "require the user to change the password on next login")
+	password := passwordFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	p, err := password()
 	if err != nil {
 		return err
 	}
-	_, err = decodeBytes(
-		c.FindOneAndUpdate(
-			ctx,
-			filter,
-			bson.D{
-				{"$set", bson.D{
-					{tag, data},
-					{"key", s},
-				}},
-			},
-			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)))
-
+	hash, err := hashPassword(p, a.cost)
 	if err != nil {
-		return pkgerrors.Errorf("Error updating master table: %s", err.Error())
+		return err
 	}
-	if query == nil {
+	return a.change(*email, func(u *User) error {
+		u.Password = hash
+		u.MustChangePassword = *mustChange
 		return nil
-	}
+	})
+}
 
-	// Update to add Query fields
-	update, err := m.updateFilter(query)
+func (a *admin) seed(args []string) error {
+	fs := flags("seed")
+	file := fs.String("f", "", "YAML file of the users")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("the seed file is required")
+	}
+	seed, err := readSeedFile(*file)
 	if err != nil {
 		return err
 	}
-	_, err = c.UpdateOne(
-		ctx,
-		filter,
-		update)
+	return seedUsers(a.ctx, seed, a.cost)
+}
 
+// change applies fn to the user of email and saves it
+func (a *admin) change(email string, fn func(u *User) error) error {
+	u, err := getUser(a.ctx, email)
 	if err != nil {
-		return pkgerrors.Errorf("Error updating Query fields: %s", err.Error())
+		return err
+	}
+	if err := fn(u); err != nil {
+		return err
+	}
+	if err := saveUser(a.ctx, *u); err != nil {
+		return err
 	}
+	log.Printf("Updated user %s", u.Email)
 	return nil
 }
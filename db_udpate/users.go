@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/middleend/db"
+	pkgerrors "github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// usersCollection holds the accounts of the authgateway, which reads
+	// the top level fields of the documents
+	usersCollection = "users"
+	// userTag holds a copy of the profile, without the password
+	userTag = "userData"
+	// minPasswordLength is enforced on the passwords set by the CLI
+	minPasswordLength = 8
+)
+
+// roles are the values the authgateway accepts for User.Role
+var roles = []string{"admin", "tenant"}
+
+// User is an account of the authgateway. The json names are the fields
+// of the users documents, an empty role is not written since the
+// authgateway accepts null but not "".
+type User struct {
+	Provider           string    `json:"provider" bson:"provider"`
+	ID                 string    `json:"id" bson:"id"`
+	DisplayName        string    `json:"displayName" bson:"displayName"`
+	FirstName          string    `json:"firstName" bson:"firstName"`
+	LastName           string    `json:"lastName" bson:"lastName"`
+	Tenant             string    `json:"tenant" bson:"tenant"`
+	Role               string    `json:"role,omitempty" bson:"role"`
+	Email              string    `json:"email" bson:"email"`
+	Password           string    `json:"password" bson:"password,omitempty"`
+	Disabled           bool      `json:"disabled" bson:"disabled"`
+	MustChangePassword bool      `json:"mustChangePassword" bson:"mustChangePassword"`
+	CreatedAt          time.Time `json:"-" bson:"createdAt,omitempty"`
+}
+
+// userKey selects the document of a user
+type userKey struct {
+	Email string `json:"email"`
+}
+
+// validate checks the fields the authgateway requires
+func (u User) validate() error {
+	switch {
+	case u.Email == "":
+		return pkgerrors.New("the email is required")
+	case u.FirstName == "":
+		return pkgerrors.New("the first name is required")
+	case u.Password == "":
+		return pkgerrors.New("the password is required")
+	case u.Role == "":
+		// like the users signed up with the authgateway, the role is
+		// left unset
+		return nil
+	}
+	return validateRole(u.Role)
+}
+
+func validateRole(role string) error {
+	for _, r := range roles {
+		if r == role {
+			return nil
+		}
+	}
+	return pkgerrors.Errorf("invalid role %q, use one of %s", role, strings.Join(roles, ", "))
+}
+
+// status describes whether the user can log in
+func (u User) status() string {
+	switch {
+	case u.Disabled:
+		return "disabled"
+	case u.MustChangePassword:
+		return "must change password"
+	}
+	return "active"
+}
+
+// hashPassword returns the bcrypt hash of password
+func hashPassword(password string, cost int) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", pkgerrors.Errorf("the password needs %d characters or more", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// validateCost checks a bcrypt cost
+func validateCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("the bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+// loadUsers returns the users sorted by email
+func loadUsers(ctx context.Context) ([]User, error) {
+	docs, err := db.DBconn.Documents(ctx, usersCollection, "")
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "reading the users")
+	}
+	users := make([]User, 0, len(docs))
+	for _, doc := range docs {
+		var u User
+		if err := bson.Unmarshal(doc, &u); err != nil {
+			return nil, pkgerrors.Wrap(err, "reading the users")
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+// findUser returns the user of email, nil when there is none
+func findUser(ctx context.Context, email string) (*User, error) {
+	users, err := loadUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if users[i].Email == email {
+			return &users[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getUser is findUser failing when there is no user of email
+func getUser(ctx context.Context, email string) (*User, error) {
+	if email == "" {
+		return nil, pkgerrors.New("the email is required")
+	}
+	u, err := findUser(ctx, email)
+	if err == nil && u == nil {
+		err = pkgerrors.Errorf("no user %s", email)
+	}
+	return u, err
+}
+
+// saveUser creates or updates the document of u
+func saveUser(ctx context.Context, u User) error {
+	if err := u.validate(); err != nil {
+		return err
+	}
+	profile := u
+	profile.Password = ""
+	if err := db.DBconn.Insert(ctx, usersCollection, userKey{Email: u.Email}, u, userTag, profile); err != nil {
+		return pkgerrors.Wrapf(err, "saving %s", u.Email)
+	}
+	return nil
+}
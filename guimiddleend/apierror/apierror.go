@@ -0,0 +1,96 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package apierror holds the envelope of the failed replies of the
+// middleend, shared by the APIs and the middlewares in front of them.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/middleend/logging"
+)
+
+// Error codes of the ErrorResponse
+const (
+	ErrBadRequest          = "BAD_REQUEST"
+	ErrUnauthorized        = "UNAUTHORIZED"
+	ErrForbidden           = "FORBIDDEN"
+	ErrNotFound            = "NOT_FOUND"
+	ErrConflict            = "CONFLICT"
+	ErrUnavailable         = "UNAVAILABLE"
+	ErrInternal            = "INTERNAL"
+	ErrUpstream            = "UPSTREAM_ERROR"
+	ErrUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	ErrUpstreamTimeout     = "UPSTREAM_TIMEOUT"
+)
+
+// ErrorResponse is the body of every failed API reply. Service and
+// UpstreamStatus are set when the error comes from an EMCO service.
+type ErrorResponse struct {
+	Status         int    `json:"status"`
+	Code           string `json:"code"`
+	Message        string `json:"message"`
+	Service        string `json:"service,omitempty"`
+	UpstreamStatus int    `json:"upstreamStatus,omitempty"`
+	RequestID      string `json:"requestId,omitempty"`
+}
+
+// Code is the error code of a reply status
+func Code(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrInternal
+	}
+	return ErrBadRequest
+}
+
+// Write replies the error of status with message to the request r
+func Write(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	Encode(w, r, ErrorResponse{
+		Status:    status,
+		Code:      Code(status),
+		Message:   message,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+	})
+}
+
+// Encode replies e to the request r
+func Encode(w http.ResponseWriter, r *http.Request, e ErrorResponse) {
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		logging.FromContext(r.Context()).WithError(err).Error("Failed to encode the error reply")
+	}
+}
@@ -33,8 +33,12 @@ import (
 	"sync"
 	"time"
 
+	"example.com/middleend/backend"
 	"example.com/middleend/db"
 	"example.com/middleend/localstore"
+	"example.com/middleend/logging"
+	"example.com/middleend/tlsutil"
+	"example.com/middleend/tracing"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
@@ -44,7 +48,7 @@ import (
 )
 
 type deployServiceData struct {
-	Name        string `json:"name"`
+	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	Spec        struct {
 		ProjectName string     `json:"projectName"`
@@ -53,7 +57,7 @@ type deployServiceData struct {
 }
 
 type deployDigData struct {
-	Name                string  `json:"name"`
+	Name                string  `json:"name" binding:"required"`
 	Description         string  `json:"description"`
 	CompositeAppName    string  `json:"compositeApp"`
 	CompositeProfile    string  `json:"compositeProfile"`
@@ -79,7 +83,7 @@ type Exists struct {
 // This is the json payload that the orchestration API expects.
 type appsData struct {
 	Metadata struct {
-		Name        string `json:"name"`
+		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
 		FileName    string `json:"filename"`
 		FileContent string `json:"filecontent,omitempty"`
@@ -304,7 +308,7 @@ type GitOpsData struct {
 	GitOpsResObject string `json:"gitOpsResourceObject"`
 }
 type apiMetaData struct {
-	Name        string `json:"name" bson:"name"`
+	Name        string `json:"name" bson:"name" binding:"required"`
 	Description string `json:"description" bson:"description"`
 	UserData1   string `userData1:"userData1"`
 	UserData2   string `userData2:"userData2"`
@@ -331,21 +335,73 @@ type orchWorkflow interface {
 
 // MiddleendConfig MiddleendConfig: The configmap of the middleend
 type MiddleendConfig struct {
-	OwnPort        string `json:"ownport"`
-	Cert           string `json:"cert"`
-	Clm            string `json:"clm"`
-	Dcm            string `json:"dcm"`
-	Ncm            string `json:"ncm"`
-	Gac            string `json:"gac"`
-	Dtc            string `json:"dtc"`
-	Its            string `json:"its"`
-	OrchService    string `json:"orchestrator"`
-	OvnService     string `json:"ovnaction"`
-	CfgService     string `json:"configSvc"`
-	Mongo          string `json:"mongo"`
-	LogLevel       string `json:"logLevel"`
-	AppInstantiate bool   `json:"appInstantiate"`
-	StoreName      string `json:"storeName"`
+	OwnPort        string         `json:"ownport"`
+	Cert           string         `json:"cert"`
+	Clm            string         `json:"clm"`
+	Dcm            string         `json:"dcm"`
+	Ncm            string         `json:"ncm"`
+	Gac            string         `json:"gac"`
+	Dtc            string         `json:"dtc"`
+	Its            string         `json:"its"`
+	OrchService    string         `json:"orchestrator"`
+	OvnService     string         `json:"ovnaction"`
+	CfgService     string         `json:"configSvc"`
+	Mongo          string         `json:"mongo"`
+	LogLevel       string         `json:"logLevel"`
+	AppInstantiate bool           `json:"appInstantiate"`
+	StoreName      string         `json:"storeName"`
+	RbacPolicy     string         `json:"rbacPolicy"`
+	Backend        backend.Config `json:"backend"`
+	// OperationWorkers bounds the asynchronous operations running at once
+	OperationWorkers int `json:"operationWorkers"`
+	// EventsPollInterval is the time in seconds between two polls of the
+	// status of the DIGs and logical clouds streamed to the GUI
+	EventsPollInterval int `json:"eventsPollInterval"`
+	// Tracing configures the export of the spans
+	Tracing tracing.Config `json:"tracing"`
+	// ReadinessCacheSeconds is how long the result of the readiness
+	// checks of mongo and the EMCO services is reused
+	ReadinessCacheSeconds int `json:"readinessCacheSeconds"`
+	// TLS serves the middleend API over https when a certificate is set
+	TLS tlsutil.ServerConfig `json:"tls"`
+	// ServiceTLS secures the calls to the EMCO services, by service name
+	// (orchestrator, clm, ...). The services listed are called over https.
+	ServiceTLS map[string]tlsutil.ClientConfig `json:"serviceTLS"`
+	// ShutdownGracePeriod is the time in seconds given to the requests
+	// and operations in flight to finish when the middleend stops
+	ShutdownGracePeriod int `json:"shutdownGracePeriod"`
+	// CheckoutLeaseSeconds is how long a user holds the checkout of a
+	// DIG without changing it, the checkout is discarded then
+	CheckoutLeaseSeconds int `json:"checkoutLeaseSeconds"`
+	// OperationRetentionHours is how long the finished operations are
+	// kept, a week by default
+	OperationRetentionHours int `json:"operationRetentionHours"`
+	// DBType is the store of the middleend data, mongo by default. The
+	// memory store loses the data on restart, the file one keeps it in
+	// the directory DBPath.
+	DBType string `json:"dbType"`
+	DBPath string `json:"dbPath"`
+}
+
+// requestState is the state the steps of a request share besides the
+// route variables in Vars
+type requestState struct {
+	// description of the composite app or project being created
+	description string
+	// checkoutOperation is migrate or update while a DIG is checked out,
+	// from originalVersion
+	checkoutOperation string
+	originalVersion   string
+	// updateIntent is set when the placement intents of a checked out DIG
+	// are saved over the existing ones
+	updateIntent bool
+	// multipart is set when the composite app tree is read with its apps
+	multipart bool
+	// multipartFiles sends the files of a multipart post as "files"
+	// rather than a single "file"
+	multipartFiles bool
+	// plan records the changes to EMCO of a dry run, which are not sent
+	plan *SubmitPlan
 }
 
 // OrchestrationHandler interface, handling the composite app APIs
@@ -353,7 +409,11 @@ type OrchestrationHandler struct {
 	sync.Mutex
 	Logger                       *logrus.Entry
 	MiddleendConf                MiddleendConfig
-	client                       http.Client
+	srv                          *Server
+	state                        requestState
+	client                       *backend.Client
+	ctx                          context.Context
+	operation                    *operationTracker
 	meta                         []appsData
 	DigData                      deployDigData
 	file                         map[string]*multipart.FileHeader
@@ -403,9 +463,25 @@ func PrintFunctionName() string {
 	return ""
 }
 
-// NewAppHandler interface implementing REST callhandler
-func NewAppHandler() *OrchestrationHandler {
-	return &OrchestrationHandler{}
+// setLogger makes l the logger of the handler and of the localstore
+// clients and the store it uses
+func (h *OrchestrationHandler) setLogger(l *logrus.Entry) {
+	h.Logger = l
+	h.ctx = logging.NewContext(h.ctx, l)
+}
+
+// startSpan starts a span as a child of the current one and makes it
+// current, so that the calls to the EMCO services and the store made
+// until the returned function is called are traced under it.
+func (h *OrchestrationHandler) startSpan(name string) func(err error) {
+	parent := h.ctx
+	ctx, span := tracing.Start(parent, name, tracing.KindInternal)
+	h.ctx = ctx
+	return func(err error) {
+		span.RecordError(err)
+		span.End()
+		h.ctx = parent
+	}
 }
 
 // GetHealth to check connectivity
@@ -416,7 +492,7 @@ func (h *OrchestrationHandler) GetHealth(w http.ResponseWriter) {
 	}
 	retval, err := json.Marshal(healthcheckResponse)
 	if err != nil {
-		log.WithError(err).Errorf("%s() : Failed to marshal healthcheckResponse", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to marshal healthcheckResponse", PrintFunctionName())
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -424,7 +500,7 @@ func (h *OrchestrationHandler) GetHealth(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
@@ -432,19 +508,19 @@ func (h *OrchestrationHandler) apiGet(url string, statusKey string) (reply HTTPR
 	start := time.Now()
 	h.InitializeResponseMap()
 	// prepare and DEL API
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := http.NewRequestWithContext(h.ctx, "GET", url, nil)
 	if err != nil {
 		return reply, err
 	}
-	resp, err := h.client.Do(request)
+	resp, err := h.do(request)
 	if err != nil {
 		return reply, err
 	}
-	log.Debugf("api request. url: %s, took: %s", url, time.Since(start))
+	h.Logger.Debugf("api request. url: %s, took: %s", url, time.Since(start))
 	defer func() {
 		err := resp.Body.Close()
 		if err != nil {
-			log.WithError(err).Warnf("%s(): Failed to close the reader.", PrintFunctionName())
+			h.Logger.WithError(err).Warnf("%s(): Failed to close the reader.", PrintFunctionName())
 		}
 	}()
 
@@ -474,7 +550,7 @@ func (h *OrchestrationHandler) apiGet(url string, statusKey string) (reply HTTPR
 func (h *OrchestrationHandler) apiGetWithArguments(url string, statusKey string, arguments [][]string) (interface{}, []byte, error) {
 	start := time.Now()
 	h.InitializeResponseMap()
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := http.NewRequestWithContext(h.ctx, "GET", url, nil)
 	if err != nil {
 		return http.StatusInternalServerError, nil, err
 	}
@@ -483,18 +559,18 @@ func (h *OrchestrationHandler) apiGetWithArguments(url string, statusKey string,
 		q.Add(argument[0], argument[1])
 	}
 	request.URL.RawQuery = q.Encode()
-	resp, err := h.client.Do(request)
+	resp, err := h.do(request)
 	if err != nil {
 		return http.StatusInternalServerError, nil, err
 	}
 
-	log.Debugf("api request. url: %s, took: %s", url, time.Since(start))
+	h.Logger.Debugf("api request. url: %s, took: %s", url, time.Since(start))
 
 	defer resp.Body.Close()
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Warningln(err)
+		h.Logger.Warningln(err)
 	}
 	if statusKey != "" {
 		h.Lock()
@@ -509,16 +585,16 @@ func (h *OrchestrationHandler) apiGetWithArguments(url string, statusKey string,
 func (h *OrchestrationHandler) apiGetMultiPart(url string, statusKey string) (interface{}, []byte, error) {
 	h.InitializeResponseMap()
 	start := time.Now()
-	request, err := http.NewRequest("GET", url, nil)
-	request.Header.Set("Accept", "multipart/form-data; charset=utf-8")
+	request, err := http.NewRequestWithContext(h.ctx, "GET", url, nil)
 	if err != nil {
 		return http.StatusInternalServerError, nil, err
 	}
-	resp, err := h.client.Do(request)
+	request.Header.Set("Accept", "multipart/form-data; charset=utf-8")
+	resp, err := h.do(request)
 	if err != nil {
-		return resp.StatusCode, nil, err
+		return http.StatusInternalServerError, nil, err
 	}
-	log.Debugf("api request. url: %s, took: %s", url, time.Since(start))
+	h.Logger.Debugf("api request. url: %s, took: %s", url, time.Since(start))
 	defer resp.Body.Close()
 
 	var cSpecContent localstore.SpecFileContent
@@ -527,12 +603,12 @@ func (h *OrchestrationHandler) apiGetMultiPart(url string, statusKey string) (in
 	mr := multipart.NewReader(resp.Body, params["boundary"])
 	for part, err := mr.NextPart(); err == nil; part, err = mr.NextPart() {
 		value, _ := ioutil.ReadAll(part)
-		log.Debugf("FormName is: %s", part.FormName())
-		log.Debugf("Value: %s", value)
+		h.Logger.Debugf("FormName is: %s", part.FormName())
+		h.Logger.Debugf("Value: %s", value)
 		if part.FormName() == "customization" {
 			err := json.Unmarshal(value, &cz)
 			if err != nil {
-				log.WithError(err).Errorf("%s(): Failed to ummarshal customaization data", PrintFunctionName())
+				h.Logger.WithError(err).Errorf("%s(): Failed to ummarshal customaization data", PrintFunctionName())
 				return nil, nil, err
 			}
 		}
@@ -562,13 +638,15 @@ func (h *OrchestrationHandler) apiGetMultiPart(url string, statusKey string) (in
 func (h *OrchestrationHandler) apiDel(url string, statusKey string) (interface{}, error) {
 	h.InitializeResponseMap()
 	// prepare and DEL API
-	request, err := http.NewRequest("DELETE", url, nil)
+	request, err := http.NewRequestWithContext(h.ctx, "DELETE", url, nil)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	resp, err := h.client.Do(request)
+	resp, err := h.do(request)
+	// Non nil error can be caused by network connectivity related
+	// problems, the resp body will nil. Returning 500 for such cases.
 	if err != nil {
-		return resp.StatusCode, err
+		return http.StatusInternalServerError, err
 	}
 	defer resp.Body.Close()
 
@@ -584,11 +662,11 @@ func (h *OrchestrationHandler) apiDel(url string, statusKey string) (interface{}
 func (h *OrchestrationHandler) apiPost(jsonLoad []byte, url string, statusKey string) (interface{}, error) {
 	h.InitializeResponseMap()
 	// prepare and POST API
-	request, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonLoad))
+	request, err := http.NewRequestWithContext(h.ctx, "POST", url, bytes.NewBuffer(jsonLoad))
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	resp, err := h.client.Do(request)
+	resp, err := h.do(request)
 	// Non nil error can be caused by network connectivity related
 	// problems, the resp body will nil. Returning 500 for such cases.
 	if err != nil {
@@ -629,7 +707,7 @@ func (h *OrchestrationHandler) apiPostMultipart(jsonLoad []byte,
 	// It returns io.Writer
 	for i, fileName := range fileNames {
 		var fileWriter io.Writer
-		if h.Vars["multipartfiles"] != "true" {
+		if !h.state.multipartFiles {
 			fileWriter, err = multiPartWriter.CreateFormFile("file", fileNames[0])
 		} else {
 			fileWriter, err = multiPartWriter.CreateFormFile("files", fileName)
@@ -667,18 +745,18 @@ func (h *OrchestrationHandler) apiPostMultipart(jsonLoad []byte,
 
 	// By now our original request body should have been populated,
 	// so let's just use it with our custom request
-	req, err := http.NewRequest("POST", url, &requestBody)
+	req, err := http.NewRequestWithContext(h.ctx, "POST", url, &requestBody)
 	if err != nil {
-		log.WithError(err).Errorf("%s(): Failed to create new POST request", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s(): Failed to create new POST request", PrintFunctionName())
 		return nil, err
 	}
 	// We need to set the content type from the writer, it includes necessary boundary as well
 	req.Header.Set("Content-Type", multiPartWriter.FormDataContentType())
 
 	// Do the request
-	resp, err := h.client.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
-		log.Error(err)
+		h.Logger.Error(err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -697,21 +775,22 @@ func (h *OrchestrationHandler) prepTreeReq() {
 	h.treeFilter.compositeAppName = h.Vars["compositeAppName"]
 	h.treeFilter.compositeAppVersion = h.Vars["version"]
 	h.treeFilter.digName = h.Vars["deploymentIntentGroupName"]
-	h.treeFilter.compositeAppMultiPart, _ = strconv.ParseBool(h.Vars["multipart"])
+	h.treeFilter.compositeAppMultiPart = h.state.multipart
 }
 
 // DelDig Delete the deployment intent group tree
 func (h *OrchestrationHandler) DelDig(w http.ResponseWriter, r *http.Request) {
-	h.Vars = mux.Vars(r)
+	req := newDigRequest(r)
+	h.Vars = req.vars()
 	filter := r.URL.Query().Get("operation")
 
 	var originalVersion string
 	var retCode int
 	if filter == "deleteAll" {
-		digInfo := h.FetchDIGInfo(h.Vars["deploymentIntentGroupName"])
+		digInfo := h.FetchDIGInfo(req.Dig)
 
 		for _, version := range digInfo.VersionList {
-			h.Vars["version"] = version
+			h.Vars = req.atVersion(version).vars()
 			retCode, _ = h.DeleteDig(filter)
 			if retCode != http.StatusNoContent {
 				w.WriteHeader(retCode)
@@ -723,6 +802,13 @@ func (h *OrchestrationHandler) DelDig(w http.ResponseWriter, r *http.Request) {
 		h.DeleteDIGInfo()
 		// retCode, _ = h.DeleteDig(filter) //  FIXME
 	} else {
+		// Discarding a checkout ends its lease
+		if filter == "local" {
+			if err := h.inTransaction(h.releaseLease); err != nil {
+				h.replyLeaseError(w, err)
+				return
+			}
+		}
 		retCode, originalVersion = h.DeleteDig(filter)
 		if retCode != http.StatusNoContent {
 			w.WriteHeader(retCode)
@@ -752,18 +838,18 @@ func (h *OrchestrationHandler) DelSvc(w http.ResponseWriter, r *http.Request) er
 	if err != nil {
 		return err
 	}
-	log.Infof("tree %+v\n", h.dataRead)
+	h.Logger.Infof("tree %+v\n", h.dataRead)
 	// Check if a dig is present in this composite application
 	if len(h.dataRead.compositeAppMap[h.Vars["compositeAppName"]+"-"+h.Vars["version"]].DigMap) != 0 {
 		w.WriteHeader(http.StatusConflict)
-		if _, err := w.Write([]byte("Non emtpy DIG in service\n")); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		if _, err := w.Write([]byte("Non empty DIG in service\n")); err != nil {
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
-		return fmt.Errorf("Non emtpy DIG in service")
+		return fmt.Errorf("Non empty DIG in service")
 	}
 
 	// 1. Call Service delete workflow
-	log.Info("Start Service delete workflow")
+	h.Logger.Info("Start Service delete workflow")
 	deleteDataPoints := []string{
 		"ProfileHandler",
 		"compAppHandler",
@@ -781,53 +867,45 @@ func (h *OrchestrationHandler) DelSvc(w http.ResponseWriter, r *http.Request) er
 	return nil
 }
 
-// GetDigStatus Get DIG Status
-func (h *OrchestrationHandler) GetDigStatus(w http.ResponseWriter, r *http.Request) {
-	h.Vars = mux.Vars(r)
+// DigStatus returns the deployment status of the DIG, and whether it is
+// checked out and in which version
+func (h *OrchestrationHandler) DigStatus(req digRequest) (*digStatus, error) {
+	h.Vars = req.vars()
 	h.InitializeResponseMap()
 	// Get the DIG detailed status
 	temp := &remoteStoreDigHandler{}
 	temp.orchInstance = h
-	thisDigStatus, err := temp.getStatus(h.Vars["compositeAppName"],
-		h.Vars["version"], h.Vars["deploymentIntentGroupName"])
+	thisDigStatus, err := temp.getStatus(req.CompositeApp, req.Version, req.Dig)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	} else {
-		h.DigStatusJSON = &thisDigStatus
-		log.Infof("status %+v\n", h.DigStatusJSON)
-		log.Infof("data  %+v\n", h.dataRead)
-
-		// Fetch all versions for a given composite application
-		retCode, versionList := h.GetCompAppVersions("")
-		if retCode != http.StatusOK {
-			w.WriteHeader(retCode)
-			return
-		}
+		return nil, err
+	}
+	h.DigStatusJSON = &thisDigStatus
+	h.Logger.Infof("status %+v\n", h.DigStatusJSON)
+	h.Logger.Infof("data  %+v\n", h.dataRead)
 
-		localDigStore := localStoreDigHandler{}
-		for _, version := range versionList {
-			_, err := localDigStore.getDig(h.Vars["projectName"],
-				h.Vars["compositeAppName"], version, h.Vars["deploymentIntentGroupName"])
-			if err == nil {
-				thisDigStatus.IsCheckedOut = true
-				h.DigStatusJSON.TargetVersion = version
-				break
-			}
-		}
+	// Fetch all versions for a given composite application
+	retCode, versionList := h.GetCompAppVersions(compositeAppVersionsRequest{Project: req.Project, CompositeApp: req.CompositeApp})
+	if retCode != http.StatusOK {
+		return nil, &apiError{code: retCode, msg: "failed to list the versions of composite app " + req.CompositeApp}
+	}
 
-		// copy dig tree
-		if len(h.DigStatusJSON.Apps) != 0 {
-			h.copyNwToStatus()
-			log.Infof("Desc %s", h.DigStatusJSON.Apps[0].Description)
+	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
+	for _, version := range versionList {
+		_, err := localDigStore.getDig(req.Project, req.CompositeApp, version, req.Dig)
+		if err == nil {
+			thisDigStatus.IsCheckedOut = true
+			h.DigStatusJSON.TargetVersion = version
+			break
 		}
 	}
-	retval, _ := json.Marshal(h.DigStatusJSON)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+
+	// copy dig tree
+	if len(h.DigStatusJSON.Apps) != 0 {
+		h.copyNwToStatus()
+		h.Logger.Infof("Desc %s", h.DigStatusJSON.Apps[0].Description)
 	}
+	return h.DigStatusJSON, nil
 }
 
 // GetDigInEdit get all the deployment intents groups by iterating all composite apps in a project
@@ -858,15 +936,18 @@ func (h *OrchestrationHandler) GetDigInEdit(w http.ResponseWriter, r *http.Reque
 	// copy dig tree
 	err = h.copyDigTreeNew()
 	if err != nil {
-		log.Errorf("Error encountered during checkout of DIG: %s", h.Vars["deploymentIntentGroupName"])
+		h.Logger.Errorf("Error encountered during checkout of DIG: %s", h.Vars["deploymentIntentGroupName"])
 		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
 	retval, _ := json.Marshal(h.guiDigViewJSON)
+	if lease, err := h.readLease(); err == nil && lease != nil {
+		w.Header().Set("ETag", lease.ETag)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		return err
 	}
 	return nil
@@ -899,7 +980,7 @@ func (h *OrchestrationHandler) GetAllDigs(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
@@ -911,28 +992,28 @@ func (h *OrchestrationHandler) GetDraftCompositeApplication(key DraftCompositeAp
 	if key != (DraftCompositeAppKey{}) {
 		jsonLoad, err = json.Marshal(key)
 		if err != nil {
-			log.Errorf("Marshalling of draft composite app key failed: %s", err)
+			h.Logger.Errorf("Marshalling of draft composite app key failed: %s", err)
 			return nil, err
 		}
 	}*/
 
-	exists := db.DBconn.CheckCollectionExists(h.MiddleendConf.StoreName)
+	exists := db.DBconn.CheckCollectionExists(h.ctx, h.MiddleendConf.StoreName)
 	if exists {
-		values, err := db.DBconn.Find(h.MiddleendConf.StoreName, key, "appmetadata")
+		values, err := db.DBconn.Find(h.ctx, h.MiddleendConf.StoreName, key, "appmetadata")
 		if err != nil {
-			log.Errorf("Encountered error while fetching draft composite application: %s", err)
+			h.Logger.Errorf("Encountered error while fetching draft composite application: %s", err)
 			return nil, err
 		} else if len(values) == 0 {
-			log.Infof("Draft composite applications does not exists")
+			h.Logger.Infof("Draft composite applications does not exists")
 		}
 
 		for _, value := range values {
 			ca := CompositeAppsInProject{}
 
 			err = db.DBconn.Unmarshal(value, &ca)
-			log.Debugf("Draft composite app after Unmarshalling: %v", ca)
+			h.Logger.Debugf("Draft composite app after Unmarshalling: %v", ca)
 			if err != nil {
-				log.Errorf("Unmarshalling composite app failed: %s", err)
+				h.Logger.Errorf("Unmarshalling composite app failed: %s", err)
 				return nil, err
 			}
 
@@ -952,63 +1033,62 @@ func (h *OrchestrationHandler) GetDraftCompositeApplication(key DraftCompositeAp
 // GetSvc get the entire tree under project/<composite app>/<version> for a given composite app
 // or fetches all composite apps under project
 func (h *OrchestrationHandler) GetSvc(w http.ResponseWriter, r *http.Request) {
-	h.Vars = mux.Vars(r)
+	req := newCompositeAppsRequest(r)
 	h.treeFilter = nil
 	h.InitializeResponseMap()
-	filter := r.URL.Query().Get("filter")
-	status := r.URL.Query().Get("status")
-	if filter != "" && filter != "depthAll" {
-		log.Errorf("Invalid query argument provided")
+	if req.Filter != "" && req.Filter != "depthAll" {
+		h.Logger.Errorf("Invalid query argument provided")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	// if any invalid app status is passed, ignore that
-	if status != "" && status != "created" && status != "checkout" {
-		status = ""
+	if req.Status != "" && req.Status != "created" && req.Status != "checkout" {
+		req.Status = ""
 	}
 
-	retCode, retval := h.GetCompApps(filter, status)
+	retCode, retval := h.GetCompApps(req)
 	if retCode != http.StatusOK {
-		log.Errorf("Ecnountered error while fetching composite apps")
+		h.Logger.Errorf("Ecnountered error while fetching composite apps")
 		w.WriteHeader(retCode)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
+// GetCompositeAppData returns the status of version v1 of a composite
+// application
 func (h *OrchestrationHandler) GetCompositeAppData(AppName string, projectName string, filter string, status string) (string, int, interface{}) {
 	var objmap map[string]interface{}
-	h.Vars = make(map[string]string)
-
-	h.Vars["compositeAppName"] = AppName
-	h.Vars["projectName"] = projectName
-	h.Vars["version"] = "v1"
-	compositeAppName := h.Vars["compositeAppName"]
 
 	if filter != "" && filter != "depthAll" {
-		log.Errorf("Invalid query argument provided")
+		h.Logger.Errorf("Invalid query argument provided")
 		return "nil", 0, "nil"
 	}
 
-	retCode, retval := h.GetCompApps("", "")
+	retCode, retval := h.GetCompApps(compositeAppsRequest{Project: projectName, CompositeApp: AppName, Version: "v1"})
 	if retCode != http.StatusOK {
-		log.Errorf("Ecnountered error while fetching composite apps")
+		h.Logger.Errorf("Ecnountered error while fetching composite apps")
 		return "nil", retCode, objmap["status"]
 	}
 	_ = json.Unmarshal(retval, &objmap)
-	log.Infof("composite:%v", objmap)
-	h.Vars["compositeAppName"] = ""
+	h.Logger.Infof("composite:%v", objmap)
 
-	return compositeAppName, retCode, objmap["status"]
+	return AppName, retCode, objmap["status"]
 }
 
-func (h *OrchestrationHandler) GetCompApps(filter string, status string) (int, []byte) {
+// GetCompApps reads the composite applications of the request, the route
+// variables of the handler are restored after
+func (h *OrchestrationHandler) GetCompApps(req compositeAppsRequest) (int, []byte) {
 	var retval []byte
 	var err error
+	vars := h.Vars
+	h.Vars = req.vars()
+	defer func() { h.Vars = vars }()
+	filter, status := req.Filter, req.Status
 	bstore := &remoteStoreIntentHandler{}
 	bstore.orchInstance = h
 	h.bstore = bstore
@@ -1044,7 +1124,7 @@ func (h *OrchestrationHandler) GetCompApps(filter string, status string) (int, [
 		}
 	}
 	if err != nil {
-		log.Errorf("Marshalling of CompositeAppReturnJSONShrunk failed: %s", err)
+		h.Logger.Errorf("Marshalling of CompositeAppReturnJSONShrunk failed: %s", err)
 		retval = []byte("some error occurred")
 		return http.StatusInternalServerError, retval
 	}
@@ -1052,155 +1132,76 @@ func (h *OrchestrationHandler) GetCompApps(filter string, status string) (int, [
 }
 
 func (h *OrchestrationHandler) rollBackApp() {
+	if retcode := h.rollBackAppData("ProfileHandler", "compAppHandler"); retcode != nil {
+		return
+	}
+	h.Logger.Infof("Rollback suucessful")
+}
+
+// rollBackAppData reads the composite application in h.Vars and deletes
+// the given data points of it.
+func (h *OrchestrationHandler) rollBackAppData(deleteDataPoints ...string) interface{} {
 	dataPoints := []string{"projectHandler", "compAppHandler", "ProfileHandler"}
 	h.treeFilter = &treeTraverseFilter{}
 	h.treeFilter.compositeAppName = h.Vars["compositeAppName"]
 	h.treeFilter.compositeAppVersion = h.Vars["version"]
 
 	h.dataRead = &ProjectTree{}
-	/*
-		retcode := h.constructTree(dataPoints)
-		if retcode != nil {
-			return
-		}
-	*/
-	_ = h.constructTree(dataPoints)
-	log.Infof("tree %+v\n", h.dataRead)
-	// 1. Call rollback workflow
-	log.Infof("Start rollback workflow")
-	deleteDataPoints := []string{
-		"ProfileHandler",
-		"compAppHandler",
+	if err := h.constructTree(dataPoints); err != nil {
+		h.Logger.WithError(err).Warnf("%s(): Failed to read composite app for rollback", PrintFunctionName())
 	}
-	retcode := h.deleteTree(deleteDataPoints)
-	if retcode != nil {
-		return
-	}
-	log.Infof("Rollback suucessful")
+	h.Logger.Infof("tree %+v\n", h.dataRead)
+	// 1. Call rollback workflow
+	h.Logger.Infof("Start rollback workflow")
+	return h.deleteTree(deleteDataPoints)
 }
 
 // CreateApp Creates all applications and uploaded profiles for a composite application
 func (h *OrchestrationHandler) CreateApp(w http.ResponseWriter, r *http.Request) {
-	var jsonData deployServiceData
-	h.Vars = mux.Vars(r)
-
-	// upto 16M of request data stored in memory, rest will go temp files on disk
-	err := r.ParseMultipartForm(16777216)
-	if err != nil {
-		log.WithError(err).Errorf("%s(): Failed to parse multipart form", PrintFunctionName())
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	// Populate the multipart.FileHeader MAP. The key will be the
-	// filename itself. The metadata Map will be keyed on the application
-	// name. The metadata has a field file name, so later we can parse the metadata
-	// Map, and fetch the file headers from this file Map with keys as the filename.
-	h.file = make(map[string]*multipart.FileHeader)
-	for _, v := range r.MultipartForm.File {
-		fh := v[0]
-		h.file[fh.Filename] = fh
-	}
-
-	jsn := []byte(r.FormValue("servicePayload"))
-	err = json.Unmarshal(jsn, &jsonData)
+	req, err := newCreateAppRequest(r)
 	if err != nil {
-		log.WithError(err).Errorf("%s(): Failed to parse service payload json", PrintFunctionName())
-		w.WriteHeader(http.StatusBadRequest)
+		h.Logger.WithError(err).Errorf("%s(): Invalid request", PrintFunctionName())
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	h.Vars["compositeAppName"] = strings.TrimSpace(jsonData.Name)
-	h.Vars["description"] = jsonData.Description
-	h.Vars["projectName"] = jsonData.Spec.ProjectName
-	h.meta = jsonData.Spec.Apps
-	h.Vars["version"] = "v1"
-
-	// Sanity check. For each metadata there should be a
-	// corresponding file in the multipart request. If it
-	// is not found we fail this API call.
-	for i := range h.meta {
-		switch {
-		case h.file[h.meta[i].Metadata.FileName] == nil:
-			t := fmt.Sprintf("File %s not in request", h.meta[i].Metadata.FileName)
-			w.WriteHeader(http.StatusBadRequest)
-			if _, err := w.Write([]byte(t)); err != nil {
-				log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-			}
-			log.Errorf("%s(): app file not found\n", PrintFunctionName())
-			return
-		case h.file[h.meta[i].ProfileMetadata.FileName] == nil:
-			t := fmt.Sprintf("File %s not in request", h.meta[i].ProfileMetadata.FileName)
-			w.WriteHeader(http.StatusBadRequest)
-			if _, err := w.Write([]byte(t)); err != nil {
-				log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-			}
-			log.Errorf("%s(): profile file not found\n", PrintFunctionName())
-			return
-		default:
-			log.WithFields(log.Fields{
-				"project":          h.Vars["projectName"],
-				"compositeAppName": h.Vars["compositeAppName"],
-			}).Infof("%s(): Request to create service", PrintFunctionName())
-		}
-	}
-
-	h.client = http.Client{}
+	h.Vars = req.vars()
+	h.state.description = req.Description
+	h.meta = req.Apps
+	h.file = req.Files
+	h.Logger.WithFields(log.Fields{
+		"project":          req.Project,
+		"compositeAppName": req.CompositeApp,
+	}).Infof("%s(): Request to create service", PrintFunctionName())
 
 	// These maps will get populated by the return status and responses of each V2 API
 	// that is called during the execution of the workflow.
 	h.InitializeResponseMap()
 
-	// 1. create the composite application. the compAppHandler implements the
-	// orchWorkflow interface.
-	appHandler := &compAppHandler{}
-	appHandler.orchInstance = h
-	httpErr := createCompositeapp(appHandler)
+	// 1. create the composite application and 2. its profiles. A failure
+	// deletes what was created so far and replies the per step report.
+	report, httpErr := newSaga("createApp", h).
+		addWorkflow("compAppHandler", func() interface{} {
+			return h.rollBackAppData("ProfileHandler", "compAppHandler")
+		}, "").
+		addWorkflow("ProfileHandler", func() interface{} {
+			return h.rollBackAppData("ProfileHandler")
+		}, "").
+		run()
 	if httpErr != nil {
-		h.rollBackApp()
-		if intval, ok := httpErr.(int); ok {
-			log.Errorf("%s(): CreateCompositeapp failed with error : %d", PrintFunctionName(), intval)
-			w.WriteHeader(intval)
-		} else {
-			log.Errorf("%s(): Encountered error for CreateCompositeapp", PrintFunctionName())
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
-		if _, err := w.Write([]byte(errMsg)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-		}
-		return
-	}
-
-	// 2. create the composite application profiles
-	profileHandler := &ProfileHandler{}
-	profileHandler.orchInstance = h
-	httpErr = createProfile(profileHandler)
-	if httpErr != nil {
-		h.rollBackApp()
-		if intval, ok := httpErr.(int); ok {
-			log.Errorf("%s(): CreateProfile failed with error : %d", PrintFunctionName(), intval)
-			w.WriteHeader(intval)
-		} else {
-			log.Errorf("%s(): Encountered error for CreateProfile", PrintFunctionName())
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
-		if _, err := w.Write([]byte(errMsg)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-		}
+		h.Logger.Errorf("%s(): CreateApp failed with error : %v", PrintFunctionName(), httpErr)
+		writeSagaFailure(w, report, httpErr)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 
-	if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_compapp"]); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	if _, err := w.Write(h.response.payload[req.CompositeApp+"_compapp"]); err != nil {
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
 func (h *OrchestrationHandler) DIGApprove(namespace string, appname string, digname string) interface{} {
-	url := "http://" + h.MiddleendConf.OrchService + "/v2/projects/" + namespace +
+	url := h.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + namespace +
 		"/composite-apps/" + appname + "/v1/deployment-intent-groups/" + digname + "/approve"
 
 	var payload []byte
@@ -1212,12 +1213,12 @@ func (h *OrchestrationHandler) DIGApprove(namespace string, appname string, dign
 	if resp != http.StatusAccepted {
 		return resp
 	}
-	log.Infof("Call Approve the Service Instance response: %d", resp)
+	h.Logger.Infof("Call Approve the Service Instance response: %d", resp)
 	return nil
 }
 
 func (h *OrchestrationHandler) DIGInstantiate(namespace string, appname string, digname string) interface{} {
-	url := "http://" + h.MiddleendConf.OrchService + "/v2/projects/" + namespace +
+	url := h.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + namespace +
 		"/composite-apps/" + appname + "/v1/deployment-intent-groups/" + digname + "/instantiate"
 	var payload []byte
 	resp, err := h.apiPost(payload, url, "")
@@ -1227,14 +1228,14 @@ func (h *OrchestrationHandler) DIGInstantiate(namespace string, appname string,
 	if resp != http.StatusAccepted {
 		return resp
 	}
-	log.Infof("Call Instantiate the Service Instance response: %d", resp)
+	h.Logger.Infof("Call Instantiate the Service Instance response: %d", resp)
 	return nil
 }
 
 func (h *OrchestrationHandler) createCluster(filename string, fh *multipart.FileHeader, clusterName string,
 	jsonData ClusterMetadata,
 ) interface{} {
-	url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" + clusterName + "/clusters"
+	url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + clusterName + "/clusters"
 	jsonLoad, _ := json.Marshal(jsonData)
 
 	var fileNames []string
@@ -1248,7 +1249,7 @@ func (h *OrchestrationHandler) createCluster(filename string, fh *multipart.File
 	if status != http.StatusCreated {
 		return status
 	}
-	log.Infof("cluster creation %s status: %d", clusterName, status)
+	h.Logger.Infof("cluster creation %s status: %d", clusterName, status)
 	return nil
 }
 
@@ -1256,7 +1257,7 @@ func (h *OrchestrationHandler) CheckConnection(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	parseErr := r.ParseMultipartForm(16777216)
 	if parseErr != nil {
-		log.Errorf("multipart error: %s", parseErr.Error())
+		h.Logger.Errorf("multipart error: %s", parseErr.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1267,7 +1268,7 @@ func (h *OrchestrationHandler) CheckConnection(w http.ResponseWriter, r *http.Re
 	}
 	file, err := fh.Open()
 	if err != nil {
-		log.Errorf("Failed to open the file: %s", err.Error())
+		h.Logger.Errorf("Failed to open the file: %s", err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1280,20 +1281,20 @@ func (h *OrchestrationHandler) CheckConnection(w http.ResponseWriter, r *http.Re
 	jsn := []byte(r.FormValue("metadata"))
 	err = json.Unmarshal(jsn, &jsonData)
 	if err != nil {
-		log.Errorf("Failed to parse json")
+		h.Logger.Errorf("Failed to parse json")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	log.Infof("metadata %+v\n", jsonData)
+	h.Logger.Infof("metadata %+v\n", jsonData)
 
 	// RESTConfigFromKubeConfig is a convenience method to give back
 	// a restconfig from your kubeconfig bytes.
 	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
 	if err != nil {
-		log.Errorf("Error while reading the kubeconfig: %s", err.Error())
+		h.Logger.Errorf("Error while reading the kubeconfig: %s", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		if _, err := w.Write([]byte("Invalid configuration: Cluster has no server defined\n")); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
 		return
 	}
@@ -1301,25 +1302,26 @@ func (h *OrchestrationHandler) CheckConnection(w http.ResponseWriter, r *http.Re
 	// create the clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		log.Errorf("Failed to create clientset: %s", err.Error())
+		h.Logger.Errorf("Failed to create clientset: %s", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		if _, err := w.Write([]byte("Invalid configuration: Cluster has no server defined\n")); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
 		return
 	}
 
+	checked := h.opStep("checkConnectivity")
 	_, err = clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	checked(err)
 	if err != nil {
-		log.Errorf("Failed to establish the connection: %s", err.Error())
+		h.Logger.Errorf("Failed to establish the connection: %s", err.Error())
 		w.WriteHeader(http.StatusForbidden)
 		if _, err := w.Write([]byte("Cluster connectivity failed x509 certificate signed by unknown authority\n")); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
 		return
 	}
-	log.Infof("Successfully established the connection")
-	h.client = http.Client{}
+	h.Logger.Infof("Successfully established the connection")
 	h.InitializeResponseMap()
 
 	// Update cluster creation payload to include gitOps information if gitEnabled flag is set
@@ -1328,78 +1330,93 @@ func (h *OrchestrationHandler) CheckConnection(w http.ResponseWriter, r *http.Re
 		jsonData.Spec.GitOps.GitOpsRefObject = "GitObjectMyRepo"
 		jsonData.Spec.GitOps.GitOpsResObject = "GitObjectMyRepo"
 	}
+	created := h.opStep("createCluster")
 	status := h.createCluster(fh.Filename, fh, vars["cluster-provider-name"], jsonData)
 	if status != nil {
+		created(fmt.Errorf("cluster creation failed with status %v: %s", status, h.response.payload[vars["cluster-provider-name"]]))
 		w.WriteHeader(status.(int))
 		if _, err := w.Write(h.response.payload[vars["cluster-provider-name"]]); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
-		log.WithFields(log.Fields{
+		h.Logger.WithFields(log.Fields{
 			"Statuscode": status,
 			"status":     h.response.statusMsg,
 		}).Error(h.response.statusMsg)
 		return
 	}
+	created(nil)
 	// Below Writeheader is for cluster payload.
 	w.WriteHeader(http.StatusCreated)
 	if _, err := w.Write(h.response.payload[vars["cluster-provider-name"]]); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 
 	clusterprovider := vars["cluster-provider-name"]
-	// Below rw variable has been created for http.ResponseWriter handle for creating Logical cloud and DIG for app monitor-agent and istio agent.
-	rw := httptest.NewRecorder()
+	// The logical cloud and the DIGs for the monitor agent and istio apps reply on
+	// recorders of their own, their failures are reported as steps of the operation.
 	AppnameMon, retcodeMon, retvalMon := h.GetCompositeAppData("MonitorApp", "amcop-system", "", "")
 	AppnameIsops, retcodeIsops, retvalIsops := h.GetCompositeAppData("IstioOperatorApp", "amcop-system", "", "")
 	AppnameIsprofile, retcodeIsprofile, retvalIsprofile := h.GetCompositeAppData("IstioProfileApp", "amcop-system", "", "")
+	// The DIGs of the apps are created in the amcop-system project
+	h.Vars = compositeAppsRequest{Project: "amcop-system", Version: "v1"}.vars()
 
 	// Creating the Logical Cloud
 	if retvalMon == "created" || retvalIsops == "created" {
-		log.Infof("Creating the LC for Apps")
-		lcResult := h.CreateAmcopSystemLogicalCloud(rw, clusterprovider, jsonData)
-		if !lcResult {
-			log.Error("Logical Cloud Creation Failed..")
+		h.Logger.Infof("Creating the LC for Apps")
+		done := h.opStep("createLogicalCloud")
+		rw := httptest.NewRecorder()
+		if !h.CreateAmcopSystemLogicalCloud(rw, clusterprovider, jsonData) {
+			h.Logger.Error("Logical Cloud Creation Failed..")
+			done(recorderError(rw, "logical cloud creation failed"))
 		} else {
-			// op := h.GetLogicalCloudsStatus()
-			op := false
-			if op {
-				log.Info("Logicalcloud is in instantiate state...")
-			}
+			done(nil)
 		}
 	} else {
-		log.Info("Monitor App & Istio App is not created by amcop-operator skipping LC Creation..")
+		h.opSkip("createLogicalCloud", "Monitor App & Istio App is not created by amcop-operator")
 	}
 
 	// Creating the Monitor Service DIG
 	if retcodeMon == http.StatusOK && retvalMon == "created" && !jsonData.Spec.GitEnabled {
-		log.Infof("Creating the DIG for App: %s", AppnameMon)
-		monResult := h.DeployMonitorService(rw, AppnameMon, "amcop-system", clusterprovider, jsonData)
-		if !monResult {
-			log.Error("Monitor Service Orchestration Failed..")
+		h.Logger.Infof("Creating the DIG for App: %s", AppnameMon)
+		done := h.opStep("deployMonitor")
+		rw := httptest.NewRecorder()
+		if !h.DeployMonitorService(rw, AppnameMon, "amcop-system", clusterprovider, jsonData) {
+			h.Logger.Error("Monitor Service Orchestration Failed..")
+			done(recorderError(rw, "monitor service orchestration failed"))
+		} else {
+			done(nil)
 		}
 	} else {
-		log.Info("Monitor App is not created by amcop-operator..skipping")
+		h.opSkip("deployMonitor", "Monitor App is not created by amcop-operator or gitOps is enabled")
 	}
 	// Creating the Istio Operator Service DIG
 	if retcodeIsops == http.StatusOK && retvalIsops == "created" {
-		log.Infof("Creating the DIG for App: %s", AppnameIsops)
-		isopsResult := h.DeployIstioOperator(rw, AppnameIsops, "amcop-system", clusterprovider, jsonData)
-		if !isopsResult {
-			log.Error("Istio Operator Service Orchestration Failed..")
+		h.Logger.Infof("Creating the DIG for App: %s", AppnameIsops)
+		done := h.opStep("deployIstioOperator")
+		rw := httptest.NewRecorder()
+		if !h.DeployIstioOperator(rw, AppnameIsops, "amcop-system", clusterprovider, jsonData) {
+			h.Logger.Error("Istio Operator Service Orchestration Failed..")
+			done(recorderError(rw, "istio operator service orchestration failed"))
+		} else {
+			done(nil)
 		}
 	} else {
-		log.Info("Istio Operator App is not created by amcop-operator..skipping")
+		h.opSkip("deployIstioOperator", "Istio Operator App is not created by amcop-operator")
 	}
 
 	// Creating the Istio Profile Service DIG
 	if retcodeIsprofile == http.StatusOK && retvalIsprofile == "created" {
-		log.Infof("Creating the DIG for App: %s", AppnameIsprofile)
-		isprofileResult := h.DeployIstioProfile(rw, AppnameIsprofile, "amcop-system", clusterprovider, jsonData)
-		if !isprofileResult {
-			log.Error("Istio Profile Service Orchestration Failed..")
+		h.Logger.Infof("Creating the DIG for App: %s", AppnameIsprofile)
+		done := h.opStep("deployIstioProfile")
+		rw := httptest.NewRecorder()
+		if !h.DeployIstioProfile(rw, AppnameIsprofile, "amcop-system", clusterprovider, jsonData) {
+			h.Logger.Error("Istio Profile Service Orchestration Failed..")
+			done(recorderError(rw, "istio profile service orchestration failed"))
+		} else {
+			done(nil)
 		}
 	} else {
-		log.Info("Istio Profile App is not created by amcop-operator..skipping")
+		h.opSkip("deployIstioProfile", "Istio Profile App is not created by amcop-operator")
 	}
 }
 
@@ -1412,20 +1429,20 @@ func (h *OrchestrationHandler) CreateDraftCompositeApp(w http.ResponseWriter, r
 
 	retCode, latestVersion := h.FetchLatestVersion()
 	if retCode != http.StatusOK {
-		log.Errorf("Encountered error while fetching latest version")
+		h.Logger.Errorf("Encountered error while fetching latest version")
 		w.WriteHeader(retCode)
 		return
 	}
 
 	// Checkout of a given composite application is only permitted, if it is the latest version
 	if latestVersion != version {
-		log.Errorf("Checkout of composite application should be for latest version")
+		h.Logger.Errorf("Checkout of composite application should be for latest version")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	h.treeFilter = nil
-	h.Vars["multipart"] = "true"
+	h.state.multipart = true
 
 	dataPoints := []string{"projectHandler", "compAppHandler", "ProfileHandler"}
 	h.prepTreeReq()
@@ -1440,7 +1457,7 @@ func (h *OrchestrationHandler) CreateDraftCompositeApp(w http.ResponseWriter, r
 
 	// Gives original copy of composite application
 	h.copyCompositeAppTree("depthAll")
-	log.Debugf("jsonresponse: %+v", h.CompositeAppReturnJSON)
+	h.Logger.Debugf("jsonresponse: %+v", h.CompositeAppReturnJSON)
 
 	// The logic below creates draft version of composite application, which will be stored in
 	// middleend collection of mco database, for processing by GUI
@@ -1449,7 +1466,7 @@ func (h *OrchestrationHandler) CreateDraftCompositeApp(w http.ResponseWriter, r
 		version := strings.SplitAfter(version, "v")
 		newversion, err := strconv.Atoi(version[1])
 		if err != nil {
-			log.Errorf("Encountered error while processing composite app version: %s", err)
+			h.Logger.Errorf("Encountered error while processing composite app version: %s", err)
 			return
 		}
 
@@ -1463,74 +1480,66 @@ func (h *OrchestrationHandler) CreateDraftCompositeApp(w http.ResponseWriter, r
 			Cversion: h.CompositeAppReturnJSON[index].Spec.Version,
 			Project:  h.Vars["projectName"],
 		}
-		log.Infof("Updated composite app version: %s", h.CompositeAppReturnJSON[index].Spec.Version)
+		h.Logger.Infof("Updated composite app version: %s", h.CompositeAppReturnJSON[index].Spec.Version)
 
 		// Check if composite application for given version already exists
-		log.Debugf("DraftCompositeAppKey: %s", key)
+		h.Logger.Debugf("DraftCompositeAppKey: %s", key)
 		retval, err := h.GetDraftCompositeApplication(key, "")
 		if err != nil {
-			log.Errorf("Encountered error while fetching composite app from middleend collection: %s", err)
+			h.Logger.Errorf("Encountered error while fetching composite app from middleend collection: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		if len(retval) > 0 {
-			log.Infof("Draft Composite application already exists")
+			h.Logger.Infof("Draft Composite application already exists")
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 	}
 
-	err = db.DBconn.Insert(h.MiddleendConf.StoreName, key, nil, "appmetadata", h.CompositeAppReturnJSON[0])
+	err = db.DBconn.Insert(h.ctx, h.MiddleendConf.StoreName, key, nil, "appmetadata", h.CompositeAppReturnJSON[0])
 	if err != nil {
-		log.Errorf("Encountered error during checkout of composite app: %s", err)
+		h.Logger.Errorf("Encountered error during checkout of composite app: %s", err)
 		return
 	}
 	retval, _ := json.Marshal(h.CompositeAppReturnJSON[0])
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
-// GetSvcVersions fetches the list of versions for a given composite application
+// SvcVersions returns the versions of a composite application, those in
+// the state of the request when it names one
 // GET middleend/projects/<projectName>/composite-apps/<compositeAppName>/versions
-func (h *OrchestrationHandler) GetSvcVersions(w http.ResponseWriter, r *http.Request) {
-	h.Vars = mux.Vars(r)
+func (h *OrchestrationHandler) SvcVersions(req compositeAppVersionsRequest) ([]string, error) {
 	h.InitializeResponseMap()
 
-	filter := r.URL.Query().Get("state")
-
-	retCode, versionList := h.GetCompAppVersions(filter)
-	log.Infof("versionList: %s", versionList)
+	retCode, versionList := h.GetCompAppVersions(req)
+	h.Logger.Infof("versionList: %s", versionList)
 	if retCode != http.StatusOK {
-		w.WriteHeader(retCode)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	retval, _ := json.Marshal(versionList)
-	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		return nil, &apiError{code: retCode, msg: "failed to list the versions of composite app " + req.CompositeApp}
 	}
+	return versionList, nil
 }
 
-func (h *OrchestrationHandler) GetCompAppVersions(filter string) (int, []string) {
+// GetCompAppVersions returns the versions of the composite application of
+// the request in its state
+func (h *OrchestrationHandler) GetCompAppVersions(req compositeAppVersionsRequest) (int, []string) {
 	var versionList []string
-	compAppName := h.Vars["compositeAppName"]
-	h.Vars["compositeAppName"] = ""
-	retCode, retval := h.GetCompApps("", "")
+	compAppName, filter := req.CompositeApp, req.State
+	retCode, retval := h.GetCompApps(compositeAppsRequest{Project: req.Project})
 	if retCode != http.StatusOK {
-		log.Errorf("Encountered error while fetching composite apps")
+		h.Logger.Errorf("Encountered error while fetching composite apps")
 		return http.StatusInternalServerError, versionList
 	}
 
 	var compArray []CompositeAppsInProjectShrunk
 	_ = json.Unmarshal(retval, &compArray)
 
-	log.Infof("composite:%v", compArray)
+	h.Logger.Infof("composite:%v", compArray)
 
 	for _, comApp := range compArray {
 		if comApp.Metadata.Name == compAppName {
@@ -1546,7 +1555,6 @@ func (h *OrchestrationHandler) GetCompAppVersions(filter string) (int, []string)
 			break
 		}
 	}
-	h.Vars["compositeAppName"] = compAppName
 	return http.StatusOK, versionList
 }
 
@@ -1560,7 +1568,7 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 
 	err := r.ParseMultipartForm(16777216)
 	if err != nil {
-		log.Errorf("Failed to parse multi part: %s", err)
+		h.Logger.Errorf("Failed to parse multi part: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 
@@ -1575,7 +1583,7 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 	}
 
 	if err != nil {
-		log.Errorf("Failed to parse json: %s", err)
+		h.Logger.Errorf("Failed to parse json: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 
@@ -1583,9 +1591,9 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 		t := fmt.Sprintf("File %s not in request", jsonData.Metadata.FileName)
 		w.WriteHeader(http.StatusBadRequest)
 		if _, err := w.Write([]byte(t)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
-		log.Error("app file not found")
+		h.Logger.Error("app file not found")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1593,9 +1601,9 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 		t := fmt.Sprintf("File %s not in request", jsonData.ProfileMetadata.FileName)
 		w.WriteHeader(http.StatusBadRequest)
 		if _, err := w.Write([]byte(t)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
-		log.Error("profile file not found")
+		h.Logger.Error("profile file not found")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1604,7 +1612,7 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 	// Open the file
 	file, err := h.file[jsonData.Metadata.FileName].Open()
 	if err != nil {
-		log.Errorf("Encountered error while processing multipart file")
+		h.Logger.Errorf("Encountered error while processing multipart file")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1615,18 +1623,18 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 	var appBuff bytes.Buffer
 	_, err = io.Copy(&appBuff, file)
 	if err != nil {
-		log.WithError(err).Errorf("%s(): Failed to copy helm chart", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s(): Failed to copy helm chart", PrintFunctionName())
 		return
 	}
 	newApp.Metadata.ChartContent = base64.StdEncoding.EncodeToString(appBuff.Bytes())
 
-	log.Debugf("newApp is : %s", newApp)
+	h.Logger.Debugf("newApp is : %s", newApp)
 
 	newProfile.Metadata.Name = strings.TrimSpace(jsonData.ProfileMetadata.Name)
 	// Open the file
 	file, err = h.file[jsonData.ProfileMetadata.FileName].Open()
 	if err != nil {
-		log.WithError(err).Errorf("%s(): Encountered error while processing multipart file", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s(): Encountered error while processing multipart file", PrintFunctionName())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1637,14 +1645,14 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 	var profileBuff bytes.Buffer
 	_, err = io.Copy(&profileBuff, file)
 	if err != nil {
-		log.WithError(err).Errorf("%s(): Failed to copy profile data", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s(): Failed to copy profile data", PrintFunctionName())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	newProfile.Metadata.ChartContent = base64.StdEncoding.EncodeToString(profileBuff.Bytes())
 	newProfile.Spec.AppName = newApp.Metadata.Name
 
-	log.Debugf("newProfile is : %s", newProfile)
+	h.Logger.Debugf("newProfile is : %s", newProfile)
 	operation := r.URL.Query().Get("operation")
 
 	var dboperation string
@@ -1653,9 +1661,9 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 	} else {
 		dboperation = "AddApplication"
 	}
-	err = db.DBconn.Update(h.MiddleendConf.StoreName, dboperation, vars, newApp.Metadata.Name, newApp)
+	err = db.DBconn.Update(h.ctx, h.MiddleendConf.StoreName, dboperation, vars, newApp.Metadata.Name, newApp)
 	if err != nil {
-		log.Errorf("Encountered error during update of composite app apps: %s", err)
+		h.Logger.Errorf("Encountered error during update of composite app apps: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1666,9 +1674,9 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 		dboperation = "AddProfile"
 	}
 
-	err = db.DBconn.Update(h.MiddleendConf.StoreName, dboperation, vars, newApp.Metadata.Name, newProfile)
+	err = db.DBconn.Update(h.ctx, h.MiddleendConf.StoreName, dboperation, vars, newApp.Metadata.Name, newProfile)
 	if err != nil {
-		log.Errorf("Encountered error during update of composite app profile: %s", err)
+		h.Logger.Errorf("Encountered error during update of composite app profile: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1676,7 +1684,7 @@ func (h *OrchestrationHandler) UpdateCompositeApp(w http.ResponseWriter, r *http
 	w.WriteHeader(http.StatusCreated)
 	retval, _ := json.Marshal(jsonData)
 	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
@@ -1687,9 +1695,9 @@ func (h *OrchestrationHandler) RemoveApp(w http.ResponseWriter, r *http.Request)
 	h.InitializeResponseMap()
 	dboperations := []string{"DeleteApplication", "DeleteProfile"}
 	for _, dboperation := range dboperations {
-		err := db.DBconn.Update(h.MiddleendConf.StoreName, dboperation, vars, "", "")
+		err := db.DBconn.Update(h.ctx, h.MiddleendConf.StoreName, dboperation, vars, "", "")
 		if err != nil {
-			log.Errorf("Encountered error during removing app in composite app : %s", err)
+			h.Logger.Errorf("Encountered error during removing app in composite app : %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -1711,13 +1719,13 @@ func (h *OrchestrationHandler) CreateService(w http.ResponseWriter, r *http.Requ
 
 	caList, err := h.GetDraftCompositeApplication(key, "depthAll")
 	if err != nil {
-		log.Errorf("Encountered error while fetching composite app from middleend collection: %s", err)
+		h.Logger.Errorf("Encountered error while fetching composite app from middleend collection: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	if len(caList) == 0 {
-		log.Errorf("Draft composite application does not exists, hence service cannot be created")
+		h.Logger.Errorf("Draft composite application does not exists, hence service cannot be created")
 		w.WriteHeader(500)
 		return
 	}
@@ -1733,7 +1741,7 @@ func (h *OrchestrationHandler) CreateService(w http.ResponseWriter, r *http.Requ
 		appData.Metadata.Description = app.Metadata.Description
 		ccBytes, err := base64.StdEncoding.DecodeString(app.Metadata.ChartContent)
 		if err != nil {
-			log.Errorf("Encountered error while decoding filecontent: %s", err)
+			h.Logger.Errorf("Encountered error while decoding filecontent: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -1749,7 +1757,7 @@ func (h *OrchestrationHandler) CreateService(w http.ResponseWriter, r *http.Requ
 					meta[m].ProfileMetadata.Name = appprofile.Metadata.Name
 					ccBytes, err := base64.StdEncoding.DecodeString(appprofile.Metadata.ChartContent)
 					if err != nil {
-						log.Errorf("Encountered error while decoding filecontent: %s", err)
+						h.Logger.Errorf("Encountered error while decoding filecontent: %s", err)
 						w.WriteHeader(http.StatusInternalServerError)
 						return
 					}
@@ -1760,7 +1768,6 @@ func (h *OrchestrationHandler) CreateService(w http.ResponseWriter, r *http.Requ
 	}
 
 	h.meta = meta
-	h.client = http.Client{}
 
 	// 1. create the composite application. the compAppHandler implements the
 	// orchWorkflow interface.
@@ -1770,15 +1777,15 @@ func (h *OrchestrationHandler) CreateService(w http.ResponseWriter, r *http.Requ
 	if httpErr != nil {
 		h.rollBackApp()
 		if intval, ok := httpErr.(int); ok {
-			log.Errorf("CreateCompositeapp failed with error : %d", intval)
+			h.Logger.Errorf("CreateCompositeapp failed with error : %d", intval)
 			w.WriteHeader(intval)
 		} else {
-			log.Infof("Encountered error for CreateCompositeapp")
+			h.Logger.Infof("Encountered error for CreateCompositeapp")
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
 		if _, err := w.Write([]byte(errMsg)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
 		return
 	}
@@ -1790,37 +1797,37 @@ func (h *OrchestrationHandler) CreateService(w http.ResponseWriter, r *http.Requ
 	if httpErr != nil {
 		h.rollBackApp()
 		if intval, ok := httpErr.(int); ok {
-			log.Errorf("CreateProfile failed with error : %d", intval)
+			h.Logger.Errorf("CreateProfile failed with error : %d", intval)
 			w.WriteHeader(intval)
 		} else {
-			log.Errorf("Encountered error for CreateProfile")
+			h.Logger.Errorf("Encountered error for CreateProfile")
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
 		if _, err := w.Write([]byte(errMsg)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 	// Delete draft composite application from middleend collection
-	err = db.DBconn.Delete(h.MiddleendConf.StoreName, h.Vars)
+	err = db.DBconn.Delete(h.ctx, h.MiddleendConf.StoreName, h.Vars)
 	if err != nil {
-		log.Errorf("Encountered error during delete of composite app from middleend collection: %s", err)
+		h.Logger.Errorf("Encountered error during delete of composite app from middleend collection: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_compapp"]); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
-// GetDashboardData get count of total composite-apps, deployment-intent-groups and clusters
-func (h *OrchestrationHandler) GetDashboardData(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	h.Vars = vars
+// Dashboard returns the count of the composite apps, deployment intent
+// groups and clusters of the project
+func (h *OrchestrationHandler) Dashboard(req projectRequest) (DashboardData, error) {
+	h.Vars = req.vars()
 	h.InitializeResponseMap()
 	// create the Dashboard client
 	dStore := &remoteStoreDigHandler{}
@@ -1829,31 +1836,20 @@ func (h *OrchestrationHandler) GetDashboardData(w http.ResponseWriter, r *http.R
 	dashboardClient := DashboardClient{h}
 	retData, retcode := dashboardClient.getDashboardData()
 	if retcode != nil {
-		if intval, ok := retcode.(int); ok {
-			log.Infof("Failed to get dashboard data : %d", intval)
-			w.WriteHeader(intval)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-			errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
-			if _, err := w.Write([]byte(errMsg)); err != nil {
-				log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-			}
-		}
-		return
-	}
-
-	var retval []byte
-	retval, err := json.Marshal(retData)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return retData, h.dashboardError("dashboard data", retcode)
 	}
+	return retData, nil
+}
 
-	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+// dashboardError is the error of a dashboard client call, which returns
+// the status it failed with or nothing but the reply it failed on
+func (h *OrchestrationHandler) dashboardError(what string, retcode interface{}) error {
+	if code, ok := retcode.(int); ok {
+		h.Logger.Infof("Failed to get %s : %d", what, code)
+		return &apiError{code: code, msg: "failed to get " + what}
 	}
+	errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
+	return &apiError{code: http.StatusInternalServerError, msg: errMsg}
 }
 
 func (h *OrchestrationHandler) DeployIstioOperator(w http.ResponseWriter, appname string, namespace string, ClusterName string, jsonData ClusterMetadata) bool {
@@ -1868,7 +1864,7 @@ func (h *OrchestrationHandler) DeployIstioOperator(w http.ResponseWriter, appnam
 	h.InitializeResponseMap()
 
 	// Creating the Service Instance for Monitor App
-	log.Info("Creating the Service Instance for Istio-Operator App")
+	h.Logger.Info("Creating the Service Instance for Istio-Operator App")
 	appdata.Metadata.Name = "istio-operator"
 	appdata.Metadata.Description = "Service Instance for Istio-Operator App"
 	appdata.PlacementCriterion = "allOf"
@@ -1900,23 +1896,25 @@ func (h *OrchestrationHandler) DeployIstioOperator(w http.ResponseWriter, appnam
 	}
 
 	h.DigData = dig
-	log.Debugf("digData: %+v", dig)
+	h.Logger.Debugf("digData: %+v", dig)
 
 	if len(h.DigData.Spec.Apps) == 0 {
-		log.Errorf("Bad request, no app metadata\n with code:%d", http.StatusBadRequest)
+		h.Logger.Errorf("Bad request, no app metadata\n with code:%d", http.StatusBadRequest)
 		Result = false
 	}
 	h.DigData.NwIntents = false
 	h.DigData.DtcIntents = false
 	// Creating the DIG
-	h.createDigData(w, "emco")
+	if !h.createDigData(w, "emco") {
+		return false
+	}
 	h.AddDIGInfo()
 
 	if h.MiddleendConf.AppInstantiate {
 		// Approve the service Instance for Monitor App
 		respAp := h.DIGApprove(namespace, h.DigData.CompositeAppName, h.DigData.Name)
 		if respAp != nil {
-			log.Errorf("Failed to Approve Service Instance for Istio Operator App: %d", respAp)
+			h.Logger.Errorf("Failed to Approve Service Instance for Istio Operator App: %d", respAp)
 			Result = false
 		}
 
@@ -1924,7 +1922,7 @@ func (h *OrchestrationHandler) DeployIstioOperator(w http.ResponseWriter, appnam
 		time.Sleep(50 * time.Millisecond)
 		respIns := h.DIGInstantiate(namespace, h.DigData.CompositeAppName, h.DigData.Name)
 		if respIns != nil {
-			log.Errorf("Failed to Instantiate Service Instance for Istio Operator App: %d", respIns)
+			h.Logger.Errorf("Failed to Instantiate Service Instance for Istio Operator App: %d", respIns)
 			Result = false
 		}
 	}
@@ -1943,7 +1941,7 @@ func (h *OrchestrationHandler) DeployIstioProfile(w http.ResponseWriter, appname
 	h.InitializeResponseMap()
 
 	// Creating the Service Instance for Monitor App
-	log.Info("Creating the Service Instance for Istio-Profile App")
+	h.Logger.Info("Creating the Service Instance for Istio-Profile App")
 	appdata.Metadata.Name = "istio-profile"
 	appdata.Metadata.Description = "Service Instance for Istio-Profile App"
 	appdata.PlacementCriterion = "allOf"
@@ -1975,22 +1973,24 @@ func (h *OrchestrationHandler) DeployIstioProfile(w http.ResponseWriter, appname
 	}
 
 	h.DigData = dig
-	log.Debugf("digData: %+v", dig)
+	h.Logger.Debugf("digData: %+v", dig)
 
 	if len(h.DigData.Spec.Apps) == 0 {
-		log.Errorf("Bad request, no app metadata\n with code:%d", http.StatusBadRequest)
+		h.Logger.Errorf("Bad request, no app metadata\n with code:%d", http.StatusBadRequest)
 		Result = false
 	}
 	h.DigData.NwIntents = false
 	h.DigData.DtcIntents = false
 	// Creating the DIG
-	h.createDigData(w, "emco")
+	if !h.createDigData(w, "emco") {
+		return false
+	}
 	h.AddDIGInfo()
 	if h.MiddleendConf.AppInstantiate {
 		// Approve the service Instance for Monitor App
 		respAp := h.DIGApprove(namespace, h.DigData.CompositeAppName, h.DigData.Name)
 		if respAp != nil {
-			log.Errorf("Failed to Approve Service Instance for Istio Profile App: %d", respAp)
+			h.Logger.Errorf("Failed to Approve Service Instance for Istio Profile App: %d", respAp)
 			Result = false
 		}
 
@@ -1998,7 +1998,7 @@ func (h *OrchestrationHandler) DeployIstioProfile(w http.ResponseWriter, appname
 		time.Sleep(50 * time.Millisecond)
 		respIns := h.DIGInstantiate(namespace, h.DigData.CompositeAppName, h.DigData.Name)
 		if respIns != nil {
-			log.Errorf("Failed to Instantiate Service Instance for Istio Profile App: %d", respIns)
+			h.Logger.Errorf("Failed to Instantiate Service Instance for Istio Profile App: %d", respIns)
 			Result = false
 		}
 	}
@@ -2017,7 +2017,7 @@ func (h *OrchestrationHandler) DeployMonitorService(w http.ResponseWriter, appna
 	h.InitializeResponseMap()
 
 	// Creating the Service Instance for Monitor App
-	log.Info("Creating the Service Instance for Monitor App")
+	h.Logger.Info("Creating the Service Instance for Monitor App")
 	appdata.Metadata.Name = "monitor"
 	appdata.Metadata.Description = "Service Instance for Monitor App"
 	appdata.PlacementCriterion = "allOf"
@@ -2049,22 +2049,24 @@ func (h *OrchestrationHandler) DeployMonitorService(w http.ResponseWriter, appna
 	}
 
 	h.DigData = dig
-	log.Debugf("digData: %+v", dig)
+	h.Logger.Debugf("digData: %+v", dig)
 
 	if len(h.DigData.Spec.Apps) == 0 {
-		log.Errorf("Bad request, no app metadata\n with code:%d", http.StatusBadRequest)
+		h.Logger.Errorf("Bad request, no app metadata\n with code:%d", http.StatusBadRequest)
 		Result = false
 	}
 	h.DigData.NwIntents = false
 	h.DigData.DtcIntents = false
 	// Creating the DIG
-	h.createDigData(w, "emco")
+	if !h.createDigData(w, "emco") {
+		return false
+	}
 	h.AddDIGInfo()
 	if h.MiddleendConf.AppInstantiate {
 		// Approve the service Instance for Monitor App
 		respAp := h.DIGApprove(namespace, h.DigData.CompositeAppName, h.DigData.Name)
 		if respAp != nil {
-			log.Errorf("Failed to Approve Service Instance for Monitor Agent App: %d", respAp)
+			h.Logger.Errorf("Failed to Approve Service Instance for Monitor Agent App: %d", respAp)
 			Result = false
 		}
 
@@ -2072,41 +2074,208 @@ func (h *OrchestrationHandler) DeployMonitorService(w http.ResponseWriter, appna
 		time.Sleep(50 * time.Millisecond)
 		respIns := h.DIGInstantiate(namespace, h.DigData.CompositeAppName, h.DigData.Name)
 		if respIns != nil {
-			log.Errorf("Failed to Instantiate Service Instance for Monitor Agent App: %d", respIns)
+			h.Logger.Errorf("Failed to Instantiate Service Instance for Monitor Agent App: %d", respIns)
 			Result = false
 		}
 	}
 	return Result
 }
 
-// GetClusterNetworks get an a array of all the cluster networks along with their rsync status
-func (h *OrchestrationHandler) GetClusterNetworks(w http.ResponseWriter, r *http.Request) {
+// projectRequest is the request of the APIs of a project
+type projectRequest struct {
+	Project string
+}
+
+func newProjectRequest(r *http.Request) projectRequest {
+	return projectRequest{Project: mux.Vars(r)["projectName"]}
+}
+
+// vars are the route variables of the request, the helpers walking the
+// EMCO objects read them from the handler
+func (req projectRequest) vars() map[string]string {
+	return map[string]string{"projectName": req.Project}
+}
+
+// compositeAppsRequest reads the composite applications of a project, or
+// one of them in a version
+type compositeAppsRequest struct {
+	Project      string
+	CompositeApp string
+	Version      string
+	// Filter depthAll reads the profiles and the DIGs of the applications
+	Filter string
+	// Status filters the applications, created or checkout
+	Status string
+}
+
+func newCompositeAppsRequest(r *http.Request) compositeAppsRequest {
 	vars := mux.Vars(r)
-	h.Vars = vars
+	return compositeAppsRequest{
+		Project:      vars["projectName"],
+		CompositeApp: vars["compositeAppName"],
+		Version:      vars["version"],
+		Filter:       r.URL.Query().Get("filter"),
+		Status:       r.URL.Query().Get("status"),
+	}
+}
+
+func (req compositeAppsRequest) vars() map[string]string {
+	return map[string]string{
+		"projectName":      req.Project,
+		"compositeAppName": req.CompositeApp,
+		"version":          req.Version,
+	}
+}
+
+// createAppRequest is the request of CreateApp: the composite application
+// in version v1 with the chart and the profile files of its apps
+type createAppRequest struct {
+	Project      string
+	CompositeApp string
+	Description  string
+	Apps         []appsData
+	// Files are the uploaded files by file name
+	Files map[string]*multipart.FileHeader
+}
+
+// newCreateAppRequest parses the multipart form of CreateApp, each app has
+// to come with its files
+func newCreateAppRequest(r *http.Request) (createAppRequest, error) {
+	// upto 16M of request data stored in memory, rest will go temp files on disk
+	if err := r.ParseMultipartForm(16777216); err != nil {
+		return createAppRequest{}, fmt.Errorf("failed to parse multipart form: %s", err)
+	}
+
+	var jsonData deployServiceData
+	if err := json.Unmarshal([]byte(r.FormValue("servicePayload")), &jsonData); err != nil {
+		return createAppRequest{}, fmt.Errorf("failed to parse service payload json: %s", err)
+	}
+	req := createAppRequest{
+		Project:      jsonData.Spec.ProjectName,
+		CompositeApp: strings.TrimSpace(jsonData.Name),
+		Description:  jsonData.Description,
+		Apps:         jsonData.Spec.Apps,
+		Files:        make(map[string]*multipart.FileHeader),
+	}
+	for _, v := range r.MultipartForm.File {
+		fh := v[0]
+		req.Files[fh.Filename] = fh
+	}
+	for _, app := range req.Apps {
+		for _, name := range []string{app.Metadata.FileName, app.ProfileMetadata.FileName} {
+			if req.Files[name] == nil {
+				return createAppRequest{}, fmt.Errorf("File %s not in request", name)
+			}
+		}
+	}
+	return req, nil
+}
+
+func (req createAppRequest) vars() map[string]string {
+	return map[string]string{
+		"projectName":      req.Project,
+		"compositeAppName": req.CompositeApp,
+		"version":          "v1",
+	}
+}
+
+// compositeAppVersionsRequest is the request of SvcVersions
+type compositeAppVersionsRequest struct {
+	Project      string
+	CompositeApp string
+	// State filters the versions, e.g. created or checkout
+	State string
+}
+
+func newCompositeAppVersionsRequest(r *http.Request) compositeAppVersionsRequest {
+	vars := mux.Vars(r)
+	return compositeAppVersionsRequest{
+		Project:      vars["projectName"],
+		CompositeApp: vars["compositeAppName"],
+		State:        r.URL.Query().Get("state"),
+	}
+}
+
+// digRequest is the request of the APIs of a DIG
+type digRequest struct {
+	Project      string
+	CompositeApp string
+	Version      string
+	Dig          string
+}
+
+func newDigRequest(r *http.Request) digRequest {
+	vars := mux.Vars(r)
+	return digRequest{
+		Project:      vars["projectName"],
+		CompositeApp: vars["compositeAppName"],
+		Version:      vars["version"],
+		Dig:          vars["deploymentIntentGroupName"],
+	}
+}
+
+// digOf is the request of the DIG the handler is working on
+func (h *OrchestrationHandler) digOf() digRequest {
+	return digRequest{
+		Project:      h.Vars["projectName"],
+		CompositeApp: h.Vars["compositeAppName"],
+		Version:      h.Vars["version"],
+		Dig:          h.Vars["deploymentIntentGroupName"],
+	}
+}
+
+// atVersion is the request of the DIG in another version of its
+// composite application
+func (req digRequest) atVersion(version string) digRequest {
+	req.Version = version
+	return req
+}
+
+// vars are the route variables of the request, the helpers building the
+// intent tree read them from the handler
+func (req digRequest) vars() map[string]string {
+	return map[string]string{
+		"projectName":               req.Project,
+		"compositeAppName":          req.CompositeApp,
+		"version":                   req.Version,
+		"deploymentIntentGroupName": req.Dig,
+	}
+}
+
+// logicalCloudRequest is the request of the APIs of a logical cloud
+type logicalCloudRequest struct {
+	Project      string
+	LogicalCloud string
+}
+
+func newLogicalCloudRequest(r *http.Request) logicalCloudRequest {
+	vars := mux.Vars(r)
+	return logicalCloudRequest{Project: vars["projectName"], LogicalCloud: vars["logicalCloud"]}
+}
+
+// clusterNetworksRequest is the request of ClusterNetworks
+type clusterNetworksRequest struct {
+	ClusterProvider string
+	Cluster         string
+}
+
+func newClusterNetworksRequest(r *http.Request) clusterNetworksRequest {
+	vars := mux.Vars(r)
+	return clusterNetworksRequest{ClusterProvider: vars["clusterprovider-name"], Cluster: vars["cluster-name"]}
+}
+
+// ClusterNetworks returns the networks and provider networks of a cluster
+func (h *OrchestrationHandler) ClusterNetworks(req clusterNetworksRequest) (ConsolidatedStatus, error) {
 	h.InitializeResponseMap()
 	nwhandler := ncmHandler{}
 	nwhandler.orchInstance = h
-	consolidatedStatus, err := nwhandler.getNetworks()
+	consolidatedStatus, err := nwhandler.getNetworks(req.ClusterProvider, req.Cluster)
 	if err != nil {
-		log.Errorf("Failed to get cluster networks : %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		h.Logger.Errorf("Failed to get cluster networks : %s", err)
 		errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
-		if _, err := w.Write([]byte(errMsg)); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-		}
-		return
-	}
-
-	retval, err := json.Marshal(consolidatedStatus)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(retval); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		return consolidatedStatus, &apiError{code: http.StatusInternalServerError, msg: errMsg}
 	}
+	return consolidatedStatus, nil
 }
 
 // DigUpdateHandler update handler
@@ -2132,7 +2301,7 @@ func (h *OrchestrationHandler) DigUpdateHandler(w http.ResponseWriter, r *http.R
 	jsn := []byte(r.FormValue("metadata"))
 	err = json.Unmarshal(jsn, &jsonData)
 	if err != nil {
-		log.Errorf("Failed to parse json: %s", err)
+		h.Logger.Errorf("Failed to parse json: %s", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -2147,56 +2316,78 @@ func (h *OrchestrationHandler) DigUpdateHandler(w http.ResponseWriter, r *http.R
 
 	filter := r.URL.Query().Get("operation")
 	if filter == "save" {
-		bstore := &localStoreIntentHandler{}
-		bstore.orchInstance = h
-		h.bstore = bstore
-		intentHandler := &placementIntentHandler{}
-		intentHandler.orchInstance = h
-		h.Vars["update-intent"] = "yes"
-		intentStatus := intentHandler.createObject()
-		if intentStatus != nil {
-			if intval, ok := intentStatus.(int); ok {
+		// The lease check and the intents of the checkout are saved in one
+		// transaction, a failed save leaves neither the lease nor the
+		// checkout changed
+		h.replyInTransaction(w, func(w http.ResponseWriter) {
+			h.saveCheckout(w, r)
+		})
+	}
+}
+
+// saveCheckout saves the intents of the DIG checkout. Only the holder of
+// the checkout saves it, over the state it read; the ETag of the lease is
+// replied once the save succeeded.
+func (h *OrchestrationHandler) saveCheckout(w http.ResponseWriter, r *http.Request) {
+	lease, err := h.checkLease(r.Header.Get("If-Match"))
+	if err != nil {
+		h.replyLeaseError(w, err)
+		return
+	}
+
+	bstore := &localStoreIntentHandler{}
+	bstore.orchInstance = h
+	h.bstore = bstore
+	intentHandler := &placementIntentHandler{}
+	intentHandler.orchInstance = h
+	h.state.updateIntent = true
+	intentStatus := intentHandler.createObject()
+	if intentStatus != nil {
+		if intval, ok := intentStatus.(int); ok {
+			w.WriteHeader(intval)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_gpint"]); err != nil {
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		}
+		return
+	}
+
+	// If the metadata contains network interface request then call the
+	// network intent related part of the workflow.
+	h.DigData.NwIntents = true // FIXME
+	if h.DigData.NwIntents {
+		nwHandler := &networkIntentHandler{}
+		nwHandler.orchInstance = h
+		nwIntentStatus := nwHandler.createObject()
+		if nwIntentStatus != nil {
+			if intval, ok := nwIntentStatus.(int); ok {
 				w.WriteHeader(intval)
 			} else {
 				w.WriteHeader(http.StatusInternalServerError)
 			}
 
-			if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_gpint"]); err != nil {
-				log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_nwctlint"]); err != nil {
+				h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 			}
 			return
 		}
+	}
 
-		// If the metadata contains network interface request then call the
-		// network intent related part of the workflow.
-		h.DigData.NwIntents = true // FIXME
-		if h.DigData.NwIntents {
-			nwHandler := &networkIntentHandler{}
-			nwHandler.orchInstance = h
-			nwIntentStatus := nwHandler.createObject()
-			if nwIntentStatus != nil {
-				if intval, ok := nwIntentStatus.(int); ok {
-					w.WriteHeader(intval)
-				} else {
-					w.WriteHeader(http.StatusInternalServerError)
-				}
-
-				if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_nwctlint"]); err != nil {
-					log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
-				}
-				return
-			}
-		}
-
-		// If the metadata contains genericK8sIntent info, process the same
-		// Validate and process resource data
-		if !h.processResourceData(w, r) {
-			log.Errorf("Unable to process resource data: %s", h.DigData.Name)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		h.createUpdateK8sResource(w, "")
+	// If the metadata contains genericK8sIntent info, process the same
+	// Validate and process resource data
+	if !h.processResourceData(w, r) {
+		h.Logger.Errorf("Unable to process resource data: %s", h.DigData.Name)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !h.createUpdateK8sResource(w, "") {
+		return
 	}
+	w.Header().Set("ETag", lease.ETag)
+	w.WriteHeader(http.StatusOK)
 }
 
 // CreateDig CreateDig exported function which creates deployment intent group
@@ -2222,7 +2413,7 @@ func (h *OrchestrationHandler) CreateDig(w http.ResponseWriter, r *http.Request)
 	jsn := []byte(r.FormValue("metadata"))
 	err = json.Unmarshal(jsn, &jsonData)
 	if err != nil {
-		log.Errorf("Failed to parse json: %s", err)
+		h.Logger.Errorf("Failed to parse json: %s", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -2237,12 +2428,12 @@ func (h *OrchestrationHandler) CreateDig(w http.ResponseWriter, r *http.Request)
 	}
 
 	h.DigData = jsonData
-	log.Debugf("digData: %+v", jsonData)
+	h.Logger.Debugf("digData: %+v", jsonData)
 
 	if len(h.DigData.Spec.Apps) == 0 {
 		w.WriteHeader(http.StatusBadRequest)
 		if _, err := w.Write([]byte("Bad request, no app metadata\n")); err != nil {
-			log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 		}
 		return
 	}
@@ -2260,20 +2451,20 @@ func (h *OrchestrationHandler) CreateDig(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	h.client = http.Client{}
-
 	// Validate and process resource data
 	if !h.processResourceData(w, r) {
-		log.Errorf("Unable to process resource data part of DIG: %s", h.DigData.Name)
+		h.Logger.Errorf("Unable to process resource data part of DIG: %s", h.DigData.Name)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	h.createDigData(w, "emco")
+	if !h.createDigData(w, "emco") {
+		return
+	}
 
 	h.AddDIGInfo()
 	if _, err := w.Write(h.response.payload[h.DigData.Name]); err != nil {
-		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
 	}
 }
 
@@ -2,7 +2,7 @@ package app
 
 import "net/http"
 
-func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
+func (s *Server) registerCertCPHandlers(handle HandleFunc) {
 	// swagger:route POST /cluster-provider/{clusterprovider-name}/caRequest ClusterProviderCaRequest ClusterProvidercaRequestPOST
 	// Create CA request
 	//  Parameters:
@@ -17,7 +17,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
-	handle("/cluster-provider/{clusterprovider-name}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{createInstance(bootConf, r)}).caRequest(w, r) }).Methods("POST")
+	handle("/cluster-provider/{clusterprovider-name}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{s.handlerFor(r)}).caRequest(w, r) }).Methods("POST")
 
 	// swagger:route DELETE /cluster-provider/{clusterprovider-name}/caRequest ClusterProviderCaRequest ClusterProvidercaRequestDelete
 	// Delete CA request
@@ -30,7 +30,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
-	handle("/cluster-provider/{clusterprovider-name}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{createInstance(bootConf, r)}).caDelete(w, r) }).Methods("DELETE")
+	handle("/cluster-provider/{clusterprovider-name}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{s.handlerFor(r)}).caDelete(w, r) }).Methods("DELETE")
 
 	// swagger:route GET /cluster-provider/{clusterprovider-name}/caRequest ClusterProviderCaRequest ClusterProviderGetCaCert
 	// Get CA Request
@@ -43,7 +43,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: JsonResponseCert
 	// default: JsonResponseError
-	handle("/cluster-provider/{clusterprovider-name}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{createInstance(bootConf, r)}).caCert(w, r) }).Methods("GET")
+	handle("/cluster-provider/{clusterprovider-name}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{s.handlerFor(r)}).caCert(w, r) }).Methods("GET")
 
 	// swagger:route GET /cluster-provider/{clusterprovider-name}/caRequest/clusters ClusterProviderCaRequest ClusterProviderGetCaClusters
 	// Get CA clusters
@@ -56,7 +56,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: JsonResponseClusters
 	// default: JsonResponseError
-	handle("/cluster-provider/{clusterprovider-name}/caRequest/clusters", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{createInstance(bootConf, r)}).caClusters(w, r) }).Methods("GET")
+	handle("/cluster-provider/{clusterprovider-name}/caRequest/clusters", func(w http.ResponseWriter, r *http.Request) { (&clpHandler{s.handlerFor(r)}).caClusters(w, r) }).Methods("GET")
 
 	// swagger:route PUT /cluster-provider/{clusterprovider-name}/caRequest/clusters ClusterProviderCaRequest ClusterProviderUpdateClusters
 	// Update Cert Clusters
@@ -73,7 +73,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: JsonResponseUpdateClusters
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/clusters", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caUpdateClusters(w, r)
+		(&clpHandler{s.handlerFor(r)}).caUpdateClusters(w, r)
 	}).Methods("PUT")
 
 	// swagger:route GET /cluster-provider/{clusterprovider-name}/caRequest/enrollment/status ClusterProviderEnrollment ClusterProviderenrollmentStatus
@@ -88,7 +88,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: JsonResponseCertStatus
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/enrollment/status", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caGetEnrollmentStatus(w, r)
+		(&clpHandler{s.handlerFor(r)}).caGetEnrollmentStatus(w, r)
 	}).Methods("GET")
 
 	// swagger:route GET /cluster-provider/{clusterprovider-name}/caRequest/distribution/status ClusterProviderDistribution ClusterProviderdistributionStatus
@@ -103,7 +103,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: JsonResponseCertStatus
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/distribution/status", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caGetDistributionStatus(w, r)
+		(&clpHandler{s.handlerFor(r)}).caGetDistributionStatus(w, r)
 	}).Methods("GET")
 
 	// swagger:route POST /cluster-provider/{clusterprovider-name}/caRequest/enrollment/instantiate ClusterProviderEnrollment ClusterProviderEnrollmentInstantiate
@@ -118,7 +118,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/enrollment/instantiate", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caEnrollmentInstantiate(w, r)
+		(&clpHandler{s.handlerFor(r)}).caEnrollmentInstantiate(w, r)
 	}).Methods("POST")
 
 	// swagger:route POST /cluster-provider/{clusterprovider-name}/caRequest/distribution/instantiate ClusterProviderDistribution ClusterProviderDistributionInstantiate
@@ -133,7 +133,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/distribution/instantiate", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caDistributionInstantiate(w, r)
+		(&clpHandler{s.handlerFor(r)}).caDistributionInstantiate(w, r)
 	}).Methods("POST")
 
 	// swagger:route POST /cluster-provider/{clusterprovider-name}/caRequest/enrollment/terminate ClusterProviderEnrollment ClusterProviderEnrollmentTerminate
@@ -148,7 +148,7 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/enrollment/terminate", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caEnrollmentTerminate(w, r)
+		(&clpHandler{s.handlerFor(r)}).caEnrollmentTerminate(w, r)
 	}).Methods("POST")
 
 	// swagger:route POST /cluster-provider/{clusterprovider-name}/caRequest/distribution/terminate ClusterProviderDistribution ClusterProviderDistributionTerminate
@@ -163,6 +163,6 @@ func RegisterCertCPHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/cluster-provider/{clusterprovider-name}/caRequest/distribution/terminate", func(w http.ResponseWriter, r *http.Request) {
-		(&clpHandler{createInstance(bootConf, r)}).caDistributionTerminate(w, r)
+		(&clpHandler{s.handlerFor(r)}).caDistributionTerminate(w, r)
 	}).Methods("POST")
 }
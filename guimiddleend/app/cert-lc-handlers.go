@@ -3,7 +3,6 @@ package app
 import (
 	"encoding/json"
 	"io/ioutil"
-	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -53,7 +52,7 @@ func (h *lcHandler) caEnrollmentInstantiate(w http.ResponseWriter, r *http.Reque
 	statusCode, err := h.InstantiateEnrollment(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 	h.jsonOK(w, "Certificate enrollment istantiated", statusCode)
@@ -67,7 +66,7 @@ func (h *lcHandler) caEnrollmentTerminate(w http.ResponseWriter, r *http.Request
 	statusCode, err := h.TerminateEnrollment(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 	h.jsonOK(w, "Certificate enrollment terminated", statusCode)
@@ -81,7 +80,7 @@ func (h *lcHandler) caDistributionInstantiate(w http.ResponseWriter, r *http.Req
 	statusCode, err := h.InstantiateDistribution(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 	h.jsonOK(w, "Certificate distribution istantiated", statusCode)
@@ -95,7 +94,7 @@ func (h *lcHandler) caDistributionTerminate(w http.ResponseWriter, r *http.Reque
 	statusCode, err := h.TerminateDistribution(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 	h.jsonOK(w, "Certificate distribution terminated", statusCode)
@@ -157,14 +156,14 @@ func (h *lcHandler) caDelete(w http.ResponseWriter, r *http.Request) {
 	clouds, err := h.GetLogicalCloudsByProject(project)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	statusCode, err := h.caCertTerminate(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
@@ -172,7 +171,7 @@ func (h *lcHandler) caDelete(w http.ResponseWriter, r *http.Request) {
 		err := h.DeleteCertLogicalCloud(caIntent, project, cl.Metadata.Name)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Println(err)
+			h.Logger.Println(err)
 			return
 		}
 	}
@@ -180,7 +179,7 @@ func (h *lcHandler) caDelete(w http.ResponseWriter, r *http.Request) {
 	statusCode, err = h.DeleteCaCert(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 	h.jsonOK(w, "Ca Request succesfully deleted", 200)
@@ -196,35 +195,35 @@ func (h *lcHandler) caUpdateClouds(w http.ResponseWriter, r *http.Request) {
 	payload, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	err = json.Unmarshal(payload, &reqCloudNames)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	clouds, err := h.GetLogicalCloudsByProject(project)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	caClouds, err := h.GetCAIntentLogicalClouds(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	reqClusters, err := findCloudsByNames(clouds, reqCloudNames)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 	reqClustersMap := make(map[string]*CaCertLogicalCloud)
@@ -249,7 +248,7 @@ func (h *lcHandler) caUpdateClouds(w http.ResponseWriter, r *http.Request) {
 	err = h.CreateCertLogicalClouds(caIntent, project, clustersForCreate)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
@@ -263,14 +262,14 @@ func (h *lcHandler) caUpdateClouds(w http.ResponseWriter, r *http.Request) {
 	err = h.DeleteCertLogicalClouds(caIntent, project, clustersForDelete)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	statusCode, err := h.caCertReInstantiate(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
@@ -290,7 +289,7 @@ func (h *lcHandler) caRequest(w http.ResponseWriter, r *http.Request) {
 	clouds, err := h.GetLogicalCloudsByProject(project)
 	if err != nil {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
@@ -298,13 +297,13 @@ func (h *lcHandler) caRequest(w http.ResponseWriter, r *http.Request) {
 
 	payload, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	err = json.Unmarshal(payload, caRequest)
 	if err != nil {
-		log.Println(err)
+		h.Logger.Println(err)
 	}
 
 	caCert := &CaCert{
@@ -340,17 +339,17 @@ func (h *lcHandler) caRequest(w http.ResponseWriter, r *http.Request) {
 	statusCode, err := h.PostCaCert(caCert, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
 	clusterList, err := findCloudsByNames(clouds, caRequest.RequestingClusters)
 	if err != nil {
-		log.Println(err)
-		log.Println("Rolling back")
+		h.Logger.Println(err)
+		h.Logger.Println("Rolling back")
 		_, delErr := h.DeleteCaCert(caIntent, project)
 		if delErr != nil {
-			log.Println(delErr)
+			h.Logger.Println(delErr)
 		}
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -361,7 +360,7 @@ func (h *lcHandler) caRequest(w http.ResponseWriter, r *http.Request) {
 		h.jsonError(w, err.Error(), http.StatusBadRequest)
 		_, err := h.DeleteCaCert(caIntent, project)
 		if err != nil {
-			log.Println(err)
+			h.Logger.Println(err)
 		}
 		return
 	}
@@ -369,7 +368,7 @@ func (h *lcHandler) caRequest(w http.ResponseWriter, r *http.Request) {
 	statusCode, err = h.caCertInstantiate(caIntent, project)
 	if err != nil {
 		h.jsonError(w, err.Error(), statusCode)
-		log.Println(err)
+		h.Logger.Println(err)
 		return
 	}
 
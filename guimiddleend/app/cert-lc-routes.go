@@ -2,7 +2,7 @@ package app
 
 import "net/http"
 
-func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
+func (s *Server) registerCertLCHandlers(handle HandleFunc) {
 
 	// Cert Logical cloud handlers
 
@@ -20,7 +20,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
-	handle("/projects/{project}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{createInstance(bootConf, r)}).caRequest(w, r) }).Methods("POST")
+	handle("/projects/{project}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{s.handlerFor(r)}).caRequest(w, r) }).Methods("POST")
 
 	// swagger:route DELETE /projects/{project}/caRequest LogicalCloudCaRequest LogicalCloudcaRequestDelete
 	// Delete CA request
@@ -33,7 +33,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
-	handle("/projects/{project}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{createInstance(bootConf, r)}).caDelete(w, r) }).Methods("DELETE")
+	handle("/projects/{project}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{s.handlerFor(r)}).caDelete(w, r) }).Methods("DELETE")
 
 	// swagger:route GET /projects/{project}/caRequest LogicalCloudCaRequest LogicalCloudGetCaCert
 	// Get CA Request
@@ -46,7 +46,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: JsonResponseCert
 	// default: JsonResponseError
-	handle("/projects/{project}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{createInstance(bootConf, r)}).caCert(w, r) }).Methods("GET")
+	handle("/projects/{project}/caRequest", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{s.handlerFor(r)}).caCert(w, r) }).Methods("GET")
 
 	// swagger:route GET /projects/{project}/caRequest/logical-clouds LogicalCloudCaRequest LogicalCloudGetCaLogicalClouds
 	// Get CA Logical Clouds
@@ -59,7 +59,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// responses:
 	// 200: swaggerJsonResponseLogicalClouds
 	// default: JsonResponseError
-	handle("/projects/{project}/caRequest/logical-clouds", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{createInstance(bootConf, r)}).caLClouds(w, r) }).Methods("GET")
+	handle("/projects/{project}/caRequest/logical-clouds", func(w http.ResponseWriter, r *http.Request) { (&lcHandler{s.handlerFor(r)}).caLClouds(w, r) }).Methods("GET")
 
 	// swagger:route PUT /projects/{project}/caRequest/logical-clouds LogicalCloudCaRequest LogicalCloudUpdateClusters
 	// Update Cert Logical Clouds
@@ -76,7 +76,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: JsonResponseUpdateClusters
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/clusters", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caUpdateClouds(w, r)
+		(&lcHandler{s.handlerFor(r)}).caUpdateClouds(w, r)
 	}).Methods("PUT")
 
 	// swagger:route GET /cluster-provider/{project}/caRequest/enrollment/status LogicalCloudEnrollment LogicalCloudenrollmentStatus
@@ -91,7 +91,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: JsonResponseCertStatus
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/enrollment/status", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caGetEnrollmentStatus(w, r)
+		(&lcHandler{s.handlerFor(r)}).caGetEnrollmentStatus(w, r)
 	}).Methods("GET")
 
 	// swagger:route GET /cluster-provider/{project}/caRequest/distribution/status LogicalCloudDistribution LogicalClouddistributionStatus
@@ -106,7 +106,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: JsonResponseCertStatus
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/distribution/status", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caGetDistributionStatus(w, r)
+		(&lcHandler{s.handlerFor(r)}).caGetDistributionStatus(w, r)
 	}).Methods("GET")
 
 	// swagger:route POST /cluster-provider/{project}/caRequest/enrollment/instantiate LogicalCloudEnrollment LogicalCloudEnrollmentInstantiate
@@ -121,7 +121,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/enrollment/instantiate", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caEnrollmentInstantiate(w, r)
+		(&lcHandler{s.handlerFor(r)}).caEnrollmentInstantiate(w, r)
 	}).Methods("POST")
 
 	// swagger:route POST /cluster-provider/{project}/caRequest/distribution/instantiate LogicalCloudDistribution LogicalCloudDistributionInstantiate
@@ -136,7 +136,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/distribution/instantiate", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caDistributionInstantiate(w, r)
+		(&lcHandler{s.handlerFor(r)}).caDistributionInstantiate(w, r)
 	}).Methods("POST")
 
 	// swagger:route POST /projects/{project}/caRequest/enrollment/terminate LogicalCloudEnrollment LogicalCloudEnrollmentTerminate
@@ -151,7 +151,7 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/enrollment/terminate", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caEnrollmentTerminate(w, r)
+		(&lcHandler{s.handlerFor(r)}).caEnrollmentTerminate(w, r)
 	}).Methods("POST")
 
 	// swagger:route POST /projects/{project}/caRequest/distribution/terminate LogicalCloudDistribution LogicalCloudDistributionTerminate
@@ -166,6 +166,6 @@ func RegisterCertLCHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	// 200: swaggerJsonResponse
 	// default: JsonResponseError
 	handle("/projects/{project}/caRequest/distribution/terminate", func(w http.ResponseWriter, r *http.Request) {
-		(&lcHandler{createInstance(bootConf, r)}).caDistributionTerminate(w, r)
+		(&lcHandler{s.handlerFor(r)}).caDistributionTerminate(w, r)
 	}).Methods("POST")
 }
\ No newline at end of file
@@ -9,12 +9,12 @@ type CaRequest struct {
 	// The issuing cluster name
 	// required: true
 	// example: issuer1
-	IssuingCluster string `json:"issuingCluster"`
+	IssuingCluster string `json:"issuingCluster" binding:"required"`
 
 	// The list of requesting clusters
 	// required: true
 	// items.example: cluster1
-	RequestingClusters []string `json:"requestingClusters" example:"sample"`
+	RequestingClusters []string `json:"requestingClusters" example:"sample" binding:"required"`
 }
 
 type CaCert struct {
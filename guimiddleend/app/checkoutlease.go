@@ -0,0 +1,393 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"example.com/middleend/authproxy"
+	"example.com/middleend/db"
+	"example.com/middleend/localstore"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	CHECKOUT_LEASE_COLLECTION = "checkoutlease"
+	checkoutLeaseTag          = "lease"
+	// defaultCheckoutLeaseSeconds applies when the configuration sets no
+	// checkoutLeaseSeconds
+	defaultCheckoutLeaseSeconds = 1800
+	// anonymousOwner holds the checkouts when the requests carry no claims
+	anonymousOwner = "anonymous"
+)
+
+// CheckoutLeaseKey selects the lease of a DIG, whichever version of it is
+// checked out
+type CheckoutLeaseKey struct {
+	Project      string `json:"project"`
+	CompositeApp string `json:"compositeApp"`
+	Dig          string `json:"dig"`
+}
+
+// CheckoutLease records who holds the checkout of a DIG. The checkout is
+// changed by its owner only, until the lease expires; every change renews
+// the lease. ETag names the state of the checkout, the changes sent with
+// an If-Match of another state are refused.
+type CheckoutLease struct {
+	Project      string    `json:"project"`
+	CompositeApp string    `json:"compositeApp"`
+	Dig          string    `json:"dig"`
+	Version      string    `json:"version"`
+	Operation    string    `json:"operation"`
+	Owner        string    `json:"owner"`
+	OwnerName    string    `json:"ownerName,omitempty"`
+	Created      time.Time `json:"created"`
+	Expires      time.Time `json:"expires"`
+	ID           string    `json:"id"`
+	Revision     int       `json:"revision"`
+	ETag         string    `json:"etag"`
+}
+
+func (l *CheckoutLease) expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+// renew extends the lease and moves the checkout to a new state
+func (l *CheckoutLease) renew(now time.Time, ttl time.Duration) {
+	l.Revision++
+	l.Expires = now.Add(ttl)
+	l.ETag = fmt.Sprintf("\"%s-%d\"", l.ID, l.Revision)
+}
+
+func (l *CheckoutLease) String() string {
+	owner := l.Owner
+	if l.OwnerName != "" {
+		owner = l.OwnerName
+	}
+	return fmt.Sprintf("DIG %s is checked out by %s until %s", l.Dig, owner, l.Expires.Format(time.RFC3339))
+}
+
+// leaseTTL is how long a checkout is held without a change
+func (h *OrchestrationHandler) leaseTTL() time.Duration {
+	seconds := h.MiddleendConf.CheckoutLeaseSeconds
+	if seconds <= 0 {
+		seconds = defaultCheckoutLeaseSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// caller returns the subject and the name of the caller of the request,
+// from its claims
+func (h *OrchestrationHandler) caller() (string, string) {
+	claims, ok := authproxy.ClaimsFromContext(h.ctx)
+	if !ok || claims.Subject == "" {
+		return anonymousOwner, ""
+	}
+	return claims.Subject, claims.Username
+}
+
+func (h *OrchestrationHandler) leaseKey() CheckoutLeaseKey {
+	return CheckoutLeaseKey{
+		Project:      h.Vars["projectName"],
+		CompositeApp: h.Vars["compositeAppName"],
+		Dig:          h.Vars["deploymentIntentGroupName"],
+	}
+}
+
+// readLease returns the lease of the DIG of the route, nil when there is
+// none
+func (h *OrchestrationHandler) readLease() (*CheckoutLease, error) {
+	values, err := db.DBconn.Find(h.ctx, CHECKOUT_LEASE_COLLECTION, h.leaseKey(), checkoutLeaseTag)
+	if err != nil || len(values) == 0 || values[0] == nil {
+		return nil, err
+	}
+	lease := &CheckoutLease{}
+	if err := db.DBconn.Unmarshal(values[0], lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+func (h *OrchestrationHandler) writeLease(lease *CheckoutLease) error {
+	return db.DBconn.Insert(h.ctx, CHECKOUT_LEASE_COLLECTION, h.leaseKey(), nil, checkoutLeaseTag, lease)
+}
+
+// removeLease removes the lease of the DIG, if any: the checkouts made
+// before the leases have none
+func (h *OrchestrationHandler) removeLease() error {
+	lease, err := h.readLease()
+	if err != nil || lease == nil {
+		return err
+	}
+	return db.DBconn.Remove(h.ctx, CHECKOUT_LEASE_COLLECTION, h.leaseKey())
+}
+
+// leaseConflict is the error of a change of the checkout by the caller
+// while another user holds it, nil when the caller may change it
+func (h *OrchestrationHandler) leaseConflict(lease *CheckoutLease, now time.Time) error {
+	if owner, _ := h.caller(); lease == nil || lease.Owner == owner || lease.expired(now) {
+		return nil
+	}
+	return &apiError{code: http.StatusConflict, msg: lease.String()}
+}
+
+// checkLeaseOwner makes sure no other user holds the checkout of the DIG
+func (h *OrchestrationHandler) checkLeaseOwner() error {
+	lease, err := h.readLease()
+	if err != nil {
+		return err
+	}
+	return h.leaseConflict(lease, time.Now())
+}
+
+// checkLease makes sure the caller may change the checkout of the DIG and
+// renews the lease, it must run in a transaction. ifMatch is the ETag the
+// change was made on, none when empty.
+func (h *OrchestrationHandler) checkLease(ifMatch string) (*CheckoutLease, error) {
+	lease, err := h.readLease()
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil {
+		return nil, &apiError{code: http.StatusConflict,
+			msg: fmt.Sprintf("DIG %s is not checked out, check it out first", h.Vars["deploymentIntentGroupName"])}
+	}
+	owner, _ := h.caller()
+	now := time.Now().UTC()
+	if err := h.leaseConflict(lease, now); err != nil {
+		return nil, err
+	}
+	if lease.Owner != owner {
+		return nil, &apiError{code: http.StatusConflict,
+			msg: fmt.Sprintf("The checkout of DIG %s by another user expired, check it out again", lease.Dig)}
+	}
+	if ifMatch != "" && ifMatch != "*" && ifMatch != lease.ETag {
+		return nil, &apiError{code: http.StatusPreconditionFailed,
+			msg: fmt.Sprintf("DIG %s changed since %s, the current version is %s", lease.Dig, ifMatch, lease.ETag)}
+	}
+	lease.renew(now, h.leaseTTL())
+	if err := h.writeLease(lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// releaseLease ends the lease of the DIG, unless another user holds it
+func (h *OrchestrationHandler) releaseLease() error {
+	lease, err := h.readLease()
+	if err != nil || lease == nil {
+		return err
+	}
+	if err := h.leaseConflict(lease, time.Now()); err != nil {
+		return err
+	}
+	return h.removeLease()
+}
+
+// acquireLease gives the checkout of the DIG in version to the caller, it
+// must run in a transaction. The lease of another owner which expired is
+// taken over, its checkout being discarded; a checkout without a lease is
+// adopted as it is.
+func (h *OrchestrationHandler) acquireLease(version string, operation string) (*CheckoutLease, error) {
+	lease, err := h.readLease()
+	if err != nil {
+		return nil, err
+	}
+	owner, ownerName := h.caller()
+	now := time.Now().UTC()
+	if err := h.leaseConflict(lease, now); err != nil {
+		return nil, err
+	}
+	if lease != nil && lease.Owner != owner {
+		h.Logger.Infof("Checkout of DIG %s by %s expired, discarding it", lease.Dig, lease.Owner)
+		if err := h.discardCheckout(lease.Version); err != nil {
+			return nil, err
+		}
+		lease = nil
+	}
+	if lease == nil {
+		key := h.leaseKey()
+		lease = &CheckoutLease{
+			Project:      key.Project,
+			CompositeApp: key.CompositeApp,
+			Dig:          key.Dig,
+			Version:      version,
+			Operation:    operation,
+			Owner:        owner,
+			OwnerName:    ownerName,
+			Created:      now,
+			ID:           uuid.New().String(),
+		}
+	}
+	lease.renew(now, h.leaseTTL())
+	if err := h.writeLease(lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// discardCheckout deletes the checkout of the DIG in version, if any
+func (h *OrchestrationHandler) discardCheckout(version string) error {
+	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
+	retValue, err := localDigStore.getDig(h.Vars["projectName"],
+		h.Vars["compositeAppName"], version, h.Vars["deploymentIntentGroupName"])
+	// the store replies an empty DIG when there is none
+	checkout := localstore.DeploymentIntentGroup{}
+	if err != nil || json.Unmarshal(retValue, &checkout) != nil || checkout.MetaData.Name == "" {
+		return nil
+	}
+	req := h.digOf()
+	h.Vars = req.atVersion(version).vars()
+	retcode, _ := h.DeleteDig("local")
+	h.Vars = req.vars()
+	if retcode != http.StatusNoContent {
+		return fmt.Errorf("failed to discard the checkout of DIG %s, status %d", h.Vars["deploymentIntentGroupName"], retcode)
+	}
+	return nil
+}
+
+// GetCheckoutLease returns the lease of the checkout of the DIG
+func (h *OrchestrationHandler) GetCheckoutLease(r *http.Request) (interface{}, error) {
+	h.Vars = mux.Vars(r)
+	lease, err := h.readLease()
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil {
+		return nil, &apiError{code: http.StatusNotFound, msg: fmt.Sprintf("DIG %s is not checked out", h.Vars["deploymentIntentGroupName"])}
+	}
+	return lease, nil
+}
+
+// StealCheckoutLease gives the checkout of the DIG to the caller, whoever
+// holds it. The changes of the previous owner are kept, those they send
+// from now on are refused.
+func (h *OrchestrationHandler) StealCheckoutLease(r *http.Request) (interface{}, error) {
+	h.Vars = mux.Vars(r)
+	var lease *CheckoutLease
+	err := h.inTransaction(func() error {
+		var err error
+		if lease, err = h.readLease(); err != nil {
+			return err
+		}
+		if lease == nil {
+			return &apiError{code: http.StatusNotFound, msg: fmt.Sprintf("DIG %s is not checked out", h.Vars["deploymentIntentGroupName"])}
+		}
+		previous := lease.Owner
+		lease.Owner, lease.OwnerName = h.caller()
+		lease.renew(time.Now().UTC(), h.leaseTTL())
+		h.Logger.Infof("Checkout of DIG %s taken over from %s by %s", lease.Dig, previous, lease.Owner)
+		return h.writeLease(lease)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// ReleaseCheckoutLease ends the lease of the caller on the checkout of
+// the DIG. The checkout is kept, the next user checking out the DIG gets
+// it as it is.
+func (h *OrchestrationHandler) ReleaseCheckoutLease(w http.ResponseWriter, r *http.Request) {
+	h.Vars = mux.Vars(r)
+	err := h.inTransaction(func() error {
+		lease, err := h.readLease()
+		if err != nil {
+			return err
+		}
+		if lease == nil {
+			return &apiError{code: http.StatusNotFound, msg: fmt.Sprintf("DIG %s is not checked out", h.Vars["deploymentIntentGroupName"])}
+		}
+		return h.releaseLease()
+	})
+	if err != nil {
+		h.replyLeaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// replyLeaseError replies err, with its code for an apiError
+func (h *OrchestrationHandler) replyLeaseError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if e, ok := err.(*apiError); ok {
+		code = e.code
+	} else {
+		h.Logger.WithError(err).Errorf("%s(): Failed to access the checkout lease", PrintFunctionName())
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// ExpireCheckouts discards, every interval until stop is closed, the
+// checkouts whose lease expired, so that those of abandoned sessions do
+// not linger
+func (s *Server) ExpireCheckouts(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.expireCheckouts()
+		}
+	}
+}
+
+func (s *Server) expireCheckouts() {
+	h := s.newHandler(context.Background())
+	h.Vars = map[string]string{}
+	values, err := db.DBconn.Find(h.ctx, CHECKOUT_LEASE_COLLECTION, CheckoutLeaseKey{}, checkoutLeaseTag)
+	if err != nil {
+		h.Logger.WithError(err).Warn("Failed to read the checkout leases")
+		return
+	}
+	for _, value := range values {
+		var lease CheckoutLease
+		if err := db.DBconn.Unmarshal(value, &lease); err != nil || !lease.expired(time.Now()) {
+			continue
+		}
+		h.Vars = map[string]string{
+			"projectName":               lease.Project,
+			"compositeAppName":          lease.CompositeApp,
+			"version":                   lease.Version,
+			"deploymentIntentGroupName": lease.Dig,
+		}
+		h.InitializeResponseMap()
+		err := h.inTransaction(func() error {
+			// the owner may have renewed the lease meanwhile
+			current, err := h.readLease()
+			if err != nil || current == nil || current.ID != lease.ID || !current.expired(time.Now()) {
+				return err
+			}
+			if err := h.discardCheckout(current.Version); err != nil {
+				return err
+			}
+			return h.removeLease()
+		})
+		if err != nil {
+			h.Logger.WithError(err).Warnf("Failed to expire the checkout of DIG %s", lease.Dig)
+			continue
+		}
+		h.Logger.Infof("Checkout of DIG %s by %s expired", lease.Dig, lease.Owner)
+	}
+}
@@ -0,0 +1,164 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/middleend/authproxy"
+	"example.com/middleend/db"
+)
+
+// leaseHandler returns a handler of the DIG d1 on behalf of subject
+func leaseHandler(s *Server, subject string) *OrchestrationHandler {
+	h := s.newHandler(authproxy.NewContext(context.Background(), &authproxy.Claims{Subject: subject}))
+	h.Vars = map[string]string{
+		"projectName":               "p1",
+		"compositeAppName":          "app",
+		"version":                   "v1",
+		"deploymentIntentGroupName": "d1",
+	}
+	return h
+}
+
+// leaseCode is the status replied for err, 0 when there is none
+func leaseCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(*apiError); ok {
+		return e.code
+	}
+	return http.StatusInternalServerError
+}
+
+func TestCheckoutLease(t *testing.T) {
+	db.DBconn = db.NewMemoryStore()
+	s := &Server{}
+	alice, bob := leaseHandler(s, "alice"), leaseHandler(s, "bob")
+
+	if _, err := alice.checkLease(""); leaseCode(err) != http.StatusConflict {
+		t.Fatalf("saved a DIG which is not checked out: %v", err)
+	}
+	lease, err := alice.acquireLease("v1", "update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := lease.ETag
+
+	tests := []struct {
+		name    string
+		h       *OrchestrationHandler
+		ifMatch string
+		code    int
+	}{
+		{"other user", bob, "", http.StatusConflict},
+		{"other user with the etag", bob, etag, http.StatusConflict},
+		{"stale etag", alice, "\"other-1\"", http.StatusPreconditionFailed},
+		{"current etag", alice, etag, 0},
+		{"etag of the previous save", alice, etag, http.StatusPreconditionFailed},
+		{"any etag", alice, "*", 0},
+		{"no etag", alice, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lease, err := tt.h.checkLease(tt.ifMatch)
+			if code := leaseCode(err); code != tt.code {
+				t.Fatalf("code %d, want %d: %v", code, tt.code, err)
+			}
+			if err == nil && lease.ETag == tt.ifMatch {
+				t.Fatalf("the save kept the etag %s", lease.ETag)
+			}
+		})
+	}
+
+	if _, err := bob.acquireLease("v1", "update"); leaseCode(err) != http.StatusConflict {
+		t.Fatalf("checked out a DIG held by another user: %v", err)
+	}
+}
+
+// TestCheckoutLeaseFailedSave renews the lease in a save which fails, the
+// renewal is rolled back with the save
+func TestCheckoutLeaseFailedSave(t *testing.T) {
+	db.DBconn = db.NewMemoryStore()
+	alice := leaseHandler(&Server{}, "alice")
+	lease, err := alice.acquireLease("v1", "update")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	alice.replyInTransaction(rec, func(w http.ResponseWriter) {
+		if _, err := alice.checkLease(lease.ETag); err != nil {
+			alice.replyLeaseError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("ETag") != "" {
+		t.Fatalf("replied %d with the etag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	current, err := alice.readLease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ETag != lease.ETag {
+		t.Fatalf("the failed save moved the etag to %s", current.ETag)
+	}
+	if _, err := alice.checkLease(lease.ETag); err != nil {
+		t.Fatalf("the etag of the failed save is refused: %v", err)
+	}
+}
+
+func TestCheckoutLeaseExpired(t *testing.T) {
+	db.DBconn = db.NewMemoryStore()
+	s := &Server{}
+	alice, bob := leaseHandler(s, "alice"), leaseHandler(s, "bob")
+	lease, err := alice.acquireLease("v1", "update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease.Expires = time.Now().Add(-time.Minute)
+	if err := alice.writeLease(lease); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := alice.checkLease(lease.ETag); err != nil {
+		t.Fatalf("the owner of an expired lease nobody took over cannot save: %v", err)
+	}
+	lease, err = alice.readLease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease.Expires = time.Now().Add(-time.Minute)
+	if err := alice.writeLease(lease); err != nil {
+		t.Fatal(err)
+	}
+	taken, err := bob.acquireLease("v1", "update")
+	if err != nil {
+		t.Fatalf("the expired lease is not taken over: %v", err)
+	}
+	if taken.Owner != "bob" || taken.ID == lease.ID {
+		t.Fatalf("unexpected lease %+v", taken)
+	}
+	if _, err := alice.checkLease(lease.ETag); leaseCode(err) != http.StatusConflict {
+		t.Fatalf("the previous owner saved over the new checkout: %v", err)
+	}
+}
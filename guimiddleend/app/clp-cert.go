@@ -3,7 +3,6 @@ package app
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
@@ -21,7 +20,7 @@ func (h *clpHandler) CreateCertClusters(caIntent, cProvider string, clusters []*
 			for j := i; j < len(clusters); j++ {
 				err := h.DeleteCertCluster(caIntent, cProvider, cl.Metadata.Name)
 				if err != nil {
-					log.Println(err)
+					h.Logger.Println(err)
 				}
 			}
 			return fmt.Errorf("Error occured during creating the clusters - %s. Rolling back", err)
@@ -41,7 +40,7 @@ func (h *clpHandler) DeleteCertClusters(caIntent, cProvider string, clusters []s
 }
 
 func (h *clpHandler) DeleteCertCluster(caIntent, cProvider string, cluster string) error {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/clusters/" + cluster
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/clusters/" + cluster
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Deleting Cert cluster")
 	statusCode, err := h.apiDel(url, "DeleteCertCluster")
@@ -61,7 +60,7 @@ func (h *clpHandler) DeleteCertCluster(caIntent, cProvider string, cluster strin
 }
 
 func (h *clpHandler) CreateCertCluster(caIntent, cProvider string, cluster *ClusterWithLabel) error {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/clusters"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/clusters"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Creating Cert Cluster")
 	certIntentCluster := &CertIntentCluster{
@@ -95,7 +94,7 @@ func (h *clpHandler) CreateCertCluster(caIntent, cProvider string, cluster *Clus
 }
 
 func (h *clpHandler) InstantiateEnrollment(caIntent, cProvider string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/enrollment/instantiate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/enrollment/instantiate"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Instantiating the enrollment")
 	sc, err := h.apiPost(nil, url, "payload")
@@ -114,7 +113,7 @@ func (h *clpHandler) InstantiateEnrollment(caIntent, cProvider string) (int, err
 }
 
 func (h *clpHandler) TerminateEnrollment(caIntent, cProvider string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/enrollment/terminate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/enrollment/terminate"
 	sc, err := h.apiPost(nil, url, "payload")
 	statusCode := sc.(int)
 	if err != nil {
@@ -129,7 +128,7 @@ func (h *clpHandler) TerminateEnrollment(caIntent, cProvider string) (int, error
 }
 
 func (h *clpHandler) InstantiateDistribution(caIntent, cProvider string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/distribution/instantiate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/distribution/instantiate"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Instantiating the distribution")
 	sc, err := h.apiPost(nil, url, "payload")
@@ -146,7 +145,7 @@ func (h *clpHandler) InstantiateDistribution(caIntent, cProvider string) (int, e
 }
 
 func (h *clpHandler) TerminateDistribution(caIntent, cProvider string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/distribution/terminate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/distribution/terminate"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Terminating the distribution")
 	sc, err := h.apiPost(nil, url, "payload")
@@ -163,7 +162,7 @@ func (h *clpHandler) TerminateDistribution(caIntent, cProvider string) (int, err
 }
 
 func (h *clpHandler) GetCaCertEnrollmentStatus(caIntent, cProvider string) (*CertStatus, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/enrollment/status"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/enrollment/status"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Fetching CA enrollment status")
 	reply, err := h.apiGet(url, "GetCaCertEnrollmentStatus")
@@ -182,7 +181,7 @@ func (h *clpHandler) GetCaCertEnrollmentStatus(caIntent, cProvider string) (*Cer
 }
 
 func (h *clpHandler) GetCaCertDistributionStatus(caIntent, cProvider string) (*CertStatus, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/distribution/status"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/distribution/status"
 
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Fetching CA distribution status")
@@ -200,7 +199,7 @@ func (h *clpHandler) GetCaCertDistributionStatus(caIntent, cProvider string) (*C
 }
 
 func (h *clpHandler) GetCaCert(caIntent, cProvider string) (*CaCert, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Fetching CA Cert")
 	reply, err := h.apiGet(url, "caCertsGet")
@@ -219,7 +218,7 @@ func (h *clpHandler) GetCaCert(caIntent, cProvider string) (*CaCert, error) {
 }
 
 func (h *clpHandler) PostCaCert(caCert *CaCert, cProvider string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Posting CA Cert")
 	statusCode := 500
@@ -246,7 +245,7 @@ func (h *clpHandler) PostCaCert(caCert *CaCert, cProvider string) (int, error) {
 }
 
 func (h *clpHandler) DeleteCaCert(caIntent, cProvider string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Deleting CA Cert")
 	sc, err := h.apiDel(url, "caCertDel")
@@ -269,7 +268,7 @@ func (h *clpHandler) DeleteCaCert(caIntent, cProvider string) (int, error) {
 }
 
 func (h *clpHandler) GetCAIntentClusters(caIntent, cProvider string) ([]*CertIntentCluster, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/clusters"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/cluster-providers/" + cProvider + "/ca-certs/" + caIntent + "/clusters"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Fetching CA Intent Clusters")
 	reply, err := h.apiGet(url, "GetCAIntentClusters")
@@ -286,7 +285,7 @@ func (h *clpHandler) GetCAIntentClusters(caIntent, cProvider string) ([]*CertInt
 }
 
 func (h *clpHandler) GetClustersByProvider(cProvider string) ([]*ClusterWithLabel, error) {
-	url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" + cProvider + "/clusters?withLabels=true"
+	url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + cProvider + "/clusters?withLabels=true"
 	l := h.Logger.WithFields(logrus.Fields{"function": PrintFunctionName(), "emco_url": url})
 	l.Debug("Fetching Clusters")
 	reply, err := h.apiGet(url, "caCertsGet")
@@ -19,7 +19,7 @@ func (h *OrchestrationHandler) CreateClusterProvider(w http.ResponseWriter, r *h
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&jsonData)
 	if err != nil {
-		log.Errorf("Failed to parse json: %s", err)
+		h.Logger.Errorf("Failed to parse json: %s", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -33,10 +33,10 @@ func (h *OrchestrationHandler) CreateClusterProvider(w http.ResponseWriter, r *h
 	}
 
 	jsonLoad, _ := json.Marshal(cp)
-	url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers"
+	url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers"
 	resp, err := h.apiPost(jsonLoad, url, jsonData.Metadata.Name+"_cp")
 	if err != nil {
-		log.Errorf("Encountered error while creating cluster provider: %s", jsonData.Metadata.Name)
+		h.Logger.Errorf("Encountered error while creating cluster provider: %s", jsonData.Metadata.Name)
 		w.WriteHeader(resp.(int))
 		return
 	}
@@ -47,7 +47,7 @@ func (h *OrchestrationHandler) CreateClusterProvider(w http.ResponseWriter, r *h
 	if jsonData.Spec.GitEnabled && len(jsonData.Spec.Kv) > 0 {
 		var kvinfo []map[string]interface{}
 		for _, kvpair := range jsonData.Spec.Kv {
-			log.Info("kvpair", log.Fields{"kvpair": kvpair})
+			h.Logger.Info("kvpair", log.Fields{"kvpair": kvpair})
 			v, ok := kvpair["gitType"]
 			if ok {
 				gitType := map[string]interface{}{
@@ -87,52 +87,29 @@ func (h *OrchestrationHandler) CreateClusterProvider(w http.ResponseWriter, r *h
 		jsonData.Spec.Kv = kvinfo
 		jsonData.Metadata.Name = "GitObjectMyRepo"
 		jsonLoad, _ := json.Marshal(jsonData)
-		url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" + clusterProvider + "/cluster-sync-objects"
+		url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + clusterProvider + "/cluster-sync-objects"
 		resp, err := h.apiPost(jsonLoad, url, jsonData.Metadata.Name+"_cp")
 		if err != nil {
-			log.Errorf("Encountered error while creating cluster sync object for clusterprovider: %s", jsonData.Metadata.Name)
+			h.Logger.Errorf("Encountered error while creating cluster sync object for clusterprovider: %s", jsonData.Metadata.Name)
 			w.WriteHeader(resp.(int))
 			return
 		}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if _, err := w.Write(h.response.payload[clusterProvider+"_cp"]); err != nil {
-		log.Error(err)
+		h.Logger.Error(err)
 	}
 }
 
-// GetClusters get an a array of all the cluster providers and the clusters within them
-func (h *OrchestrationHandler) GetClusters(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	h.Vars = vars
+// Clusters returns all the cluster providers with the clusters within them
+func (h *OrchestrationHandler) Clusters() ([]ClusterProvider, error) {
+	h.Vars = map[string]string{}
 	h.InitializeResponseMap()
 	dashboardClient := DashboardClient{h}
-	retcode := dashboardClient.getAllClusters()
-	if retcode != nil {
-		if intval, ok := retcode.(int); ok {
-			log.Infof("Failed to get clusterdata : %d", intval)
-			w.WriteHeader(intval)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-			errMsg := string(h.response.payload[h.response.lastKey]) + h.response.lastKey
-			if _, err := w.Write([]byte(errMsg)); err != nil {
-				log.Error(err)
-			}
-		}
-		return
-	}
-
-	var retval []byte
-	retval, err := json.Marshal(h.ClusterProviders)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if _, err := w.Write(retval); err != nil {
-		log.Error(err)
+	if retcode := dashboardClient.getAllClusters(); retcode != nil {
+		return nil, h.dashboardError("cluster data", retcode)
 	}
+	return h.ClusterProviders, nil
 }
 
 // Delete Cluster Provider and cluster-sync-objects
@@ -140,45 +117,45 @@ func (h *OrchestrationHandler) DeleteClusterProvider(w http.ResponseWriter, r *h
 	h.Vars = mux.Vars(r)
 	h.InitializeResponseMap()
 	// Fetch cluster sync object
-	url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" + h.Vars["clusterProvider"] + "/cluster-sync-objects"
+	url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + h.Vars["clusterProvider"] + "/cluster-sync-objects"
 	reply, err := h.apiGet(url, h.Vars["clusterProvider"])
 	if err != nil {
-		log.Errorf("Encountered error while fetching cluster sync object for clusterprovider: %s", h.Vars["clusterProvider"])
+		h.Logger.Errorf("Encountered error while fetching cluster sync object for clusterprovider: %s", h.Vars["clusterProvider"])
 		w.WriteHeader(reply.StatusCode)
 		return
 	}
 	var jsonData []ClusterProvider
 	if err := json.Unmarshal(reply.Data, &jsonData); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.Logger.Error(err, PrintFunctionName())
 	}
-	log.Infof("clustersyncobjects: %+v", jsonData)
+	h.Logger.Infof("clustersyncobjects: %+v", jsonData)
 
 	// Delete cluster sync object
 	if len(jsonData) > 0 && len(jsonData[0].Spec.Kv) != 0 {
-		url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" + h.Vars["clusterProvider"] + "/cluster-sync-objects/" + "GitObjectMyRepo"
+		url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + h.Vars["clusterProvider"] + "/cluster-sync-objects/" + "GitObjectMyRepo"
 		resp, err := h.apiDel(url, h.Vars["clusterProvider"])
 		if err != nil {
-			log.Errorf("Encountered error while deleting cluster sync object for clusterprovider: %s", h.Vars["clusterProvider"])
+			h.Logger.Errorf("Encountered error while deleting cluster sync object for clusterprovider: %s", h.Vars["clusterProvider"])
 			w.WriteHeader(resp.(int))
 			return
 		}
 		if resp != nil && resp.(int) != http.StatusNoContent {
-			log.Errorf("Encountered error while deleting cluster sync object for clusterprovider: %s", h.Vars["clusterProvider"])
+			h.Logger.Errorf("Encountered error while deleting cluster sync object for clusterprovider: %s", h.Vars["clusterProvider"])
 			w.WriteHeader(resp.(int))
 			return
 		}
 	}
 
 	// Delete cluster provider
-	url = "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" + h.Vars["clusterProvider"]
+	url = h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + h.Vars["clusterProvider"]
 	resp, err := h.apiDel(url, h.Vars["clusterProvider"])
 	if err != nil {
-		log.Errorf("Encountered error while deleting clusterprovider: %s", h.Vars["clusterProvider"])
+		h.Logger.Errorf("Encountered error while deleting clusterprovider: %s", h.Vars["clusterProvider"])
 		w.WriteHeader(resp.(int))
 		return
 	}
 	if resp != nil && resp.(int) != http.StatusNoContent {
-		log.Errorf("Encountered error while deleting clusterprovider: %s", h.Vars["clusterProvider"])
+		h.Logger.Errorf("Encountered error while deleting clusterprovider: %s", h.Vars["clusterProvider"])
 		w.WriteHeader(resp.(int))
 		return
 	}
@@ -22,8 +22,6 @@ import (
 	"sync"
 
 	"example.com/middleend/db"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type AppconfigData struct {
@@ -126,21 +124,21 @@ func (h *compAppHandler) getEMCOObject() error {
 
 		go func(compositeAppMetadata apiMetaData, CompositeAppSpec compositeAppSpec) {
 			defer wg.Done()
-			url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+			url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 				vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 				"/" + CompositeAppSpec.Version + "/apps"
-			log.Infof("composite app object URL: %s", url)
+			h.orchInstance.Logger.Infof("composite app object URL: %s", url)
 			reply, err := orch.apiGet(url, vars["compositeAppName"]+"_getapps")
 			if err != nil {
 				ERR.Error(err)
 				return
 			}
-			log.Infof("Get app status: %d apps: %s", reply.StatusCode, reply.Data)
+			h.orchInstance.Logger.Infof("Get app status: %d apps: %s", reply.StatusCode, reply.Data)
 
 			compositeAppValue.AppsDataArray = make(map[string]*AppsData, len(reply.Data))
 			var appList []Application
 			if err := json.Unmarshal(reply.Data, &appList); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.orchInstance.Logger.Error(err, PrintFunctionName())
 			}
 			for _, value := range appList {
 				wg.Add(1)
@@ -183,16 +181,16 @@ func (h *compAppHandler) getAnchor() error {
 
 		go func(compositeAppMetadata apiMetaData, CompositeAppSpec compositeAppSpec) {
 			defer wg.Done()
-			url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+			url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 				vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 				"/" + CompositeAppSpec.Version
-			log.Debugf("composite app anchor URL: %s", h.orchURL)
+			h.orchInstance.Logger.Debugf("composite app anchor URL: %s", h.orchURL)
 			reply, err := orch.apiGet(url, vars["composie-app-name"]+"_getcompositeapp")
 			if err != nil {
 				ERR.Error(err)
 				return
 			}
-			log.Infof("Get composite App status: %d", reply.StatusCode)
+			h.orchInstance.Logger.Infof("Get composite App status: %d", reply.StatusCode)
 			// json.Unmarshal(respdata, &dataRead.CompositeApp)
 		}(compositeAppMetadata, CompositeAppSpec)
 	}
@@ -210,13 +208,13 @@ func (h *compAppHandler) deleteObject() interface{} {
 		}
 		compositeAppMetadata := compositeAppValue.Metadata.Metadata
 		compositeAppSpec := compositeAppValue.Metadata.Spec
-		h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 			"/" + compositeAppSpec.Version
 		appList := compositeAppValue.AppsDataArray
 		for _, value := range appList {
 			url := h.orchURL + "/apps/" + value.App.Metadata.Name
-			log.Infof("Delete app %s\n", url)
+			h.orchInstance.Logger.Infof("Delete app %s\n", url)
 			resp, err := orch.apiDel(url, compositeAppMetadata.Name+"_delapp")
 			if err != nil {
 				return err // need to add the retcode
@@ -224,7 +222,7 @@ func (h *compAppHandler) deleteObject() interface{} {
 			if resp != http.StatusNoContent {
 				return resp
 			}
-			log.Infof("Delete app status %d\n", resp)
+			h.orchInstance.Logger.Infof("Delete app status %d\n", resp)
 		}
 	}
 	return nil
@@ -240,17 +238,17 @@ func (h *compAppHandler) deleteAnchor() interface{} {
 
 		// if status is checkout, delete the object from db
 		if compositeAppValue.Status == "checkout" {
-			err := db.DBconn.Delete(orch.MiddleendConf.StoreName, vars)
+			err := db.DBconn.Delete(orch.ctx, orch.MiddleendConf.StoreName, vars)
 			if err != nil {
-				log.Info("Unable to delete compapp from middleend", err)
+				h.orchInstance.Logger.Info("Unable to delete compapp from middleend", err)
 			} else {
-				log.Infof("Composite app %s : %s deleted from middleend", compositeAppMetadata.Name, compositeAppSpec.Version)
+				h.orchInstance.Logger.Infof("Composite app %s : %s deleted from middleend", compositeAppMetadata.Name, compositeAppSpec.Version)
 			}
 		} else {
-			h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+			h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 				vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 				"/" + compositeAppSpec.Version
-			log.Infof("Delete composite app %s\n", h.orchURL)
+			h.orchInstance.Logger.Infof("Delete composite app %s\n", h.orchURL)
 			resp, err := orch.apiDel(h.orchURL, compositeAppMetadata.Name+"_delcompapp")
 			if err != nil {
 				return err // need to add the retcode
@@ -258,7 +256,7 @@ func (h *compAppHandler) deleteAnchor() interface{} {
 			if resp != http.StatusNoContent {
 				return resp
 			}
-			log.Infof("Delete compapp status %d\n", resp)
+			h.orchInstance.Logger.Infof("Delete compapp status %d\n", resp)
 		}
 	}
 	return nil
@@ -275,7 +273,7 @@ func (h *compAppHandler) createAnchor() interface{} {
 	compAppCreate := CompositeApp{
 		Metadata: apiMetaData{
 			Name:        vars["compositeAppName"],
-			Description: vars["description"],
+			Description: orch.state.description,
 			UserData1:   "data 1",
 			UserData2:   "data 2",
 		},
@@ -285,12 +283,12 @@ func (h *compAppHandler) createAnchor() interface{} {
 	}
 
 	jsonLoad, _ := json.Marshal(compAppCreate)
-	log.Debugf("create anchor composite app: %s", jsonLoad)
+	h.orchInstance.Logger.Debugf("create anchor composite app: %s", jsonLoad)
 	tem := CompositeApp{}
 	if err := json.Unmarshal(jsonLoad, &tem); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.orchInstance.Logger.Error(err, PrintFunctionName())
 	}
-	h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		vars["projectName"] + "/composite-apps"
 	orch.response.lastKey = vars["compositeAppName"]
 	resp, err := orch.apiPost(jsonLoad, h.orchURL, vars["compositeAppName"]+"_compapp")
@@ -301,7 +299,7 @@ func (h *compAppHandler) createAnchor() interface{} {
 		return resp
 	}
 	// orch.version = "v1"
-	log.Infof("compAppHandler response: %d", resp)
+	h.orchInstance.Logger.Infof("compAppHandler response: %d", resp)
 
 	return nil
 }
@@ -342,7 +340,7 @@ func (h *compAppHandler) createObject() interface{} {
 		if status != http.StatusCreated {
 			return status
 		}
-		log.Infof("Composite app %s createObject status: %d", appName, status)
+		h.orchInstance.Logger.Infof("Composite app %s createObject status: %d", appName, status)
 
 		// Upload the confiuration BPs to the config svc
 		if len(orch.meta[i].BlueprintModels) != 0 {
@@ -352,12 +350,12 @@ func (h *compAppHandler) createObject() interface{} {
 			c.CompVersion = vars["version"]
 			c.AppName = appName
 			c.BpArray = orch.meta[i].BlueprintModels
-			url := "http://" + orch.MiddleendConf.CfgService + "/configsvc/appBps"
+			url := orch.MiddleendConf.serviceURL("configSvc") + "/configsvc/appBps"
 			jsonLoad, _ := json.Marshal(c)
-			log.Infof("app bp %s\n", c)
+			h.orchInstance.Logger.Infof("app bp %s\n", c)
 			status, err := orch.apiPost(jsonLoad, url, appName+"configwf")
 			if err != nil {
-				log.Errorf("Failed to store BP %s\n", err.Error())
+				h.orchInstance.Logger.Errorf("Failed to store BP %s\n", err.Error())
 				return status
 			}
 		}
@@ -0,0 +1,210 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/middleend/db"
+	"example.com/middleend/localstore"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultLogLevel applies when the configuration sets no logLevel
+const defaultLogLevel = log.DebugLevel
+
+// requiredServices must be configured for the middleend to start
+var requiredServices = []string{"orchestrator", "clm"}
+
+// Level returns the configured log level, the default one when unset.
+// The level is checked by Validate.
+func (c MiddleendConfig) Level() log.Level {
+	level, err := log.ParseLevel(c.LogLevel)
+	if err != nil {
+		return defaultLogLevel
+	}
+	return level
+}
+
+// Validate checks the configuration, reporting every invalid field at once
+func (c MiddleendConfig) Validate() error {
+	var errs []string
+	if err := validatePort(c.OwnPort); err != nil {
+		errs = append(errs, "ownport: "+err.Error())
+	}
+	services := c.addresses()
+	for _, name := range requiredServices {
+		if services[name] == "" {
+			errs = append(errs, name+": address is required")
+		}
+	}
+	for _, name := range sortedKeys(services) {
+		if addr := services[name]; addr != "" {
+			if err := validateAddress(addr); err != nil {
+				errs = append(errs, name+": "+err.Error())
+			}
+		}
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, "tls: "+err.Error())
+	}
+	for _, name := range sortedKeys(c.ServiceTLS) {
+		if _, ok := services[name]; !ok {
+			errs = append(errs, "serviceTLS: unknown service "+name)
+			continue
+		}
+		if _, err := c.ServiceTLS[name].Build(); err != nil {
+			errs = append(errs, "serviceTLS: "+name+": "+err.Error())
+		}
+	}
+	switch dbType, _ := c.Database(); {
+	case !db.Registered(dbType):
+		errs = append(errs, fmt.Sprintf("dbType: unknown store %q, use one of %s", dbType, strings.Join(db.Types(), ", ")))
+	case dbType == "file" && c.DBPath == "":
+		errs = append(errs, "dbPath: directory is required by the file store")
+	case dbType != "mongo":
+	case c.Mongo == "":
+		errs = append(errs, "mongo: address is required")
+	default:
+		// the hosts of a replica set are comma separated, the credentials
+		// may precede them and the options follow them
+		hosts := c.Mongo[strings.LastIndex(c.Mongo, "@")+1:]
+		if slash := strings.Index(hosts, "/"); slash >= 0 {
+			hosts = hosts[:slash]
+		}
+		for _, host := range strings.Split(hosts, ",") {
+			if err := validateAddress(host); err != nil {
+				errs = append(errs, "mongo: "+err.Error())
+			}
+		}
+	}
+	if c.LogLevel != "" {
+		if _, err := log.ParseLevel(c.LogLevel); err != nil {
+			errs = append(errs, "logLevel: "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Database returns the type of the store and its location, the address
+// of mongo or the directory of the file store
+func (c MiddleendConfig) Database() (string, string) {
+	switch c.DBType {
+	case "", "mongo":
+		return "mongo", c.Mongo
+	case "file":
+		return c.DBType, c.DBPath
+	}
+	return c.DBType, ""
+}
+
+// Indexes are the indexes of the collections of the middleend store
+func (c MiddleendConfig) Indexes() []db.Index {
+	indexes := append(localstore.Indexes(),
+		db.Index{Collection: DIG_INFO_COLLECTION, Keys: []string{"name"}},
+		db.Index{Collection: OPERATION_COLLECTION, Keys: []string{"operationId"}},
+		db.Index{Collection: CHECKOUT_LEASE_COLLECTION, Keys: []string{"project", "compositeApp", "dig"}},
+	)
+	if c.StoreName != "" {
+		// the composite apps are updated and deleted by their scope
+		indexes = append(indexes, db.Index{Collection: c.StoreName, Keys: []string{"project", "compositeapp", "compositeappversion"}})
+	}
+	return indexes
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, key.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("%q is not a port number", port)
+	}
+	return nil
+}
+
+// validateAddress checks the host:port form the URLs of the services are
+// built from
+func validateAddress(addr string) error {
+	if strings.Contains(addr, "://") || strings.Contains(addr, "/") {
+		return fmt.Errorf("%q must be host:port, without scheme or path; https is set by serviceTLS", addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q must be host:port: %v", addr, err)
+	}
+	if host == "" {
+		return fmt.Errorf("%q has no host", addr)
+	}
+	if err := validatePort(port); err != nil {
+		return fmt.Errorf("%q: %v", addr, err)
+	}
+	return nil
+}
+
+// Reconfigure applies the log level and the service endpoints, with their
+// tls settings, of conf to the requests which start from now on. The other
+// settings are fixed at startup; a change of them is reported and ignored.
+func (s *Server) Reconfigure(conf MiddleendConfig) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+	s.confMu.Lock()
+	defer s.confMu.Unlock()
+	cur := s.conf
+	next := cur
+	next.LogLevel = conf.LogLevel
+	next.OrchService = conf.OrchService
+	next.Clm = conf.Clm
+	next.Dcm = conf.Dcm
+	next.Ncm = conf.Ncm
+	next.Gac = conf.Gac
+	next.Dtc = conf.Dtc
+	next.Its = conf.Its
+	next.Cert = conf.Cert
+	next.OvnService = conf.OvnService
+	next.CfgService = conf.CfgService
+	next.ServiceTLS = conf.ServiceTLS
+	if !reflect.DeepEqual(next, conf) {
+		log.Warn("Only the log level and the service endpoints are reloaded, restart the middleend to apply the other changes")
+	}
+	if reflect.DeepEqual(next, cur) {
+		return nil
+	}
+
+	if err := s.client.SetServices(next.services()); err != nil {
+		return err
+	}
+	log.SetLevel(next.Level())
+	s.conf = next
+	log.Infof("%s(): Reloaded the log level and the service endpoints", PrintFunctionName())
+	return nil
+}
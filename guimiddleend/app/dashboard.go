@@ -20,7 +20,6 @@ import (
 	"sync"
 
 	pkgerrors "github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 type DashboardClient struct {
@@ -56,15 +55,15 @@ type ClusterLabel struct {
 func (h *DashboardClient) getClusterProviders() interface{} {
 	var clusterProviderList []ClusterProvider
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Clm + "/v2/cluster-providers"
+	url := orch.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers"
 	reply, err := orch.apiGet(url, "getClusterProviders")
-	log.Infof("Get cluster providers status: %d", reply.StatusCode)
+	h.orchInstance.Logger.Infof("Get cluster providers status: %d", reply.StatusCode)
 	orch.response.lastKey = "getClusterProviders"
 	if err != nil {
 		return pkgerrors.New("Error getting ClusterProviders")
 	}
 	if err := json.Unmarshal(reply.Data, &clusterProviderList); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.orchInstance.Logger.Error(err, PrintFunctionName())
 	}
 	orch.ClusterProviders = clusterProviderList
 	return nil
@@ -81,7 +80,7 @@ func (h *DashboardClient) getClusters() error {
 		go func(index int, provider ClusterProvider) {
 			defer wg.Done()
 			var ClusterList []Cluster
-			url := "http://" + orch.MiddleendConf.Clm + "/v2/cluster-providers/" + provider.Metadata.Name + "/clusters"
+			url := orch.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" + provider.Metadata.Name + "/clusters"
 			orch.response.lastKey = "getClusters"
 			reply, err := orch.apiGet(url, "getClusters")
 			if err != nil {
@@ -89,10 +88,10 @@ func (h *DashboardClient) getClusters() error {
 				return
 			}
 			if err := json.Unmarshal(reply.Data, &ClusterList); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.orchInstance.Logger.Error(err, PrintFunctionName())
 			}
 			orch.ClusterProviders[index].Spec.Clusters = ClusterList
-			log.Infof("Get clusters status: %d", reply.StatusCode)
+			h.orchInstance.Logger.Infof("Get clusters status: %d", reply.StatusCode)
 		}(index, provider)
 	}
 	wg.Wait()
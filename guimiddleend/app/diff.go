@@ -0,0 +1,144 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// IntentChange is a value which differs between two versions of the
+// intents of a DIG. Path is the dotted json path of the value, the
+// applications are named by their name rather than their index. From is
+// absent for an added value and To for a removed one.
+type IntentChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// diffIntents lists the changes turning the intents from into to
+func diffIntents(from, to *deployDigData) ([]IntentChange, error) {
+	a, err := intentsTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := intentsTree(to)
+	if err != nil {
+		return nil, err
+	}
+	changes := []IntentChange{}
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+// intentsTree is the json form of d with the applications keyed by name,
+// so that a change of their order is not a difference
+func intentsTree(d *deployDigData) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	if d == nil {
+		return tree, nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	spec, ok := tree["spec"].(map[string]interface{})
+	if !ok {
+		return tree, nil
+	}
+	apps, ok := spec["appsData"].([]interface{})
+	if !ok {
+		return tree, nil
+	}
+	byName := make(map[string]interface{}, len(apps))
+	for i, app := range apps {
+		name := fmt.Sprint(i)
+		if meta, ok := app.(map[string]interface{})["metadata"].(map[string]interface{}); ok {
+			if n, ok := meta["name"].(string); ok && n != "" {
+				name = n
+			}
+		}
+		byName[name] = app
+	}
+	spec["appsData"] = byName
+	return tree, nil
+}
+
+// diffValues appends to changes the differences between the json values
+// a and b at path
+func diffValues(path string, a, b interface{}, changes *[]IntentChange) {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if aok && bok {
+		keys := make([]string, 0, len(am)+len(bm))
+		for k := range am {
+			keys = append(keys, k)
+		}
+		for k := range bm {
+			if _, ok := am[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			av, inA := am[k]
+			bv, inB := bm[k]
+			switch {
+			case !inA:
+				*changes = append(*changes, IntentChange{Path: joinPath(path, k), To: bv})
+			case !inB:
+				*changes = append(*changes, IntentChange{Path: joinPath(path, k), From: av})
+			default:
+				diffValues(joinPath(path, k), av, bv, changes)
+			}
+		}
+		return
+	}
+
+	al, aok := a.([]interface{})
+	bl, bok := b.([]interface{})
+	if aok && bok {
+		for i := 0; i < len(al) || i < len(bl); i++ {
+			p := joinPath(path, fmt.Sprint(i))
+			switch {
+			case i >= len(al):
+				*changes = append(*changes, IntentChange{Path: p, To: bl[i]})
+			case i >= len(bl):
+				*changes = append(*changes, IntentChange{Path: p, From: al[i]})
+			default:
+				diffValues(p, al[i], bl[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, IntentChange{Path: path, From: a, To: b})
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,312 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"example.com/middleend/localstore"
+)
+
+// CheckoutDiff lists, application by application, the changes from the
+// DIG deployed in EMCO to its checkout
+type CheckoutDiff struct {
+	// Operation is migrate or update, from DeployedVersion to
+	// CheckoutVersion
+	Operation       string `json:"operation"`
+	DeployedVersion string `json:"deployedVersion"`
+	CheckoutVersion string `json:"checkoutVersion"`
+	// Dtc is set when the checkout holds DTC intents. A checkout copies
+	// only the placement, network and generic k8s intents, the DTC ones are
+	// compared when it has some.
+	Dtc  bool      `json:"dtc"`
+	Apps []AppDiff `json:"apps"`
+}
+
+// AppDiff is the changes of an application, by kind of intent. The paths
+// of the changes are relative to the kind, the interfaces, inbound intents
+// and resources are named by their name.
+type AppDiff struct {
+	App string `json:"app"`
+	// Status is added, removed, changed or unchanged
+	Status         string         `json:"status"`
+	Placement      []IntentChange `json:"placement,omitempty"`
+	Interfaces     []IntentChange `json:"interfaces,omitempty"`
+	InboundServer  []IntentChange `json:"inboundServer,omitempty"`
+	InboundClients []IntentChange `json:"inboundClients,omitempty"`
+	Resources      []IntentChange `json:"resources,omitempty"`
+	OverrideValues []IntentChange `json:"overrideValues,omitempty"`
+}
+
+// appIntents are the intents of an application of a DIG as the checkout
+// diff compares them, the lists are keyed by name so that their order is
+// not a change
+type appIntents struct {
+	PlacementCriterion string                                         `json:"placementCriterion,omitempty"`
+	Placement          *localstore.IntentStruc                        `json:"placement,omitempty"`
+	Interfaces         map[string]InterfaceSpec                       `json:"interfaces,omitempty"`
+	InboundServer      map[string]localstore.InbondServerIntentSpec   `json:"inboundServer,omitempty"`
+	InboundClients     map[string]localstore.InboundClientsIntentSpec `json:"inboundClients,omitempty"`
+	Resources          map[string]ResourceInfo                        `json:"resources,omitempty"`
+	OverrideValues     map[string]string                              `json:"overrideValues,omitempty"`
+}
+
+// readDigIntents reads the DIG of the route from the store and returns the
+// intents of its applications by name, and whether it has DTC intents
+func (h *OrchestrationHandler) readDigIntents(storeType string) (map[string]*appIntents, bool, error) {
+	dataPoints := []string{
+		"projectHandler", "compAppHandler",
+		"digpHandler",
+		"placementIntentHandler",
+		"networkIntentHandler", "genericK8sIntentHandler", "dtcIntentHandler",
+	}
+
+	h.dataRead = &ProjectTree{}
+	h.prepTreeReq()
+	if storeType == "emco" {
+		dStore := &remoteStoreDigHandler{}
+		dStore.orchInstance = h
+		h.digStore = dStore
+		bstore := &remoteStoreIntentHandler{}
+		bstore.orchInstance = h
+		h.bstore = bstore
+	} else {
+		dStore := &localStoreDigHandler{}
+		dStore.orchInstance = h
+		h.digStore = dStore
+		bstore := &localStoreIntentHandler{}
+		bstore.orchInstance = h
+		h.bstore = bstore
+	}
+	if err := h.constructTree(dataPoints); err != nil {
+		return nil, false, err
+	}
+
+	apps := map[string]*appIntents{}
+	app := func(name string) *appIntents {
+		if _, ok := apps[name]; !ok {
+			apps[name] = &appIntents{}
+		}
+		return apps[name]
+	}
+	dtc := false
+	for compositeAppName, compositeAppValue := range h.dataRead.compositeAppMap {
+		for _, a := range compositeAppValue.AppsDataArray {
+			app(a.App.Metadata.Name)
+		}
+		digValue, ok := compositeAppValue.DigMap[h.Vars["deploymentIntentGroupName"]]
+		if !ok {
+			continue
+		}
+
+		for _, gpintValue := range digValue.GpintMap {
+			for _, appIntent := range gpintValue.AppIntentArray {
+				a := app(appIntent.Spec.AppName)
+				intent := appIntent.Spec.Intent
+				a.Placement = &intent
+				a.PlacementCriterion = "allOf"
+				if len(intent.AllOfArray) == 0 && len(intent.AnyOfArray) > 0 {
+					a.PlacementCriterion = "anyOf"
+				}
+			}
+		}
+
+		for _, nwintValue := range digValue.NwintMap {
+			for _, workloadIntents := range nwintValue.WrkintMap {
+				a := app(workloadIntents.Wrkint.Spec.AppName)
+				for _, nwinterface := range workloadIntents.Interfaces {
+					if a.Interfaces == nil {
+						a.Interfaces = map[string]InterfaceSpec{}
+					}
+					a.Interfaces[nwinterface.Spec.Interface] = nwinterface.Spec
+				}
+			}
+		}
+
+		for _, dtintValue := range digValue.DtintMap {
+			dtc = true
+			for _, server := range dtintValue.ServerIntentArray {
+				a := app(server.Spec.AppName)
+				if a.InboundServer == nil {
+					a.InboundServer = map[string]localstore.InbondServerIntentSpec{}
+				}
+				a.InboundServer[server.Metadata.Name] = server.Spec
+			}
+			for _, client := range dtintValue.ClientsIntentArray {
+				a := app(client.Spec.AppName)
+				if a.InboundClients == nil {
+					a.InboundClients = map[string]localstore.InboundClientsIntentSpec{}
+				}
+				a.InboundClients[client.Metadata.Name] = client.Spec
+			}
+		}
+
+		if genK8sData, ok := h.genK8sInfo[compositeAppName+"_genk8sint"]; ok {
+			for appName, resources := range genK8sData.resData {
+				a := app(appName)
+				for _, res := range resources {
+					if a.Resources == nil {
+						a.Resources = map[string]ResourceInfo{}
+					}
+					gvk := res.ResourceSpec.ResourceGVK
+					a.Resources[gvk.Kind+"/"+gvk.Name] = res
+				}
+			}
+		}
+
+		for _, override := range digValue.DigpData.Spec.OverrideValuesObj {
+			a := app(override.AppName)
+			for k, v := range override.ValuesObj {
+				if a.OverrideValues == nil {
+					a.OverrideValues = map[string]string{}
+				}
+				a.OverrideValues[k] = v
+			}
+		}
+	}
+	return apps, dtc, nil
+}
+
+// diffApps compares the intents of the applications deployed with those
+// of the checkout
+func diffApps(deployed, checkout map[string]*appIntents, dtc bool) ([]AppDiff, error) {
+	names := make([]string, 0, len(deployed)+len(checkout))
+	for name := range deployed {
+		names = append(names, name)
+	}
+	for name := range checkout {
+		if _, ok := deployed[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := make([]AppDiff, 0, len(names))
+	for _, name := range names {
+		d := AppDiff{App: name, Status: "changed"}
+		from, inDeployed := deployed[name]
+		to, inCheckout := checkout[name]
+		switch {
+		case !inDeployed:
+			d.Status, from = "added", &appIntents{}
+		case !inCheckout:
+			d.Status, to = "removed", &appIntents{}
+		}
+		if !dtc {
+			a, b := *from, *to
+			a.InboundServer, a.InboundClients = nil, nil
+			b.InboundServer, b.InboundClients = nil, nil
+			from, to = &a, &b
+		}
+
+		a, err := jsonTree(from)
+		if err != nil {
+			return nil, err
+		}
+		b, err := jsonTree(to)
+		if err != nil {
+			return nil, err
+		}
+		changes := []IntentChange{}
+		diffValues("", a, b, &changes)
+		if len(changes) == 0 && d.Status == "changed" {
+			d.Status = "unchanged"
+		}
+		for _, c := range changes {
+			kind := strings.SplitN(c.Path, ".", 2)
+			c.Path = ""
+			if len(kind) > 1 {
+				c.Path = kind[1]
+			}
+			switch kind[0] {
+			case "placementCriterion", "placement":
+				if kind[0] == "placementCriterion" {
+					c.Path = "criterion"
+				}
+				d.Placement = append(d.Placement, c)
+			case "interfaces":
+				d.Interfaces = append(d.Interfaces, c)
+			case "inboundServer":
+				d.InboundServer = append(d.InboundServer, c)
+			case "inboundClients":
+				d.InboundClients = append(d.InboundClients, c)
+			case "resources":
+				d.Resources = append(d.Resources, c)
+			case "overrideValues":
+				d.OverrideValues = append(d.OverrideValues, c)
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// jsonTree is the generic json form of v
+func jsonTree(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	err = json.Unmarshal(data, &tree)
+	return tree, err
+}
+
+// GetCheckoutDiff compares the checkout of the DIG with the DIG deployed
+// in EMCO, which is in the original version for a migration
+func (h *OrchestrationHandler) GetCheckoutDiff(req digRequest) (*CheckoutDiff, error) {
+	h.Vars = req.vars()
+	h.InitializeResponseMap()
+	version := req.Version
+
+	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
+	retValue, err := localDigStore.getDig(req.Project, req.CompositeApp, version, req.Dig)
+	if err != nil {
+		return nil, &apiError{code: http.StatusNotFound,
+			msg: fmt.Sprintf("DIG %s is not checked out in version %s", req.Dig, version)}
+	}
+	checkoutDig := localstore.DeploymentIntentGroup{}
+	if err := json.Unmarshal(retValue, &checkoutDig); err != nil {
+		return nil, err
+	}
+
+	res := &CheckoutDiff{Operation: checkoutDig.MetaData.UserData1, DeployedVersion: version, CheckoutVersion: version}
+	if res.Operation == "migrate" {
+		res.DeployedVersion = checkoutDig.MetaData.UserData2
+	}
+
+	checkout, dtc, err := h.readDigIntents("middleend")
+	if err != nil {
+		return nil, err
+	}
+	h.Vars = req.atVersion(res.DeployedVersion).vars()
+	deployed, _, err := h.readDigIntents("emco")
+	h.Vars = req.vars()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Dtc = dtc
+	if res.Apps, err = diffApps(deployed, checkout, dtc); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 
@@ -33,6 +34,10 @@ const DIG_INFO_COLLECTION = "diginfo"
 type DigInfo struct {
 	DigName     string   `json:"name"`
 	VersionList []string `json:"versionList"`
+	// Revisions are the intent snapshots to roll back to
+	Revisions []DigRevision `json:"revisions,omitempty"`
+	// Rollout is the last rollout of the DIG
+	Rollout *DigRollout `json:"rollout,omitempty"`
 }
 
 type DigInfoKey struct {
@@ -88,12 +93,12 @@ type digBackendStore interface {
 func (h *localStoreDigHandler) getDig(project string, compositeAppName string, version string,
 	digName string,
 ) ([]byte, error) {
-	c := localstore.NewDeploymentIntentGroupClient()
+	c := localstore.NewDeploymentIntentGroupClient(h.orchInstance.ctx)
 	dig, err := c.GetDeploymentIntentGroup(digName, project, compositeAppName, version)
-	log.Infof("Get Dig localStore in Composite app %s dig %s status: %s : value %+v", compositeAppName,
+	h.orchInstance.Logger.Infof("Get Dig localStore in Composite app %s dig %s status: %s : value %+v", compositeAppName,
 		digName, err, dig)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		return nil, err
 	}
 	retval, _ := json.Marshal(dig)
@@ -104,24 +109,24 @@ func (h *remoteStoreDigHandler) getDig(project string, compositeAppName string,
 	digName string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		project + "/composite-apps/" + compositeAppName +
 		"/" + version +
 		"/deployment-intent-groups/" + digName
 	reply, err := orch.apiGet(orchURL, compositeAppName+"_getdig")
-	log.Infof("Get Dig in Composite app %s dig %s status: %d", compositeAppName,
+	h.orchInstance.Logger.Infof("Get Dig in Composite app %s dig %s status: %d", compositeAppName,
 		digName, reply.StatusCode)
 	return reply.Data, err
 }
 
 func (h *localStoreDigHandler) getAllDig(project string, compositeAppName string, version string,
 ) ([]byte, error) {
-	c := localstore.NewDeploymentIntentGroupClient()
+	c := localstore.NewDeploymentIntentGroupClient(h.orchInstance.ctx)
 	gPIntent, err := c.GetAllDeploymentIntentGroups(project, compositeAppName, version)
-	log.Infof("Get All DIG localStore in Composite app %s version %s status: %s", compositeAppName,
+	h.orchInstance.Logger.Infof("Get All DIG localStore in Composite app %s version %s status: %s", compositeAppName,
 		version, err)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "Unable to find") {
 			return nil, err
 		} else if strings.Contains(err.Error(), "db Find error") {
@@ -138,12 +143,12 @@ func (h *remoteStoreDigHandler) getAllDig(project string, compositeAppName strin
 ) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		project + "/composite-apps/" + compositeAppName +
 		"/" + version +
 		"/deployment-intent-groups"
 	reply, err := orch.apiGet(orchURL, compositeAppName+"_getdig")
-	log.Infof("Get ALl Dig in Composite app %s version %s status: %d", compositeAppName, version,
+	h.orchInstance.Logger.Infof("Get ALl Dig in Composite app %s version %s status: %d", compositeAppName, version,
 		reply.StatusCode)
 	return reply.Data, err
 }
@@ -153,7 +158,7 @@ func (h *remoteStoreDigHandler) getIntents(project string, compositeAppName stri
 ) ([]byte, error) {
 	orch := h.orchInstance
 
-	url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		project + "/composite-apps/" + compositeAppName +
 		"/" + version + "/deployment-intent-groups/" + digName + "/intents"
 	reply, err := orch.apiGet(url, compositeAppName+"_getappPint")
@@ -166,12 +171,12 @@ func (h *remoteStoreDigHandler) getIntents(project string, compositeAppName stri
 func (h *localStoreDigHandler) getIntents(project string, compositeAppName string, version string,
 	digName string,
 ) ([]byte, error) {
-	c := localstore.NewIntentClient()
+	c := localstore.NewIntentClient(h.orchInstance.ctx)
 	appIntent, err := c.GetAllIntents(project, compositeAppName, version, digName)
-	log.Infof("Get All Intents localStore in Composite app %s version %s appIntent: %s", compositeAppName,
+	h.orchInstance.Logger.Infof("Get All Intents localStore in Composite app %s version %s appIntent: %s", compositeAppName,
 		version, appIntent)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "db Find error") {
 			return nil, err
 		} else {
@@ -185,12 +190,12 @@ func (h *localStoreDigHandler) getIntents(project string, compositeAppName strin
 func (h *localStoreDigHandler) createDig(g localstore.DeploymentIntentGroup, p string, ca string,
 	v string,
 ) (interface{}, interface{}) {
-	c := localstore.NewDeploymentIntentGroupClient()
+	c := localstore.NewDeploymentIntentGroupClient(h.orchInstance.ctx)
 	g.Spec.IsCheckedOut = true
 
 	_, createErr := c.CreateDeploymentIntentGroup(g, p, ca, v)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -211,7 +216,7 @@ func (h *remoteStoreDigHandler) createDig(g localstore.DeploymentIntentGroup, p
 	orch := h.orchInstance
 	digName := orch.DigData.Name
 	jsonLoad, _ := json.Marshal(g)
-	url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups"
 	resp, err := orch.apiPost(jsonLoad, url, digName)
@@ -221,11 +226,11 @@ func (h *remoteStoreDigHandler) createDig(g localstore.DeploymentIntentGroup, p
 func (h *localStoreDigHandler) deleteDig(digName string, p string, ca string,
 	v string,
 ) (interface{}, interface{}) {
-	c := localstore.NewDeploymentIntentGroupClient()
+	c := localstore.NewDeploymentIntentGroupClient(h.orchInstance.ctx)
 
 	err := c.DeleteDeploymentIntentGroup(digName, p, ca, v)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "Error getting appcontext") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "not found") {
@@ -244,7 +249,7 @@ func (h *remoteStoreDigHandler) deleteDig(digName string, p string, ca string,
 	v string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	resp, err := orch.apiDel(url, digName)
@@ -255,10 +260,10 @@ func (h *localStoreDigHandler) createIntents(i localstore.Intent, p string, ca s
 	digName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewIntentClient()
+	c := localstore.NewIntentClient(h.orchInstance.ctx)
 	_, createErr := c.AddIntent(i, p, ca, v, digName)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -279,7 +284,7 @@ func (h *remoteStoreDigHandler) createIntents(i localstore.Intent, p string, ca
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(i)
-	url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/intents"
 	status, err := orch.apiPost(jsonLoad, url, "DIGIntents")
@@ -290,10 +295,10 @@ func (h *localStoreDigHandler) deleteIntents(i string, p string, ca string, v st
 	digName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewIntentClient()
+	c := localstore.NewIntentClient(h.orchInstance.ctx)
 	err := c.DeleteIntent(i, p, ca, v, digName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -309,7 +314,7 @@ func (h *remoteStoreDigHandler) deleteIntents(i string, p string, ca string, v s
 	digName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/intents/" + i
 	status, err := orch.apiDel(url, digName)
@@ -320,7 +325,7 @@ func (h *remoteStoreDigHandler) getStatus(compositeAppName string, compositeAppV
 	orch := h.orchInstance
 	vars := orch.Vars
 	thisDigStatus := digStatus{}
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		vars["projectName"] + "/composite-apps/" + compositeAppName +
 		"/" + compositeAppVersion +
 		"/deployment-intent-groups/" + digName + "/status"
@@ -344,6 +349,7 @@ func (h *remoteStoreDigHandler) getStatus(compositeAppName string, compositeAppV
 		return thisDigStatus, err
 	}
 	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h.orchInstance
 	_, err = localDigStore.getDig(vars["projectName"], compositeAppName, compositeAppVersion, digName)
 	thisDigStatus.IsCheckedOut = true
 	if err != nil {
@@ -356,14 +362,14 @@ func (h *remoteStoreDigHandler) getStatus(compositeAppName string, compositeAppV
 func (h *remoteStoreDigHandler) getDigStatus(url string, statusKey string, arguments [][]string) (digStatus, error) {
 	retCode, retVal, err := h.orchInstance.apiGetWithArguments(url, statusKey, arguments)
 	if err != nil || retCode != http.StatusOK {
-		log.Errorf("Failed to read DIG status. Err: %v, ReturnCode: %v", err, retCode)
+		h.orchInstance.Logger.Errorf("Failed to read DIG status. Err: %v, ReturnCode: %v", err, retCode)
 		// Changing error code to StatusInternalServerError after logging actual response code from the backend
 		return digStatus{}, fmt.Errorf("Failed to read DIG status. Err: %v, ReturnCode: %v", err, retCode)
 	}
 	status := digStatus{}
 	err = json.Unmarshal(retVal, &status)
 	if err != nil {
-		log.Errorf("Failed to parse DIG status: %v", err)
+		h.orchInstance.Logger.Errorf("Failed to parse DIG status: %v", err)
 		return digStatus{}, fmt.Errorf("Failed to parse DIG status: %v", err)
 	}
 	return status, nil
@@ -402,9 +408,9 @@ func (h *digpHandler) getAnchor() error {
 				retval, err := orch.digStore.getDig(vars["projectName"],
 					compositeAppMetadata.Name, compositeAppSpec.Version,
 					orch.treeFilter.digName)
-				log.Infof("Get Digp in composite app %s", compositeAppMetadata.Name)
+				h.orchInstance.Logger.Infof("Get Digp in composite app %s", compositeAppMetadata.Name)
 				if err != nil {
-					log.Error("A Failed to read digp", err)
+					h.orchInstance.Logger.Error("A Failed to read digp", err)
 					ERR.Error(err)
 					return
 				}
@@ -416,14 +422,14 @@ func (h *digpHandler) getAnchor() error {
 				digpList = append(digpList, temp)
 			} else {
 				retval, err := orch.digStore.getAllDig(vars["projectName"], compositeAppMetadata.Name, compositeAppSpec.Version)
-				log.Infof("Get Digp in composite app %s", compositeAppMetadata.Name)
+				h.orchInstance.Logger.Infof("Get Digp in composite app %s", compositeAppMetadata.Name)
 				if err != nil {
-					log.Error("B Failed to read digp", err)
+					h.orchInstance.Logger.Error("B Failed to read digp", err)
 					ERR.Error(err)
 					return
 				}
 				if err := json.Unmarshal(retval, &digpList); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.orchInstance.Logger.Error(err, PrintFunctionName())
 				}
 			}
 
@@ -439,14 +445,14 @@ func (h *digpHandler) getAnchor() error {
 						compositeAppValue.Metadata.Spec.Version,
 						digpList[k].MetaData.Name)
 					if err != nil {
-						log.Errorf("C Failed to read digp %s", err)
+						h.orchInstance.Logger.Errorf("C Failed to read digp %s", err)
 						// return nil, retcode
 						return
 					}
 					// Fetch the lastest state and populate the digpValue
 					state := thisDigStatus.States.Actions[len(thisDigStatus.States.Actions)-1].State
 					digpList[k].Spec.Status = state
-					log.Debugf("DIG checkout state %s: %+v", digpList[k].MetaData.Name, thisDigStatus.IsCheckedOut)
+					h.orchInstance.Logger.Debugf("DIG checkout state %s: %+v", digpList[k].MetaData.Name, thisDigStatus.IsCheckedOut)
 					digpList[k].Spec.IsCheckedOut = thisDigStatus.IsCheckedOut
 					Dig.DigpData = digpList[k]
 					compositeAppValue.Lock()
@@ -472,7 +478,7 @@ func (h *digpHandler) getObject() error {
 		}
 		compositeAppMetadata := compositeAppValue.Metadata.Metadata
 		CompositeAppSpec := compositeAppValue.Metadata.Spec
-		h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 			"/" + CompositeAppSpec.Version +
 			"/deployment-intent-groups/"
@@ -486,7 +492,7 @@ func (h *digpHandler) getObject() error {
 				defer wg.Done()
 				retval, err := orch.digStore.getIntents(vars["projectName"], compositeAppMetadata.Name,
 					CompositeAppSpec.Version, digName)
-				// log.Infof("Get Dig int composite app %s Dig %s status %d \n", vars["compositeAppName"],
+				// h.orchInstance.Logger.Infof("Get Dig int composite app %s Dig %s status %d \n", vars["compositeAppName"],
 				// 	digName, retcode)
 				if err != nil {
 					ERR.Error(fmt.Errorf("Failed to read digp intents %s", err))
@@ -515,7 +521,7 @@ func (h *digpHandler) deleteObject() interface{} {
 
 		for digName := range digpList {
 			grpIntent := digName + "/intents/DIGIntents"
-			log.Infof("delete group intents %s", grpIntent)
+			h.orchInstance.Logger.Infof("delete group intents %s", grpIntent)
 			resp, err := orch.digStore.deleteIntents("DIGIntents", vars["projectName"],
 				compositeAppMetadata.Name, compositeAppSpec.Version, digName)
 			if err != nil {
@@ -541,7 +547,7 @@ func (h *digpHandler) deleteAnchor() interface{} {
 		// loop through all the intents in the dig
 		for digName := range digpList {
 			url := h.orchURL + digName
-			log.Infof("delete intents %s", url)
+			h.orchInstance.Logger.Infof("delete intents %s", url)
 			resp, err := orch.digStore.deleteDig(digName, vars["projectName"], compositeAppMetadata.Name, compositeAppSpec.Version)
 			if err != nil {
 				return err // need to add the retcode
@@ -549,7 +555,7 @@ func (h *digpHandler) deleteAnchor() interface{} {
 			if resp != http.StatusNoContent {
 				return resp
 			}
-			log.Infof("Delete dig response: %d", resp)
+			h.orchInstance.Logger.Infof("Delete dig response: %d", resp)
 		}
 	}
 	return nil
@@ -560,17 +566,11 @@ func (h *digpHandler) createAnchor() interface{} {
 	orch := h.orchInstance
 	vars := orch.Vars
 
-	var customData string
-	if len(orch.Vars["operation"]) > 0 {
-		customData = orch.Vars["operation"]
-	} else {
-		customData = "data1"
-	}
-
-	var originalVersion string
-	if len(orch.Vars["originalversion"]) > 0 {
-		originalVersion = orch.Vars["originalversion"]
+	customData := "data1"
+	if orch.state.checkoutOperation != "" {
+		customData = orch.state.checkoutOperation
 	}
+	originalVersion := orch.state.originalVersion
 	digp := localstore.DeploymentIntentGroup{
 		MetaData: localstore.DepMetaData{
 			Name:        digData.Name,
@@ -598,7 +598,7 @@ func (h *digpHandler) createAnchor() interface{} {
 	if resp != http.StatusCreated {
 		return resp
 	}
-	log.Infof("Deployment intent group response: %d", resp)
+	h.orchInstance.Logger.Infof("Deployment intent group response: %d", resp)
 
 	return nil
 }
@@ -651,25 +651,11 @@ func (h *digpHandler) createObject() interface{} {
 	if status != http.StatusCreated {
 		return status
 	}
-	log.Infof("Group intent %s status: %d ", intentName, status)
+	h.orchInstance.Logger.Infof("Group intent %s status: %d ", intentName, status)
 
 	return nil
 }
 
-func createDInents(I orchWorkflow) interface{} {
-	// 1. Create the Anchor point
-	err := I.createAnchor()
-	if err != nil {
-		return err // need to add the retcode
-	}
-	// 2. Create the Objects
-	err = I.createObject()
-	if err != nil {
-		return err // need to add the retcode
-	}
-	return nil
-}
-
 // func delDigp(I orchWorkflow) interface{} {
 // 	// 1. Delete the object
 // 	err := I.deleteObject()
@@ -684,7 +670,10 @@ func createDInents(I orchWorkflow) interface{} {
 // 	return nil
 // }
 
-func (h *OrchestrationHandler) createDigData(w http.ResponseWriter, storeType string) {
+// createDigData creates the DIG and its intents in the given store. On
+// failure the DIG is rolled back, the per step report is replied and
+// false is returned.
+func (h *OrchestrationHandler) createDigData(w http.ResponseWriter, storeType string) bool {
 	// 1. Create DIG
 	if storeType == "emco" {
 		dStore := &remoteStoreDigHandler{}
@@ -701,102 +690,51 @@ func (h *OrchestrationHandler) createDigData(w http.ResponseWriter, storeType st
 		bstore.orchInstance = h
 		h.bstore = bstore
 	}
-	igHandler := &digpHandler{}
-	igHandler.orchInstance = h
-	igpStatus := createDInents(igHandler)
-	if igpStatus != nil {
-		if intval, ok := igpStatus.(int); ok {
-			w.WriteHeader(intval)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		// Rollback DIG
-		retCode, _ := h.DeleteDig("remote")
-		if retCode != http.StatusNoContent {
-			log.Errorf("Rollback of DIG failed...")
-		}
-		if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_digp"]); err != nil {
-			log.Error(err, PrintFunctionName())
-		}
-		return
+	rollbackFilter := "remote"
+	if storeType != "emco" {
+		rollbackFilter = "local"
 	}
-
-	// 2. Create intents
-	h.Vars["deploymentIntentGroupName"] = h.DigData.Name // SANDEEP : is this gettign initalized anywhere ?
-	intentHandler := &placementIntentHandler{}
-	intentHandler.orchInstance = h
-	intentStatus := addPlacementIntent(intentHandler)
-	if intentStatus != nil {
-		if intval, ok := intentStatus.(int); ok {
-			w.WriteHeader(intval)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		// Rollback DIG
-		retCode, _ := h.DeleteDig("remote")
+	// Deleting the DIG removes all its intents, so the intent steps have no
+	// compensation of their own.
+	rollbackDig := func() interface{} {
+		retCode, _ := h.DeleteDig(rollbackFilter)
 		if retCode != http.StatusNoContent {
-			log.Errorf("Rollback of DIG failed...")
-		}
-		if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_gpint"]); err != nil {
-			log.Error(err, PrintFunctionName())
+			h.Logger.Errorf("Rollback of DIG failed...")
+			return retCode
 		}
-		return
+		return nil
 	}
+	// The rollback is scoped to this DIG through the vars
+	h.Vars["deploymentIntentGroupName"] = h.DigData.Name
 
+	// 1. Create DIG and 2. its intents
+	s := newSaga("createDig", h).
+		addWorkflow("digpHandler", rollbackDig, h.Vars["compositeAppName"]+"_digp").
+		addWorkflow("placementIntentHandler", nil, h.Vars["compositeAppName"]+"_gpint")
 	// 3. Create DTC Traffic Group Intents
 	if h.DigData.DtcIntents {
-		h.Vars["deploymentIntentGroupName"] = h.DigData.Name // SANDEEP : is this gettign initalized anywhere ?
-		dtcHandler := &dtcIntentHandler{}
-		dtcHandler.orchInstance = h
-		dtcintentStatus := addDtcIntent(dtcHandler)
-		if dtcintentStatus != nil {
-			if intval, ok := dtcintentStatus.(int); ok {
-				w.WriteHeader(intval)
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-			}
-			// Rollback DIG
-			retCode, _ := h.DeleteDig("remote")
-			if retCode != http.StatusNoContent {
-				log.Errorf("Rollback of DIG failed...")
-			}
-			if _, err := w.Write(h.response.payload["testdtc"]); err != nil {
-				log.Error(err, PrintFunctionName())
-			}
-			return
-		}
+		s.addWorkflow("dtcIntentHandler", nil, "testdtc")
 	}
-
 	// If the metadata contains network interface request then call the
 	// network intent related part of the workflow.
 	if h.DigData.NwIntents {
-		nwHandler := &networkIntentHandler{}
-		nwHandler.orchInstance = h
-		nwIntentStatus := addNetworkIntent(nwHandler)
-		if nwIntentStatus != nil {
-			if intval, ok := nwIntentStatus.(int); ok {
-				w.WriteHeader(intval)
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-			}
-			// Rollback DIG
-			retCode, _ := h.DeleteDig("remote")
-			if retCode != http.StatusNoContent {
-				log.Errorf("Rollback of DIG failed...")
-			}
-			if _, err := w.Write(h.response.payload[h.Vars["compositeAppName"]+"_nwctlint"]); err != nil {
-				log.Error(err, PrintFunctionName())
-			}
-			return
-		}
+		s.addWorkflow("networkIntentHandler", nil, h.Vars["compositeAppName"]+"_nwctlint")
 	}
-
 	// If the metadata contains resource information, create resources and customizations
-	h.createUpdateK8sResource(w, storeType)
+	s.addWorkflow("genericK8sIntentHandler", nil, "")
+
+	report, status := s.run()
+	if status != nil {
+		writeSagaFailure(w, report, status)
+		return false
+	}
+	return true
 }
 
-// Checkout DIG to middleend collection for migrate
-func (h *OrchestrationHandler) CheckoutDIGForMigrate(targetVersion string, w http.ResponseWriter) error {
+// readMigrateCheckout reads from EMCO the DIG to check out for a migrate
+// to targetVersion into DigData, the version of the route is then
+// targetVersion
+func (h *OrchestrationHandler) readMigrateCheckout(targetVersion string, w http.ResponseWriter) error {
 	dStore := &remoteStoreDigHandler{}
 	dStore.orchInstance = h
 	h.digStore = dStore
@@ -810,31 +748,26 @@ func (h *OrchestrationHandler) CheckoutDIGForMigrate(targetVersion string, w htt
 	// Check if DIG with targetVersion already exists
 	_, err := h.digStore.getDig(h.Vars["projectName"],
 		h.Vars["compositeAppName"], targetVersion, h.Vars["deploymentIntentGroupName"])
-	// log.Infof("Fetch DIG status: %d", retcode)
+	// h.Logger.Infof("Fetch DIG status: %d", retcode)
 	if err != nil {
-		log.Errorf("Failed to read digp")
+		h.Logger.Errorf("Failed to read digp")
 		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
 
-	retCode, _ := h.DeleteDig("local")
-	if retCode != http.StatusNoContent {
-		w.WriteHeader(retCode)
-		return fmt.Errorf("Delete dig status %d", retCode)
-	}
-
-	// If DIG with targetVersion exists, populate deployDigData, and create DIG in middleend collection
+	// If DIG with targetVersion exists, populate deployDigData from it
 	if targetDIGExists {
-		// As DIG for targetVersion already exists, invoking StoreDIG
-		h.Vars["version"] = targetVersion
+		h.Vars = h.digOf().atVersion(targetVersion).vars()
 		appList := make([]string, 0)
-		_ = h.readDIGData(w, "emco", appList)
-		h.createDigData(w, "middleend")
-		w.WriteHeader(http.StatusCreated)
+		if err := h.readDIGData(w, "emco", appList); err != nil {
+			h.Logger.Errorf("Failed to read the DIG %s: %s", h.Vars["deploymentIntentGroupName"], err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return err
+		}
 		return nil
 	}
 
-	// If DIG with targetVersion does not exists, populate deployDigData, and create DIG in middleend collection
+	// If DIG with targetVersion does not exists, populate deployDigData from the source version
 	// Read sourceVersion DIG data
 	dataPoints := []string{
 		"projectHandler", "compAppHandler", "ProfileHandler",
@@ -859,11 +792,11 @@ func (h *OrchestrationHandler) CheckoutDIGForMigrate(targetVersion string, w htt
 	}
 
 	sourceVersionData := h.dataRead
-	log.Debugf("source DIG data: %+v", sourceVersionData)
+	h.Logger.Debugf("source DIG data: %+v", sourceVersionData)
 
 	// Read targetVersion service data
 	dataPoints = []string{"projectHandler", "compAppHandler", "ProfileHandler"}
-	h.Vars["version"] = targetVersion
+	h.Vars = h.digOf().atVersion(targetVersion).vars()
 	h.prepTreeReq()
 	h.dataRead = &ProjectTree{}
 	dStore = &remoteStoreDigHandler{}
@@ -875,7 +808,7 @@ func (h *OrchestrationHandler) CheckoutDIGForMigrate(targetVersion string, w htt
 	}
 
 	targetVersionData := h.dataRead
-	log.Debugf("target DIG data: %+v", targetVersionData)
+	h.Logger.Debugf("target DIG data: %+v", targetVersionData)
 
 	// Populate deployDigData
 	var jsonData deployDigData
@@ -936,12 +869,10 @@ func (h *OrchestrationHandler) CheckoutDIGForMigrate(targetVersion string, w htt
 			o.ValuesObj = v
 			jsonData.Spec.OverrideValuesObj = append(jsonData.Spec.OverrideValuesObj, o)
 		}
-		log.Debugf("json data for migrate checkout: +%v", jsonData)
+		h.Logger.Debugf("json data for migrate checkout: +%v", jsonData)
 	}
 	h.DigData.NwIntents = true
 	h.DigData = jsonData
-	h.createDigData(w, "middleend")
-	w.WriteHeader(http.StatusCreated)
 	return nil
 }
 
@@ -986,7 +917,7 @@ func (h *OrchestrationHandler) readDIGData(w http.ResponseWriter, storeType stri
 	if err != nil {
 		return err
 	}
-	log.Debugf("readDIGData() Data Read : +%v", h.dataRead)
+	h.Logger.Debugf("readDIGData() Data Read : +%v", h.dataRead)
 
 	for _, compositeAppValue := range h.dataRead.compositeAppMap {
 		jsonData.CompositeAppName = compositeAppValue.Metadata.Metadata.Name
@@ -1040,7 +971,7 @@ func (h *OrchestrationHandler) readDIGData(w http.ResponseWriter, storeType stri
 				jsonData.Spec.Apps = meta
 				jsonData.Spec.ProjectName = h.Vars["projectName"]
 				jsonData.Spec.OverrideValuesObj = digValue.DigpData.Spec.OverrideValuesObj
-				log.Debugf("json data: +%v", jsonData)
+				h.Logger.Debugf("json data: +%v", jsonData)
 			}
 		}
 		h.DigData.NwIntents = true
@@ -1052,14 +983,14 @@ func (h *OrchestrationHandler) readDIGData(w http.ResponseWriter, storeType stri
 func (h *OrchestrationHandler) PopulateIntents(digValue *DigReadData, meta []appsData, appList []string) {
 	// Populate the generic placement intents
 	SourceGpintMap := digValue.GpintMap
-	log.Debugf("SourceGpintMap: %+v", SourceGpintMap)
+	h.Logger.Debugf("SourceGpintMap: %+v", SourceGpintMap)
 	for _, gpintValue := range SourceGpintMap {
 		for k := range gpintValue.AppIntentArray {
 			for m, app := range meta {
 				if len(appList) == 0 || h.isAppExists(app.Metadata.Name, appList) {
 					if app.Metadata.Name == gpintValue.AppIntentArray[k].Spec.AppName {
 						meta[m].Clusters = make([]ClusterInfo, 0)
-						log.Infof("app name %s : %s %d", app.Metadata.Name, gpintValue.AppIntentArray[k].Spec.AppName, m)
+						h.Logger.Infof("app name %s : %s %d", app.Metadata.Name, gpintValue.AppIntentArray[k].Spec.AppName, m)
 						for i := range gpintValue.AppIntentArray[k].Spec.Intent.AllOfArray {
 							var cluster ClusterInfo
 							cluster.SelectedClusters = make([]SelectedCluster, 0)
@@ -1102,12 +1033,12 @@ func (h *OrchestrationHandler) PopulateIntents(digValue *DigReadData, meta []app
 	}
 
 	networkIntents := digValue.NwintMap
-	log.Debugf("PopulateIntents() networkIntents: %+v", networkIntents)
+	h.Logger.Debugf("PopulateIntents() networkIntents: %+v", networkIntents)
 	for _, nwintValue := range networkIntents {
 		for _, workloadIntents := range nwintValue.WrkintMap {
 			appName := workloadIntents.Wrkint.Spec.AppName
 			for m, app := range meta {
-				log.Debugf("PopulateIntents() appName %s == app.Metadata.Name %s", appName, app.Metadata.Name)
+				h.Logger.Debugf("PopulateIntents() appName %s == app.Metadata.Name %s", appName, app.Metadata.Name)
 				if len(appList) == 0 || h.isAppExists(app.Metadata.Name, appList) {
 					if app.Metadata.Name == appName {
 						meta[m].Interfaces = make([]NwInterfaces, len(workloadIntents.Interfaces))
@@ -1128,7 +1059,9 @@ func (h *OrchestrationHandler) CheckoutDIGForUpdate(w http.ResponseWriter) {
 	// Read data from EMCO and write to middleend
 	appList := make([]string, 0)
 	_ = h.readDIGData(w, "emco", appList)
-	h.createDigData(w, "middlend")
+	if !h.createDigData(w, "middlend") {
+		return
+	}
 
 	w.WriteHeader(http.StatusCreated)
 }
@@ -1138,28 +1071,55 @@ func (h *OrchestrationHandler) ScaleOutDig(w http.ResponseWriter, r *http.Reques
 	q := r.URL.Query()
 	q.Set("operation", "update")
 	r.URL.RawQuery = q.Encode()
-	h.CheckoutDIG(w, r)
-	log.Debugf("1. Header value %s", w.Header())
+	done := h.opStep("checkout")
+	rw := httptest.NewRecorder()
+	h.CheckoutDIG(rw, r)
+	if !endRecordedStep(w, rw, done, "checkout failed") {
+		return
+	}
+	h.Logger.Debugf("1. Header value %s", rw.Header())
 
 	// 2. PUT the intet update
 	q = r.URL.Query()
 	q.Set("operation", "save")
 	r.URL.RawQuery = q.Encode()
-	h.DigUpdateHandler(w, r)
-	log.Debugf("2. Header value %s", w.Header())
+	done = h.opStep("saveIntents")
+	rw = httptest.NewRecorder()
+	h.DigUpdateHandler(rw, r)
+	if !endRecordedStep(w, rw, done, "saving the intents failed") {
+		return
+	}
+	h.Logger.Debugf("2. Header value %s", rw.Header())
 
-	// 3. Submit the dig update
+	// 3. Submit the dig update, or roll it out in the waves of the rollout
+	// form field
+	if rollout := r.FormValue("rollout"); rollout != "" {
+		var req RolloutRequest
+		if err := json.Unmarshal([]byte(rollout), &req); err != nil {
+			http.Error(w, "invalid rollout request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.startRollout(w, req)
+		return
+	}
 	h.UpgradeDIG(w, r)
-	log.Debugf("3. Header value %s", w.Header())
+	h.Logger.Debugf("3. Header value %s", w.Header())
 }
 
-// Checkout DIG information to middleend collection
+// Checkout DIG information to middleend collection. The DIG is read from
+// EMCO first; the lease, the check for an existing checkout and the copy
+// of the DIG with its intents are then one transaction, so concurrent
+// checkouts of the DIG do not interleave.
 func (h *OrchestrationHandler) CheckoutDIG(w http.ResponseWriter, r *http.Request) {
 	h.Vars = mux.Vars(r)
 	h.InitializeResponseMap()
 
 	filter := r.URL.Query().Get("operation")
 	targetVersion := r.URL.Query().Get("targetVersion")
+	if filter != "migrate" && filter != "update" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
 	var version string
 	if targetVersion != "" {
@@ -1167,33 +1127,78 @@ func (h *OrchestrationHandler) CheckoutDIG(w http.ResponseWriter, r *http.Reques
 	} else {
 		version = h.Vars["version"]
 	}
+	h.state.checkoutOperation = filter
+	h.state.originalVersion = h.Vars["version"]
 
+	// An existing checkout is kept, the DIG is read only for a new one
 	localDigStore := localStoreDigHandler{}
-	// Check if checkout version already exists
+	localDigStore.orchInstance = h
 	_, err := localDigStore.getDig(h.Vars["projectName"],
 		h.Vars["compositeAppName"], version, h.Vars["deploymentIntentGroupName"])
-	if err == nil {
-		log.Infof("Checkout for DIG %s already exists", h.Vars["deploymentIntentGroupName"])
-		w.WriteHeader(http.StatusOK)
+	read := false
+	if err != nil {
+		if filter == "migrate" {
+			if err := h.readMigrateCheckout(targetVersion, w); err != nil {
+				return
+			}
+		} else {
+			appList := make([]string, 0)
+			if err := h.readDIGData(w, "emco", appList); err != nil {
+				h.Logger.Errorf("Failed to read the DIG %s: %s", h.Vars["deploymentIntentGroupName"], err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		read = true
+	}
+
+	h.replyInTransaction(w, func(w http.ResponseWriter) {
+		h.saveDIGCheckout(w, filter, version, read)
+	})
+}
+
+// saveDIGCheckout takes the lease of the checkout and copies the DIG read in
+// DigData to the middleend collection, unless the checkout exists
+func (h *OrchestrationHandler) saveDIGCheckout(w http.ResponseWriter, filter string, version string, read bool) {
+	// The checkout is held by the caller until the lease expires
+	lease, err := h.acquireLease(version, filter)
+	if err != nil {
+		h.replyLeaseError(w, err)
 		return
 	}
+	w.Header().Set("ETag", lease.ETag)
 
-	if filter == "migrate" {
-		h.Vars["operation"] = "migrate"
-		h.Vars["originalversion"] = h.Vars["version"]
-		_ = h.CheckoutDIGForMigrate(targetVersion, w)
+	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
+	// Check if checkout version already exists
+	_, err = localDigStore.getDig(h.Vars["projectName"],
+		h.Vars["compositeAppName"], version, h.Vars["deploymentIntentGroupName"])
+	if err == nil {
+		h.Logger.Infof("Checkout for DIG %s already exists", h.Vars["deploymentIntentGroupName"])
+		w.WriteHeader(http.StatusOK)
 		return
 	}
-	if filter == "update" {
-		h.Vars["operation"] = "update"
-		h.Vars["originalversion"] = h.Vars["version"]
-		h.CheckoutDIGForUpdate(w)
+	if !read {
+		http.Error(w, fmt.Sprintf("the checkout of DIG %s was discarded meanwhile, check it out again",
+			h.Vars["deploymentIntentGroupName"]), http.StatusConflict)
 		return
 	}
-	if filter != "migrate" && filter != "update" {
-		w.WriteHeader(http.StatusBadRequest)
+
+	if filter == "migrate" {
+		// The checkout of the original version is replaced
+		req := h.digOf().atVersion(version)
+		h.Vars = req.atVersion(h.state.originalVersion).vars()
+		retCode, _ := h.DeleteDig("local")
+		h.Vars = req.vars()
+		if retCode != http.StatusNoContent {
+			w.WriteHeader(retCode)
+			return
+		}
+	}
+	if !h.createDigData(w, "middleend") {
 		return
 	}
+	w.WriteHeader(http.StatusCreated)
 }
 
 func (h *OrchestrationHandler) UpdateIntents(w http.ResponseWriter) error {
@@ -1254,22 +1259,29 @@ func (h *OrchestrationHandler) UpdateIntents(w http.ResponseWriter) error {
 			for gpintName, gpintValue := range digValue.GpintMap {
 				for _, appIntent := range gpintValue.AppIntentArray {
 					if appIntent.MetaData.UserData1 == "updated" {
-						// Play updated changes on EMCO
+						// Play updated changes on EMCO, an intent which is gone
+						// already is created again
 						retcode, err := temp.orchInstance.bstore.deleteAppPIntent(appIntent.MetaData.Name, h.Vars["projectName"],
 							h.Vars["compositeAppName"], h.Vars["version"], gpintName, digName)
 						if err != nil {
-							log.Errorf("%s", err)
+							h.Logger.Errorf("%s", err)
 							w.WriteHeader(retcode.(int))
 							return fmt.Errorf("%s", err)
 						}
+						if code := retcode.(int); code >= http.StatusBadRequest && code != http.StatusNotFound {
+							return h.replyIntentFailure(w, code, gpintName, "deleting the app intent "+appIntent.MetaData.Name)
+						}
 
 						retcode, err = temp.orchInstance.bstore.createAppPIntent(appIntent, h.Vars["projectName"],
 							h.Vars["compositeAppName"], h.Vars["version"], digName, gpintName)
 						if err != nil {
-							log.Errorf("%s", err)
+							h.Logger.Errorf("%s", err)
 							w.WriteHeader(retcode.(int))
 							return fmt.Errorf("%s", err)
 						}
+						if code := retcode.(int); code >= http.StatusBadRequest {
+							return h.replyIntentFailure(w, code, h.Vars["compositeAppName"]+"_gpint", "creating the app intent "+appIntent.MetaData.Name)
+						}
 					}
 				}
 			}
@@ -1313,6 +1325,18 @@ func (h *OrchestrationHandler) UpdateIntents(w http.ResponseWriter) error {
 	return nil
 }
 
+// replyIntentFailure replies the status and the body EMCO replied to the
+// call of statusKey, which failed
+func (h *OrchestrationHandler) replyIntentFailure(w http.ResponseWriter, code int, statusKey string, action string) error {
+	body := h.response.payload[statusKey]
+	h.Logger.Errorf("%s replied status %d: %s", action, code, body)
+	w.WriteHeader(code)
+	if _, err := w.Write(body); err != nil {
+		h.Logger.Errorf("%s() : Failed to respond client: %s", PrintFunctionName(), err)
+	}
+	return fmt.Errorf("%s replied status %d", action, code)
+}
+
 // Perform DIG upgrade
 func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request) {
 	h.Vars = mux.Vars(r)
@@ -1320,22 +1344,31 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 
 	// Read DIG from middleend, and determine type of operation
 	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
 	tempDIG := localstore.DeploymentIntentGroup{}
 	retValue, err := localDigStore.getDig(h.Vars["projectName"],
 		h.Vars["compositeAppName"], h.Vars["version"], h.Vars["deploymentIntentGroupName"])
 	if err != nil {
-		log.Errorf("Failed to read digp")
+		h.Logger.Errorf("Failed to read digp")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	if err != nil {
-		log.Errorf("Failed to read digp")
+		h.Logger.Errorf("Failed to read digp")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.Unmarshal(retValue, &tempDIG); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.Logger.Error(err, PrintFunctionName())
+	}
+
+	// Only the holder of the checkout submits it
+	if h.state.plan == nil {
+		if err := h.checkLeaseOwner(); err != nil {
+			h.replyLeaseError(w, err)
+			return
+		}
 	}
 
 	targetDIGExists := true
@@ -1349,7 +1382,7 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 	_, err = h.digStore.getDig(h.Vars["projectName"],
 		h.Vars["compositeAppName"], h.Vars["version"], h.Vars["deploymentIntentGroupName"])
 	if err != nil {
-		log.Error("D Failed to read digp", err)
+		h.Logger.Error("D Failed to read digp", err)
 		targetDIGExists = false
 	}
 
@@ -1359,7 +1392,7 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 			h.Vars["version"],
 			h.Vars["deploymentIntentGroupName"])
 		if err != nil {
-			log.Errorf("Failed to read digp:md")
+			h.Logger.Errorf("Failed to read digp:md")
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -1367,7 +1400,7 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 		// Fetch the latest DIG state
 		state := digStatus.States.Actions[len(digStatus.States.Actions)-1].State
 		if tempDIG.MetaData.UserData1 == "update" && state != localstore.StateEnum.Instantiated {
-			log.Errorf("DIG %s is not instantiated", h.Vars["deploymentIntentGroupName"])
+			h.Logger.Errorf("DIG %s is not instantiated", h.Vars["deploymentIntentGroupName"])
 			w.WriteHeader(http.StatusExpectationFailed)
 			return
 		}
@@ -1375,25 +1408,52 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 
 	// Create DIG with targetVersion, if not exists, else update intents
 	if !targetDIGExists {
+		done := h.opStep("createDig")
 		appList := make([]string, 0)
-		_ = h.readDIGData(w, "middleend", appList)
-		h.createDigData(w, "emco")
+		if err := h.readDIGData(w, "middleend", appList); err != nil {
+			h.Logger.Errorf("Failed to read the DIG %s: %s", h.Vars["deploymentIntentGroupName"], err)
+			done(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !h.createDigData(w, "emco") {
+			done(fmt.Errorf("failed to create DIG %s", h.Vars["deploymentIntentGroupName"]))
+			return
+		}
+		done(nil)
 	} else {
-		_ = h.UpdateIntents(w)
+		if tempDIG.MetaData.UserData1 == "update" && h.state.plan == nil {
+			// Keep the intents of the current revision, to roll back to
+			h.recordRevisionStep(DigRevision{})
+		}
+		done := h.opStep("updateIntents")
+		rw := httptest.NewRecorder()
+		if err := h.UpdateIntents(rw); err != nil && rw.Code < http.StatusBadRequest {
+			h.Logger.Errorf("Failed to update the intents of %s: %s", h.Vars["deploymentIntentGroupName"], err)
+			done(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !endRecordedStep(w, rw, done, "updating the intents failed") {
+			return
+		}
 	}
 
+	migrated := false
 	if tempDIG.MetaData.UserData1 == "migrate" {
 		originalVersion := tempDIG.MetaData.UserData2
 		// Approve DIG with targetVersion
-		orchURL := "http://" + h.MiddleendConf.OrchService + "/v2/projects/" +
+		orchURL := h.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			h.Vars["projectName"] + "/composite-apps/" + h.Vars["compositeAppName"] +
 			"/" + h.Vars["version"] +
 			"/deployment-intent-groups/" + h.Vars["deploymentIntentGroupName"] + "/approve"
 
 		var jsonLoad []byte
+		done := h.opStep("approve")
 		retcode, err := h.apiPost(jsonLoad, orchURL, h.Vars["deploymentIntentGroupName"])
+		done(err)
 		if err != nil {
-			log.Errorf("Failed to invoke dig approve: %s", err)
+			h.Logger.Errorf("Failed to invoke dig approve: %s", err)
 			w.WriteHeader(retcode.(int))
 			return
 		}
@@ -1405,26 +1465,28 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 		temp.Spec.TargetDigName = h.Vars["deploymentIntentGroupName"]
 
 		jsonLoad, _ = json.Marshal(temp)
-		orchURL = "http://" + h.MiddleendConf.OrchService + "/v2/projects/" +
+		orchURL = h.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			h.Vars["projectName"] + "/composite-apps/" + h.Vars["compositeAppName"] +
 			"/" + originalVersion +
 			"/deployment-intent-groups/" + h.Vars["deploymentIntentGroupName"] + "/migrate"
 
+		done = h.opStep("migrate")
 		retcode, err = h.apiPost(jsonLoad, orchURL, h.Vars["deploymentIntentGroupName"])
 		if err != nil {
-			log.Errorf("Failed to invoke dig update: %s", err)
+			h.Logger.Errorf("Failed to invoke dig update: %s", err)
+			done(err)
 			w.WriteHeader(retcode.(int))
 			return
 		}
 
 		if retcode != http.StatusAccepted {
-			log.Errorf("Encountered error while migrating DIG %s", h.Vars["deploymentIntentGroupName"])
+			h.Logger.Errorf("Encountered error while migrating DIG %s", h.Vars["deploymentIntentGroupName"])
+			done(fmt.Errorf("migrate replied status %v", retcode))
 			w.WriteHeader(retcode.(int))
 			return
 		}
-
-		// Append current version to the list of version for which migrate occurred
-		h.UpdateDIGInfo()
+		done(nil)
+		migrated = true
 
 		w.WriteHeader(retcode.(int))
 	}
@@ -1432,33 +1494,66 @@ func (h *OrchestrationHandler) UpgradeDIG(w http.ResponseWriter, r *http.Request
 	if tempDIG.MetaData.UserData1 == "update" {
 		// Invoke EMCO update API
 		var jsonLoad []byte
-		orchURL := "http://" + newdStore.orchInstance.MiddleendConf.OrchService + "/v2/projects/" +
+		orchURL := newdStore.orchInstance.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			h.Vars["projectName"] + "/composite-apps/" + h.Vars["compositeAppName"] +
 			"/" + h.Vars["version"] +
 			"/deployment-intent-groups/" + h.Vars["deploymentIntentGroupName"] + "/update"
 
+		done := h.opStep("update")
 		retCode, err := newdStore.orchInstance.apiPost(jsonLoad, orchURL, h.Vars["deploymentIntentGroupName"])
 		if err != nil {
-			log.Errorf("Failed to invoke dig update: %s", err)
+			h.Logger.Errorf("Failed to invoke dig update: %s", err)
+			done(err)
 			w.WriteHeader(retCode.(int))
 			return
 		}
 
 		if retCode != http.StatusAccepted {
-			log.Errorf("Encountered error while updating DIG %s", h.Vars["deploymentIntentGroupName"])
+			h.Logger.Errorf("Encountered error while updating DIG %s", h.Vars["deploymentIntentGroupName"])
+			done(fmt.Errorf("update replied status %v", retCode))
 			w.WriteHeader(retCode.(int))
 			return
 		}
+		done(nil)
 
 		w.WriteHeader(retCode.(int))
 	}
 
-	// Delete checkout DIG
-	retcode, _ := h.DeleteDig("local")
-	if retcode != http.StatusNoContent {
+	// A dry run leaves the middleend store as it is
+	if h.state.plan != nil {
+		return
+	}
+
+	// Delete checkout DIG, in the transaction recording the migrated version
+	done := h.opStep("deleteCheckout")
+	retcode := http.StatusNoContent
+	err = h.inTransaction(func() error {
+		if migrated {
+			// Append current version to the list of version for which migrate occurred
+			if err := h.UpdateDIGInfo(); err != nil {
+				return err
+			}
+		}
+		retcode, _ = h.DeleteDig("local")
+		if retcode != http.StatusNoContent {
+			return fmt.Errorf("failed to delete the checked out DIG, status %d", retcode)
+		}
+		return h.removeLease()
+	})
+	if err != nil {
+		done(err)
+		if retcode == http.StatusNoContent {
+			retcode = http.StatusInternalServerError
+		}
 		w.WriteHeader(retcode)
 		return
 	}
+	done(nil)
+
+	// Snapshot the intents of the new revision
+	if migrated || tempDIG.MetaData.UserData1 == "update" {
+		h.recordRevisionStep(DigRevision{Operation: tempDIG.MetaData.UserData1})
+	}
 }
 
 // Get all DIGs
@@ -1540,7 +1635,7 @@ func (h *OrchestrationHandler) DeleteDig(filter string) (int, string) {
 	for compositeAppName, value := range h.dataRead.compositeAppMap {
 		for _, digValue := range h.dataRead.compositeAppMap[compositeAppName].DigMap {
 			if value.Metadata.Spec.Version == h.Vars["version"] {
-				log.Debugf("Found original version: %s", digValue.DigpData.MetaData.UserData2)
+				h.Logger.Debugf("Found original version: %s", digValue.DigpData.MetaData.UserData2)
 				originalVersion = digValue.DigpData.MetaData.UserData2
 				break
 			}
@@ -1548,7 +1643,7 @@ func (h *OrchestrationHandler) DeleteDig(filter string) (int, string) {
 	}
 
 	// 1. Call DIG delete workflow
-	log.Info("Start DIG delete workflow")
+	h.Logger.Info("Start DIG delete workflow")
 	deleteDataPoints := []string{
 		"networkIntentHandler",
 		"placementIntentHandler", "genericK8sIntentHandler", "dtcIntentHandler",
@@ -1563,7 +1658,7 @@ func (h *OrchestrationHandler) DeleteDig(filter string) (int, string) {
 			return http.StatusInternalServerError, originalVersion
 		}
 	}
-	log.Info("DIG delete workflow successful")
+	h.Logger.Info("DIG delete workflow successful")
 	return http.StatusNoContent, originalVersion
 }
 
@@ -1577,9 +1672,9 @@ func (h *OrchestrationHandler) AddDIGInfo() {
 	diginfo.DigName = digName
 	diginfo.VersionList = append(diginfo.VersionList, h.Vars["version"])
 
-	err := db.DBconn.Insert(DIG_INFO_COLLECTION, key, nil, "digmeta", diginfo)
+	err := db.DBconn.Insert(h.ctx, DIG_INFO_COLLECTION, key, nil, "digmeta", diginfo)
 	if err != nil {
-		log.Errorf("Encountered error during add of dig info for %s: %s", h.Vars["deploymentIntentGroupName"], err)
+		h.Logger.Errorf("Encountered error during add of dig info for %s: %s", h.Vars["deploymentIntentGroupName"], err)
 		return
 	}
 }
@@ -1588,9 +1683,9 @@ func (h *OrchestrationHandler) AddDIGInfo() {
 func (h *OrchestrationHandler) DeleteDIGInfo() {
 	key := DigInfoKey{DigName: h.Vars["deploymentIntentGroupName"]}
 
-	err := db.DBconn.Remove(DIG_INFO_COLLECTION, key)
+	err := db.DBconn.Remove(h.ctx, DIG_INFO_COLLECTION, key)
 	if err != nil {
-		log.Errorf("Encountered error during delete of dig info for %s: %s", h.Vars["deploymentIntentGroupName"], err)
+		h.Logger.Errorf("Encountered error during delete of dig info for %s: %s", h.Vars["deploymentIntentGroupName"], err)
 		return
 	}
 }
@@ -1599,55 +1694,57 @@ func (h *OrchestrationHandler) DeleteDIGInfo() {
 func (h *OrchestrationHandler) FetchDIGInfo(digName string) DigInfo {
 	var diginfo DigInfo
 	key := DigInfoKey{DigName: digName}
-	exists := db.DBconn.CheckCollectionExists(DIG_INFO_COLLECTION)
+	exists := db.DBconn.CheckCollectionExists(h.ctx, DIG_INFO_COLLECTION)
 	if exists {
-		values, err := db.DBconn.Find(DIG_INFO_COLLECTION, key, "digmeta")
+		values, err := db.DBconn.Find(h.ctx, DIG_INFO_COLLECTION, key, "digmeta")
 		if err != nil {
-			log.Errorf("Encountered error while fetching DIG info for %s: %s", digName, err)
+			h.Logger.Errorf("Encountered error while fetching DIG info for %s: %s", digName, err)
 			return diginfo
 		} else if len(values) == 0 {
-			log.Infof("DIG info does not exists")
+			h.Logger.Infof("DIG info does not exists")
 			return diginfo
 		}
 		err = db.DBconn.Unmarshal(values[0], &diginfo)
-		log.Infof("DIG Info after Unmarshalling: %s", diginfo)
+		h.Logger.Infof("DIG Info after Unmarshalling: %+v", diginfo)
 		if err != nil {
-			log.Errorf("Unmarshalling DIG Info failed: %s", err)
+			h.Logger.Errorf("Unmarshalling DIG Info failed: %s", err)
 			return diginfo
 		}
 	}
 	return diginfo
 }
 
-// Update DIG Info collection
-func (h *OrchestrationHandler) UpdateDIGInfo() {
-	var diginfo DigInfo
+// UpdateDIGInfo adds the current version to the versions of the DIG. The
+// read and the write of the info are one transaction, so that concurrent
+// updates do not lose a version.
+func (h *OrchestrationHandler) UpdateDIGInfo() error {
 	key := DigInfoKey{DigName: h.Vars["deploymentIntentGroupName"]}
-	exists := db.DBconn.CheckCollectionExists(DIG_INFO_COLLECTION)
-	if exists {
-		values, err := db.DBconn.Find(DIG_INFO_COLLECTION, key, "digmeta")
+	return h.inTransaction(func() error {
+		var diginfo DigInfo
+		values, err := db.DBconn.Find(h.ctx, DIG_INFO_COLLECTION, key, "digmeta")
 		if err != nil {
-			log.Errorf("Encountered error while fetching draft composite application: %s", err)
-			return
+			h.Logger.Errorf("Encountered error while fetching DIG info for %s: %s", key.DigName, err)
+			return err
 		} else if len(values) == 0 {
-			log.Infof("DIG info does not exists")
-			return
+			h.Logger.Infof("DIG info does not exists")
+			return nil
 		}
 
 		err = db.DBconn.Unmarshal(values[0], &diginfo)
-		log.Infof("DIG Info after Unmarshalling: %s", diginfo)
+		h.Logger.Infof("DIG Info after Unmarshalling: %+v", diginfo)
 		if err != nil {
-			log.Errorf("Unmarshalling DIG Info failed: %s", err)
-			return
+			h.Logger.Errorf("Unmarshalling DIG Info failed: %s", err)
+			return err
 		}
 
 		// Add current version to the list of versions of composite-app mapped to DIG
 		diginfo.VersionList = append(diginfo.VersionList, h.Vars["version"])
 
-		err = db.DBconn.Insert(DIG_INFO_COLLECTION, key, nil, "digmeta", diginfo)
+		err = db.DBconn.Insert(h.ctx, DIG_INFO_COLLECTION, key, nil, "digmeta", diginfo)
 		if err != nil {
-			log.Errorf("Encountered error during update of dig info for %s: %s", h.Vars["deploymentIntentGroupName"], err)
-			return
+			h.Logger.Errorf("Encountered error during update of dig info for %s: %s", key.DigName, err)
+			return err
 		}
-	}
+		return nil
+	})
 }
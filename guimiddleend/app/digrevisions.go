@@ -0,0 +1,479 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"time"
+
+	"example.com/middleend/db"
+	"example.com/middleend/localstore"
+	"github.com/gorilla/mux"
+)
+
+// maxDigRevisions bounds the intent snapshots kept in the info of a DIG,
+// the oldest are dropped first
+const maxDigRevisions = 20
+
+// DigRevision is the snapshot of the intents of a DIG at a revision of
+// EMCO, recorded in the DIG info when the middleend changes the DIG. The
+// intents are kept as json, which the store does not alter.
+type DigRevision struct {
+	Revision            int             `json:"revision"`
+	CompositeAppVersion string          `json:"compositeAppVersion"`
+	Operation           string          `json:"operation"`
+	RolledBackTo        int             `json:"rolledBackTo,omitempty"`
+	Time                time.Time       `json:"time"`
+	Intents             json.RawMessage `json:"intents"`
+}
+
+// intents decodes the snapshot, nil when there is none
+func (r *DigRevision) intents() (*deployDigData, error) {
+	if r == nil || len(r.Intents) == 0 {
+		return nil, nil
+	}
+	d := &deployDigData{}
+	if err := json.Unmarshal(r.Intents, d); err != nil {
+		return nil, fmt.Errorf("failed to read the intents of revision %d: %s", r.Revision, err)
+	}
+	return d, nil
+}
+
+// revision returns the snapshot of revision in version, nil when there is
+// none
+func (d DigInfo) revision(version string, revision int) *DigRevision {
+	for i := range d.Revisions {
+		if d.Revisions[i].CompositeAppVersion == version && d.Revisions[i].Revision == revision {
+			return &d.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// addRevision records rev, over the snapshot of the same revision
+func (d *DigInfo) addRevision(rev DigRevision) {
+	if old := d.revision(rev.CompositeAppVersion, rev.Revision); old != nil {
+		*old = rev
+		return
+	}
+	d.Revisions = append(d.Revisions, rev)
+	if len(d.Revisions) > maxDigRevisions {
+		d.Revisions = d.Revisions[len(d.Revisions)-maxDigRevisions:]
+	}
+}
+
+// DigRevisionView is a revision of a DIG: the last state EMCO reached at
+// the revision, with the snapshot of its intents when the middleend
+// recorded one
+type DigRevisionView struct {
+	Revision  int            `json:"revision"`
+	State     string         `json:"state"`
+	Time      time.Time      `json:"time"`
+	Current   bool           `json:"current"`
+	Operation string         `json:"operation,omitempty"`
+	Intents   *deployDigData `json:"intents,omitempty"`
+}
+
+// DigRevisions lists the revisions of a DIG in a composite app version
+type DigRevisions struct {
+	Current   int               `json:"current"`
+	Revisions []DigRevisionView `json:"revisions"`
+	// Versions are the composite app versions the DIG went through
+	Versions []string `json:"versions"`
+}
+
+// DigRevisionDiff lists the changes a rollback to Revision makes to the
+// intents of the Current revision
+type DigRevisionDiff struct {
+	Revision int            `json:"revision"`
+	Current  int            `json:"current"`
+	Changes  []IntentChange `json:"changes"`
+}
+
+// DigRollbackRequest is the body of a rollback
+type DigRollbackRequest struct {
+	Revision    int    `json:"revision" binding:"required"`
+	Description string `json:"description"`
+}
+
+// digURL is the EMCO URL of the DIG of the route
+func (h *OrchestrationHandler) digURL() string {
+	return h.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
+		h.Vars["projectName"] + "/composite-apps/" + h.Vars["compositeAppName"] +
+		"/" + h.Vars["version"] +
+		"/deployment-intent-groups/" + h.Vars["deploymentIntentGroupName"]
+}
+
+// digRevisionActions reads the status actions of the DIG, each of them
+// names the revision EMCO was at
+func (h *OrchestrationHandler) digRevisionActions() ([]digActions, error) {
+	dStore := &remoteStoreDigHandler{}
+	dStore.orchInstance = h
+	status, err := dStore.getDigStatus(h.digURL()+"/status", h.Vars["compositeAppName"]+"_digpStatus",
+		[][]string{{"status", "deployed"}})
+	if err != nil {
+		return nil, err
+	}
+	return status.States.Actions, nil
+}
+
+// currentRevision is the latest revision of the actions
+func currentRevision(actions []digActions) int {
+	current := 0
+	for _, a := range actions {
+		if a.Revision > current {
+			current = a.Revision
+		}
+	}
+	return current
+}
+
+// currentIntents reads the intents of the DIG from EMCO
+func (h *OrchestrationHandler) currentIntents() (*deployDigData, error) {
+	if err := h.readDIGData(httptest.NewRecorder(), "emco", []string{}); err != nil {
+		return nil, err
+	}
+	d := h.DigData
+	return &d, nil
+}
+
+// readDIGInfo reads the info of the DIG of the route, which is empty when
+// there is none
+func (h *OrchestrationHandler) readDIGInfo() (DigInfo, error) {
+	var diginfo DigInfo
+	key := DigInfoKey{DigName: h.Vars["deploymentIntentGroupName"]}
+	values, err := db.DBconn.Find(h.ctx, DIG_INFO_COLLECTION, key, "digmeta")
+	if err != nil || len(values) == 0 || values[0] == nil {
+		return diginfo, err
+	}
+	err = db.DBconn.Unmarshal(values[0], &diginfo)
+	return diginfo, err
+}
+
+// recordRevision snapshots the intents of the DIG at its current revision
+// in the DIG info. A snapshot of the revision recorded already is kept
+// when rev has no operation, so that the state before a change can be
+// recorded any number of times.
+func (h *OrchestrationHandler) recordRevision(rev DigRevision) error {
+	actions, err := h.digRevisionActions()
+	if err != nil {
+		return err
+	}
+	rev.Revision = currentRevision(actions)
+	rev.CompositeAppVersion = h.Vars["version"]
+	rev.Time = time.Now().UTC()
+
+	if rev.Operation == "" {
+		diginfo, err := h.readDIGInfo()
+		if err != nil {
+			return err
+		}
+		if diginfo.revision(rev.CompositeAppVersion, rev.Revision) != nil {
+			return nil
+		}
+	}
+
+	intents, err := h.currentIntents()
+	if err != nil {
+		return err
+	}
+	if rev.Intents, err = json.Marshal(intents); err != nil {
+		return err
+	}
+
+	key := DigInfoKey{DigName: h.Vars["deploymentIntentGroupName"]}
+	return h.inTransaction(func() error {
+		diginfo, err := h.readDIGInfo()
+		if err != nil {
+			return err
+		}
+		if diginfo.DigName == "" {
+			// a DIG created before the middleend kept its info
+			diginfo.DigName = key.DigName
+			diginfo.VersionList = []string{h.Vars["version"]}
+		}
+		diginfo.addRevision(rev)
+		return db.DBconn.Insert(h.ctx, DIG_INFO_COLLECTION, key, nil, "digmeta", diginfo)
+	})
+}
+
+// recordRevisionStep runs recordRevision as a step of the operation. The
+// change of the DIG is done by then, failing to record it only loses the
+// snapshot, so the failure is logged and the operation goes on.
+func (h *OrchestrationHandler) recordRevisionStep(rev DigRevision) {
+	done := h.opStep("recordRevision")
+	err := h.recordRevision(rev)
+	if err != nil {
+		h.Logger.Warnf("Failed to record the revision of DIG %s: %s", h.Vars["deploymentIntentGroupName"], err)
+	}
+	done(err)
+}
+
+// GetDigRevisions lists the revisions of the DIG, from its status actions
+// and the snapshots of its DIG info
+func (h *OrchestrationHandler) GetDigRevisions(r *http.Request) (interface{}, error) {
+	h.Vars = mux.Vars(r)
+	h.InitializeResponseMap()
+
+	actions, err := h.digRevisionActions()
+	if err != nil {
+		return nil, err
+	}
+	diginfo, err := h.readDIGInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	res := DigRevisions{Current: currentRevision(actions), Revisions: []DigRevisionView{}, Versions: diginfo.VersionList}
+	index := map[int]int{}
+	for _, a := range actions {
+		i, ok := index[a.Revision]
+		if !ok {
+			i = len(res.Revisions)
+			index[a.Revision] = i
+			res.Revisions = append(res.Revisions, DigRevisionView{Revision: a.Revision})
+		}
+		res.Revisions[i].State = a.State
+		res.Revisions[i].Time = a.Time
+	}
+	for i := range res.Revisions {
+		v := &res.Revisions[i]
+		v.Current = v.Revision == res.Current
+		if snapshot := diginfo.revision(h.Vars["version"], v.Revision); snapshot != nil {
+			v.Operation = snapshot.Operation
+			if v.Intents, err = snapshot.intents(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(res.Revisions, func(i, j int) bool { return res.Revisions[i].Revision < res.Revisions[j].Revision })
+	return res, nil
+}
+
+// GetDigRevisionDiff previews a rollback: it lists the changes from the
+// intents of the DIG to those of the snapshot of the revision
+func (h *OrchestrationHandler) GetDigRevisionDiff(r *http.Request) (interface{}, error) {
+	h.Vars = mux.Vars(r)
+	h.InitializeResponseMap()
+
+	revision, err := strconv.Atoi(h.Vars["revision"])
+	if err != nil {
+		return nil, &apiError{code: http.StatusBadRequest, msg: "invalid revision " + h.Vars["revision"]}
+	}
+	diginfo, err := h.readDIGInfo()
+	if err != nil {
+		return nil, err
+	}
+	target, err := diginfo.revision(h.Vars["version"], revision).intents()
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, &apiError{code: http.StatusNotFound, msg: fmt.Sprintf("no snapshot of the intents at revision %d", revision)}
+	}
+
+	actions, err := h.digRevisionActions()
+	if err != nil {
+		return nil, err
+	}
+	current, err := h.currentIntents()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffIntents(current, target)
+	if err != nil {
+		return nil, err
+	}
+	return DigRevisionDiff{Revision: revision, Current: currentRevision(actions), Changes: changes}, nil
+}
+
+// RollbackDIG rolls the DIG back to a previous revision with the EMCO
+// rollback API. When the DIG info holds the snapshot of the revision, the
+// intents are restored from it, any checkout of the DIG being discarded,
+// and the rollback is recorded as a revision of its own.
+func (h *OrchestrationHandler) RollbackDIG(w http.ResponseWriter, r *http.Request) {
+	h.Vars = mux.Vars(r)
+	h.InitializeResponseMap()
+
+	var req DigRollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid rollback request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.rollbackTo(w, req.Revision, req.Description) {
+		return
+	}
+	// A paused rollout of the DIG ends with the rollback
+	h.endRollout(RolloutRolledBack, fmt.Sprintf("rolled back to revision %d", req.Revision))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// rollbackTo rolls the DIG back to revision, on failure the reply is
+// written on w and false is returned
+func (h *OrchestrationHandler) rollbackTo(w http.ResponseWriter, revision int, description string) bool {
+	dig := h.Vars["deploymentIntentGroupName"]
+
+	done := h.opStep("checkRevision")
+	actions, err := h.digRevisionActions()
+	if err != nil {
+		done(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	known := false
+	for _, a := range actions {
+		known = known || a.Revision == revision
+	}
+	current := currentRevision(actions)
+	if !known || revision == current {
+		err = fmt.Errorf("DIG %s cannot be rolled back to revision %d, its current revision is %d", dig, revision, current)
+		done(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	diginfo, err := h.readDIGInfo()
+	var snapshot *deployDigData
+	if err == nil {
+		snapshot, err = diginfo.revision(h.Vars["version"], revision).intents()
+	}
+	done(err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	// Invoke EMCO rollback API
+	var rollback localstore.RollbackJson
+	rollback.MetaData.Description = description
+	if rollback.MetaData.Description == "" {
+		rollback.MetaData.Description = fmt.Sprintf("Rollback DIG to revision %d", revision)
+	}
+	rollback.Spec.Revison = strconv.Itoa(revision)
+	jsonLoad, _ := json.Marshal(rollback)
+
+	done = h.opStep("rollback")
+	retcode, err := h.apiPost(jsonLoad, h.digURL()+"/rollback", dig)
+	if err = stepError(retcode.(int), http.StatusAccepted, err); err != nil {
+		h.Logger.Errorf("Encountered error while rolling back DIG %s: %s", dig, err)
+		done(err)
+		w.WriteHeader(retcode.(int))
+		if _, werr := w.Write(h.response.payload[dig]); werr != nil {
+			h.Logger.WithError(werr).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		}
+		return false
+	}
+	done(nil)
+
+	if snapshot == nil {
+		h.opSkip("restoreIntents", fmt.Sprintf("no snapshot of the intents at revision %d", revision))
+	} else if !h.restoreIntents(w, *snapshot) {
+		return false
+	}
+
+	h.recordRevisionStep(DigRevision{Operation: "rollback", RolledBackTo: revision})
+	return true
+}
+
+// restoreIntents plays the intents of a snapshot to EMCO: they are saved
+// over a fresh checkout of the DIG for update, which is then submitted
+// like an update, without the EMCO update call the rollback stands for.
+// On failure the reply is written on w and false is returned.
+func (h *OrchestrationHandler) restoreIntents(w http.ResponseWriter, intents deployDigData) bool {
+	done := h.opStep("discardCheckout")
+	if err := h.discardCheckout(h.Vars["version"]); err != nil {
+		done(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	done(nil)
+
+	done = h.opStep("checkout")
+	rw := httptest.NewRecorder()
+	h.state.checkoutOperation = "update"
+	h.state.originalVersion = h.Vars["version"]
+	h.CheckoutDIGForUpdate(rw)
+	if !endRecordedStep(w, rw, done, "checkout failed") {
+		return false
+	}
+
+	done = h.opStep("saveIntents")
+	if err := h.saveCheckoutIntents(intents.Spec.Apps); err != nil {
+		done(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	done(nil)
+
+	done = h.opStep("updateIntents")
+	rw = httptest.NewRecorder()
+	err := h.UpdateIntents(rw)
+	if err == nil && rw.Code >= http.StatusBadRequest {
+		err = recorderError(rw, "updating the intents failed")
+	}
+	if err != nil {
+		done(err)
+		http.Error(w, "the DIG is rolled back but its intents are not restored: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	done(nil)
+
+	done = h.opStep("deleteCheckout")
+	retcode, _ := h.DeleteDig("local")
+	if err := stepError(retcode, http.StatusNoContent, nil); err != nil {
+		done(err)
+		http.Error(w, "failed to delete the checked out DIG: "+err.Error(), retcode)
+		return false
+	}
+	// The checkout discarded above had its lease too
+	if err := h.removeLease(); err != nil {
+		done(err)
+		http.Error(w, "failed to release the checkout of the DIG: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	done(nil)
+	return true
+}
+
+// saveCheckoutIntents saves the placement and network intents of apps
+// over the checkout of the DIG, like DigUpdateHandler
+func (h *OrchestrationHandler) saveCheckoutIntents(apps []appsData) error {
+	h.DigData = deployDigData{}
+	h.DigData.Name = h.Vars["deploymentIntentGroupName"]
+	h.DigData.CompositeAppName = h.Vars["compositeAppName"]
+	h.DigData.NwIntents = true
+	h.DigData.Spec.Apps = apps
+	bstore := &localStoreIntentHandler{}
+	bstore.orchInstance = h
+	h.bstore = bstore
+	h.state.updateIntent = true
+	intentHandler := &placementIntentHandler{}
+	intentHandler.orchInstance = h
+	status := intentHandler.createObject()
+	if status == nil {
+		nwHandler := &networkIntentHandler{}
+		nwHandler.orchInstance = h
+		status = nwHandler.createObject()
+	}
+	if status != nil {
+		return fmt.Errorf("saving the intents failed: %v", status)
+	}
+	return nil
+}
@@ -2,63 +2,78 @@ package app
 
 import "net/http"
 
-func RegisterDIGHandlers(handle HandleFunc, bootConf MiddleendConfig) {
-	handle(digUriPattern, func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetAllDigs(w, r)
-	}).Methods("GET")
+func (s *Server) registerDIGHandlers(handle HandleFunc) {
+	handle(digUriPattern, s.api((*OrchestrationHandler).GetAllDigs)).Methods("GET")
 
-	handle(digUriPattern, func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DelDig(w, r)
-	}).Methods("DELETE")
+	handle(digUriPattern, s.api((*OrchestrationHandler).DelDig)).Methods("DELETE")
 
-	handle(digUriPattern+"/", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DelDig(w, r)
-	}).Queries("operation", "{operation}").Methods("DELETE")
+	handle(digUriPattern+"/", s.api((*OrchestrationHandler).DelDig)).Queries("operation", "{operation}").Methods("DELETE")
 
-	handle(digUriPattern+"/status", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetDigStatus(w, r)
-	}).Methods("GET")
+	handle(digUriPattern+"/status",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.DigStatus(newDigRequest(r))
+		})).Methods("GET")
 
 	// DIG migrate/update/rollback related APIs
 	handle(digUriPattern+"/checkout", func(w http.ResponseWriter, r *http.Request) {
-		_ = createInstance(bootConf, r).GetDigInEdit(w, r)
+		_ = s.handlerFor(r).GetDigInEdit(w, r)
 	}).Methods("GET")
 
-	handle(digUriPattern+"/checkout", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).CheckoutDIG(w, r)
-	}).Methods("POST")
+	handle(digUriPattern+"/checkout/diff",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.GetCheckoutDiff(newDigRequest(r))
+		})).Methods("GET")
+
+	handle(digUriPattern+"/checkout", s.api((*OrchestrationHandler).CheckoutDIG)).Methods("POST")
 
-	handle(digUriPattern+"/checkout/", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).CheckoutDIG(w, r)
-	}).Queries("operation", "{operation}").Methods("POST")
+	handle(digUriPattern+"/checkout/lease", s.jsonAPI((*OrchestrationHandler).GetCheckoutLease)).Methods("GET")
 
+	handle(digUriPattern+"/checkout/lease", s.api((*OrchestrationHandler).ReleaseCheckoutLease)).Methods("DELETE")
+
+	handle(digUriPattern+"/checkout/lease/steal", s.jsonAPI((*OrchestrationHandler).StealCheckoutLease)).Methods("POST")
+
+	handle(digUriPattern+"/checkout/", s.api((*OrchestrationHandler).CheckoutDIG)).Queries("operation", "{operation}").Methods("POST")
+
+	// dryRun=true replies the plan of the submit rather than running it,
+	// the plan is also read on GET by the roles which may not submit
+	submit := s.async("upgradeDig", (*OrchestrationHandler).UpgradeDIG)
+	plan := s.jsonAPI((*OrchestrationHandler).PlanDIGSubmit)
+	handle(digUriPattern+"/checkout/plan", plan).Methods("GET")
 	handle(digUriPattern+"/checkout/submit", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).UpgradeDIG(w, r)
+		if r.URL.Query().Get("dryRun") == "true" {
+			plan(w, r)
+			return
+		}
+		submit(w, r)
 	}).Methods("POST")
 
-	handle(digUriPattern+"/checkout", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DigUpdateHandler(w, r)
-	}).Methods("PUT")
+	handle(digUriPattern+"/checkout", s.api((*OrchestrationHandler).DigUpdateHandler)).Methods("PUT")
 
-	handle(digUriPattern+"/checkout/", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DigUpdateHandler(w, r)
-	}).Queries("operation", "{operation}").Methods("PUT")
+	handle(digUriPattern+"/checkout/", s.api((*OrchestrationHandler).DigUpdateHandler)).Queries("operation", "{operation}").Methods("PUT")
 
-	handle(digUriPattern+"/scaleout", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).ScaleOutDig(w, r)
-	}).Methods("POST")
+	handle(digUriPattern+"/scaleout", s.async("scaleOutDig", (*OrchestrationHandler).ScaleOutDig)).Methods("POST")
+
+	handle(digUriPattern+"/revisions", s.jsonAPI((*OrchestrationHandler).GetDigRevisions)).Methods("GET")
+
+	handle(digUriPattern+"/revisions/{revision}/diff", s.jsonAPI((*OrchestrationHandler).GetDigRevisionDiff)).Methods("GET")
+
+	handle(digUriPattern+"/rollback", s.async("rollbackDig", (*OrchestrationHandler).RollbackDIG)).Methods("POST")
+
+	// Staged rollout of a checked out update, wave by wave
+	handle(digUriPattern+"/checkout/rollout", s.async("rolloutDig", (*OrchestrationHandler).RolloutDIG)).Methods("POST")
+
+	handle(digUriPattern+"/rollout", s.jsonAPI((*OrchestrationHandler).GetRollout)).Methods("GET")
+
+	handle(digUriPattern+"/rollout/resume", s.async("resumeRollout", (*OrchestrationHandler).ResumeRollout)).Methods("POST")
 
 	// GAC related APIs
-	handle(digUriPattern+"/resources", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetK8sResources(w, r)
-	}).Methods("GET")
+	handle(digUriPattern+"/resources",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.K8sResources(newDigRequest(r))
+		})).Methods("GET")
 
-	handle(digUriPattern+"/resources/{resourceName}", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DeleteK8sResources(w, r)
-	}).Methods("DELETE")
+	handle(digUriPattern+"/resources/{resourceName}", s.api((*OrchestrationHandler).DeleteK8sResources)).Methods("DELETE")
 
 	handle(digUriPattern+"/resources/{resourceName}/customizations/{customizationName}",
-		func(w http.ResponseWriter, r *http.Request) {
-			createInstance(bootConf, r).DeleteK8sResourceCustomizations(w, r)
-		}).Methods("DELETE")
+		s.api((*OrchestrationHandler).DeleteK8sResourceCustomizations)).Methods("DELETE")
 }
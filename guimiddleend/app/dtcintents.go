@@ -35,10 +35,10 @@ func (h *localStoreIntentHandler) DeleteClientsInboundIntent(clientIntentName st
 	digName string, trafficIntentName string, inboundIntentName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewClientsInboundIntentClient()
+	c := localstore.NewClientsInboundIntentClient(h.orchInstance.ctx)
 	deleteErr := c.DeleteClientsInboundIntent(clientIntentName, p, ca, v, digName, trafficIntentName, inboundIntentName)
 	if deleteErr != nil {
-		log.Error(deleteErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(deleteErr.Error(), log.Fields{})
 		if strings.Contains(deleteErr.Error(), "not found") {
 			return http.StatusNotFound, deleteErr
 		} else if strings.Contains(deleteErr.Error(), "conflict") {
@@ -54,7 +54,7 @@ func (h *remoteStoreIntentHandler) DeleteClientsInboundIntent(clientIntentName s
 	digName string, trafficIntentName string, inboundIntentName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents/" + trafficIntentName +
@@ -67,7 +67,7 @@ func (h *remoteStoreIntentHandler) GetClientsInboundIntents(p string, ca string,
 	v string, digName string, trafficIntentName string, inboundIntentName string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents/" + trafficIntentName + "/inbound-intents/" + inboundIntentName + "/clients"
@@ -79,10 +79,10 @@ func (h *localStoreIntentHandler) GetClientsInboundIntents(p string, ca string,
 	v string, digName string, trafficIntentName string, inboundIntentName string,
 ) ([]byte, error) {
 	var retval []byte
-	c := localstore.NewClientsInboundIntentClient()
+	c := localstore.NewClientsInboundIntentClient(h.orchInstance.ctx)
 	inboundclientIntent, err := c.GetClientsInboundIntents(p, ca, v, digName, trafficIntentName, inboundIntentName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		return retval, err
 	}
 	retval, _ = json.Marshal(inboundclientIntent)
@@ -93,10 +93,10 @@ func (h *localStoreIntentHandler) CreateClientsInboundIntent(g localstore.Inboun
 	v string, digName string, trafficIntentName string, serverName string, exist bool,
 ) (interface{}, interface{}) {
 	// Get the local store handler
-	c := localstore.NewClientsInboundIntentClient()
+	c := localstore.NewClientsInboundIntentClient(h.orchInstance.ctx)
 	_, createErr := c.CreateClientsInboundIntent(g, p, ca, v, digName, trafficIntentName, serverName, exist)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -123,7 +123,7 @@ func (h *remoteStoreIntentHandler) CreateClientsInboundIntent(g localstore.Inbou
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(g)
 	dtcintName := ca + "_inboundclient"
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents/" + trafficIntentName + "/inbound-intents/" + serverName + "/clients"
@@ -136,10 +136,10 @@ func (h *localStoreIntentHandler) DeleteServerInboundIntent(serverIntentName str
 	digName string, trafficIntentName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewServerInboundIntentClient()
+	c := localstore.NewServerInboundIntentClient(h.orchInstance.ctx)
 	deleteErr := c.DeleteServerInboundIntent(serverIntentName, p, ca, v, digName, trafficIntentName)
 	if deleteErr != nil {
-		log.Error(deleteErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(deleteErr.Error(), log.Fields{})
 		if strings.Contains(deleteErr.Error(), "not found") {
 			return http.StatusNotFound, deleteErr
 		} else if strings.Contains(deleteErr.Error(), "conflict") {
@@ -155,7 +155,7 @@ func (h *remoteStoreIntentHandler) DeleteServerInboundIntent(serverIntentName st
 	digName string, trafficIntentName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents/" + trafficIntentName + "/inbound-intents/" + serverIntentName
@@ -167,7 +167,7 @@ func (h *remoteStoreIntentHandler) GetServerInboundIntents(p string, ca string,
 	v string, digName string, trafficIntentName string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents/" + trafficIntentName + "/inbound-intents"
@@ -180,10 +180,10 @@ func (h *localStoreIntentHandler) GetServerInboundIntents(p string, ca string,
 	v string, digName string, trafficIntentName string,
 ) ([]byte, error) {
 	var retval []byte
-	c := localstore.NewServerInboundIntentClient()
+	c := localstore.NewServerInboundIntentClient(h.orchInstance.ctx)
 	inboundserverIntent, err := c.GetServerInboundIntents(p, ca, v, digName, trafficIntentName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "db Find error") {
 			return retval, err
 		} else {
@@ -198,10 +198,10 @@ func (h *localStoreIntentHandler) CreateServerInboundIntent(g localstore.Inbound
 	v string, digName string, trafficIntentName string, exist bool,
 ) (interface{}, interface{}) {
 	// Get the local store handler
-	c := localstore.NewServerInboundIntentClient()
+	c := localstore.NewServerInboundIntentClient(h.orchInstance.ctx)
 	_, createErr := c.CreateServerInboundIntent(g, p, ca, v, digName, trafficIntentName, exist)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -225,7 +225,7 @@ func (h *remoteStoreIntentHandler) CreateServerInboundIntent(g localstore.Inboun
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(g)
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents/" + trafficIntentName + "/inbound-intents"
@@ -237,11 +237,11 @@ func (h *remoteStoreIntentHandler) CreateServerInboundIntent(g localstore.Inboun
 func (h *localStoreIntentHandler) DeleteTrafficGroupIntent(dtintName string, p string, ca string,
 	v string, digName string,
 ) (interface{}, interface{}) {
-	c := localstore.NewTrafficGroupIntentClient()
+	c := localstore.NewTrafficGroupIntentClient(h.orchInstance.ctx)
 
 	err := c.DeleteTrafficGroupIntent(dtintName, p, ca, v, digName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -258,7 +258,7 @@ func (h *remoteStoreIntentHandler) DeleteTrafficGroupIntent(dtintName string, p
 	v string, digName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/traffic-group-intents/" + dtintName
 	resp, err := orch.apiDel(orchURL, dtintName)
@@ -269,10 +269,10 @@ func (h *localStoreIntentHandler) GetTrafficGroupIntents(project string, composi
 	digName string,
 ) ([]byte, error) {
 	var retval []byte
-	c := localstore.NewTrafficGroupIntentClient()
+	c := localstore.NewTrafficGroupIntentClient(h.orchInstance.ctx)
 	dTIntent, err := c.GetTrafficGroupIntents(project, compositeAppName, version, digName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "Unable to find") {
 			return retval, err
 		} else if strings.Contains(err.Error(), "db Find error") {
@@ -281,7 +281,7 @@ func (h *localStoreIntentHandler) GetTrafficGroupIntents(project string, composi
 			return retval, err
 		}
 	}
-	log.Infof("Get All dtcint localstore Composite app %s dig %s value %+v", compositeAppName,
+	h.orchInstance.Logger.Infof("Get All dtcint localstore Composite app %s dig %s value %+v", compositeAppName,
 		digName, dTIntent)
 	retval, _ = json.Marshal(dTIntent)
 	return retval, err
@@ -292,13 +292,13 @@ func (h *remoteStoreIntentHandler) GetTrafficGroupIntents(project string, compos
 ) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" +
 		project + "/composite-apps/" + compositeAppName +
 		"/" + version +
 		"/deployment-intent-groups/" + digName + "/traffic-group-intents"
 	// retcode, retval, err := orch.apiGet(orchURL, compositeAppName+"_dtcint")
 	reply, err := orch.apiGet(orchURL, "testdtc")
-	log.Infof("Get Dtint in Composite app %s dig %s status: %d", compositeAppName,
+	h.orchInstance.Logger.Infof("Get Dtint in Composite app %s dig %s status: %d", compositeAppName,
 		digName, reply.StatusCode)
 	return reply.Data, err
 }
@@ -306,11 +306,11 @@ func (h *remoteStoreIntentHandler) GetTrafficGroupIntents(project string, compos
 func (h *localStoreIntentHandler) CreateTrafficGroupIntent(g localstore.TrafficGroupIntent, p string, ca string,
 	v string, digName string, exist bool,
 ) (interface{}, interface{}) {
-	c := localstore.NewTrafficGroupIntentClient()
+	c := localstore.NewTrafficGroupIntentClient(h.orchInstance.ctx)
 
 	_, createErr := c.CreateTrafficGroupIntent(g, p, ca, v, digName, true)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -334,11 +334,11 @@ func (h *remoteStoreIntentHandler) CreateTrafficGroupIntent(g localstore.Traffic
 	dtcintName := "testdtc"
 
 	jsonLoad, _ := json.Marshal(g)
-	orchURL := "http://" + orch.MiddleendConf.Dtc + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("dtc") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/traffic-group-intents"
-	log.Infof("url traffic %s", url)
+	h.orchInstance.Logger.Infof("url traffic %s", url)
 	resp, err := orch.apiPost(jsonLoad, url, dtcintName)
 
 	return resp, err
@@ -365,8 +365,8 @@ func (h *dtcIntentHandler) createAnchor() interface{} {
 	}
 
 	// POST the Dtc placement intent
-	log.Infof("compositeAppName %s", intentData.CompositeAppName)
-	log.Infof("dpi %s", dpi)
+	h.orchInstance.Logger.Infof("compositeAppName %s", intentData.CompositeAppName)
+	h.orchInstance.Logger.Infof("dpi %s", dpi)
 	exist := false
 	resp, err := orch.bstore.CreateTrafficGroupIntent(dpi, projectName, intentData.CompositeAppName, version, digName, exist)
 	if err != nil {
@@ -375,7 +375,7 @@ func (h *dtcIntentHandler) createAnchor() interface{} {
 	if resp != http.StatusCreated {
 		return resp
 	}
-	log.Infof("Dtc Placement intent response: %d", resp)
+	h.orchInstance.Logger.Infof("Dtc Placement intent response: %d", resp)
 	jsonLoad, _ := json.Marshal(dpi)
 	orch.response.payload["testdtc"] = jsonLoad
 	orch.response.status["testdtc"] = resp.(int)
@@ -422,7 +422,7 @@ func (h *dtcIntentHandler) createObject() interface{} {
 			}
 
 			retcode, err := orch.bstore.CreateServerInboundIntent(dpi, projectName, intentData.CompositeAppName, version, digName, dTintName, exist)
-			log.Infof("Creation of inbound server intent response: %s", retcode)
+			h.orchInstance.Logger.Infof("Creation of inbound server intent response: %s", retcode)
 			if err != nil {
 				return err
 			}
@@ -451,7 +451,7 @@ func (h *dtcIntentHandler) createObject() interface{} {
 					},
 				}
 				retcode, err := orch.bstore.CreateClientsInboundIntent(dpi, projectName, intentData.CompositeAppName, version, digName, dTintName, serverName, exist)
-				log.Infof("Creation of inbound client intent response: %s", retcode)
+				h.orchInstance.Logger.Infof("Creation of inbound client intent response: %s", retcode)
 				if err != nil {
 					return err
 				}
@@ -486,32 +486,32 @@ func (h *dtcIntentHandler) getObject() error {
 				serverPint := []localstore.InboundServerIntent{}
 				retval_server, err_server := orch.bstore.GetServerInboundIntents(project, compositeAppMetadata.Name,
 					compositeAppSpec.Version, digName, dtintName)
-				log.Infof("Get Dtint Dtc intent in Composite app %s dig %s Dtint %s status: %d",
+				h.orchInstance.Logger.Infof("Get Dtint Dtc intent in Composite app %s dig %s Dtint %s status: %d",
 					compositeAppMetadata.Name, digName, dtintName, retcode)
 				if err_server != nil {
-					log.Error("Failed to read dtc dint\n")
+					h.orchInstance.Logger.Error("Failed to read dtc dint\n")
 					return err_server
 				}
 				err := json.Unmarshal(retval_server, &serverPint)
 				if err != nil {
-					log.Errorf("Failed to unmarshal json %s\n", err)
+					h.orchInstance.Logger.Errorf("Failed to unmarshal json %s\n", err)
 					return err
 				}
 				dtintValue.ServerIntentArray = serverPint
-				log.Infof("dtint pratik %v", dtintValue.ServerIntentArray)
+				h.orchInstance.Logger.Infof("dtint pratik %v", dtintValue.ServerIntentArray)
 
 				for _, servername := range serverPint {
 					clientPint := []localstore.InboundClientsIntent{}
 					retval, err := orch.bstore.GetClientsInboundIntents(project, compositeAppMetadata.Name,
 						compositeAppSpec.Version, digName, dtintName, servername.Metadata.Name)
-					log.Infof("Get Dtint Dtc intent in Composite app %s dig %s Dtint %s status: %d", compositeAppMetadata.Name, digName, dtintName, retcode)
+					h.orchInstance.Logger.Infof("Get Dtint Dtc intent in Composite app %s dig %s Dtint %s status: %d", compositeAppMetadata.Name, digName, dtintName, retcode)
 					if err != nil {
-						log.Error("Failed to read dtc dint\n")
+						h.orchInstance.Logger.Error("Failed to read dtc dint\n")
 						return err
 					}
 					err = json.Unmarshal(retval, &clientPint)
 					if err != nil {
-						log.Errorf("Failed to unmarshal json %s\n", err)
+						h.orchInstance.Logger.Errorf("Failed to unmarshal json %s\n", err)
 						return err
 					}
 					if clientPint != nil {
@@ -526,7 +526,7 @@ func (h *dtcIntentHandler) getObject() error {
 		}
 	}
 	orch.dtck8sInfo = dtcData
-	log.Infof("dtc data %v", orch.dtck8sInfo)
+	h.orchInstance.Logger.Infof("dtc data %v", orch.dtck8sInfo)
 	return nil
 }
 
@@ -547,16 +547,16 @@ func (h *dtcIntentHandler) getAnchor() error {
 			var dtcintList []localstore.TrafficGroupIntent
 			retval, err := orch.bstore.GetTrafficGroupIntents(project, compositeAppMetadata.Name,
 				compositeAppSpec.Version, digName)
-			// log.Infof("Get Dtcint in Composite app %s dig %s status: %d", vars["compositeAppName"], digName, retcode)
+			// h.orchInstance.Logger.Infof("Get Dtcint in Composite app %s dig %s status: %d", vars["compositeAppName"], digName, retcode)
 			if err != nil {
-				log.Error("Failed to read dtcint\n")
+				h.orchInstance.Logger.Error("Failed to read dtcint\n")
 				return err
 			}
 			if err := json.Unmarshal(retval, &dtcintList); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.orchInstance.Logger.Error(err, PrintFunctionName())
 			}
-			log.Info("anchor payload", &dtcintList)
-			log.Info("anchor payload", dtcintList)
+			h.orchInstance.Logger.Info("anchor payload", &dtcintList)
+			h.orchInstance.Logger.Info("anchor payload", dtcintList)
 			digValue.DtintMap = make(map[string]*DtintData, len(dtcintList))
 			for _, value := range dtcintList {
 				var DtintDataInstance DtintData
@@ -574,7 +574,7 @@ func (h *dtcIntentHandler) deleteObject() interface{} {
 	dataRead := h.orchInstance.dataRead
 	dtcData := h.orchInstance.dtck8sInfo
 	project := vars["projectName"]
-	log.Info(project)
+	h.orchInstance.Logger.Info(project)
 	for _, compositeAppValue := range dataRead.compositeAppMap {
 		if compositeAppValue.Status == "checkout" {
 			continue
@@ -582,18 +582,18 @@ func (h *dtcIntentHandler) deleteObject() interface{} {
 		compositeAppMetadata := compositeAppValue.Metadata.Metadata
 		compositeAppSpec := compositeAppValue.Metadata.Spec
 		Dig := compositeAppValue.DigMap
-		log.Info(compositeAppMetadata)
-		log.Info(compositeAppSpec)
+		h.orchInstance.Logger.Info(compositeAppMetadata)
+		h.orchInstance.Logger.Info(compositeAppSpec)
 		// AppData := compositeAppValue.AppsDataArray
 
 		// loop through all app intens in the dtint
 		for digName, digValue := range Dig {
 			for dtintName := range digValue.DtintMap {
 				for server, clientlist := range dtcData {
-					log.Infof("client %v, server %v", clientlist, server)
-					log.Infof("client pratik %v", clientlist)
-					log.Infof("server pratik %v", server)
-					log.Infof("client len %v", len(clientlist))
+					h.orchInstance.Logger.Infof("client %v, server %v", clientlist, server)
+					h.orchInstance.Logger.Infof("client pratik %v", clientlist)
+					h.orchInstance.Logger.Infof("server pratik %v", server)
+					h.orchInstance.Logger.Infof("client len %v", len(clientlist))
 					for _, client := range clientlist {
 						if client == "no" {
 							continue
@@ -607,7 +607,7 @@ func (h *dtcIntentHandler) deleteObject() interface{} {
 						if resp != http.StatusNoContent {
 							return resp
 						}
-						log.Infof("Delete client dpint intents response: %d", resp)
+						h.orchInstance.Logger.Infof("Delete client dpint intents response: %d", resp)
 
 					}
 
@@ -621,7 +621,7 @@ func (h *dtcIntentHandler) deleteObject() interface{} {
 					if resp_server != http.StatusNoContent {
 						return resp_server
 					}
-					log.Infof("Delete server dpint intents response: %d", resp_server)
+					h.orchInstance.Logger.Infof("Delete server dpint intents response: %d", resp_server)
 				}
 			}
 		}
@@ -644,7 +644,7 @@ func (h dtcIntentHandler) deleteAnchor() interface{} {
 		// loop through all app intens in the dtint
 		for digName, digValue := range Dig {
 			for dtintName := range digValue.DtintMap {
-				log.Infof("Delete dtint  %s", h.orchURL)
+				h.orchInstance.Logger.Infof("Delete dtint  %s", h.orchURL)
 				resp, err := orch.bstore.DeleteTrafficGroupIntent(dtintName, vars["projectName"],
 					compositeAppMetadata.Name, compositeAppSpec.Version, digName)
 				if err != nil {
@@ -653,7 +653,7 @@ func (h dtcIntentHandler) deleteAnchor() interface{} {
 				if resp != http.StatusNoContent {
 					return resp
 				}
-				log.Infof("Delete dtint response: %d", resp)
+				h.orchInstance.Logger.Infof("Delete dtint response: %d", resp)
 			}
 		}
 	}
@@ -0,0 +1,276 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"example.com/middleend/apierror"
+	"example.com/middleend/backend"
+	"example.com/middleend/logging"
+	"github.com/gorilla/mux"
+)
+
+// Error codes of the ErrorResponse
+const (
+	ErrBadRequest          = apierror.ErrBadRequest
+	ErrUnauthorized        = apierror.ErrUnauthorized
+	ErrForbidden           = apierror.ErrForbidden
+	ErrNotFound            = apierror.ErrNotFound
+	ErrConflict            = apierror.ErrConflict
+	ErrUnavailable         = apierror.ErrUnavailable
+	ErrInternal            = apierror.ErrInternal
+	ErrUpstream            = apierror.ErrUpstream
+	ErrUpstreamUnavailable = apierror.ErrUpstreamUnavailable
+	ErrUpstreamTimeout     = apierror.ErrUpstreamTimeout
+)
+
+// ErrorResponse is the body of every failed API reply, the one the
+// authentication and RBAC middlewares reply too
+type ErrorResponse = apierror.ErrorResponse
+
+// emcoErrors refine the code of the EMCO replies, which report most of
+// their errors with a 500 whatever the cause
+var emcoErrors = []struct {
+	pattern *regexp.Regexp
+	code    string
+}{
+	{regexp.MustCompile(`(?i)already exists|duplicate|conflict|in use|non empty|not empty`), ErrConflict},
+	{regexp.MustCompile(`(?i)not found|does not exist|doesn't exist|no such`), ErrNotFound},
+	{regexp.MustCompile(`(?i)invalid|malformed|missing|required|unmarshal|bad request`), ErrBadRequest},
+}
+
+// upstreamCall is a failed call to an EMCO service
+type upstreamCall struct {
+	service string
+	status  int
+	body    []byte
+	err     error
+}
+
+// upstreamTracker records the last failed call of a request to the EMCO
+// services, the request context carries it to the handlers
+type upstreamTracker struct {
+	sync.Mutex
+	last *upstreamCall
+}
+
+type upstreamKey struct{}
+
+func withUpstreamTracker(ctx context.Context) (context.Context, *upstreamTracker) {
+	t := &upstreamTracker{}
+	return context.WithValue(ctx, upstreamKey{}, t), t
+}
+
+func (t *upstreamTracker) record(c *upstreamCall) {
+	t.Lock()
+	t.last = c
+	t.Unlock()
+}
+
+func (t *upstreamTracker) lastCall() *upstreamCall {
+	t.Lock()
+	defer t.Unlock()
+	return t.last
+}
+
+// do sends a request to an EMCO service, recording its failure for the
+// error reply of the request. The body of a failed reply is buffered and
+// can still be read by the caller. In a dry run the changes are recorded
+// in the plan instead.
+func (h *OrchestrationHandler) do(req *http.Request) (*http.Response, error) {
+	if h.state.plan != nil && req.Method != http.MethodGet {
+		return h.state.plan.record(h.client.ServiceOf(req), req, h.objectExists)
+	}
+	resp, err := h.client.Do(req)
+	t, _ := h.ctx.Value(upstreamKey{}).(*upstreamTracker)
+	if t == nil {
+		return resp, err
+	}
+	if err != nil {
+		t.record(&upstreamCall{service: h.client.ServiceOf(req), err: err})
+		return resp, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		t.record(&upstreamCall{service: h.client.ServiceOf(req), status: resp.StatusCode, body: body})
+	}
+	return resp, nil
+}
+
+// withErrors wraps the APIs registered through handle so that their
+// failed replies are sent as an ErrorResponse
+func withErrors(handle HandleFunc) HandleFunc {
+	return func(path string, fn func(http.ResponseWriter, *http.Request)) *mux.Route {
+		return handle(path, func(w http.ResponseWriter, r *http.Request) {
+			ctx, t := withUpstreamTracker(r.Context())
+			r = r.WithContext(ctx)
+			ew := newErrorWriter(w, r, t)
+			defer ew.finish()
+			fn(ew, r)
+		})
+	}
+}
+
+// errorWriter turns the failed replies of a handler into an ErrorResponse.
+// The body of a reply of status 400 or more is buffered and replaced by
+// the envelope once the handler returns. Only the first status written
+// counts, the later ones are logged and dropped.
+type errorWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	tracker *upstreamTracker
+	code    int
+	body    bytes.Buffer
+}
+
+func newErrorWriter(w http.ResponseWriter, r *http.Request, t *upstreamTracker) *errorWriter {
+	return &errorWriter{ResponseWriter: w, r: r, tracker: t}
+}
+
+func (w *errorWriter) failed() bool {
+	return w.code >= http.StatusBadRequest
+}
+
+func (w *errorWriter) WriteHeader(code int) {
+	if w.code != 0 {
+		if code != w.code {
+			logging.FromContext(w.r.Context()).Warnf("%s %s: dropped status %d, already replied %d",
+				w.r.Method, w.r.URL.Path, code, w.code)
+		}
+		return
+	}
+	w.code = code
+	if !w.failed() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed() {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorWriter) Flush() {
+	if w.failed() {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish sends the buffered error as an ErrorResponse
+func (w *errorWriter) finish() {
+	if !w.failed() {
+		return
+	}
+	apierror.Encode(w.ResponseWriter, w.r, newErrorResponse(w.code, w.body.Bytes(), w.tracker.lastCall(),
+		logging.RequestIDFromContext(w.r.Context())))
+}
+
+// newErrorResponse maps a failed reply of status and body to the
+// envelope. The last failed call to an EMCO service is reported as the
+// cause when the reply passes on its status or its body, or when the
+// service could not be reached.
+func newErrorResponse(status int, body []byte, up *upstreamCall, requestID string) ErrorResponse {
+	e := ErrorResponse{
+		Status:    status,
+		Code:      apierror.Code(status),
+		Message:   messageOf(body),
+		RequestID: requestID,
+	}
+	if up != nil && upstreamCaused(status, body, up) {
+		e.Service = up.service
+		e.UpstreamStatus = up.status
+		e.Code, e.Message = upstreamError(up, e.Message)
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+	return e
+}
+
+func upstreamCaused(status int, body []byte, up *upstreamCall) bool {
+	if up.err != nil {
+		return status >= http.StatusInternalServerError
+	}
+	return up.status == status || (len(up.body) > 0 && bytes.Equal(bytes.TrimSpace(body), bytes.TrimSpace(up.body)))
+}
+
+// upstreamError returns the code and the message of a failed call, the
+// message of the middleend is kept when the service gave none
+func upstreamError(up *upstreamCall, fallback string) (string, string) {
+	if up.err != nil {
+		code := ErrUpstreamUnavailable
+		if errors.Is(up.err, context.DeadlineExceeded) || strings.Contains(up.err.Error(), "Client.Timeout") {
+			code = ErrUpstreamTimeout
+		}
+		msg := up.service + " is unavailable"
+		if errors.Is(up.err, backend.ErrCircuitOpen) {
+			msg += ", its circuit breaker is open"
+		}
+		return code, msg
+	}
+	msg := messageOf(up.body)
+	if msg == "" {
+		msg = fallback
+	}
+	if up.status < http.StatusInternalServerError {
+		return apierror.Code(up.status), msg
+	}
+	for _, e := range emcoErrors {
+		if e.pattern.MatchString(msg) {
+			return e.code, msg
+		}
+	}
+	return ErrUpstream, msg
+}
+
+// messageOf extracts the message of an error body, either plain text or
+// a json object with a message or error field
+func messageOf(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	if body[0] == '{' {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err == nil {
+			for _, key := range []string{"message", "error", "Error", "msg"} {
+				if s, ok := fields[key].(string); ok && s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return string(body)
+}
@@ -0,0 +1,583 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of the resources reported by status events
+const (
+	eventKindDig          = "dig"
+	eventKindLogicalCloud = "logicalCloud"
+)
+
+// Status event types
+const (
+	eventAdded          = "added"
+	eventRemoved        = "removed"
+	eventState          = "state"
+	eventDeployedStatus = "deployedStatus"
+	eventReadyStatus    = "readyStatus"
+	eventResource       = "resource"
+)
+
+const (
+	defaultEventsPollInterval = 5
+	// the composite apps and logical clouds of a project are listed
+	// again every eventsDiscoverEvery polls
+	eventsDiscoverEvery   = 6
+	eventsSubscriberQueue = 256
+	eventsKeepAlive       = 15 * time.Second
+	// streams end before the WriteTimeout of the server, the clients
+	// reconnect after eventsRetry and receive the current state again
+	eventStreamMaxDuration = 50 * time.Second
+	eventsRetry            = time.Second
+)
+
+// StatusEvent is a change of the status of a DIG or a logical cloud.
+// Added events carry the current status of the resource, they are also
+// sent to new subscribers for every known resource.
+type StatusEvent struct {
+	Kind            string    `json:"kind"`
+	Type            string    `json:"type"`
+	Project         string    `json:"project"`
+	CompositeApp    string    `json:"compositeApp,omitempty"`
+	Version         string    `json:"compositeAppVersion,omitempty"`
+	Name            string    `json:"name"`
+	State           string    `json:"state,omitempty"`
+	DeployedStatus  string    `json:"deployedStatus,omitempty"`
+	ReadyStatus     string    `json:"readyStatus,omitempty"`
+	App             string    `json:"app,omitempty"`
+	ClusterProvider string    `json:"clusterProvider,omitempty"`
+	Cluster         string    `json:"cluster,omitempty"`
+	Resource        string    `json:"resource,omitempty"`
+	From            string    `json:"from,omitempty"`
+	To              string    `json:"to,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+func (e StatusEvent) name() string {
+	return e.Kind + "." + e.Type
+}
+
+type digRef struct {
+	compositeApp string
+	version      string
+	name         string
+}
+
+type clusterResourceRef struct {
+	app             string
+	clusterProvider string
+	cluster         string
+	resource        string
+}
+
+// resourceStatus is the part of the EMCO status of a DIG or a logical
+// cloud which is compared between two polls
+type resourceStatus struct {
+	actions   []digActions
+	deployed  string
+	ready     string
+	resources map[clusterResourceRef]string
+}
+
+func (s *resourceStatus) state() string {
+	if len(s.actions) == 0 {
+		return ""
+	}
+	return s.actions[len(s.actions)-1].State
+}
+
+func digResourceStatus(st digStatus) *resourceStatus {
+	s := &resourceStatus{
+		actions:   st.States.Actions,
+		deployed:  st.DeployedStatus,
+		resources: make(map[clusterResourceRef]string),
+	}
+	for _, app := range st.Apps {
+		for _, c := range app.Clusters {
+			for _, r := range c.Resources {
+				ref := clusterResourceRef{app.Name, c.ClusterProvider, c.Cluster, r.GVK.Kind + "/" + r.Name}
+				s.resources[ref] = r.DeployedStatus
+			}
+		}
+	}
+	return s
+}
+
+func lcResourceStatus(st LogicalCloudStatus) *resourceStatus {
+	s := &resourceStatus{
+		deployed:  st.DeployedStatus,
+		ready:     st.ReadyStatus,
+		resources: make(map[clusterResourceRef]string),
+	}
+	for _, a := range st.States.Actions {
+		s.actions = append(s.actions, digActions{State: a.State, Instance: a.Instance, Time: a.Time, Revision: a.Revision})
+	}
+	for _, c := range st.Clusters {
+		for _, r := range c.Resources {
+			ref := clusterResourceRef{"", c.ClusterProvider, c.Cluster, r.Gvk.Kind + "/" + r.Name}
+			s.resources[ref] = r.ReadyStatus
+		}
+	}
+	return s
+}
+
+// added describes the current status of a resource
+func (s *resourceStatus) added(base StatusEvent) StatusEvent {
+	e := base
+	e.Type = eventAdded
+	e.State = s.state()
+	e.DeployedStatus = s.deployed
+	e.ReadyStatus = s.ready
+	e.Time = time.Now()
+	return e
+}
+
+// diffStatus returns the events leading from old to cur
+func diffStatus(base StatusEvent, old, cur *resourceStatus) []StatusEvent {
+	if old == nil {
+		return []StatusEvent{cur.added(base)}
+	}
+	var events []StatusEvent
+	now := time.Now()
+	change := func(typ, from, to string) StatusEvent {
+		e := base
+		e.Type = typ
+		e.From = from
+		e.To = to
+		e.Time = now
+		return e
+	}
+
+	// EMCO appends to the actions, a shorter list means the status was reset
+	seen := len(old.actions)
+	if len(cur.actions) < seen {
+		seen = 0
+	}
+	from := old.state()
+	for _, a := range cur.actions[seen:] {
+		e := change(eventState, from, a.State)
+		e.Time = a.Time
+		events = append(events, e)
+		from = a.State
+	}
+	if old.deployed != cur.deployed {
+		events = append(events, change(eventDeployedStatus, old.deployed, cur.deployed))
+	}
+	if old.ready != cur.ready {
+		events = append(events, change(eventReadyStatus, old.ready, cur.ready))
+	}
+	resourceChange := func(ref clusterResourceRef, from, to string) StatusEvent {
+		e := change(eventResource, from, to)
+		e.App = ref.app
+		e.ClusterProvider = ref.clusterProvider
+		e.Cluster = ref.cluster
+		e.Resource = ref.resource
+		return e
+	}
+	for ref, status := range cur.resources {
+		if prev, ok := old.resources[ref]; !ok || prev != status {
+			events = append(events, resourceChange(ref, prev, status))
+		}
+	}
+	for ref, prev := range old.resources {
+		if _, ok := cur.resources[ref]; !ok {
+			events = append(events, resourceChange(ref, prev, ""))
+		}
+	}
+	return events
+}
+
+// projectWatcher polls the status of the DIGs and logical clouds of a
+// project once per interval, however many clients subscribed, and sends
+// the changes to the subscribers.
+type projectWatcher struct {
+	project    string
+	interval   time.Duration
+	newHandler func(ctx context.Context) *OrchestrationHandler
+	cancel     context.CancelFunc
+
+	// owned by the poll loop
+	digRefs []digRef
+	lcNames []string
+
+	mu          sync.Mutex
+	subscribers map[chan StatusEvent]bool
+	digs        map[digRef]*resourceStatus
+	lcs         map[string]*resourceStatus
+}
+
+func (pw *projectWatcher) digEvent(ref digRef) StatusEvent {
+	return StatusEvent{Kind: eventKindDig, Project: pw.project, CompositeApp: ref.compositeApp, Version: ref.version, Name: ref.name}
+}
+
+func (pw *projectWatcher) lcEvent(name string) StatusEvent {
+	return StatusEvent{Kind: eventKindLogicalCloud, Project: pw.project, Name: name}
+}
+
+// handler returns an OrchestrationHandler for the calls of a poll
+func (pw *projectWatcher) handler(ctx context.Context) *OrchestrationHandler {
+	h := pw.newHandler(ctx)
+	h.setLogger(log.WithFields(log.Fields{"watcher": pw.project}))
+	h.Vars = map[string]string{"projectName": pw.project}
+	h.InitializeResponseMap()
+	return h
+}
+
+func (pw *projectWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+	for n := 0; ; n++ {
+		if n%eventsDiscoverEvery == 0 {
+			pw.discover(ctx)
+		}
+		pw.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// discover lists the DIGs and the logical clouds of the project. The
+// previous lists are kept when EMCO cannot be reached.
+func (pw *projectWatcher) discover(ctx context.Context) {
+	h := pw.handler(ctx)
+	h.prepTreeReq()
+	h.dataRead = &ProjectTree{}
+	if err := h.constructTree([]string{"projectHandler", "compAppHandler", "digpHandler"}); err != nil {
+		log.WithError(err).Warnf("%s(): Failed to list the DIGs of project %s", PrintFunctionName(), pw.project)
+	} else {
+		refs := []digRef{}
+		for _, ca := range h.dataRead.compositeAppMap {
+			for name := range ca.DigMap {
+				refs = append(refs, digRef{ca.Metadata.Metadata.Name, ca.Metadata.Spec.Version, name})
+			}
+		}
+		pw.digRefs = refs
+	}
+
+	lcHandler := &logicalCloudHandler{orchInstance: pw.handler(ctx)}
+	lcList, err := lcHandler.getLogicalClouds()
+	if err != nil {
+		log.WithError(err).Warnf("%s(): Failed to list the logical clouds of project %s", PrintFunctionName(), pw.project)
+		return
+	}
+	names := []string{}
+	for _, lc := range lcList {
+		names = append(names, lc.Metadata.Name)
+	}
+	pw.lcNames = names
+}
+
+// poll fetches the status of every known resource and publishes the
+// changes since the previous poll
+func (pw *projectWatcher) poll(ctx context.Context) {
+	digs := make(map[digRef]*resourceStatus, len(pw.digRefs))
+	for _, ref := range pw.digRefs {
+		if ctx.Err() != nil {
+			return
+		}
+		h := pw.handler(ctx)
+		store := &remoteStoreDigHandler{orchInstance: h}
+		url := h.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + pw.project +
+			"/composite-apps/" + ref.compositeApp + "/" + ref.version +
+			"/deployment-intent-groups/" + ref.name + "/status"
+		st, err := store.getDigStatus(url, ref.compositeApp+"_digpStatus", [][]string{{"status", "deployed"}})
+		if err != nil {
+			// keep the previous status, the DIG may be gone which is
+			// reported once the next discovery misses it
+			pw.mu.Lock()
+			digs[ref] = pw.digs[ref]
+			pw.mu.Unlock()
+			continue
+		}
+		digs[ref] = digResourceStatus(st)
+	}
+
+	lcs := make(map[string]*resourceStatus, len(pw.lcNames))
+	for _, name := range pw.lcNames {
+		if ctx.Err() != nil {
+			return
+		}
+		lcHandler := &logicalCloudHandler{orchInstance: pw.handler(ctx)}
+		st, err := lcHandler.getLogicalCloudsStatus(pw.project, name)
+		if err != nil {
+			pw.mu.Lock()
+			lcs[name] = pw.lcs[name]
+			pw.mu.Unlock()
+			continue
+		}
+		lcs[name] = lcResourceStatus(st)
+	}
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	var events []StatusEvent
+	for ref, cur := range digs {
+		if cur != nil {
+			events = append(events, diffStatus(pw.digEvent(ref), pw.digs[ref], cur)...)
+		}
+	}
+	for ref := range pw.digs {
+		if _, ok := digs[ref]; !ok {
+			e := pw.digEvent(ref)
+			e.Type = eventRemoved
+			e.Time = time.Now()
+			events = append(events, e)
+		}
+	}
+	for name, cur := range lcs {
+		if cur != nil {
+			events = append(events, diffStatus(pw.lcEvent(name), pw.lcs[name], cur)...)
+		}
+	}
+	for name := range pw.lcs {
+		if _, ok := lcs[name]; !ok {
+			e := pw.lcEvent(name)
+			e.Type = eventRemoved
+			e.Time = time.Now()
+			events = append(events, e)
+		}
+	}
+	for ref, cur := range digs {
+		if cur == nil {
+			delete(digs, ref)
+		}
+	}
+	for name, cur := range lcs {
+		if cur == nil {
+			delete(lcs, name)
+		}
+	}
+	pw.digs = digs
+	pw.lcs = lcs
+	for _, e := range events {
+		pw.publish(e)
+	}
+}
+
+// publish sends the event to the subscribers, pw.mu must be held. A
+// subscriber which does not keep up is dropped, its client reconnects.
+func (pw *projectWatcher) publish(e StatusEvent) {
+	for c := range pw.subscribers {
+		select {
+		case c <- e:
+		default:
+			log.Warnf("%s(): Dropping slow subscriber of project %s", PrintFunctionName(), pw.project)
+			delete(pw.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+// snapshot describes the current status of every known resource
+func (pw *projectWatcher) snapshot() []StatusEvent {
+	events := []StatusEvent{}
+	for ref, s := range pw.digs {
+		events = append(events, s.added(pw.digEvent(ref)))
+	}
+	for name, s := range pw.lcs {
+		events = append(events, s.added(pw.lcEvent(name)))
+	}
+	return events
+}
+
+// eventHub runs a projectWatcher per project while it has subscribers
+type eventHub struct {
+	sync.Mutex
+	interval   time.Duration
+	newHandler func(ctx context.Context) *OrchestrationHandler
+	watchers   map[string]*projectWatcher
+	closed     bool
+}
+
+// newEventHub creates the hub polling every interval seconds through the
+// handlers of newHandler
+func newEventHub(interval int, newHandler func(ctx context.Context) *OrchestrationHandler) *eventHub {
+	if interval <= 0 {
+		interval = defaultEventsPollInterval
+	}
+	return &eventHub{
+		interval:   time.Duration(interval) * time.Second,
+		newHandler: newHandler,
+		watchers:   make(map[string]*projectWatcher),
+	}
+}
+
+// subscribe returns the channel of the status events of the project, the
+// current status of its resources and the function ending the subscription.
+// The hub refuses subscriptions once closed.
+func (hub *eventHub) subscribe(project string) (chan StatusEvent, []StatusEvent, func(), error) {
+	hub.Lock()
+	defer hub.Unlock()
+	if hub.closed {
+		return nil, nil, nil, errShuttingDown
+	}
+	pw, ok := hub.watchers[project]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		pw = &projectWatcher{
+			project:     project,
+			interval:    hub.interval,
+			newHandler:  hub.newHandler,
+			cancel:      cancel,
+			subscribers: make(map[chan StatusEvent]bool),
+		}
+		hub.watchers[project] = pw
+		log.Infof("%s(): Watching the status of project %s", PrintFunctionName(), project)
+		go pw.run(ctx)
+	}
+
+	c := make(chan StatusEvent, eventsSubscriberQueue)
+	pw.mu.Lock()
+	pw.subscribers[c] = true
+	snapshot := pw.snapshot()
+	pw.mu.Unlock()
+
+	return c, snapshot, func() {
+		hub.Lock()
+		defer hub.Unlock()
+		pw.mu.Lock()
+		if pw.subscribers[c] {
+			delete(pw.subscribers, c)
+			close(c)
+		}
+		idle := len(pw.subscribers) == 0
+		pw.mu.Unlock()
+		if idle && hub.watchers[project] == pw {
+			delete(hub.watchers, project)
+			pw.cancel()
+			log.Infof("%s(): Stopped watching the status of project %s", PrintFunctionName(), project)
+		}
+	}, nil
+}
+
+// close ends the streams, which the clients reopen on another instance,
+// and stops the watchers
+func (hub *eventHub) close() {
+	hub.Lock()
+	defer hub.Unlock()
+	hub.closed = true
+	for project, pw := range hub.watchers {
+		pw.mu.Lock()
+		for c := range pw.subscribers {
+			delete(pw.subscribers, c)
+			close(c)
+		}
+		pw.mu.Unlock()
+		pw.cancel()
+		delete(hub.watchers, project)
+	}
+}
+
+// eventStream writes server sent events
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	s := &eventStream{w: w, flusher: flusher}
+	return s, s.write(fmt.Sprintf("retry: %d\n\n", eventsRetry.Milliseconds()))
+}
+
+func (s *eventStream) write(data string) bool {
+	if _, err := fmt.Fprint(s.w, data); err != nil {
+		return false
+	}
+	s.flusher.Flush()
+	return true
+}
+
+// send writes v as json data of the named event
+func (s *eventStream) send(event string, v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.WithError(err).Errorf("%s(): Failed to encode event %s", PrintFunctionName(), event)
+		return false
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+}
+
+// keepAlive writes a comment so proxies do not close an idle stream
+func (s *eventStream) keepAlive() bool {
+	return s.write(": keep-alive\n\n")
+}
+
+// StreamProjectEvents streams the status changes of the DIGs and logical
+// clouds of the project as server sent events. The stream starts with an
+// added event for every resource known to the watcher of the project.
+func (h *OrchestrationHandler) StreamProjectEvents(w http.ResponseWriter, r *http.Request) {
+	project := mux.Vars(r)["projectName"]
+	c, snapshot, stop, err := h.srv.events.subscribe(project)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer stop()
+	stream, ok := newEventStream(w)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusNotAcceptable)
+		return
+	}
+	for _, e := range snapshot {
+		if !stream.send(e.name(), e) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	deadline := time.NewTimer(eventStreamMaxDuration)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-keepAlive.C:
+			if !stream.keepAlive() {
+				return
+			}
+		case e, ok := <-c:
+			if !ok || !stream.send(e.name(), e) {
+				return
+			}
+		}
+	}
+}
@@ -16,12 +16,11 @@ package app
 
 import (
 	"net/http"
-	"os"
 
+	"example.com/middleend/backend"
+	"example.com/middleend/metrics"
 	"github.com/go-openapi/runtime/middleware"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -32,33 +31,67 @@ const (
 	digUriPattern  = cAppUriPattern + "/{version}/deployment-intent-groups/{deploymentIntentGroupName}"
 )
 
-func createInstance(bootConf MiddleendConfig, r *http.Request) *OrchestrationHandler {
-	o := NewAppHandler()
+// RouteTemplates returns the route patterns which may be referenced by
+// name in the rbac policy
+func RouteTemplates() map[string]string {
+	return map[string]string{
+		"cAppUriPattern": cAppUriPattern,
+		"digUriPattern":  digUriPattern,
+	}
+}
 
-	l := logrus.New()
-	l.SetFormatter(&logrus.JSONFormatter{})
-	if bootConf.LogLevel == "debug" {
-		l.SetLevel(logrus.DebugLevel)
+// addresses maps the EMCO service names to their addresses
+func (c MiddleendConfig) addresses() map[string]string {
+	return map[string]string{
+		"orchestrator": c.OrchService,
+		"clm":          c.Clm,
+		"dcm":          c.Dcm,
+		"ncm":          c.Ncm,
+		"gac":          c.Gac,
+		"dtc":          c.Dtc,
+		"its":          c.Its,
+		"cert":         c.Cert,
+		"ovnaction":    c.OvnService,
+		"configSvc":    c.CfgService,
 	}
-	fields := logrus.Fields{
-		"uuid":   uuid.New(),
-		"from":   r.RemoteAddr,
-		"method": r.Method,
-		"url":    r.URL.String(),
+}
+
+// services returns the EMCO services by name
+func (c MiddleendConfig) services() map[string]backend.Service {
+	services := make(map[string]backend.Service)
+	for name, addr := range c.addresses() {
+		services[name] = c.service(name, addr)
 	}
-	o.Logger = l.WithFields(fields)
-	o.MiddleendConf = bootConf
-	return o
+	return services
+}
+
+func (c MiddleendConfig) service(name, addr string) backend.Service {
+	s := backend.Service{Address: addr}
+	if tlsConf, ok := c.ServiceTLS[name]; ok {
+		s.TLS = &tlsConf
+	}
+	return s
+}
+
+// serviceURL returns the base url of the named EMCO service, with the
+// scheme its tls settings call for
+func (c MiddleendConfig) serviceURL(name string) string {
+	return c.service(name, c.addresses()[name]).URL()
 }
 
-// RegisterHandlers is a helper function for net/http/HandleFunc
-// This function was introduced as a workaround for a concurrency issue in the middleend code
-// The receiver for handlers functions was not thread-safe.
-// All ServeHTTP functions required a refactoring to instantiate a new instance of
-// the receiver, OrchestrationHandler, on every request.
-// Ideally, future endpoints should not be using the closure for creating instance,
-// instead do it as part handler intialization
-func RegisterHandlers(handle HandleFunc, bootConf MiddleendConfig) {
+// RegisterHandlers registers the APIs of the middleend. Every request is
+// served by an OrchestrationHandler of its own, created by the server with
+// the shared dependencies; the handlers are not safe for concurrent use.
+// The read APIs take a typed request and return a typed result, served
+// through jsonAPI. The APIs writing the intents of an application or a DIG
+// parse a typed request too, and are served through api and async; the
+// helpers building the intent tree still read it from the handler state
+// (Vars, DigData, dataRead, response). The failed replies of every API are
+// sent as an ErrorResponse. The request bodies are validated against the
+// embedded swagger.yaml, which is generated from apiDocs.
+func (s *Server) RegisterHandlers(handle HandleFunc) {
+	handle = validateRequests(withErrors(handle))
+
 	rapiopts := middleware.RapiDocOpts{SpecURL: "/middleend/swagger.yaml", BasePath: "/middleend/", Path: "/rapidocs"}
 	rapidoc := middleware.RapiDoc(rapiopts, nil)
 
@@ -68,65 +101,63 @@ func RegisterHandlers(handle HandleFunc, bootConf MiddleendConfig) {
 	handle("/redocs", redoc.ServeHTTP).Methods("GET")
 	handle("/rapidocs", rapidoc.ServeHTTP).Methods("GET")
 	handle("/swagger.yaml", func(w http.ResponseWriter, r *http.Request) {
-		dat, err := os.ReadFile("./swagger.yaml")
-		if err != nil {
-			w.WriteHeader(404)
-			return
-		}
-		if _, err := w.Write(dat); err != nil {
+		w.Header().Set("Content-Type", "application/yaml")
+		if _, err := w.Write(swaggerYAML); err != nil {
 			log.Error(err, PrintFunctionName())
 		}
 	}).Methods("GET")
 
 	handle("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetHealth(w)
+		s.handlerFor(r).GetHealth(w)
 	}).Methods("GET")
+	handle("/healthz", s.api((*OrchestrationHandler).GetLiveness)).Methods("GET")
+	handle("/readyz", s.api((*OrchestrationHandler).GetReadiness)).Methods("GET")
+
+	handle("/metrics", metrics.Handler().ServeHTTP).Methods("GET")
 
-	RegisterApplicationHandlers(handle, bootConf)
-	RegisterDIGHandlers(handle, bootConf)
+	// Asynchronous operations
+	handle("/operations/{operationId}", s.api((*OrchestrationHandler).GetOperation)).Methods("GET")
+
+	s.registerApplicationHandlers(handle)
+	s.registerDIGHandlers(handle)
 
 	// ClusterProvider/Cluster creation APIs
-	handle("/cluster-providers", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).CreateClusterProvider(w, r)
-	}).Methods("POST")
-	handle("/cluster-providers/{clusterProvider}", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DeleteClusterProvider(w, r)
-	}).Methods("DELETE")
-	handle("/cluster-providers/{cluster-provider-name}/clusters", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).CheckConnection(w, r)
-	}).Methods("POST")
-	handle("/all-clusters", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetClusters(w, r)
-	}).Methods("GET")
+	handle("/cluster-providers", s.api((*OrchestrationHandler).CreateClusterProvider)).Methods("POST")
+	handle("/cluster-providers/{clusterProvider}", s.api((*OrchestrationHandler).DeleteClusterProvider)).Methods("DELETE")
+	handle("/cluster-providers/{cluster-provider-name}/clusters", s.async("onboardCluster", (*OrchestrationHandler).CheckConnection)).Methods("POST")
+	handle("/all-clusters",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.Clusters()
+		})).Methods("GET")
+
+	// Status changes of the DIGs and logical clouds of a project
+	handle("/projects/{projectName}/events", s.api((*OrchestrationHandler).StreamProjectEvents)).Methods("GET")
 
 	// GET dashboard
-	handle("/projects/{projectName}/dashboard", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetDashboardData(w, r)
-	}).Methods("GET")
+	handle("/projects/{projectName}/dashboard",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.Dashboard(newProjectRequest(r))
+		})).Methods("GET")
 
 	// Logical Cloud related APIs
-	handle("/projects/{projectName}/logical-clouds", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).HandleLCCreateRequest(w, r)
-	}).Methods("POST")
-	handle("/projects/{projectName}/logical-clouds", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetLogicalClouds(w, r)
-	}).Methods("GET")
-	handle("/projects/{projectName}/logical-clouds/{logicalCloud}", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).DeleteLogicalCloud(w, r)
-	}).Methods("DELETE")
-	handle("/projects/{projectName}/logical-clouds/{logicalCloud}", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).UpdateLogicalCloud(w, r)
-	}).Methods("PUT")
-	handle("/projects/{projectName}/logical-cloud/{logicalCloud}/status", func(w http.ResponseWriter, r *http.Request) {
-		createInstance(bootConf, r).GetLogicalCloudsStatus(w, r)
-	}).Methods("GET")
+	handle("/projects/{projectName}/logical-clouds", s.api((*OrchestrationHandler).HandleLCCreateRequest)).Methods("POST")
+	handle("/projects/{projectName}/logical-clouds",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.LogicalClouds(newProjectRequest(r))
+		})).Methods("GET")
+	handle("/projects/{projectName}/logical-clouds/{logicalCloud}", s.async("deleteLogicalCloud", (*OrchestrationHandler).DeleteLogicalCloud)).Methods("DELETE")
+	handle("/projects/{projectName}/logical-clouds/{logicalCloud}", s.api((*OrchestrationHandler).UpdateLogicalCloud)).Methods("PUT")
+	handle("/projects/{projectName}/logical-cloud/{logicalCloud}/status",
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.LogicalCloudStatus(newLogicalCloudRequest(r))
+		})).Methods("GET")
 
 	// Get cluster networks
 	handle("/cluster-providers/{clusterprovider-name}/clusters/{cluster-name}/networks",
-		func(w http.ResponseWriter, r *http.Request) {
-			createInstance(bootConf, r).GetClusterNetworks(w, r)
-		}).Methods("GET")
+		s.jsonAPI(func(h *OrchestrationHandler, r *http.Request) (interface{}, error) {
+			return h.ClusterNetworks(newClusterNetworksRequest(r))
+		})).Methods("GET")
 
-	RegisterCertCPHandlers(handle, bootConf)
-	RegisterCertLCHandlers(handle, bootConf)
+	s.registerCertCPHandlers(handle)
+	s.registerCertLCHandlers(handle)
 }
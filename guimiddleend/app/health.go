@@ -0,0 +1,169 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"example.com/middleend/backend"
+	"example.com/middleend/db"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	healthPass = "pass"
+	healthFail = "fail"
+
+	defaultReadinessCacheSeconds = 5
+	dependencyCheckTimeout       = 3 * time.Second
+)
+
+// DependencyHealth is the state of a dependency of the middleend
+type DependencyHealth struct {
+	Status    string  `json:"status"`
+	Latency   string  `json:"latency"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessResponse is the reply of /readyz
+type ReadinessResponse struct {
+	HealthcheckResponse
+	CheckedAt    time.Time                   `json:"checkedAt"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+// readinessChecker pings mongo and the configured EMCO services. The
+// result is cached so that probes and curious clients do not hammer the
+// dependencies; concurrent requests share a single round of checks.
+type readinessChecker struct {
+	ttl    time.Duration
+	client *backend.Client
+
+	mu   sync.Mutex
+	last *ReadinessResponse
+}
+
+var errStoreNotConnected = errors.New("store not connected")
+
+func newReadinessChecker(conf MiddleendConfig, client *backend.Client) *readinessChecker {
+	seconds := conf.ReadinessCacheSeconds
+	if seconds <= 0 {
+		seconds = defaultReadinessCacheSeconds
+	}
+	return &readinessChecker{ttl: time.Duration(seconds) * time.Second, client: client}
+}
+
+// check returns the cached result, or runs the checks when it expired
+func (rc *readinessChecker) check() *ReadinessResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.last != nil && time.Since(rc.last.CheckedAt) < rc.ttl {
+		return rc.last
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		"mongo": pingStore,
+	}
+	for _, service := range rc.client.Services() {
+		service := service
+		checks[service] = func(ctx context.Context) error {
+			return rc.client.Ping(ctx, service)
+		}
+	}
+
+	res := &ReadinessResponse{
+		HealthcheckResponse: HealthcheckResponse{Name: "amcop_middleend", Status: healthPass},
+		CheckedAt:           time.Now(),
+		Dependencies:        make(map[string]DependencyHealth, len(checks)),
+	}
+	var wg sync.WaitGroup
+	var resMu sync.Mutex
+	for name, fn := range checks {
+		wg.Add(1)
+		go func(name string, fn func(ctx context.Context) error) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), dependencyCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := fn(ctx)
+			took := time.Since(start)
+			dep := DependencyHealth{
+				Status:    healthPass,
+				Latency:   took.String(),
+				LatencyMs: float64(took) / float64(time.Millisecond),
+			}
+			if err != nil {
+				dep.Status = healthFail
+				dep.Error = err.Error()
+				log.WithError(err).Warnf("Readiness check of %s failed", name)
+			}
+			resMu.Lock()
+			res.Dependencies[name] = dep
+			if err != nil {
+				res.Status = healthFail
+			}
+			resMu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+	rc.last = res
+	return res
+}
+
+// pingStore checks the store within the deadline of ctx
+func pingStore(ctx context.Context) error {
+	if db.DBconn == nil {
+		return errStoreNotConnected
+	}
+	return db.DBconn.HealthCheck(ctx)
+}
+
+// GetLiveness replies whether the process serves requests. It checks no
+// dependency, a restart would not bring them back.
+func (h *OrchestrationHandler) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	h.writeHealth(w, http.StatusOK, HealthcheckResponse{Name: "amcop_middleend", Status: healthPass})
+}
+
+// GetReadiness replies 200 when mongo and every configured EMCO service
+// answer and 503 otherwise, with the state of each dependency.
+// A middleend shutting down is never ready.
+func (h *OrchestrationHandler) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	if h.srv.isShuttingDown() {
+		h.writeHealth(w, http.StatusServiceUnavailable, HealthcheckResponse{Name: "amcop_middleend", Status: healthFail})
+		return
+	}
+	res := h.srv.readiness.check()
+	code := http.StatusOK
+	if res.Status != healthPass {
+		code = http.StatusServiceUnavailable
+	}
+	h.writeHealth(w, code, res)
+}
+
+func (h *OrchestrationHandler) writeHealth(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	}
+}
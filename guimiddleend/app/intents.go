@@ -180,7 +180,7 @@ func (h *remoteStoreIntentHandler) createWorkloadIfIntent(wifint localstore.Work
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(wifint)
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" +
 		nwControllerIntentName + "/workload-intents/" + workloadIntentName + "/interfaces"
@@ -192,10 +192,10 @@ func (h *localStoreIntentHandler) createWorkloadIfIntent(wifint localstore.Workl
 	digName string, nwControllerIntentName string, workloadIntentName string, exists bool, intentName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewWorkloadIfIntentClient()
+	c := localstore.NewWorkloadIfIntentClient(h.orchInstance.ctx)
 	_, createErr := c.CreateWorkloadIfIntent(wifint, p, ca, v, digName, nwControllerIntentName, workloadIntentName, true)
 	if createErr != nil {
-		log.Error(":: Error creating workload interface ::", log.Fields{"Error": createErr})
+		h.orchInstance.Logger.Error(":: Error creating workload interface ::", log.Fields{"Error": createErr})
 		if strings.Contains(createErr.Error(), "does not exist") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "WorkloadIfIntent already exists") {
@@ -211,7 +211,7 @@ func (h *remoteStoreIntentHandler) getWorkloadIfIntents(p string, ca string, v s
 	digName string, nwControllerIntent string, workloadIntentName string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" +
 		nwControllerIntent + "/workload-intents/" + workloadIntentName + "/interfaces"
@@ -224,10 +224,10 @@ func (h *localStoreIntentHandler) getWorkloadIfIntents(p string, ca string, v st
 ) ([]byte, error) {
 	// Get the local store handler.
 	var retval []byte
-	c := localstore.NewWorkloadIfIntentClient()
+	c := localstore.NewWorkloadIfIntentClient(h.orchInstance.ctx)
 	interfaces, err := c.GetWorkloadIfIntents(p, ca, v, digName, nwControllerIntent, workloadIntentName)
 	if err != nil {
-		log.Error(":: Error getting workload interfaces ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: Error getting workload interfaces ::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "db Find error") {
 			return retval, err
 		} else {
@@ -242,7 +242,7 @@ func (h *remoteStoreIntentHandler) deleteWorkloadIfIntent(ifaceName string, work
 	digName string, nwControllerIntent string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" +
 		nwControllerIntent + "/workload-intents/" + workloadIntentName + "/interfaces/" + ifaceName
@@ -254,10 +254,10 @@ func (h *localStoreIntentHandler) deleteWorkloadIfIntent(ifaceName string, workl
 	digName string, nwControllerIntent string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewWorkloadIfIntentClient()
+	c := localstore.NewWorkloadIfIntentClient(h.orchInstance.ctx)
 	err := c.DeleteWorkloadIfIntent(ifaceName, p, ca, v, digName, nwControllerIntent, workloadIntentName)
 	if err != nil {
-		log.Error(":: Error deleting workloadIfIntent ::", log.Fields{"Error": err, "Name": ifaceName})
+		h.orchInstance.Logger.Error(":: Error deleting workloadIfIntent ::", log.Fields{"Error": err, "Name": ifaceName})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -274,7 +274,7 @@ func (h *remoteStoreIntentHandler) createWorkloadIntent(wint localstore.Workload
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(wint)
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" +
 		nwControllerIntentName + "/workload-intents"
@@ -286,10 +286,10 @@ func (h *localStoreIntentHandler) createWorkloadIntent(wint localstore.WorkloadI
 	digName string, nwControllerIntentName string, exists bool, intentName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewWorkloadIntentClient()
+	c := localstore.NewWorkloadIntentClient(h.orchInstance.ctx)
 	_, createErr := c.CreateWorkloadIntent(wint, p, ca, v, digName, nwControllerIntentName, true)
 	if createErr != nil {
-		log.Error(":: Error creating workload intent ::", log.Fields{"Error": createErr})
+		h.orchInstance.Logger.Error(":: Error creating workload intent ::", log.Fields{"Error": createErr})
 		if strings.Contains(createErr.Error(), "does not exist") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "WorkloadIntent already exists") {
@@ -305,7 +305,7 @@ func (h *remoteStoreIntentHandler) getWorkloadIntents(p string, ca string, v str
 	digName string, nwControllerIntent string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" +
 		nwControllerIntent + "/workload-intents"
@@ -318,10 +318,10 @@ func (h *localStoreIntentHandler) getWorkloadIntents(p string, ca string, v stri
 ) ([]byte, error) {
 	// Get the local store handler.
 	var retval []byte
-	c := localstore.NewWorkloadIntentClient()
+	c := localstore.NewWorkloadIntentClient(h.orchInstance.ctx)
 	workloadIntents, err := c.GetWorkloadIntents(p, ca, v, digName, nwControllerIntent)
 	if err != nil {
-		log.Error(":: Error getting workload intents ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: Error getting workload intents ::", log.Fields{"Error": err})
 		return retval, err
 	}
 	retval, _ = json.Marshal(workloadIntents)
@@ -332,7 +332,7 @@ func (h *remoteStoreIntentHandler) deleteWorkloadIntent(workloadIntentName strin
 	digName string, nwControllerIntent string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" +
 		nwControllerIntent + "/workload-intents/" + workloadIntentName
@@ -344,10 +344,10 @@ func (h *localStoreIntentHandler) deleteWorkloadIntent(workloadIntentName string
 	digName string, nwControllerIntent string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewWorkloadIntentClient()
+	c := localstore.NewWorkloadIntentClient(h.orchInstance.ctx)
 	err := c.DeleteWorkloadIntent(workloadIntentName, p, ca, v, digName, nwControllerIntent)
 	if err != nil {
-		log.Error(":: Error deleting workload intent ::", log.Fields{"Error": err, "Name": workloadIntentName})
+		h.orchInstance.Logger.Error(":: Error deleting workload intent ::", log.Fields{"Error": err, "Name": workloadIntentName})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -364,7 +364,7 @@ func (h *remoteStoreIntentHandler) createControllerIntent(cint localstore.NetCon
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(cint)
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent"
 	resp, err := orch.apiPost(jsonLoad, url, intentName)
@@ -375,10 +375,10 @@ func (h *localStoreIntentHandler) createControllerIntent(cint localstore.NetCont
 	digName string, exists bool, intentName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewNetControlIntentClient()
+	c := localstore.NewNetControlIntentClient(h.orchInstance.ctx)
 	_, createErr := c.CreateNetControlIntent(cint, p, ca, v, digName, true)
 	if createErr != nil {
-		log.Error(":: Error creating network control intent ::", log.Fields{"Error": createErr})
+		h.orchInstance.Logger.Error(":: Error creating network control intent ::", log.Fields{"Error": createErr})
 		if strings.Contains(createErr.Error(), "NetControlIntent already exists") {
 			return http.StatusConflict, createErr
 		} else {
@@ -392,7 +392,7 @@ func (h *remoteStoreIntentHandler) getControllerIntents(p string, ca string, v s
 	digName string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent"
 	reply, err := orch.apiGet(url, ca+"_getNwCtlInt")
@@ -404,10 +404,10 @@ func (h *localStoreIntentHandler) getControllerIntents(p string, ca string, v st
 ) ([]byte, error) {
 	// Get the local store handler.
 	var retval []byte
-	c := localstore.NewNetControlIntentClient()
+	c := localstore.NewNetControlIntentClient(h.orchInstance.ctx)
 	ctlInents, err := c.GetNetControlIntents(p, ca, v, digName)
 	if err != nil {
-		log.Error(":: Error getting network control intents ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: Error getting network control intents ::", log.Fields{"Error": err})
 		return retval, err
 	}
 	retval, _ = json.Marshal(ctlInents)
@@ -418,7 +418,7 @@ func (h *remoteStoreIntentHandler) deleteControllerIntent(nwIntentName string, p
 	digName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" + p +
+	url := orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/network-controller-intent/" + nwIntentName
 	resp, err := orch.apiDel(url, ca+"_delnwCtlInt")
@@ -429,10 +429,10 @@ func (h *localStoreIntentHandler) deleteControllerIntent(nwIntentName string, p
 	digName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewNetControlIntentClient()
+	c := localstore.NewNetControlIntentClient(h.orchInstance.ctx)
 	err := c.DeleteNetControlIntent(nwIntentName, p, ca, v, digName)
 	if err != nil {
-		log.Error(":: Error deleting network control intent ::", log.Fields{"Error": err, "Name": nwIntentName})
+		h.orchInstance.Logger.Error(":: Error deleting network control intent ::", log.Fields{"Error": err, "Name": nwIntentName})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -448,10 +448,10 @@ func (h *localStoreIntentHandler) getAllGPint(project string, compositeAppName s
 	digName string,
 ) ([]byte, error) {
 	var retval []byte
-	c := localstore.NewGenericPlacementIntentClient()
+	c := localstore.NewGenericPlacementIntentClient(h.orchInstance.ctx)
 	gPIntent, err := c.GetAllGenericPlacementIntents(project, compositeAppName, version, digName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "Unable to find") {
 			return retval, err
 		} else if strings.Contains(err.Error(), "db Find error") {
@@ -460,7 +460,7 @@ func (h *localStoreIntentHandler) getAllGPint(project string, compositeAppName s
 			return retval, err
 		}
 	}
-	log.Infof("Get All gpint localstore Composite app %s dig %s value %+v", compositeAppName,
+	h.orchInstance.Logger.Infof("Get All gpint localstore Composite app %s dig %s value %+v", compositeAppName,
 		digName, gPIntent)
 	retval, _ = json.Marshal(gPIntent)
 	return retval, err
@@ -471,12 +471,12 @@ func (h *remoteStoreIntentHandler) getAllGPint(project string, compositeAppName
 ) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		project + "/composite-apps/" + compositeAppName +
 		"/" + version +
 		"/deployment-intent-groups/" + digName + "/generic-placement-intents"
 	reply, err := orch.apiGet(orchURL, compositeAppName+"_gpint")
-	log.Infof("Get Gpint in Composite app %s dig %s status: %d", compositeAppName,
+	h.orchInstance.Logger.Infof("Get Gpint in Composite app %s dig %s status: %d", compositeAppName,
 		digName, reply.StatusCode)
 	return reply.Data, err
 }
@@ -485,7 +485,7 @@ func (h *remoteStoreIntentHandler) getAppPIntent(intentName string, gpintName st
 	digName string,
 ) ([]byte, error) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		project + "/composite-apps/" + compositeAppName +
 		"/" + version + "/deployment-intent-groups/" + digName + "/generic-placement-intents"
 	url := orchURL + "/" + gpintName + "/app-intents/" + intentName
@@ -497,10 +497,10 @@ func (h *localStoreIntentHandler) getAppPIntent(intentName string, gpintName str
 	digName string,
 ) ([]byte, error) {
 	var retval []byte
-	c := localstore.NewAppIntentClient()
+	c := localstore.NewAppIntentClient(h.orchInstance.ctx)
 	appIntent, err := c.GetAppIntent(intentName, project, compositeAppName, version, gpintName, digName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "db Find error") {
 			return retval, err
 		} else {
@@ -517,11 +517,11 @@ func (h *localStoreIntentHandler) getAppPIntent(intentName string, gpintName str
 func (h *localStoreIntentHandler) createGpint(g localstore.GenericPlacementIntent, p string, ca string,
 	v string, digName string,
 ) (interface{}, interface{}) {
-	c := localstore.NewGenericPlacementIntentClient()
+	c := localstore.NewGenericPlacementIntentClient(h.orchInstance.ctx)
 
 	_, createErr := c.CreateGenericPlacementIntent(g, p, ca, v, digName)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -543,7 +543,7 @@ func (h *remoteStoreIntentHandler) createGpint(g localstore.GenericPlacementInte
 	orch := h.orchInstance
 	gPintName := ca + "_gpint"
 	jsonLoad, _ := json.Marshal(g)
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-placement-intents"
@@ -555,10 +555,10 @@ func (h *localStoreIntentHandler) deleteAppPIntent(appIntentName string, p strin
 	gpintName string, digName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewAppIntentClient()
+	c := localstore.NewAppIntentClient(h.orchInstance.ctx)
 	deleteErr := c.DeleteAppIntent(appIntentName, p, ca, v, gpintName, digName)
 	if deleteErr != nil {
-		log.Error(deleteErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(deleteErr.Error(), log.Fields{})
 		if strings.Contains(deleteErr.Error(), "not found") {
 			return http.StatusNotFound, deleteErr
 		} else if strings.Contains(deleteErr.Error(), "conflict") {
@@ -574,7 +574,7 @@ func (h *remoteStoreIntentHandler) deleteAppPIntent(appIntentName string, p stri
 	gpintName string, digName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-placement-intents/" + gpintName + "/app-intents/" + appIntentName
@@ -585,11 +585,11 @@ func (h *remoteStoreIntentHandler) deleteAppPIntent(appIntentName string, p stri
 func (h *localStoreIntentHandler) deleteGpint(gpintName string, p string, ca string,
 	v string, digName string,
 ) (interface{}, interface{}) {
-	c := localstore.NewGenericPlacementIntentClient()
+	c := localstore.NewGenericPlacementIntentClient(h.orchInstance.ctx)
 
 	err := c.DeleteGenericPlacementIntent(gpintName, p, ca, v, digName)
 	if err != nil {
-		log.Error(err.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(err.Error(), log.Fields{})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -606,7 +606,7 @@ func (h *remoteStoreIntentHandler) deleteGpint(gpintName string, p string, ca st
 	v string, digName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName + "/generic-placement-intents/" + gpintName
 	resp, err := orch.apiDel(orchURL, gpintName)
@@ -617,10 +617,10 @@ func (h *localStoreIntentHandler) createAppPIntent(pint localstore.AppIntent, p
 	digName string, gpintName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewAppIntentClient()
+	c := localstore.NewAppIntentClient(h.orchInstance.ctx)
 	_, createErr := c.CreateAppIntent(pint, p, ca, v, gpintName, digName)
 	if createErr != nil {
-		log.Error(createErr.Error(), log.Fields{})
+		h.orchInstance.Logger.Error(createErr.Error(), log.Fields{})
 		if strings.Contains(createErr.Error(), "Unable to find the project") {
 			return http.StatusNotFound, createErr
 		} else if strings.Contains(createErr.Error(), "Unable to find the composite-app") {
@@ -643,7 +643,7 @@ func (h *remoteStoreIntentHandler) createAppPIntent(pint localstore.AppIntent, p
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(pint)
-	orchURL := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-placement-intents/" + gpintName + "/app-intents"
@@ -655,10 +655,10 @@ func (h *localStoreIntentHandler) createGenericK8sIntent(gki localstore.GenericK
 	v string, digName string, exists bool,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewGenericK8sIntentClient()
+	c := localstore.NewGenericK8sIntentClient(h.orchInstance.ctx)
 	_, err := c.CreateGenericK8sIntent(gki, p, ca, v, digName, exists)
 	if err != nil {
-		log.Error(":: CreateGenericK8sIntent error ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: CreateGenericK8sIntent error ::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "GenericK8sIntent already exists") {
 			return http.StatusConflict, nil
 		} else {
@@ -673,7 +673,7 @@ func (h *remoteStoreIntentHandler) createGenericK8sIntent(gki localstore.Generic
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(gki)
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents"
@@ -685,10 +685,10 @@ func (h *localStoreIntentHandler) deleteGenericK8sIntent(gkiName string, p strin
 	v string, digName string,
 ) (interface{}, interface{}) {
 	// Get the local store handler.
-	c := localstore.NewGenericK8sIntentClient()
+	c := localstore.NewGenericK8sIntentClient(h.orchInstance.ctx)
 	err := c.DeleteGenericK8sIntent(gkiName, p, ca, v, digName)
 	if err != nil {
-		log.Error(":: DeleteGenericK8sIntent failure ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: DeleteGenericK8sIntent failure ::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err.Error()
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -704,7 +704,7 @@ func (h *remoteStoreIntentHandler) deleteGenericK8sIntent(gkiName string, p stri
 	v string, digName string,
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gkiName
@@ -716,10 +716,10 @@ func (h *localStoreIntentHandler) createResource(r localstore.Resource, rc local
 	v string, digName string, gi string, exists bool,
 ) (interface{}, interface{}) {
 	// Get the local store handler
-	c := localstore.NewResourceClient()
+	c := localstore.NewResourceClient(h.orchInstance.ctx)
 	_, err := c.CreateResource(r, rc, p, ca, v, digName, gi, false)
 	if err != nil {
-		log.Error(":: Creation resource failure::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: Creation resource failure::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "resource already exists") {
 			return http.StatusConflict, err.Error()
 		} else {
@@ -734,7 +734,7 @@ func (h *remoteStoreIntentHandler) createResource(r localstore.Resource, rc loca
 ) (interface{}, interface{}) {
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(r)
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources"
@@ -755,10 +755,10 @@ func (h *localStoreIntentHandler) createCustomization(cz localstore.Customizatio
 	v string, digName string, gi string, rs string, exists bool,
 ) (interface{}, interface{}) {
 	// Get the local store handler
-	c := localstore.NewCustomizationClient()
+	c := localstore.NewCustomizationClient(h.orchInstance.ctx)
 	_, err := c.CreateCustomization(cz, t, p, ca, v, digName, gi, rs, false)
 	if err != nil {
-		log.Error(":: Create customization failure::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: Create customization failure::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "Customization already exists") {
 			return http.StatusConflict, err.Error()
 		} else {
@@ -774,27 +774,27 @@ func (h *remoteStoreIntentHandler) createCustomization(c localstore.Customizatio
 	orch := h.orchInstance
 	jsonLoad, _ := json.Marshal(c)
 
-	orch.Vars["multipartfiles"] = "true"
+	orch.state.multipartFiles = true
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rs + "/customizations"
 
 	status, err := orch.apiPostMultipart(jsonLoad, nil, url, ca+"_"+c.Metadata.Name, t.FileNames, t.FileContents)
-	orch.Vars["multipartfiles"] = "false"
+	orch.state.multipartFiles = false
 	return status, err
 }
 
 func (h *localStoreIntentHandler) getAllResources(p string, ca string, v string, digName string, gi string) ([]byte, error) {
 	// Get the local store handler
-	c := localstore.NewResourceClient()
+	c := localstore.NewResourceClient(h.orchInstance.ctx)
 	var retval []byte
 	var brList []localstore.Resource
 
 	ret, err := c.GetAllResources(p, ca, v, digName, gi)
 	if err != nil {
-		log.Error(":: GetAllResources failure::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: GetAllResources failure::", log.Fields{"Error": err})
 		return retval, err
 	}
 	for _, br := range ret {
@@ -807,7 +807,7 @@ func (h *localStoreIntentHandler) getAllResources(p string, ca string, v string,
 func (h *remoteStoreIntentHandler) getAllResources(p string, ca string, v string, digName string, gi string) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources"
@@ -817,13 +817,13 @@ func (h *remoteStoreIntentHandler) getAllResources(p string, ca string, v string
 
 func (h *localStoreIntentHandler) getResource(rName, p, ca, v, digName, gi string) ([]byte, error) {
 	// Get the local store handler
-	c := localstore.NewResourceClient()
+	c := localstore.NewResourceClient(h.orchInstance.ctx)
 
 	var resource localstore.Resource
 	var retval []byte
 	resource, err := c.GetResource(rName, p, ca, v, digName, gi)
 	if err != nil {
-		log.Error(":: GetResource failure::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: GetResource failure::", log.Fields{"Error": err})
 		return retval, err
 	}
 	retval, _ = json.Marshal(resource)
@@ -833,7 +833,7 @@ func (h *localStoreIntentHandler) getResource(rName, p, ca, v, digName, gi strin
 func (h *remoteStoreIntentHandler) getResource(rName, p, ca, v, digName, gi string) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rName
@@ -843,11 +843,11 @@ func (h *remoteStoreIntentHandler) getResource(rName, p, ca, v, digName, gi stri
 
 func (h *localStoreIntentHandler) deleteResource(rName, p, ca, v, digName, gi string) (interface{}, interface{}) {
 	// Get the local store handler
-	c := localstore.NewResourceClient()
+	c := localstore.NewResourceClient(h.orchInstance.ctx)
 
 	err := c.DeleteResource(rName, p, ca, v, digName, gi)
 	if err != nil {
-		log.Error(":: DeleteResource failure ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: DeleteResource failure ::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err.Error()
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -862,7 +862,7 @@ func (h *localStoreIntentHandler) deleteResource(rName, p, ca, v, digName, gi st
 func (h *remoteStoreIntentHandler) deleteResource(rName, p, ca, v, digName, gi string) (interface{}, interface{}) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rName
@@ -872,14 +872,14 @@ func (h *remoteStoreIntentHandler) deleteResource(rName, p, ca, v, digName, gi s
 
 func (h *localStoreIntentHandler) getAllCustomization(p, ca, v, digName, gi, rs string) ([]byte, error) {
 	// Get the local store handler
-	c := localstore.NewCustomizationClient()
+	c := localstore.NewCustomizationClient(h.orchInstance.ctx)
 
 	var czList []localstore.Customization
 	var retval []byte
 
 	ret, err := c.GetAllCustomization(p, ca, v, digName, gi, rs)
 	if err != nil {
-		log.Error(":: GetAllCustomization failure::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: GetAllCustomization failure::", log.Fields{"Error": err})
 		return retval, err
 	}
 
@@ -893,7 +893,7 @@ func (h *localStoreIntentHandler) getAllCustomization(p, ca, v, digName, gi, rs
 func (h *remoteStoreIntentHandler) getAllCustomization(p, ca, v, digName, gi, rs string) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rs + "/customizations"
@@ -903,12 +903,12 @@ func (h *remoteStoreIntentHandler) getAllCustomization(p, ca, v, digName, gi, rs
 
 func (h *localStoreIntentHandler) getCustomization(cz, p, ca, v, digName, gi, rs string) ([]byte, error) {
 	// Get the local store handler
-	c := localstore.NewCustomizationClient()
+	c := localstore.NewCustomizationClient(h.orchInstance.ctx)
 	var retval []byte
 
 	cusz, err := c.GetCustomization(cz, p, ca, v, digName, gi, rs)
 	if err != nil {
-		log.Error(":: GetCustomization failure::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: GetCustomization failure::", log.Fields{"Error": err})
 		return retval, err
 	}
 	retval, _ = json.Marshal(cusz)
@@ -918,7 +918,7 @@ func (h *localStoreIntentHandler) getCustomization(cz, p, ca, v, digName, gi, rs
 func (h *remoteStoreIntentHandler) getCustomization(cz, p, ca, v, digName, gi, rs string) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rs + "/customizations/" + cz
@@ -928,11 +928,11 @@ func (h *remoteStoreIntentHandler) getCustomization(cz, p, ca, v, digName, gi, r
 
 func (h *localStoreIntentHandler) deleteCustomization(cz, p, ca, v, digName, gi, rs string) (interface{}, interface{}) {
 	// Get the local store handler
-	c := localstore.NewCustomizationClient()
+	c := localstore.NewCustomizationClient(h.orchInstance.ctx)
 
 	err := c.DeleteCustomization(cz, p, ca, v, digName, gi, rs)
 	if err != nil {
-		log.Error(":: DeleteCustomization failure ::", log.Fields{"Error": err})
+		h.orchInstance.Logger.Error(":: DeleteCustomization failure ::", log.Fields{"Error": err})
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound, err.Error()
 		} else if strings.Contains(err.Error(), "conflict") {
@@ -945,7 +945,7 @@ func (h *localStoreIntentHandler) deleteCustomization(cz, p, ca, v, digName, gi,
 func (h *remoteStoreIntentHandler) deleteCustomization(cz, p, ca, v, digName, gi, rs string) (interface{}, interface{}) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rs + "/customizations/" + cz
@@ -955,12 +955,12 @@ func (h *remoteStoreIntentHandler) deleteCustomization(cz, p, ca, v, digName, gi
 
 func (h *localStoreIntentHandler) getResourceContent(rName, p, ca, v, digName, gi string) ([]byte, error) {
 	// Get the local store handler
-	c := localstore.NewResourceClient()
+	c := localstore.NewResourceClient(h.orchInstance.ctx)
 
 	var retval []byte
 	retBrContent, err := c.GetResourceContent(rName, p, ca, v, digName, gi)
 	if err != nil {
-		log.Errorf("Error encountered while fetching resource file content: %s", err)
+		h.orchInstance.Logger.Errorf("Error encountered while fetching resource file content: %s", err)
 		return retval, err
 	}
 	retval = []byte(retBrContent.FileContent)
@@ -970,7 +970,7 @@ func (h *localStoreIntentHandler) getResourceContent(rName, p, ca, v, digName, g
 func (h *remoteStoreIntentHandler) getResourceContent(rName, p, ca, v, digName, gi string) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rName
@@ -980,12 +980,12 @@ func (h *remoteStoreIntentHandler) getResourceContent(rName, p, ca, v, digName,
 
 func (h *localStoreIntentHandler) getCustomizationContent(cz, p, ca, v, digName, gi, rs string) ([]byte, error) {
 	// Get the local store handler
-	c := localstore.NewCustomizationClient()
+	c := localstore.NewCustomizationClient(h.orchInstance.ctx)
 
 	var retval []byte
 	specFC, err := c.GetCustomizationContent(cz, p, ca, v, digName, gi, rs)
 	if err != nil {
-		log.Errorf("Error encountered while fetching customization file content: %s", err)
+		h.orchInstance.Logger.Errorf("Error encountered while fetching customization file content: %s", err)
 		return retval, err
 	}
 	/*if len(specFC.FileContents) > 0 {
@@ -998,7 +998,7 @@ func (h *localStoreIntentHandler) getCustomizationContent(cz, p, ca, v, digName,
 func (h *remoteStoreIntentHandler) getCustomizationContent(c, p, ca, v, digName, gi, rs string) ([]byte, error) {
 	orch := h.orchInstance
 
-	orchURL := "http://" + orch.MiddleendConf.Gac + "/v2/projects/" + p +
+	orchURL := orch.MiddleendConf.serviceURL("gac") + "/v2/projects/" + p +
 		"/composite-apps/" + ca + "/" + v +
 		"/deployment-intent-groups/" + digName
 	url := orchURL + "/generic-k8s-intents/" + gi + "/resources/" + rs + "/customizations/" + c
@@ -1031,7 +1031,7 @@ func (h *placementIntentHandler) getObject() error {
 						var appPint localstore.AppIntent
 						retval, err := orch.bstore.getAppPIntent(appName+"_pint", gpintName,
 							project, compositeAppMetadata.Name, compositeAppSpec.Version, digName)
-						log.Infof("Get Gpint App intent in Composite app %s dig %s Gpint %s",
+						h.orchInstance.Logger.Infof("Get Gpint App intent in Composite app %s dig %s Gpint %s",
 							vars["compositeAppName"], digName, gpintName)
 						if err != nil {
 							ERR.Error(fmt.Errorf("Failed to read app pint\n"))
@@ -1079,14 +1079,14 @@ func (h *placementIntentHandler) getAnchor() error {
 				var gpintList []localstore.GenericPlacementIntent
 				retval, err := orch.bstore.getAllGPint(project, compositeAppMetadata.Name,
 					compositeAppSpec.Version, digName)
-				log.Infof("Get Gpint in Composite app %s dig %s", vars["compositeAppName"],
+				h.orchInstance.Logger.Infof("Get Gpint in Composite app %s dig %s", vars["compositeAppName"],
 					digName)
 				if err != nil {
-					log.Error("Failed to read gpint\n")
+					h.orchInstance.Logger.Error("Failed to read gpint\n")
 					return
 				}
 				if err := json.Unmarshal(retval, &gpintList); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.orchInstance.Logger.Error(err, PrintFunctionName())
 				}
 				digValue.GpintMap = make(map[string]*GpintData, len(gpintList))
 				for _, value := range gpintList {
@@ -1127,7 +1127,7 @@ func (h *placementIntentHandler) deleteObject() interface{} {
 					if resp != http.StatusNoContent {
 						return resp
 					}
-					log.Infof("Delete gpint intents response: %d", resp)
+					h.orchInstance.Logger.Infof("Delete gpint intents response: %d", resp)
 				}
 			}
 		}
@@ -1150,7 +1150,7 @@ func (h placementIntentHandler) deleteAnchor() interface{} {
 		// loop through all app intens in the gpint
 		for digName, digValue := range Dig {
 			for gpintName := range digValue.GpintMap {
-				log.Infof("Delete gpint  %s", h.orchURL)
+				h.orchInstance.Logger.Infof("Delete gpint  %s", h.orchURL)
 				resp, err := orch.bstore.deleteGpint(gpintName, vars["projectName"],
 					compositeAppMetadata.Name, compositeAppSpec.Version, digName)
 				if err != nil {
@@ -1159,7 +1159,7 @@ func (h placementIntentHandler) deleteAnchor() interface{} {
 				if resp != http.StatusNoContent {
 					return resp
 				}
-				log.Infof("Delete gpint response: %d", resp)
+				h.orchInstance.Logger.Infof("Delete gpint response: %d", resp)
 			}
 		}
 	}
@@ -1184,10 +1184,10 @@ func (h *placementIntentHandler) createAnchor() interface{} {
 			UserData2:   "data2",
 		},
 	}
-	log.Infof("gpint %s", gpi)
+	h.orchInstance.Logger.Infof("gpint %s", gpi)
 
 	// POST the generic placement intent
-	log.Infof("compositeAppName %s", intentData.CompositeAppName)
+	h.orchInstance.Logger.Infof("compositeAppName %s", intentData.CompositeAppName)
 	resp, err := orch.bstore.createGpint(gpi, projectName, intentData.CompositeAppName, version, digName)
 	jsonLoad, _ := json.Marshal(gpi)
 	orch.response.payload[intentData.CompositeAppName+"_gpint"] = jsonLoad
@@ -1198,7 +1198,7 @@ func (h *placementIntentHandler) createAnchor() interface{} {
 	if resp != http.StatusCreated {
 		return resp
 	}
-	log.Infof("Generic placement intent response: %d", resp)
+	h.orchInstance.Logger.Infof("Generic placement intent response: %d", resp)
 
 	return nil
 }
@@ -1219,7 +1219,7 @@ func (h *placementIntentHandler) createObject() interface{} {
 		// Initialize the base structure and then add the cluster values,
 		// we support only allof for now.
 		var customData string
-		if orch.Vars["update-intent"] == "yes" {
+		if orch.state.updateIntent {
 			customData = "updated"
 		} else {
 			customData = "data 1"
@@ -1268,7 +1268,7 @@ func (h *placementIntentHandler) createObject() interface{} {
 				}
 			}
 		}
-		log.Debugf("pint is: %+v", pint)
+		h.orchInstance.Logger.Debugf("pint is: %+v", pint)
 		status, err := orch.bstore.createAppPIntent(pint, projectName, intentData.CompositeAppName, version, digName, genericAppIntentName)
 		jsonLoad, _ := json.Marshal(pint)
 		orch.response.payload[genericAppIntentName] = jsonLoad
@@ -1279,7 +1279,7 @@ func (h *placementIntentHandler) createObject() interface{} {
 		if status != http.StatusCreated {
 			return status
 		}
-		log.Infof("Placement intent %s status: %d", intentName, status)
+		h.orchInstance.Logger.Infof("Placement intent %s status: %d", intentName, status)
 	}
 	return nil
 }
@@ -1337,7 +1337,7 @@ func (h *networkIntentHandler) createAnchor() interface{} {
 	if resp != http.StatusCreated {
 		return resp
 	}
-	log.Infof("Network controller intent response: %d", resp)
+	h.orchInstance.Logger.Infof("Network controller intent response: %d", resp)
 
 	return nil
 }
@@ -1363,7 +1363,7 @@ func (h *networkIntentHandler) createObject() interface{} {
 		workloadIntentName := appName + "_wlint"
 
 		var customData string
-		if orch.Vars["update-intent"] == "yes" {
+		if orch.state.updateIntent {
 			customData = "updated"
 		} else {
 			customData = "data 1"
@@ -1394,7 +1394,7 @@ func (h *networkIntentHandler) createObject() interface{} {
 		if status != http.StatusCreated {
 			return status
 		}
-		log.Infof("Workload intent %s status: %d", workloadIntentName, status)
+		h.orchInstance.Logger.Infof("Workload intent %s status: %d", workloadIntentName, status)
 
 		// Create interfaces for each per app workload intent.
 		for i, iface := range app.Interfaces {
@@ -1434,7 +1434,7 @@ func (h *networkIntentHandler) createObject() interface{} {
 			if status != http.StatusCreated {
 				return status
 			}
-			log.Infof("interface %s status: %d ", interfaceName, status)
+			h.orchInstance.Logger.Infof("interface %s status: %d ", interfaceName, status)
 		}
 	}
 
@@ -1459,14 +1459,14 @@ func (h *networkIntentHandler) getObject() error {
 				var wrlintList []NetworkWlIntent
 				retval, err := orch.bstore.getWorkloadIntents(projectName, compositeAppMetadata.Name,
 					compositeAppSpec.Version, digName, nwintName)
-				log.Infof("Get Wrkld intents in Composite app %s dig %s nw intent %s",
+				h.orchInstance.Logger.Infof("Get Wrkld intents in Composite app %s dig %s nw intent %s",
 					compositeAppName, digName, nwintName)
 				if err != nil {
-					log.Error("Failed to read nw  workload int")
+					h.orchInstance.Logger.Error("Failed to read nw  workload int")
 					return err
 				}
 				if err := json.Unmarshal(retval, &wrlintList); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.orchInstance.Logger.Error(err, PrintFunctionName())
 				}
 				nwintValue.WrkintMap = make(map[string]*WrkintData, len(wrlintList))
 				for _, wrlIntValue := range wrlintList {
@@ -1474,16 +1474,16 @@ func (h *networkIntentHandler) getObject() error {
 					WrkintDataInstance.Wrkint = wrlIntValue
 
 					var ifaceList []NwInterface
-					log.Infof("Get interface in Composite app %s dig %s nw intent %s wrkld intent %s",
+					h.orchInstance.Logger.Infof("Get interface in Composite app %s dig %s nw intent %s wrkld intent %s",
 						compositeAppName, digName, nwintName, wrlIntValue.Metadata.Name)
 					retval, err := orch.bstore.getWorkloadIfIntents(projectName, compositeAppMetadata.Name,
 						compositeAppSpec.Version, digName, nwintName, wrlIntValue.Metadata.Name)
 					if err != nil {
-						log.Error("Failed to read nw interface")
+						h.orchInstance.Logger.Error("Failed to read nw interface")
 						return err
 					}
 					if err := json.Unmarshal(retval, &ifaceList); err != nil {
-						log.Error(err, PrintFunctionName())
+						h.orchInstance.Logger.Error(err, PrintFunctionName())
 					}
 					WrkintDataInstance.Interfaces = ifaceList
 					nwintValue.WrkintMap[wrlIntValue.Metadata.Name] = &WrkintDataInstance
@@ -1512,14 +1512,14 @@ func (h *networkIntentHandler) getAnchor() error {
 			var nwintList []NetworkCtlIntent
 
 			retval, err := orch.bstore.getControllerIntents(projectName, compositeAppMetadata.Name, compositeAppSpec.Version, digName)
-			log.Infof("Get Network Ctl intent in Composite app %s dig %s status: %d",
+			h.orchInstance.Logger.Infof("Get Network Ctl intent in Composite app %s dig %s status: %d",
 				compositeAppName, digName, retcode)
 			if err != nil {
-				log.Errorf("Failed to read nw int %s\n", err)
+				h.orchInstance.Logger.Errorf("Failed to read nw int %s\n", err)
 				return err
 			}
 			if err := json.Unmarshal(retval, &nwintList); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.orchInstance.Logger.Error(err, PrintFunctionName())
 			}
 			digValue.NwintMap = make(map[string]*NwintData, len(nwintList))
 			for _, nwIntValue := range nwintList {
@@ -1546,7 +1546,7 @@ func (h *networkIntentHandler) deleteObject() interface{} {
 		compositeAppSpec := compositeAppValue.Metadata.Spec
 		Dig := compositeAppValue.DigMap
 		for digName, digValue := range Dig {
-			h.ovnURL = "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" +
+			h.ovnURL = orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" +
 				projectName + "/composite-apps/" + compositeAppMetadata.Name +
 				"/" + compositeAppSpec.Version +
 				"/deployment-intent-groups/" + digName
@@ -1564,14 +1564,14 @@ func (h *networkIntentHandler) deleteObject() interface{} {
 						if retcode != http.StatusNoContent {
 							return retcode
 						}
-						log.Infof("Delete nw interface response: %d", retcode)
+						h.orchInstance.Logger.Infof("Delete nw interface response: %d", retcode)
 					}
 					// Delete the workload intents.
 					url := h.ovnURL + "network-controller-intent/" + nwintName + "/workload-intents/" + wrkintName
-					log.Infof("Delete app nw wl intent %s", url)
+					h.orchInstance.Logger.Infof("Delete app nw wl intent %s", url)
 					retcode, err := orch.bstore.deleteWorkloadIntent(wrkintName, projectName, compositeAppMetadata.Name,
 						compositeAppSpec.Version, digName, nwintName)
-					log.Infof("Delete nw wl intent response: %d", retcode)
+					h.orchInstance.Logger.Infof("Delete nw wl intent response: %d", retcode)
 					if err != nil {
 						return err
 					}
@@ -1599,7 +1599,7 @@ func (h networkIntentHandler) deleteAnchor() interface{} {
 		compositeAppSpec := compositeAppValue.Metadata.Spec
 		Dig := compositeAppValue.DigMap
 		for digName, digValue := range Dig {
-			h.ovnURL = "http://" + orch.MiddleendConf.OvnService + "/v2/projects/" +
+			h.ovnURL = orch.MiddleendConf.serviceURL("ovnaction") + "/v2/projects/" +
 				projectName + "/composite-apps/" + compositeAppMetadata.Name +
 				"/" + compositeAppSpec.Version +
 				"/deployment-intent-groups/" + digName
@@ -1607,7 +1607,7 @@ func (h networkIntentHandler) deleteAnchor() interface{} {
 				// loop through all app intens in the gpint
 				retcode, err := orch.bstore.deleteControllerIntent(nwintName, projectName, compositeAppMetadata.Name,
 					compositeAppSpec.Version, digName)
-				log.Infof("Delete nw controller intent response: %d", retcode)
+				h.orchInstance.Logger.Infof("Delete nw controller intent response: %d", retcode)
 				if err != nil {
 					return err
 				}
@@ -1679,7 +1679,7 @@ func (h genericK8sIntentHandler) createAnchor() interface{} {
 		}
 
 		retcode, err := orch.bstore.createGenericK8sIntent(gki, projectName, compositeAppName, version, digName, false)
-		log.Infof("Creation of generic K8s intent response: %s", retcode)
+		h.orchInstance.Logger.Infof("Creation of generic K8s intent response: %s", retcode)
 		if err != nil {
 			return err
 		}
@@ -1719,7 +1719,7 @@ func (h genericK8sIntentHandler) createObject() interface{} {
 
 			retcode, err := orch.bstore.createResource(resource, resObj.ResourceFile, resObj.ResourceFileName, projectName, compositeAppName,
 				version, digName, compositeAppName+"_genk8sint", false)
-			log.Infof("Creation of resource response: %s", retcode)
+			h.orchInstance.Logger.Infof("Creation of resource response: %s", retcode)
 			if err != nil {
 				return err
 			}
@@ -1768,7 +1768,7 @@ func (h genericK8sIntentHandler) createObject() interface{} {
 
 			retcode, err = orch.bstore.createCustomization(customization, resObj.CustomFile, projectName, compositeAppName,
 				version, digName, compositeAppName+"_genk8sint", resourceName, false)
-			log.Infof("Creation of customization response: %s", retcode)
+			h.orchInstance.Logger.Infof("Creation of customization response: %s", retcode)
 			if err != nil {
 				return err
 			}
@@ -1796,13 +1796,13 @@ func (h genericK8sIntentHandler) getAnchor() error {
 	}
 
 	if err := json.Unmarshal(retval, &brList); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.orchInstance.Logger.Error(err, PrintFunctionName())
 	}
 	var genK8sInfo GenericK8sIntentInfo
 	orch.genK8sInfo = make(map[string]*GenericK8sIntentInfo)
 	genK8sInfo.listGenK8sData.resource = make([]localstore.Resource, len(brList))
 	genK8sInfo.listGenK8sData.resource = brList
-	log.Infof("resources: %+v", brList)
+	h.orchInstance.Logger.Infof("resources: %+v", brList)
 	h.orchInstance.genK8sInfo[compositeAppName+"_genk8sint"] = &genK8sInfo
 
 	return nil
@@ -1835,9 +1835,9 @@ func (h genericK8sIntentHandler) getObject() error {
 				return err
 			}
 			if err := json.Unmarshal(retvalue, &cList); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.orchInstance.Logger.Error(err, PrintFunctionName())
 			}
-			log.Debugf("customization: %+v", cList)
+			h.orchInstance.Logger.Debugf("customization: %+v", cList)
 			genK8sRes.listGenK8sData.resMap[res.Metadata.Name] = cList
 
 			// Populate resData structure
@@ -1857,7 +1857,7 @@ func (h genericK8sIntentHandler) getObject() error {
 
 				var cSpecContent localstore.SpecFileContent
 				if err := json.Unmarshal(retval, &cSpecContent); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.orchInstance.Logger.Error(err, PrintFunctionName())
 				}
 				resInfo.CustomizationSpec = cz.Spec
 				if len(cz.Metadata.UserData1) > 0 {
@@ -1883,7 +1883,7 @@ func (h genericK8sIntentHandler) deleteAnchor() interface{} {
 	// Delete genericK8sIntent belonging to DIG
 	retcode, err := orch.bstore.deleteGenericK8sIntent(compositeAppName+"_genk8sint", projectName, compositeAppName,
 		version, digName)
-	log.Infof("delete genericGenK8sIntent response: %s", retcode)
+	h.orchInstance.Logger.Infof("delete genericGenK8sIntent response: %s", retcode)
 	if err != nil {
 		return err
 	}
@@ -1903,7 +1903,7 @@ func (h genericK8sIntentHandler) deleteObject() interface{} {
 	digName := vars["deploymentIntentGroupName"]
 
 	genK8sInfo := orch.genK8sInfo[compositeAppName+"_genk8sint"]
-	log.Debugf("genK8sInfo: %+v", genK8sInfo)
+	h.orchInstance.Logger.Debugf("genK8sInfo: %+v", genK8sInfo)
 	if genK8sInfo == nil {
 		return nil
 	}
@@ -1914,14 +1914,14 @@ func (h genericK8sIntentHandler) deleteObject() interface{} {
 			retcode, _ := orch.bstore.deleteCustomization(czObj.Metadata.Name, projectName,
 				compositeAppName,
 				version, digName, compositeAppName+"_genk8sint", resObj)
-			log.Infof("deleteCustomization response: %s", retcode)
+			h.orchInstance.Logger.Infof("deleteCustomization response: %s", retcode)
 			if retcode != nil && retcode.(int) != http.StatusNoContent {
 				return retcode.(int)
 			}
 		}
 		retcode, _ := orch.bstore.deleteResource(resObj, orch.Vars["projectName"], compositeAppName,
 			version, digName, compositeAppName+"_genk8sint")
-		log.Infof("deleteResource response: %s", retcode)
+		h.orchInstance.Logger.Infof("deleteResource response: %s", retcode)
 		if retcode != nil && retcode.(int) != http.StatusNoContent {
 			return retcode.(int)
 		}
@@ -3,7 +3,6 @@ package app
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 )
@@ -19,7 +18,7 @@ func (h *lcHandler) CreateCertLogicalClouds(caIntent, cProject string, clouds []
 			for j := i; j < len(clouds); j++ {
 				err := h.DeleteCertLogicalCloud(caIntent, cProject, cl.Metadata.Name)
 				if err != nil {
-					log.Println(err)
+					h.Logger.Println(err)
 				}
 			}
 			return fmt.Errorf("Error occured during creating the logical clouds - %s. Rolling back", err)
@@ -39,7 +38,7 @@ func (h *lcHandler) DeleteCertLogicalClouds(caIntent, cProject string, clusters
 }
 
 func (h *lcHandler) DeleteCertLogicalCloud(caIntent, cProject string, cluster string) error {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/logical-clouds/" + cluster
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/logical-clouds/" + cluster
 
 	statusCode, err := h.apiDel(url, "DeleteCertLogicalCloud")
 	if err != nil {
@@ -57,7 +56,7 @@ func (h *lcHandler) DeleteCertLogicalCloud(caIntent, cProject string, cluster st
 }
 
 func (h *lcHandler) CreateCertLogicalCloud(caIntent, cProject string, cloud *CaCertLogicalCloud) error {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/logical-clouds"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/logical-clouds"
 	cloud.Spec.LogicalCloud = cloud.Metadata.Name
 	payload, err := json.Marshal(cloud)
 	if err != nil {
@@ -79,7 +78,7 @@ func (h *lcHandler) CreateCertLogicalCloud(caIntent, cProject string, cloud *CaC
 }
 
 func (h *lcHandler) InstantiateEnrollment(caIntent, cProject string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/enrollment/instantiate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/enrollment/instantiate"
 	sc, err := h.apiPost(nil, url, "payload")
 	statusCode := sc.(int)
 	if err != nil {
@@ -94,7 +93,7 @@ func (h *lcHandler) InstantiateEnrollment(caIntent, cProject string) (int, error
 }
 
 func (h *lcHandler) TerminateEnrollment(caIntent, cProject string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/enrollment/terminate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/enrollment/terminate"
 	sc, err := h.apiPost(nil, url, "payload")
 	statusCode := sc.(int)
 	if err != nil {
@@ -109,7 +108,7 @@ func (h *lcHandler) TerminateEnrollment(caIntent, cProject string) (int, error)
 }
 
 func (h *lcHandler) InstantiateDistribution(caIntent, cProject string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/distribution/instantiate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/distribution/instantiate"
 	sc, err := h.apiPost(nil, url, "payload")
 	statusCode := sc.(int)
 	if err != nil {
@@ -124,7 +123,7 @@ func (h *lcHandler) InstantiateDistribution(caIntent, cProject string) (int, err
 }
 
 func (h *lcHandler) TerminateDistribution(caIntent, cProject string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/distribution/terminate"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/distribution/terminate"
 	sc, err := h.apiPost(nil, url, "payload")
 	statusCode := sc.(int)
 	if err != nil {
@@ -139,7 +138,7 @@ func (h *lcHandler) TerminateDistribution(caIntent, cProject string) (int, error
 }
 
 func (h *lcHandler) GetCaCertEnrollmentStatus(caIntent, cProject string) (*CertStatus, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/enrollment/status"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/enrollment/status"
 
 	reply, err := h.apiGet(url, "GetCaCertEnrollmentStatus")
 	if err != nil {
@@ -155,7 +154,7 @@ func (h *lcHandler) GetCaCertEnrollmentStatus(caIntent, cProject string) (*CertS
 }
 
 func (h *lcHandler) GetCaCertDistributionStatus(caIntent, cProject string) (*CertStatus, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/distribution/status"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/distribution/status"
 
 	reply, err := h.apiGet(url, "GetCaCertDistributionStatus")
 	if err != nil {
@@ -171,7 +170,7 @@ func (h *lcHandler) GetCaCertDistributionStatus(caIntent, cProject string) (*Cer
 }
 
 func (h *lcHandler) GetCaCert(caIntent, cProject string) (*CaCert, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent
 
 	reply, err := h.apiGet(url, "caCertsGet")
 	if err != nil {
@@ -187,7 +186,7 @@ func (h *lcHandler) GetCaCert(caIntent, cProject string) (*CaCert, error) {
 }
 
 func (h *lcHandler) PostCaCert(caCert *CaCert, cProject string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs"
 	statusCode := 500
 	payload, err := json.Marshal(caCert)
 	if err != nil {
@@ -208,7 +207,7 @@ func (h *lcHandler) PostCaCert(caCert *CaCert, cProject string) (int, error) {
 }
 
 func (h *lcHandler) DeleteCaCert(caIntent, cProject string) (int, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent
 
 	sc, err := h.apiDel(url, "caCertDel")
 	statusCode := sc.(int)
@@ -228,7 +227,7 @@ func (h *lcHandler) DeleteCaCert(caIntent, cProject string) (int, error) {
 }
 
 func (h *lcHandler) GetCAIntentLogicalClouds(caIntent, cProject string) ([]*CaCertLogicalCloud, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/logical-clouds"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/logical-clouds"
 
 	reply, err := h.apiGet(url, "payload")
 	if err != nil {
@@ -244,7 +243,7 @@ func (h *lcHandler) GetCAIntentLogicalClouds(caIntent, cProject string) ([]*CaCe
 }
 
 func (h *lcHandler) GetCAIntentClusters(caIntent, cProject string) ([]*CertIntentCluster, error) {
-	url := "http://" + h.MiddleendConf.Cert + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/clusters"
+	url := h.MiddleendConf.serviceURL("cert") + "/v2/projects/" + cProject + "/ca-certs/" + caIntent + "/clusters"
 
 	reply, err := h.apiGet(url, "GetCAIntentClusters")
 	if err != nil {
@@ -260,7 +259,7 @@ func (h *lcHandler) GetCAIntentClusters(caIntent, cProject string) ([]*CertInten
 }
 
 func (h *lcHandler) GetLogicalCloudsByProject(cProject string) ([]*CaCertLogicalCloud, error) {
-	url := "http://" + h.MiddleendConf.Dcm + "/v2/projects/" + cProject + "/logical-clouds"
+	url := h.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + cProject + "/logical-clouds"
 
 	reply, err := h.apiGet(url, "payload")
 	if err != nil {
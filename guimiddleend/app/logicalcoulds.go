@@ -23,7 +23,6 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	log "github.com/sirupsen/logrus"
 )
 
 type logicalCloudData struct {
@@ -225,7 +224,7 @@ type userPermissions struct {
 }
 
 type logicalCloudsPayload struct {
-	Name                   string `json:"name"`
+	Name                   string `json:"name" binding:"required"`
 	Description            string `json:"description"`
 	CloudType              string `json:"cloudType"`
 	Namespace              string `json:"namespace"`
@@ -258,16 +257,16 @@ func (h *logicalCloudHandler) getLogicalClouds() (lc []LogicalClouds, err error)
 	orch := h.orchInstance
 	lc = []LogicalClouds{}
 	projectName := orch.Vars["projectName"]
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projectName + "/logical-clouds"
 	reply, err := orch.apiGet(url, projectName)
 	if err != nil {
-		log.Errorf("%s(): Failed to GET LC for %s", PrintFunctionName(), projectName)
+		h.orchInstance.Logger.Errorf("%s(): Failed to GET LC for %s", PrintFunctionName(), projectName)
 		return lc, err
 	}
-	log.Infof("%s(): Get LC status: %d", PrintFunctionName(), reply.StatusCode)
+	h.orchInstance.Logger.Infof("%s(): Get LC status: %d", PrintFunctionName(), reply.StatusCode)
 	if err := json.Unmarshal(reply.Data, &lc); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.orchInstance.Logger.Error(err, PrintFunctionName())
 	}
 	return lc, err
 }
@@ -275,23 +274,23 @@ func (h *logicalCloudHandler) getLogicalClouds() (lc []LogicalClouds, err error)
 func (h *logicalCloudHandler) getLogicalCloud(lcName string) (lc LogicalClouds, err error) {
 	orch := h.orchInstance
 	projectName := orch.Vars["projectName"]
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projectName + "/logical-clouds/" + lcName
 	reply, err := orch.apiGet(url, projectName)
 	if err != nil {
-		log.Errorf("%s(): Failed to GET LC %s", PrintFunctionName(), lcName)
+		h.orchInstance.Logger.Errorf("%s(): Failed to GET LC %s", PrintFunctionName(), lcName)
 		return lc, err
 	}
-	log.Infof("%s(): Get LC status: %d", PrintFunctionName(), reply.StatusCode)
+	h.orchInstance.Logger.Infof("%s(): Get LC status: %d", PrintFunctionName(), reply.StatusCode)
 	if err := json.Unmarshal(reply.Data, &lc); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.orchInstance.Logger.Error(err, PrintFunctionName())
 	}
 	return lc, err
 }
 
 func (h *logicalCloudHandler) getLogicalCloudsStatus(ProjectName string, LogicalCloudName string) (lcStatus LogicalCloudStatus, err error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" + ProjectName + "/logical-clouds/" + LogicalCloudName + "/status"
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + ProjectName + "/logical-clouds/" + LogicalCloudName + "/status"
 	reply, err := orch.apiGet(url, LogicalCloudName)
 	if err != nil {
 		err = fmt.Errorf("%s(): Failed to get LC status for project %s and logical cloud %s with error %s", PrintFunctionName(),
@@ -305,15 +304,15 @@ func (h *logicalCloudHandler) getLogicalCloudsStatus(ProjectName string, Logical
 func (h *logicalCloudHandler) fetchLCReferencesFlat(lcName string) (lcRefList []clusterReferenceFlat, err error) {
 	lcRefList = []clusterReferenceFlat{}
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		orch.Vars["projectName"] + "/logical-clouds/" + lcName + "/cluster-references"
 	reply, err := orch.apiGet(url, lcName)
 	if err != nil {
-		log.Errorf("%s(): Failed to LC reference for %s", PrintFunctionName(), lcName)
+		h.orchInstance.Logger.Errorf("%s(): Failed to LC reference for %s", PrintFunctionName(), lcName)
 		return lcRefList, err
 	}
 	err = json.Unmarshal(reply.Data, &lcRefList)
-	log.Debugf("lc references: %+v", lcRefList)
+	h.orchInstance.Logger.Debugf("lc references: %+v", lcRefList)
 	return lcRefList, err
 }
 
@@ -350,11 +349,11 @@ func (h *logicalCloudHandler) getLogicalCloudReferences(lcName string) (nestedRe
 		wg.Add(1)
 		go func(clusterProvider string) {
 			defer wg.Done()
-			url := "http://" + orch.MiddleendConf.Clm + "/v2/cluster-providers/" +
+			url := orch.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" +
 				clusterProvider + "/clusters?withLabels=true"
 			reply, err := orch.apiGet(url, clusterProvider)
 			if err != nil {
-				log.Errorf("%s(): Encountered error while fetching labels for cluster provider %s",
+				h.orchInstance.Logger.Errorf("%s(): Encountered error while fetching labels for cluster provider %s",
 					PrintFunctionName(), clusterProvider)
 				ERR.Error(err)
 				return
@@ -398,33 +397,110 @@ func (h *logicalCloudHandler) getLogicalCloudReferences(lcName string) (nestedRe
 	return nestedRef, ERR.Errors()
 }
 
+// createLogicalCloud creates, references and instantiates the logical
+// cloud. On failure the objects created so far are deleted again and the
+// per step report is returned along with the status code.
 func (h *logicalCloudHandler) createLogicalCloud(lcData logicalCloudsPayload,
 	lcDataRetPayload *LogicalClouds,
-) int {
+) (int, *sagaReport) {
 	orch := h.orchInstance
+	projectName := orch.Vars["projectName"]
+	report, status := newSaga("createLogicalCloud", orch).
+		addPartialStep("logicalCloud", func() interface{} {
+			return h.createCloud(lcData, lcDataRetPayload)
+		}, func() interface{} {
+			return h.deleteCloud(projectName, lcDataRetPayload)
+		}, lcData.Name).
+		addPartialStep("clusterReferences", func() interface{} {
+			return h.createClusterReferences(lcData, lcDataRetPayload)
+		}, func() interface{} {
+			return h.deleteClusterReferences(projectName, lcDataRetPayload)
+		}, "").
+		addStep("instantiate", func() interface{} {
+			// Instantiate the cluster.
+			url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
+				projectName + "/logical-clouds/" + lcData.Name + "/instantiate"
+			var jsonLoad []byte
+			resp, err := orch.apiPost(jsonLoad, url, lcData.Name+"-instantiate")
+			if err != nil {
+				h.orchInstance.Logger.Errorf("%s(): Failed to instantiate logical cloud %s", PrintFunctionName(), lcData.Name)
+				return err
+			}
+			if resp != http.StatusAccepted {
+				h.orchInstance.Logger.Errorf("%s(): Failed to instantiate logical cloud %s", PrintFunctionName(), lcData.Name)
+				return resp
+			}
+			lcDataRetPayload.Spec.Status = "Instantiated"
+			return nil
+		}, nil, lcData.Name+"-instantiate").
+		run()
+	if status != nil {
+		return statusCode(status), report
+	}
+	return http.StatusCreated, report
+}
+
+func (h *logicalCloudHandler) createCloud(lcData logicalCloudsPayload, lcDataRetPayload *LogicalClouds) interface{} {
 	if lcData.CloudType == "admin" {
 		resp, err := h.createAdminLogicalCloud(lcData)
 		if err != nil || resp != http.StatusCreated {
-			log.Errorf("Error encountered during creation of Admin Logical Cloud: %s", err)
+			h.orchInstance.Logger.Errorf("Error encountered during creation of Admin Logical Cloud: %s", err)
 			return resp
 		}
 		// Prepare ret payload
 		if err := json.Unmarshal(h.orchInstance.response.payload[lcData.Name], lcDataRetPayload); err != nil {
-			log.Error(err, PrintFunctionName())
-			return resp
+			h.orchInstance.Logger.Error(err, PrintFunctionName())
+			return err
 		}
 	} else if lcData.CloudType == "user" || lcData.CloudType == "privileged" {
 		resp, err := h.createStandardLogicalCloud(lcData, lcDataRetPayload)
 		if err != nil || resp != http.StatusCreated {
-			log.Errorf("Error encountered during creation of User Logical Cloud: %s", err)
+			h.orchInstance.Logger.Errorf("Error encountered during creation of User Logical Cloud: %s", err)
 			return resp
 		}
 	} else {
-		log.Errorf("%s(): Invalid cloud type for creation of logical cloud: %s", PrintFunctionName(), lcData.CloudType)
-		return http.StatusInternalServerError
+		h.orchInstance.Logger.Errorf("%s(): Invalid cloud type for creation of logical cloud: %s", PrintFunctionName(), lcData.CloudType)
+		return http.StatusBadRequest
 	}
+	return nil
+}
 
-	// Now Create the reference for each cluster in the logical cloud
+// deleteCloud deletes the logical cloud with its permissions and quota. The
+// ret payload is only filled once the logical cloud was created, so an
+// existing logical cloud of the same name is never deleted.
+func (h *logicalCloudHandler) deleteCloud(projectName string, lcData *LogicalClouds) interface{} {
+	lcName := lcData.Metadata.Name
+	if lcName == "" {
+		return nil
+	}
+	if lcData.Spec.Level != "0" {
+		for _, p := range lcData.Spec.UserPerminssionMetadata {
+			retval, _ := h.deleteUserPermissions(projectName, lcName, p.UserPermissionName)
+			if !deleted(retval) {
+				h.orchInstance.Logger.Errorf("%s(): Failed to delete user permissions for lc %s", PrintFunctionName(), lcName)
+				return retval
+			}
+		}
+		if lcData.Spec.UserQuotaMetadata.QuotaName != "" {
+			retval, _ := h.deleteUserQuota(projectName, lcName, lcData.Spec.UserQuotaMetadata.QuotaName)
+			if !deleted(retval) {
+				h.orchInstance.Logger.Errorf("%s(): Failed to delete quota info for lc %s", PrintFunctionName(), lcName)
+				return retval
+			}
+		}
+	}
+	retval, _ := h.deleteLogicalCloud(projectName, lcName)
+	if !deleted(retval) {
+		h.orchInstance.Logger.Errorf("%s(): Failed to delete lc %s", PrintFunctionName(), lcName)
+		return retval
+	}
+	h.orchInstance.Logger.Infof("%s(): Deleted Logical cloud %s", PrintFunctionName(), lcName)
+	return nil
+}
+
+// createClusterReferences creates the reference for each cluster in the logical cloud
+func (h *logicalCloudHandler) createClusterReferences(lcData logicalCloudsPayload, lcDataRetPayload *LogicalClouds) interface{} {
+	orch := h.orchInstance
 	cretVal := clusterReferenceNested{}
 	for _, clusterProvider := range lcData.Spec.ClusterProvidersList {
 		cpp := ClusterProviders{}
@@ -433,7 +509,7 @@ func (h *logicalCloudHandler) createLogicalCloud(lcData logicalCloudsPayload,
 		for _, cluster := range clusterProvider.Spec.ClustersList {
 			resp := h.createClusterReference(orch.Vars["projectName"], lcData.Name, clusterProvider.Metadata.Name, cluster.Metadata.Name)
 			if resp != http.StatusCreated {
-				log.Errorf("%s(): Failed to add Cluster referecens for cloud %s", PrintFunctionName(), lcData.Name)
+				h.orchInstance.Logger.Errorf("%s(): Failed to add Cluster referecens for cloud %s", PrintFunctionName(), lcData.Name)
 				lcDataRetPayload.Spec.ClusterReferences = cretVal
 				return resp
 			}
@@ -441,7 +517,7 @@ func (h *logicalCloudHandler) createLogicalCloud(lcData logicalCloudsPayload,
 			cpayload := clusterReferenceFlat{}
 			clp := Clusters{}
 			if err := json.Unmarshal(h.orchInstance.response.payload[lcData.Name+"-"+cluster.Metadata.Name], &cpayload); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.orchInstance.Logger.Error(err, PrintFunctionName())
 			}
 			cpp.Metadata.Name = cpayload.Spec.ClusterProvider
 			clp.Metadata.Name = cpayload.Spec.ClusterName
@@ -451,18 +527,19 @@ func (h *logicalCloudHandler) createLogicalCloud(lcData logicalCloudsPayload,
 		cretVal.Spec.ClusterProvidersList = append(cretVal.Spec.ClusterProvidersList, cpp)
 		lcDataRetPayload.Spec.ClusterReferences = cretVal
 	}
+	return nil
+}
 
-	// Instantiate the cluster.
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
-		orch.Vars["projectName"] + "/logical-clouds/" + lcData.Name + "/instantiate"
-	var jsonLoad []byte
-	resp, err := orch.apiPost(jsonLoad, url, lcData.Name+"-instantiate")
-	if err != nil || resp != http.StatusAccepted {
-		log.Errorf("%s(): Failed to instantiate logical cloud %s", PrintFunctionName(), lcData.Name)
-		return resp.(int)
+func (h *logicalCloudHandler) deleteClusterReferences(projectName string, lcData *LogicalClouds) interface{} {
+	lcName := lcData.Metadata.Name
+	for _, n := range lcData.Spec.ClusterReferences.Metadata.ClusterRefenceNames {
+		retval, _ := h.deleteClusterReference(projectName, lcName, n)
+		if !deleted(retval) {
+			h.orchInstance.Logger.Errorf("%s(): Failed to delete lc reference %s for %s", PrintFunctionName(), n, lcName)
+			return retval
+		}
 	}
-	lcDataRetPayload.Spec.Status = "Instantiated"
-	return http.StatusCreated
+	return nil
 }
 
 func (h *logicalCloudHandler) createAdminLogicalCloud(lcData logicalCloudsPayload) (int, error) {
@@ -481,7 +558,7 @@ func (h *logicalCloudHandler) createAdminLogicalCloud(lcData logicalCloudsPayloa
 		},
 	}
 	jsonLoad, _ := json.Marshal(apiPayload)
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" + vars["projectName"] + "/logical-clouds"
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + vars["projectName"] + "/logical-clouds"
 	resp, err := orch.apiPost(jsonLoad, url, lcData.Name)
 	return resp.(int), err
 }
@@ -495,19 +572,19 @@ func (h *logicalCloudHandler) addPrivilegedPermisions(lcData logicalCloudsPayloa
 	}
 	retCode, err := h.createUserPermissions(projectName, lcData.Name, lcData.Spec.Namespace, lcData.Spec.Permissions, lcDataRetPayload)
 	if retCode != http.StatusCreated {
-		log.Errorf("Creating user permissions failed for logical cloud: %s", lcData.Name)
+		h.orchInstance.Logger.Errorf("Creating user permissions failed for logical cloud: %s", lcData.Name)
 		return retCode, err
 	}
 
 	retCode, err = h.createUserPermissions("kube", lcData.Name, "kube-system", lcData.Spec.Permissions, lcDataRetPayload)
 	if retCode != http.StatusCreated {
-		log.Errorf("Kube-system NS : Creating user permissions failed for logical cloud: %s", lcData.Name)
+		h.orchInstance.Logger.Errorf("Kube-system NS : Creating user permissions failed for logical cloud: %s", lcData.Name)
 		return retCode, err
 	}
 	// Create Cluster Wide User Permissions for Privileged Logical Cloud
 	retCode, err = h.createUserPermissions("cluster", lcData.Name, "", lcData.Spec.Permissions, lcDataRetPayload)
 	if retCode != http.StatusCreated {
-		log.Errorf("Cluster-Wide: Creating user permissions failed for logical cloud: %s", lcData.Name)
+		h.orchInstance.Logger.Errorf("Cluster-Wide: Creating user permissions failed for logical cloud: %s", lcData.Name)
 		return retCode, err
 	}
 	return http.StatusCreated, nil
@@ -538,15 +615,15 @@ func (h *logicalCloudHandler) createStandardLogicalCloud(lcData logicalCloudsPay
 		lcData.CloudType = "standard"
 	}
 	jsonLoad, _ := json.Marshal(apiPayload)
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" + vars["projectName"] + "/logical-clouds"
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + vars["projectName"] + "/logical-clouds"
 	resp, err := orch.apiPost(jsonLoad, url, lcData.Name)
 	if err != nil || resp != http.StatusCreated {
-		log.Errorf("%s(): Failed to crreate cloud type %s name %s", PrintFunctionName(), lcData.CloudType, lcData.Name)
+		h.orchInstance.Logger.Errorf("%s(): Failed to crreate cloud type %s name %s", PrintFunctionName(), lcData.CloudType, lcData.Name)
 		return resp.(int), err
 	}
-	log.Infof("%s(): Created %s logical-cloud %s retcode %d  ", PrintFunctionName(), lcData.CloudType, lcData.Name, resp.(int))
+	h.orchInstance.Logger.Infof("%s(): Created %s logical-cloud %s retcode %d  ", PrintFunctionName(), lcData.CloudType, lcData.Name, resp.(int))
 	if err := json.Unmarshal(h.orchInstance.response.payload[lcData.Name], lcDataRetPayload); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.orchInstance.Logger.Error(err, PrintFunctionName())
 		return resp.(int), err
 	}
 
@@ -582,7 +659,7 @@ func (h *logicalCloudHandler) createStandardLogicalCloud(lcData logicalCloudsPay
 		retCode, err := h.createUserPermissions(vars["projectName"], lcData.Name, lcData.Spec.Namespace,
 			lcData.Spec.Permissions, lcDataRetPayload)
 		if err != nil || retCode != http.StatusCreated {
-			log.Errorf("Creating user permissions failed for logical cloud: %s: status code %d", lcData.Name, retCode)
+			h.orchInstance.Logger.Errorf("Creating user permissions failed for logical cloud: %s: status code %d", lcData.Name, retCode)
 			return retCode, err
 		}
 
@@ -618,14 +695,14 @@ func (h *logicalCloudHandler) createStandardLogicalCloud(lcData logicalCloudsPay
 		// Create User Quotas for Standard Logical Cloud
 		retCode, err = h.createUserQuota(vars["projectName"], lcData.Name, lcData.Spec.Quotas, lcDataRetPayload)
 		if err != nil || retCode != http.StatusCreated {
-			log.Errorf("Updating user quota failed for logical cloud: %s", lcData.Name)
+			h.orchInstance.Logger.Errorf("Updating user quota failed for logical cloud: %s", lcData.Name)
 			return retCode, err
 		}
 
 	} else {
 		retCode, err := h.addPrivilegedPermisions(lcData, vars["projectName"], lcDataRetPayload)
 		if err != nil || retCode != http.StatusCreated {
-			log.Errorf("Updating user quota failed for logical cloud: %s", lcData.Name)
+			h.orchInstance.Logger.Errorf("Updating user quota failed for logical cloud: %s", lcData.Name)
 			return retCode, err
 		}
 	}
@@ -660,51 +737,19 @@ func (h *OrchestrationHandler) CreateAmcopSystemLogicalCloud(w http.ResponseWrit
 		},
 	}
 
-	h.client = http.Client{}
-
 	lcHandler := &logicalCloudHandler{}
 	lcHandler.orchInstance = h
 	// Creating the Logical Cloud for Monitoring Service
 	lcData.CloudType = "admin"
 	lcDataRetPayload := LogicalClouds{}
-	lcStatus := lcHandler.createLogicalCloud(lcData, &lcDataRetPayload)
+	lcStatus, _ := lcHandler.createLogicalCloud(lcData, &lcDataRetPayload)
 	if lcStatus != http.StatusCreated {
-		log.Errorf("%s(): Failed to create logical cloud %s", PrintFunctionName(), lcData.Name)
-		lcRollback(lcHandler, &lcDataRetPayload)
+		h.Logger.Errorf("%s(): Failed to create logical cloud %s", PrintFunctionName(), lcData.Name)
 		Result = false
 	}
 	return Result
 }
 
-func lcRollback(lcHandler *logicalCloudHandler, lcData *LogicalClouds) {
-	vars := lcHandler.orchInstance.Vars
-	projectName := vars["projectName"]
-	lcName := lcData.Metadata.Name
-	if lcData.Spec.Level != "0" {
-		for _, p := range lcData.Spec.UserPerminssionMetadata {
-			retval, _ := lcHandler.deleteUserPermissions(projectName, lcName, p.UserPermissionName)
-			if retval != http.StatusNoContent {
-				log.Debugf("%s(): Failed to delete user permissions for lc %s", PrintFunctionName(), lcName)
-			}
-		}
-		retval, _ := lcHandler.deleteUserQuota(projectName, lcName, lcData.Spec.UserQuotaMetadata.QuotaName)
-		if retval != http.StatusNoContent {
-			log.Debugf("%s(): Failed to delete quota info for lc %s", PrintFunctionName(), lcName)
-		}
-	}
-	for _, n := range lcData.Spec.ClusterReferences.Metadata.ClusterRefenceNames {
-		retval, _ := lcHandler.deleteClusterReference(projectName, lcName, n)
-		if retval != http.StatusNoContent {
-			log.Debugf("%s(): Failed to delete lc reference for %s", PrintFunctionName(), lcName)
-		}
-	}
-	retval, _ := lcHandler.deleteLogicalCloud(projectName, lcName)
-	if retval != http.StatusNoContent {
-		log.Debugf("%s(): Failed to delete lc %s", PrintFunctionName(), lcName)
-	}
-	log.Infof("%s(): Deleted Logical cloud %s", PrintFunctionName(), lcName)
-}
-
 // HandleLCCreateRequest CreateLogicalCloud, creates the logical clouds (level 0/level 1)
 func (h *OrchestrationHandler) HandleLCCreateRequest(w http.ResponseWriter, r *http.Request) {
 	var lcData logicalCloudsPayload
@@ -714,49 +759,42 @@ func (h *OrchestrationHandler) HandleLCCreateRequest(w http.ResponseWriter, r *h
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&lcData)
 	if err != nil {
-		log.Errorf("%s(): failed to parse json: %s", PrintFunctionName(), err)
+		h.Logger.Errorf("%s(): failed to parse json: %s", PrintFunctionName(), err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 	lcHandler := &logicalCloudHandler{}
 	lcHandler.orchInstance = h
-	lcStatus := lcHandler.createLogicalCloud(lcData, &lcDataRetPayload)
+	lcStatus, report := lcHandler.createLogicalCloud(lcData, &lcDataRetPayload)
 	if lcStatus != http.StatusCreated {
-		log.Errorf("%s(): Failed to create logical cloud %s", PrintFunctionName(), lcData.Name)
-		lcRollback(lcHandler, &lcDataRetPayload)
+		h.Logger.Errorf("%s(): Failed to create logical cloud %s", PrintFunctionName(), lcData.Name)
+		writeSagaFailure(w, report, lcStatus)
+		return
 	}
-	log.Infof("---------- %s", lcDataRetPayload)
+	h.Logger.Infof("---------- %s", lcDataRetPayload)
 	w.WriteHeader(lcStatus)
 	retVal, _ := json.Marshal(lcDataRetPayload)
 	if _, err := w.Write(retVal); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.Logger.Error(err, PrintFunctionName())
 	}
 }
 
-func (h *OrchestrationHandler) GetLogicalCloudsStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+// LogicalCloudStatus returns the deployment status of a logical cloud
+func (h *OrchestrationHandler) LogicalCloudStatus(req logicalCloudRequest) (LogicalCloudStatus, error) {
 	h.InitializeResponseMap()
 	lcHandler := &logicalCloudHandler{}
 	lcHandler.orchInstance = h
 
-	// Get the logical cloud list
-	lcStatus, err := lcHandler.getLogicalCloudsStatus(vars["projectName"], vars["logicalCloud"])
+	lcStatus, err := lcHandler.getLogicalCloudsStatus(req.Project, req.LogicalCloud)
 	if err != nil {
-		log.Infof("Failed to get logical cloud %s status, error %s", vars["logicalCloud"], err)
-		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Error(err, PrintFunctionName())
-		}
-		return
-	}
-	js, _ := json.Marshal(lcStatus)
-	if _, err := w.Write(js); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.Logger.Infof("Failed to get logical cloud %s status, error %s", req.LogicalCloud, err)
+		return lcStatus, &apiError{code: http.StatusInternalServerError, msg: err.Error()}
 	}
+	return lcStatus, nil
 }
 
 func getCloudProperties(lcHandler *logicalCloudHandler, lcList []LogicalClouds) error {
-	log.Infof("%s(): lcList: %+v", PrintFunctionName(), lcList)
+	lcHandler.orchInstance.Logger.Infof("%s(): lcList: %+v", PrintFunctionName(), lcList)
 	var wg sync.WaitGroup
 	ERR := &globalErr{}
 	for k := range lcList {
@@ -766,19 +804,19 @@ func getCloudProperties(lcHandler *logicalCloudHandler, lcList []LogicalClouds)
 			defer wg.Done()
 			nestedRef, err := lcHandler.getLogicalCloudReferences(lcList[k].Metadata.Name)
 			if err != nil {
-				log.Errorf("%s(): Failed to get lcReferences: for LC %s: %+v", PrintFunctionName(),
+				lcHandler.orchInstance.Logger.Errorf("%s(): Failed to get lcReferences: for LC %s: %+v", PrintFunctionName(),
 					lcList[k].Metadata.Name, nestedRef)
 				ERR.Error(err)
 				return
 			}
-			log.Infof("%s(): lcReferences: for LC %s: %+v", PrintFunctionName(), lcList[k].Metadata.Name, nestedRef)
+			lcHandler.orchInstance.Logger.Infof("%s(): lcReferences: for LC %s: %+v", PrintFunctionName(), lcList[k].Metadata.Name, nestedRef)
 
 			lcList[k].Spec.ClusterReferences = nestedRef
 			if lcList[k].Spec.Level != "0" {
 				// Fetch logical cloud permissions, if it is standard/privileged logical cloud
 				usrPm, err := lcHandler.GetUserPermissions(lcList[k].Metadata.Name)
 				if err != nil {
-					log.Errorf("%s(): Unable to fetch user permissions for L1 logical cloud: %s",
+					lcHandler.orchInstance.Logger.Errorf("%s(): Unable to fetch user permissions for L1 logical cloud: %s",
 						PrintFunctionName(), lcList[k].Metadata.Name)
 					ERR.Error(err)
 					return
@@ -792,7 +830,7 @@ func getCloudProperties(lcHandler *logicalCloudHandler, lcList []LogicalClouds)
 				// Fetch logical cloud quota info, if it is standard/privileged logical cloud
 				quota, err := lcHandler.GetClusterQuotas(lcList[k].Metadata.Name)
 				if err != nil {
-					log.Errorf("%s(): Unable to fetch Quota for L1 logical cloud: %s",
+					lcHandler.orchInstance.Logger.Errorf("%s(): Unable to fetch Quota for L1 logical cloud: %s",
 						PrintFunctionName(), lcList[k].Metadata.Name)
 					ERR.Error(err)
 					return
@@ -808,7 +846,7 @@ func getCloudProperties(lcHandler *logicalCloudHandler, lcList []LogicalClouds)
 			lcStatus, err := lcHandler.getLogicalCloudsStatus(lcHandler.orchInstance.Vars["projectName"],
 				lcList[k].Metadata.Name)
 			if err != nil {
-				log.Infof("Failed to get logical cloud %s  error: %s", lcList[k].Metadata.Name,
+				lcHandler.orchInstance.Logger.Infof("Failed to get logical cloud %s  error: %s", lcList[k].Metadata.Name,
 					err)
 				ERR.Error(err)
 				return
@@ -821,40 +859,27 @@ func getCloudProperties(lcHandler *logicalCloudHandler, lcList []LogicalClouds)
 	return ERR.Errors()
 }
 
-// GetLogicalClouds Get LC information
-func (h *OrchestrationHandler) GetLogicalClouds(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	h.Vars = vars
+// LogicalClouds returns the logical clouds of the project with their
+// cluster references, permissions, quotas and status
+func (h *OrchestrationHandler) LogicalClouds(req projectRequest) ([]LogicalClouds, error) {
+	h.Vars = req.vars()
 	h.InitializeResponseMap()
 	lcHandler := &logicalCloudHandler{}
 	lcHandler.orchInstance = h
 	// Get the logical cloud list
 	lcList, err := lcHandler.getLogicalClouds()
 	if err != nil {
-		log.Infof("Failed to get logical clouds : %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		h.Logger.Infof("Failed to get logical clouds : %s", err)
+		return nil, err
 	}
 
 	err = getCloudProperties(lcHandler, lcList)
 	if err != nil {
-		log.Infof("%s(): Failed to get logical clouds properties : %s", PrintFunctionName(), err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	log.Debugf("%s(): LC list after filling the permissions and quotas : %+v", PrintFunctionName(), lcList)
-
-	retval, err := json.Marshal(lcList)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	log.Debugf("%s(): retval of GetLogicalCloud date: %s", PrintFunctionName(), retval)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(retval); err != nil {
-		log.Error(err, PrintFunctionName())
+		h.Logger.Infof("%s(): Failed to get logical clouds properties : %s", PrintFunctionName(), err)
+		return nil, err
 	}
+	h.Logger.Debugf("%s(): LC list after filling the permissions and quotas : %+v", PrintFunctionName(), lcList)
+	return lcList, nil
 }
 
 // GetUserPermissions Fetch User Permissions for L1 Logical Cloud
@@ -862,15 +887,15 @@ func (h *logicalCloudHandler) GetUserPermissions(lcName string) ([]UserPermissio
 	userPermList := []UserPermission{}
 	var url string
 	orch := h.orchInstance
-	url = "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url = orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		orch.Vars["projectName"] + "/logical-clouds/" + lcName + "/user-permissions"
 	reply, err := orch.apiGet(url, lcName+"-permissions")
 	if err != nil {
-		log.Errorf("%s(): Failed to get userpermission for LC %s", PrintFunctionName(), lcName)
+		h.orchInstance.Logger.Errorf("%s(): Failed to get userpermission for LC %s", PrintFunctionName(), lcName)
 		return userPermList, err
 	}
 	err = json.Unmarshal(reply.Data, &userPermList)
-	log.Debugf("%s(): lc user permission: %+v", PrintFunctionName(), userPermList)
+	h.orchInstance.Logger.Debugf("%s(): lc user permission: %+v", PrintFunctionName(), userPermList)
 	return userPermList, err
 }
 
@@ -878,15 +903,15 @@ func (h *logicalCloudHandler) GetUserPermissions(lcName string) ([]UserPermissio
 func (h *logicalCloudHandler) GetClusterQuotas(lcName string) ([]Quota, error) {
 	quotas := []Quota{}
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		orch.Vars["projectName"] + "/logical-clouds/" + lcName + "/cluster-quotas"
 	reply, err := orch.apiGet(url, lcName+"-quotas")
 	if err != nil {
-		log.Errorf("%s(): Failed to get quota info for LC %s", PrintFunctionName(), lcName)
+		h.orchInstance.Logger.Errorf("%s(): Failed to get quota info for LC %s", PrintFunctionName(), lcName)
 		return quotas, err
 	}
 	err = json.Unmarshal(reply.Data, &quotas)
-	log.Debugf("%s(): LC quotas: %+v", PrintFunctionName(), quotas)
+	h.orchInstance.Logger.Debugf("%s(): LC quotas: %+v", PrintFunctionName(), quotas)
 	return quotas, err
 }
 
@@ -904,50 +929,58 @@ func (h *OrchestrationHandler) DeleteLogicalCloud(w http.ResponseWriter, r *http
 	// Fetch logical cloud information
 	lc, err := lcHandler.getLogicalCloud(lcName)
 	if err != nil {
-		log.Infof("Failed to get logical clouds : %s", err)
+		h.Logger.Infof("Failed to get logical clouds : %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	lcList = append(lcList, lc)
 	err = getCloudProperties(lcHandler, lcList)
 	if err != nil {
-		log.Errorf("%s(): Failed to get logical clouds properties : %s", PrintFunctionName(), err)
+		h.Logger.Errorf("%s(): Failed to get logical clouds properties : %s", PrintFunctionName(), err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	log.Debugf("%s(): LC list after filling the permissions and quotas : %+v", PrintFunctionName(), lcList)
+	h.Logger.Debugf("%s(): LC list after filling the permissions and quotas : %+v", PrintFunctionName(), lcList)
 
 	if lcList[0].Spec.Level != "0" {
 		for _, p := range lcList[0].Spec.UserPerminssionMetadata {
+			done := h.opStep("deleteUserPermission/" + p.UserPermissionName)
 			retval, err := lcHandler.deleteUserPermissions(projectName, lcName, p.UserPermissionName)
+			done(stepError(retval, http.StatusNoContent, err))
 			if retval != http.StatusNoContent {
-				log.Errorf("%s(): Failed to delete user permissions for lc %s", PrintFunctionName(), lcName)
+				h.Logger.Errorf("%s(): Failed to delete user permissions for lc %s", PrintFunctionName(), lcName)
 				w.WriteHeader(retval)
 				if err != nil {
 					if _, err := w.Write([]byte(err.Error())); err != nil {
-						log.Error(err, PrintFunctionName())
+						h.Logger.Error(err, PrintFunctionName())
 					}
 				}
 				return
 			}
 		}
 		if len(lcList[0].Spec.UserQuota) != 0 {
+			done := h.opStep("deleteUserQuota")
 			retval, err := lcHandler.deleteUserQuota(projectName, lcName, lcList[0].Spec.UserQuotaMetadata.QuotaName)
+			done(stepError(retval, http.StatusNoContent, err))
 			if retval != http.StatusNoContent {
-				log.Errorf("%s(): Failed to delete quota info for lc %s", PrintFunctionName(), lcName)
+				h.Logger.Errorf("%s(): Failed to delete quota info for lc %s", PrintFunctionName(), lcName)
 				w.WriteHeader(retval)
 				if err != nil {
 					if _, err := w.Write([]byte(err.Error())); err != nil {
-						log.Error(err, PrintFunctionName())
+						h.Logger.Error(err, PrintFunctionName())
 					}
 				}
 				return
 			}
 		}
 	}
+	done := h.opStep("terminate")
 	retval, _ := lcHandler.terminateLogicalCloud(projectName, lcName)
-	if retval != http.StatusAccepted {
-		log.Errorf("%s(): Failed to tetminate lc %s", PrintFunctionName(), lcName)
+	if retval == http.StatusAccepted {
+		done(nil)
+	} else {
+		h.Logger.Errorf("%s(): Failed to tetminate lc %s", PrintFunctionName(), lcName)
+		done(skipStep(fmt.Sprintf("terminate replied status %d, deleting the cluster references anyway", retval)))
 		//w.WriteHeader(retval)
 		//if err != nil {
 		//	w.Write([]byte(err.Error()))
@@ -958,36 +991,41 @@ func (h *OrchestrationHandler) DeleteLogicalCloud(w http.ResponseWriter, r *http
 	for _, n := range lcList[0].Spec.ClusterReferences.Metadata.ClusterRefenceNames {
 		count := 0
 		retval := http.StatusConflict
+		done := h.opStep("deleteClusterReference/" + n)
 		for retval != http.StatusNoContent {
 			retval, err = lcHandler.deleteClusterReference(projectName, lcName, n)
-			log.Infof("Count %d", count)
+			h.Logger.Infof("Count %d", count)
 			count += 1
 			time.Sleep(time.Second)
 			if count > 20 {
-				log.Errorf("%s(): Failed to delete lc reference for %s", PrintFunctionName(), lcName)
+				h.Logger.Errorf("%s(): Failed to delete lc reference for %s", PrintFunctionName(), lcName)
+				done(stepError(retval, http.StatusNoContent, err))
 				w.WriteHeader(retval)
 				if err != nil {
 					if _, err := w.Write([]byte(err.Error())); err != nil {
-						log.Error(err, PrintFunctionName())
+						h.Logger.Error(err, PrintFunctionName())
 					}
 				}
 				return
 			}
 		}
+		done(nil)
 	}
+	done = h.opStep("deleteLogicalCloud")
 	retval, err = lcHandler.deleteLogicalCloud(projectName, lcName)
+	done(stepError(retval, http.StatusNoContent, err))
 	if retval != http.StatusNoContent {
-		log.Errorf("%s(): Failed to delete lc %s", PrintFunctionName(), lcName)
+		h.Logger.Errorf("%s(): Failed to delete lc %s", PrintFunctionName(), lcName)
 		w.WriteHeader(retval)
 		if err != nil {
 			if _, err := w.Write([]byte(err.Error())); err != nil {
-				log.Error(err, PrintFunctionName())
+				h.Logger.Error(err, PrintFunctionName())
 			}
 		}
 		return
 	}
 
-	log.Infof("%s(): Deleted Logical cloud %s", PrintFunctionName(), lcName)
+	h.Logger.Infof("%s(): Deleted Logical cloud %s", PrintFunctionName(), lcName)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -1002,7 +1040,7 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&lcData)
 	if err != nil {
-		log.Errorf("failed to parse update json: %s", err)
+		h.Logger.Errorf("failed to parse update json: %s", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -1011,11 +1049,11 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 		// Delete user permissions for standard logical cloud
 		retCode, err := lcHandler.deleteUserPermissions(h.Vars["projectName"], h.Vars["logicalCloud"], "")
 		if retCode != http.StatusNoContent {
-			log.Errorf("Deleting user permissions failed for logical cloud: %s", h.Vars["logicalCloud"])
+			h.Logger.Errorf("Deleting user permissions failed for logical cloud: %s", h.Vars["logicalCloud"])
 			w.WriteHeader(retCode)
 			if err != nil {
 				if _, err := w.Write([]byte(err.Error())); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.Logger.Error(err, PrintFunctionName())
 				}
 			}
 			return
@@ -1025,11 +1063,11 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 		retCode, err = lcHandler.createUserPermissions(h.Vars["projectName"], h.Vars["logicalCloud"], lcData.Namespace,
 			lcData.Permissions, &lcDataRetPayload)
 		if retCode != http.StatusCreated {
-			log.Errorf("Updating user permissions failed for logical cloud: %s", h.Vars["logicalCloud"])
+			h.Logger.Errorf("Updating user permissions failed for logical cloud: %s", h.Vars["logicalCloud"])
 			w.WriteHeader(retCode)
 			if err != nil {
 				if _, err := w.Write([]byte(err.Error())); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.Logger.Error(err, PrintFunctionName())
 				}
 			}
 			return
@@ -1038,11 +1076,11 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 		// Delete user quotas for standard logical cloud
 		retCode, err = lcHandler.deleteUserQuota(h.Vars["projectName"], h.Vars["logicalCloud"], "name")
 		if retCode != http.StatusNoContent {
-			log.Errorf("Deleting user quota failed for logical cloud: %s", h.Vars["logicalCloud"])
+			h.Logger.Errorf("Deleting user quota failed for logical cloud: %s", h.Vars["logicalCloud"])
 			w.WriteHeader(retCode)
 			if err != nil {
 				if _, err := w.Write([]byte(err.Error())); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.Logger.Error(err, PrintFunctionName())
 				}
 			}
 			return
@@ -1051,11 +1089,11 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 		// Create user quotas for standard logical cloud based on updated user quotas
 		retCode, err = lcHandler.createUserQuota(h.Vars["projectName"], h.Vars["logicalCloud"], lcData.Quotas, &lcDataRetPayload)
 		if retCode != http.StatusCreated {
-			log.Errorf("Updating user quota failed for logical cloud: %s", h.Vars["logicalCloud"])
+			h.Logger.Errorf("Updating user quota failed for logical cloud: %s", h.Vars["logicalCloud"])
 			w.WriteHeader(retCode)
 			if err != nil {
 				if _, err := w.Write([]byte(err.Error())); err != nil {
-					log.Error(err, PrintFunctionName())
+					h.Logger.Error(err, PrintFunctionName())
 				}
 			}
 			return
@@ -1083,7 +1121,7 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 					w.WriteHeader(retCode)
 					if err != nil {
 						if _, err := w.Write([]byte(err.Error())); err != nil {
-							log.Error(err, PrintFunctionName())
+							h.Logger.Error(err, PrintFunctionName())
 						}
 					}
 					return
@@ -1094,11 +1132,11 @@ func (h *OrchestrationHandler) UpdateLogicalCloud(w http.ResponseWriter, r *http
 
 	// Invoke logical cloud update to apply updated configuration
 	var jsonLoad []byte
-	url := "http://" + h.MiddleendConf.Dcm + "/v2/projects/" +
+	url := h.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		h.Vars["projectName"] + "/logical-clouds/" + h.Vars["logicalCloud"] + "/update"
 	resp, err := h.apiPost(jsonLoad, url, h.Vars["logicalCloud"]+"_update")
 	if err != nil {
-		log.Errorf("Encountered error while updating logical cloud: %s", h.Vars["logicalCloud"])
+		h.Logger.Errorf("Encountered error while updating logical cloud: %s", h.Vars["logicalCloud"])
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1120,18 +1158,18 @@ func (h *logicalCloudHandler) createClusterReference(projectName string, lcName
 	clusterReferencePayload.Spec.ClusterName = clusterName
 	clusterReferencePayload.Spec.LoadbalancerIP = "0.0.0.0"
 	jsonLoad, _ := json.Marshal(clusterReferencePayload)
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projectName + "/logical-clouds/" + lcName + "/cluster-references"
 	resp, err := orch.apiPost(jsonLoad, url, lcName+"-"+clusterName)
 	if err != nil {
-		log.Errorf("Encountered error while creating cluster reference: %s", err)
+		h.orchInstance.Logger.Errorf("Encountered error while creating cluster reference: %s", err)
 	}
 	return resp.(int)
 }
 
 func (h *logicalCloudHandler) deleteClusterReference(projName string, lcName string, clusterReference string) (int, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projName + "/logical-clouds/" + lcName + "/cluster-references/" + clusterReference
 	resp, err := orch.apiDel(url, lcName+"_lcrefdel")
 	return resp.(int), err
@@ -1157,7 +1195,7 @@ func (h *logicalCloudHandler) createUserPermissions(projName string, lcName stri
 		},
 	}
 	jsonLoad, _ := json.Marshal(userPerm)
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" + orch.Vars["projectName"] + "/logical-clouds/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + orch.Vars["projectName"] + "/logical-clouds/" +
 		lcName + "/user-permissions"
 	resp, err := orch.apiPost(jsonLoad, url, lcName+"_usrperm")
 	_ = json.Unmarshal(h.orchInstance.response.payload[lcName+"_usrperm"], &usrPermRetVal)
@@ -1173,7 +1211,7 @@ func (h *logicalCloudHandler) deleteUserPermissions(projName string, lcName stri
 	if permName == "" {
 		permName = projName
 	}
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projName + "/logical-clouds/" + lcName + "/user-permissions/" + permName
 	resp, err := orch.apiDel(url, lcName+"_permdel")
 	return resp.(int), err
@@ -1217,7 +1255,7 @@ func (h *logicalCloudHandler) createUserQuota(projName string, lcName string, qu
 	}
 
 	jsonLoad, _ := json.Marshal(quota)
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projName + "/logical-clouds/" + lcName + "/cluster-quotas"
 	resp, err := orch.apiPost(jsonLoad, url, lcName+"_quota")
 	_ = json.Unmarshal(h.orchInstance.response.payload[lcName+"_quota"], &quotaRetVal)
@@ -1228,7 +1266,7 @@ func (h *logicalCloudHandler) createUserQuota(projName string, lcName string, qu
 
 func (h *logicalCloudHandler) deleteUserQuota(projName string, lcName string, quotaName string) (int, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" +
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" +
 		projName + "/logical-clouds/" + lcName + "/cluster-quotas/" + quotaName
 	resp, err := orch.apiDel(url, lcName+"_quotadel")
 	return resp.(int), err
@@ -1236,15 +1274,15 @@ func (h *logicalCloudHandler) deleteUserQuota(projName string, lcName string, qu
 
 func (h *logicalCloudHandler) deleteLogicalCloud(projName string, lcName string) (int, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" + projName + "/logical-clouds/" + lcName
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + projName + "/logical-clouds/" + lcName
 	resp, err := orch.apiDel(url, lcName+"_lcdel")
-	log.Infof("Delete logical cloud %s : %d", lcName, resp)
+	h.orchInstance.Logger.Infof("Delete logical cloud %s : %d", lcName, resp)
 	return resp.(int), err
 }
 
 func (h *logicalCloudHandler) terminateLogicalCloud(projName string, lcName string) (int, error) {
 	orch := h.orchInstance
-	url := "http://" + orch.MiddleendConf.Dcm + "/v2/projects/" + projName + "/logical-clouds/" + lcName + "/terminate"
+	url := orch.MiddleendConf.serviceURL("dcm") + "/v2/projects/" + projName + "/logical-clouds/" + lcName + "/terminate"
 	var jsonLoad []byte
 	resp, err := orch.apiPost(jsonLoad, url, lcName+"-terminate")
 	return resp.(int), err
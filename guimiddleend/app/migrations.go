@@ -0,0 +1,66 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	"example.com/middleend/migrate"
+	pkgerrors "github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Migrations returns the schema migrations of the middleend store, in
+// order. A change to the types stored needs a migration appended here,
+// the versions of the released ones must not change.
+func (c MiddleendConfig) Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{
+			// InboundServerIntentSpec stored the port as a string, the
+			// intents are decoded into InbondServerIntentSpec now
+			Version:     1,
+			Description: "store the port of the inbound server intents as a number",
+			Collection:  "resources",
+			Tag:         "serverintentmetadata",
+			Apply: func(doc *primitive.D) (bool, error) {
+				return portToNumber(*doc, "serverintentmetadata.spec.port")
+			},
+		},
+	}
+}
+
+// portToNumber converts the string port at path, an empty one is 0 like
+// an intent without a port
+func portToNumber(doc primitive.D, path string) (bool, error) {
+	v, ok := migrate.Lookup(doc, path)
+	if !ok {
+		return false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false, nil
+	}
+	port := 0
+	if s = strings.TrimSpace(s); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return false, pkgerrors.Errorf("%s %q is not a port number", path, s)
+		}
+		port = n
+	}
+	return migrate.Set(doc, path, port), nil
+}
@@ -0,0 +1,100 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"testing"
+
+	"example.com/middleend/db"
+	"example.com/middleend/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type serverIntentKey struct {
+	Intent string `json:"intent"`
+}
+
+// intentPorts returns the port of each inbound server intent, by intent
+func intentPorts(t *testing.T, store db.Store) map[string]interface{} {
+	t.Helper()
+	docs, err := store.Documents(context.Background(), "resources", "serverintentmetadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := map[string]interface{}{}
+	for _, raw := range docs {
+		var doc primitive.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			t.Fatal(err)
+		}
+		intent, _ := migrate.Lookup(doc, "intent")
+		ports[intent.(string)], _ = migrate.Lookup(doc, "serverintentmetadata.spec.port")
+	}
+	return ports
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	for intent, port := range map[string]interface{}{"string": "8080", "empty": " ", "number": int32(9090)} {
+		meta := bson.D{{Key: "metadata", Value: bson.D{{Key: "name", Value: intent}}},
+			{Key: "spec", Value: bson.D{{Key: "port", Value: port}}}}
+		if err := store.Insert(ctx, "resources", serverIntentKey{intent}, nil, "serverintentmetadata", meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	migrations := MiddleendConfig{}.Migrations()
+
+	report, err := migrate.Run(ctx, store, migrations, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 1 || len(report.Results[0].Changed) != 2 {
+		t.Fatalf("the dry run reported %+v", report)
+	}
+	if port := intentPorts(t, store)["string"]; port != "8080" {
+		t.Fatalf("the dry run changed the port to %v", port)
+	}
+
+	if _, err := migrate.Run(ctx, store, migrations, false); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int32{"string": 8080, "empty": 0, "number": 9090}
+	ports := intentPorts(t, store)
+	for intent, port := range want {
+		if ports[intent] != port {
+			t.Errorf("intent %s has port %#v, want %d", intent, ports[intent], port)
+		}
+	}
+
+	// a migrated document is left alone, so the migration can run again
+	docs, err := store.Documents(ctx, "resources", "serverintentmetadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range docs {
+		var doc primitive.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			t.Fatal(err)
+		}
+		changed, err := migrations[0].Apply(&doc)
+		if err != nil || changed {
+			t.Fatalf("the migration changed a migrated document: %t, %v", changed, err)
+		}
+	}
+}
@@ -18,8 +18,6 @@ package app
 import (
 	"encoding/json"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 // logicalCloudHandler implements the orchworkflow interface
@@ -112,20 +110,18 @@ type ConsolidatedStatus struct {
 	} `json:"spec"`
 }
 
-func (h *ncmHandler) getNetworks() (cs ConsolidatedStatus, err error) {
+func (h *ncmHandler) getNetworks(clusterProvider, clusterName string) (cs ConsolidatedStatus, err error) {
 	orch := h.orchInstance
 	// Call the networks status
 	// http://192.168.122.240:30431/v2/cluster-providers/cluster-provider-a/clusters/kud2/status
 	var nwStatus networkStatus
-	clusterProvider := orch.Vars["clusterprovider-name"]
-	clusterName := orch.Vars["cluster-name"]
-	url := "http://" + orch.MiddleendConf.Ncm + "/v2/cluster-providers/" +
+	url := orch.MiddleendConf.serviceURL("ncm") + "/v2/cluster-providers/" +
 		clusterProvider + "/clusters/" + clusterName + "/status"
 
 	reply, err := orch.apiGet(url, clusterProvider)
-	log.Infof("Get cluster status : %d", reply.StatusCode)
+	h.orchInstance.Logger.Infof("Get cluster status : %d", reply.StatusCode)
 	if err != nil {
-		log.Errorf("Failed to get cluster status for %s: ", clusterName)
+		h.orchInstance.Logger.Errorf("Failed to get cluster status for %s: ", clusterName)
 		return cs, err
 	}
 	if err := json.Unmarshal(reply.Data, &nwStatus); err != nil {
@@ -134,13 +130,13 @@ func (h *ncmHandler) getNetworks() (cs ConsolidatedStatus, err error) {
 
 	// Get all networks
 	var nw []network
-	url = "http://" + orch.MiddleendConf.Ncm + "/v2/cluster-providers/" +
+	url = orch.MiddleendConf.serviceURL("ncm") + "/v2/cluster-providers/" +
 		clusterProvider + "/clusters/" + clusterName + "/networks"
 
 	reply, err = orch.apiGet(url, clusterProvider)
-	log.Infof("Get cluster networks : %d", reply.StatusCode)
+	h.orchInstance.Logger.Infof("Get cluster networks : %d", reply.StatusCode)
 	if err != nil {
-		log.Errorf("Failed to get cluster networks %s: error %s", clusterName, err)
+		h.orchInstance.Logger.Errorf("Failed to get cluster networks %s: error %s", clusterName, err)
 		return cs, err
 	}
 	if err := json.Unmarshal(reply.Data, &nw); err != nil {
@@ -161,13 +157,13 @@ func (h *ncmHandler) getNetworks() (cs ConsolidatedStatus, err error) {
 
 	// Get all provider networks
 	var pnw []providerNetwork
-	url = "http://" + orch.MiddleendConf.Ncm + "/v2/cluster-providers/" +
+	url = orch.MiddleendConf.serviceURL("ncm") + "/v2/cluster-providers/" +
 		clusterProvider + "/clusters/" + clusterName + "/provider-networks"
 
 	reply, err = orch.apiGet(url, clusterProvider)
-	log.Infof("Get cluster provider networks : %d", reply.StatusCode)
+	h.orchInstance.Logger.Infof("Get cluster provider networks : %d", reply.StatusCode)
 	if err != nil {
-		log.Errorf("Failed to get cluster provider networks %s: ", clusterName)
+		h.orchInstance.Logger.Errorf("Failed to get cluster provider networks %s: ", clusterName)
 		return cs, err
 	}
 
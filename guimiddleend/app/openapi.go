@@ -0,0 +1,537 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/middleend/localstore"
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/gorilla/mux"
+)
+
+// jsonFieldExt links a multipart field holding json to the definition of
+// its value, which swagger 2.0 cannot express
+const jsonFieldExt = "x-json-schema"
+
+// apiDoc documents an API registered by RegisterHandlers. The parameters
+// of the path come from its route, the schemas from the types of the
+// request and of the reply.
+type apiDoc struct {
+	id      string
+	tag     string
+	summary string
+	// query lists the optional query parameters
+	query []string
+	// body is the json body of the request
+	body interface{}
+	// form maps the multipart fields holding json to their value, files
+	// are uploaded along with them
+	form map[string]interface{}
+	// status and response describe the reply on success, 200 by default
+	status   int
+	response interface{}
+	produces string
+	// dryRun is the reply of the dryRun=true query, which plans the API
+	// without running it
+	dryRun interface{}
+}
+
+// operationAccepted documents the APIs run as asynchronous operations
+func operationAccepted(doc apiDoc) apiDoc {
+	doc.status = http.StatusAccepted
+	doc.response = Operation{}
+	return doc
+}
+
+// apiDocs documents every API, by method and route template
+var apiDocs = map[string]apiDoc{
+	// Documentation, health and metrics
+	"GET /redocs":       {id: "getRedoc", tag: "docs", summary: "Browse the API with ReDoc", produces: "text/html"},
+	"GET /rapidocs":     {id: "getRapiDoc", tag: "docs", summary: "Browse the API with RapiDoc", produces: "text/html"},
+	"GET /swagger.yaml": {id: "getSwagger", tag: "docs", summary: "Get this document", produces: "application/yaml"},
+	"GET /healthcheck":  {id: "getHealth", tag: "health", summary: "Check that the middleend answers", response: HealthcheckResponse{}},
+	"GET /healthz":      {id: "getLiveness", tag: "health", summary: "Liveness probe", response: HealthcheckResponse{}},
+	"GET /readyz":       {id: "getReadiness", tag: "health", summary: "Readiness probe, checking mongo and the EMCO services", response: ReadinessResponse{}},
+	"GET /metrics":      {id: "getMetrics", tag: "health", summary: "Prometheus metrics", produces: "text/plain"},
+
+	"GET /operations/{operationId}": {id: "getOperation", tag: "operations", query: []string{"watch"},
+		summary: "Get an asynchronous operation, watch=true waits for its next change", response: Operation{}},
+
+	// Composite applications
+	"POST " + cAppUriPattern + "/{version}/checkout": {id: "checkoutCompositeApp", tag: "compositeApps",
+		summary: "Check out the next version of a composite application as a draft", status: http.StatusCreated, response: CompositeAppsInProject{}},
+	"GET " + cAppUriPattern + "/versions": {id: "getCompositeAppVersions", tag: "compositeApps", query: []string{"state"},
+		summary: "List the versions of a composite application", response: []string{}},
+	"GET " + cAppUriPattern + "/versions/": {id: "getCompositeAppVersionsByState", tag: "compositeApps", query: []string{"state"},
+		summary: "List the versions of a composite application in a state", response: []string{}},
+	"POST " + cAppUriPattern + "/{version}/app": {id: "addApp", tag: "compositeApps",
+		summary: "Add an application to a draft composite application",
+		form:    map[string]interface{}{"appsPayload": appsData{}}, status: http.StatusCreated, response: appsData{}},
+	"DELETE " + cAppUriPattern + "/{version}/apps/{appName}": {id: "removeApp", tag: "compositeApps",
+		summary: "Remove an application from a draft composite application", status: http.StatusNoContent},
+	"POST " + cAppUriPattern + "/{version}/update": {id: "submitCompositeApp", tag: "compositeApps",
+		summary: "Create the checked out version of a composite application in EMCO", status: http.StatusCreated},
+	"POST /projects/{projectName}/composite-apps": {id: "createCompositeApp", tag: "compositeApps",
+		summary: "Create a composite application with its applications and profiles",
+		form:    map[string]interface{}{"servicePayload": deployServiceData{}}, status: http.StatusCreated},
+	"GET " + cAppUriPattern + "/{version}": {id: "getCompositeApp", tag: "compositeApps", query: []string{"filter", "status"},
+		summary: "Get a composite application", response: CompositeAppsInProjectShrunk{}},
+	"GET /projects/{projectName}/composite-apps": {id: "getCompositeApps", tag: "compositeApps", query: []string{"filter", "status"},
+		summary: "List the composite applications of a project", response: []CompositeAppsInProjectShrunk{}},
+	"DELETE " + cAppUriPattern + "/{version}": {id: "deleteCompositeApp", tag: "compositeApps",
+		summary: "Delete a composite application without deployment intent groups", status: http.StatusNoContent},
+
+	// Deployment intent groups
+	"POST " + cAppUriPattern + "/{version}/deployment-intent-groups": {id: "createDig", tag: "deploymentIntentGroups",
+		summary: "Create a deployment intent group with its intents",
+		form:    map[string]interface{}{"metadata": deployDigData{}}, status: http.StatusCreated},
+	"GET /projects/{projectName}/deployment-intent-groups": {id: "getProjectDigs", tag: "deploymentIntentGroups",
+		summary: "List the deployment intent groups of a project", response: []DigsInProject{}},
+	"GET " + cAppUriPattern + "/{version}/deployment-intent-groups": {id: "getCompositeAppDigs", tag: "deploymentIntentGroups",
+		summary: "List the deployment intent groups of a composite application", response: []DigsInProject{}},
+	"GET " + digUriPattern: {id: "getDig", tag: "deploymentIntentGroups",
+		summary: "Get a deployment intent group", response: []DigsInProject{}},
+	"DELETE " + digUriPattern: {id: "deleteDig", tag: "deploymentIntentGroups",
+		summary: "Delete a deployment intent group", status: http.StatusNoContent},
+	"DELETE " + digUriPattern + "/": {id: "deleteDigVersions", tag: "deploymentIntentGroups", query: []string{"operation"},
+		summary: "Delete a deployment intent group, operation=deleteAll deletes all its versions", status: http.StatusNoContent},
+	"GET " + digUriPattern + "/status": {id: "getDigStatus", tag: "deploymentIntentGroups",
+		summary: "Get the deployment status of a deployment intent group", response: digStatus{}},
+	"GET " + digUriPattern + "/checkout": {id: "getDigCheckout", tag: "deploymentIntentGroups",
+		summary: "Get the checked out version of a deployment intent group", response: guiDigView{}},
+	"GET " + digUriPattern + "/checkout/diff": {id: "getDigCheckoutDiff", tag: "deploymentIntentGroups",
+		summary: "Compare the checked out version of a deployment intent group with the deployed one", response: CheckoutDiff{}},
+	"POST " + digUriPattern + "/checkout": {id: "checkoutDig", tag: "deploymentIntentGroups",
+		summary: "Check out a deployment intent group", query: []string{"operation", "targetVersion"}},
+	"GET " + digUriPattern + "/checkout/lease": {id: "getDigCheckoutLease", tag: "deploymentIntentGroups",
+		summary: "Get the owner and the expiry of the checkout of a deployment intent group", response: CheckoutLease{}},
+	"DELETE " + digUriPattern + "/checkout/lease": {id: "releaseDigCheckoutLease", tag: "deploymentIntentGroups",
+		summary: "Release the checkout of a deployment intent group, keeping its changes", status: http.StatusNoContent},
+	"POST " + digUriPattern + "/checkout/lease/steal": {id: "stealDigCheckoutLease", tag: "deploymentIntentGroups",
+		summary: "Take over the checkout of a deployment intent group from its owner", response: CheckoutLease{}},
+	"POST " + digUriPattern + "/checkout/": {id: "checkoutDigForOperation", tag: "deploymentIntentGroups",
+		summary: "Check out a deployment intent group for an update or a migration", query: []string{"operation", "targetVersion"}},
+	"POST " + digUriPattern + "/checkout/submit": operationAccepted(apiDoc{id: "submitDig", tag: "deploymentIntentGroups",
+		summary: "Apply the checked out version of a deployment intent group, or plan it with dryRun=true",
+		dryRun:  SubmitPlan{}}),
+	"GET " + digUriPattern + "/checkout/plan": {id: "getDigSubmitPlan", tag: "deploymentIntentGroups",
+		summary: "Plan the submit of the checked out version of a deployment intent group without running it", response: SubmitPlan{}},
+	"PUT " + digUriPattern + "/checkout": {id: "updateDigCheckout", tag: "deploymentIntentGroups",
+		summary: "Update the intents of an application of a checked out deployment intent group, refused with If-Match when the checkout changed since that ETag",
+		form:    map[string]interface{}{"metadata": appsData{}}},
+	"PUT " + digUriPattern + "/checkout/": {id: "updateDigCheckoutForOperation", tag: "deploymentIntentGroups", query: []string{"operation"},
+		summary: "Update the intents of an application of a checked out deployment intent group, refused with If-Match when the checkout changed since that ETag",
+		form:    map[string]interface{}{"metadata": appsData{}}},
+	"POST " + digUriPattern + "/scaleout": operationAccepted(apiDoc{id: "scaleOutDig", tag: "deploymentIntentGroups",
+		summary: "Check out, update and apply a deployment intent group in one operation, in waves with a rollout",
+		form:    map[string]interface{}{"metadata": appsData{}, "rollout": RolloutRequest{}}}),
+	"GET " + digUriPattern + "/revisions": {id: "getDigRevisions", tag: "deploymentIntentGroups",
+		summary: "List the revisions of a deployment intent group with the snapshots of their intents", response: DigRevisions{}},
+	"GET " + digUriPattern + "/revisions/{revision}/diff": {id: "getDigRevisionDiff", tag: "deploymentIntentGroups",
+		summary: "Preview the changes of the intents a rollback to the revision makes", response: DigRevisionDiff{}},
+	"POST " + digUriPattern + "/rollback": operationAccepted(apiDoc{id: "rollbackDig", tag: "deploymentIntentGroups",
+		summary: "Roll a deployment intent group back to a previous revision and restore its intents", body: DigRollbackRequest{}}),
+	"POST " + digUriPattern + "/checkout/rollout": operationAccepted(apiDoc{id: "rolloutDig", tag: "deploymentIntentGroups",
+		summary: "Apply the checked out update of a deployment intent group wave by wave, watching the clusters of each wave", body: RolloutRequest{}}),
+	"GET " + digUriPattern + "/rollout": {id: "getDigRollout", tag: "deploymentIntentGroups",
+		summary: "Get the last rollout of a deployment intent group with the progress of its waves", response: DigRollout{}},
+	"POST " + digUriPattern + "/rollout/resume": operationAccepted(apiDoc{id: "resumeDigRollout", tag: "deploymentIntentGroups",
+		summary: "Resume a paused rollout of a deployment intent group from its failed wave"}),
+
+	// Resources of the generic k8s intents
+	"GET " + digUriPattern + "/resources": {id: "getResources", tag: "resources",
+		summary: "List the resources of a deployment intent group with their customizations", response: map[string][]localstore.Customization{}},
+	"DELETE " + digUriPattern + "/resources/{resourceName}": {id: "deleteResource", tag: "resources",
+		summary: "Delete a resource with its customizations", status: http.StatusNoContent},
+	"DELETE " + digUriPattern + "/resources/{resourceName}/customizations/{customizationName}": {id: "deleteCustomization", tag: "resources",
+		summary: "Delete a customization of a resource", status: http.StatusNoContent},
+
+	// Cluster providers and clusters
+	"POST /cluster-providers": {id: "createClusterProvider", tag: "clusters",
+		summary: "Create a cluster provider", body: ClusterProvider{}, status: http.StatusCreated},
+	"DELETE /cluster-providers/{clusterProvider}": {id: "deleteClusterProvider", tag: "clusters",
+		summary: "Delete a cluster provider", status: http.StatusNoContent},
+	"POST /cluster-providers/{cluster-provider-name}/clusters": operationAccepted(apiDoc{id: "onboardCluster", tag: "clusters",
+		summary: "Check the connection to a cluster and onboard it with its kubeconfig",
+		form:    map[string]interface{}{"metadata": ClusterMetadata{}}}),
+	"GET /all-clusters": {id: "getClusters", tag: "clusters",
+		summary: "List the cluster providers with their clusters", response: []ClusterProvider{}},
+	"GET /cluster-providers/{clusterprovider-name}/clusters/{cluster-name}/networks": {id: "getClusterNetworks", tag: "networks",
+		summary: "Get the networks and provider networks of a cluster", response: ConsolidatedStatus{}},
+
+	// Projects
+	"GET /projects/{projectName}/events": {id: "streamProjectEvents", tag: "events",
+		summary: "Stream the status changes of the deployment intent groups and logical clouds of a project", produces: "text/event-stream"},
+	"GET /projects/{projectName}/dashboard": {id: "getDashboard", tag: "dashboard",
+		summary: "Count the composite applications, deployment intent groups and clusters of a project", response: DashboardData{}},
+
+	// Logical clouds
+	"POST /projects/{projectName}/logical-clouds": {id: "createLogicalCloud", tag: "logicalClouds",
+		summary: "Create a logical cloud", body: logicalCloudsPayload{}, status: http.StatusCreated, response: LogicalClouds{}},
+	"GET /projects/{projectName}/logical-clouds": {id: "getLogicalClouds", tag: "logicalClouds",
+		summary: "List the logical clouds of a project", response: []LogicalClouds{}},
+	"DELETE /projects/{projectName}/logical-clouds/{logicalCloud}": operationAccepted(apiDoc{id: "deleteLogicalCloud", tag: "logicalClouds",
+		summary: "Terminate and delete a logical cloud"}),
+	"PUT /projects/{projectName}/logical-clouds/{logicalCloud}": {id: "updateLogicalCloud", tag: "logicalClouds",
+		summary: "Update the clusters, permissions and quotas of a logical cloud", body: logicalCloudUpdatePayload{}, response: LogicalClouds{}},
+	"GET /projects/{projectName}/logical-cloud/{logicalCloud}/status": {id: "getLogicalCloudStatus", tag: "logicalClouds",
+		summary: "Get the status of a logical cloud", response: LogicalCloudStatus{}},
+
+	// Certificates of the cluster providers
+	"POST /cluster-provider/{clusterprovider-name}/caRequest": {id: "createClusterProviderCaRequest", tag: "certificates",
+		summary: "Create the CA request of a cluster provider", body: CaRequest{}, response: JsonResponseInterface{}},
+	"DELETE /cluster-provider/{clusterprovider-name}/caRequest": {id: "deleteClusterProviderCaRequest", tag: "certificates",
+		summary: "Delete the CA request of a cluster provider", response: JsonResponseInterface{}},
+	"GET /cluster-provider/{clusterprovider-name}/caRequest": {id: "getClusterProviderCaCert", tag: "certificates",
+		summary: "Get the CA request of a cluster provider", response: JsonResponseCert{}},
+	"GET /cluster-provider/{clusterprovider-name}/caRequest/clusters": {id: "getClusterProviderCaClusters", tag: "certificates",
+		summary: "List the clusters of the CA request of a cluster provider", response: JsonResponseCertIntentClusters{}},
+	"PUT /cluster-provider/{clusterprovider-name}/caRequest/clusters": {id: "updateClusterProviderCaClusters", tag: "certificates",
+		summary: "Set the clusters of the CA request of a cluster provider", body: []string{}, response: JsonResponseUpdateClusters{}},
+	"GET /cluster-provider/{clusterprovider-name}/caRequest/enrollment/status": {id: "getClusterProviderEnrollmentStatus", tag: "certificates",
+		summary: "Get the enrollment status of the CA request of a cluster provider", response: JsonResponseCertStatus{}},
+	"GET /cluster-provider/{clusterprovider-name}/caRequest/distribution/status": {id: "getClusterProviderDistributionStatus", tag: "certificates",
+		summary: "Get the distribution status of the CA request of a cluster provider", response: JsonResponseCertStatus{}},
+	"POST /cluster-provider/{clusterprovider-name}/caRequest/enrollment/instantiate": {id: "instantiateClusterProviderEnrollment", tag: "certificates",
+		summary: "Instantiate the enrollment of the CA request of a cluster provider", response: JsonResponseInterface{}},
+	"POST /cluster-provider/{clusterprovider-name}/caRequest/distribution/instantiate": {id: "instantiateClusterProviderDistribution", tag: "certificates",
+		summary: "Instantiate the distribution of the CA request of a cluster provider", response: JsonResponseInterface{}},
+	"POST /cluster-provider/{clusterprovider-name}/caRequest/enrollment/terminate": {id: "terminateClusterProviderEnrollment", tag: "certificates",
+		summary: "Terminate the enrollment of the CA request of a cluster provider", response: JsonResponseInterface{}},
+	"POST /cluster-provider/{clusterprovider-name}/caRequest/distribution/terminate": {id: "terminateClusterProviderDistribution", tag: "certificates",
+		summary: "Terminate the distribution of the CA request of a cluster provider", response: JsonResponseInterface{}},
+
+	// Certificates of the logical clouds
+	"POST /projects/{project}/caRequest": {id: "createLogicalCloudCaRequest", tag: "certificates",
+		summary: "Create the CA request of the logical clouds of a project", body: CaRequest{}, response: JsonResponseInterface{}},
+	"DELETE /projects/{project}/caRequest": {id: "deleteLogicalCloudCaRequest", tag: "certificates",
+		summary: "Delete the CA request of the logical clouds of a project", response: JsonResponseInterface{}},
+	"GET /projects/{project}/caRequest": {id: "getLogicalCloudCaCert", tag: "certificates",
+		summary: "Get the CA request of the logical clouds of a project", response: JsonResponseCert{}},
+	"GET /projects/{project}/caRequest/logical-clouds": {id: "getLogicalCloudCaLogicalClouds", tag: "certificates",
+		summary: "List the logical clouds of the CA request of a project", response: JsonResponseCertIntentLogicalClouds{}},
+	"PUT /projects/{project}/caRequest/clusters": {id: "updateLogicalCloudCaLogicalClouds", tag: "certificates",
+		summary: "Set the logical clouds of the CA request of a project", body: []string{}, response: JsonResponseUpdateClusters{}},
+	"GET /projects/{project}/caRequest/enrollment/status": {id: "getLogicalCloudEnrollmentStatus", tag: "certificates",
+		summary: "Get the enrollment status of the CA request of a project", response: JsonResponseCertStatus{}},
+	"GET /projects/{project}/caRequest/distribution/status": {id: "getLogicalCloudDistributionStatus", tag: "certificates",
+		summary: "Get the distribution status of the CA request of a project", response: JsonResponseCertStatus{}},
+	"POST /projects/{project}/caRequest/enrollment/instantiate": {id: "instantiateLogicalCloudEnrollment", tag: "certificates",
+		summary: "Instantiate the enrollment of the CA request of a project", response: JsonResponseInterface{}},
+	"POST /projects/{project}/caRequest/distribution/instantiate": {id: "instantiateLogicalCloudDistribution", tag: "certificates",
+		summary: "Instantiate the distribution of the CA request of a project", response: JsonResponseInterface{}},
+	"POST /projects/{project}/caRequest/enrollment/terminate": {id: "terminateLogicalCloudEnrollment", tag: "certificates",
+		summary: "Terminate the enrollment of the CA request of a project", response: JsonResponseInterface{}},
+	"POST /projects/{project}/caRequest/distribution/terminate": {id: "terminateLogicalCloudDistribution", tag: "certificates",
+		summary: "Terminate the distribution of the CA request of a project", response: JsonResponseInterface{}},
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+// Spec generates the OpenAPI document of the APIs registered by
+// RegisterHandlers. Every route must be documented in apiDocs.
+func Spec() (*spec.Swagger, error) {
+	router := mux.NewRouter()
+	(&Server{}).RegisterHandlers(router.HandleFunc)
+
+	gen := newSchemaGen()
+	sw := &spec.Swagger{SwaggerProps: spec.SwaggerProps{
+		Swagger:  "2.0",
+		Schemes:  []string{"http", "https"},
+		BasePath: "/middleend",
+		Consumes: []string{"application/json"},
+		Produces: []string{"application/json"},
+		Info: &spec.Info{InfoProps: spec.InfoProps{
+			Title:       "Middleend API",
+			Description: "APIs of the middleend serving the AMCOP GUI. Failed requests are replied with an ErrorResponse.",
+			Version:     "1.0.0",
+		}},
+		Paths: &spec.Paths{Paths: make(map[string]spec.PathItem)},
+	}}
+	errorRef := gen.schemaOf(reflect.TypeOf(ErrorResponse{}))
+
+	var undocumented []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// the routes matching on their query only are read
+			methods = []string{http.MethodGet}
+		}
+		queries, _ := route.GetQueriesTemplates()
+		for _, method := range methods {
+			doc, ok := apiDocs[method+" "+tmpl]
+			if !ok {
+				undocumented = append(undocumented, method+" "+tmpl)
+				continue
+			}
+			item := sw.Paths.Paths[tmpl]
+			setOperation(&item, method, gen.operation(tmpl, doc, queries, errorRef))
+			sw.Paths.Paths[tmpl] = item
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("undocumented APIs, add them to apiDocs: %s", strings.Join(undocumented, ", "))
+	}
+	sw.Definitions = gen.defs
+	return sw, nil
+}
+
+// SpecYAML returns the document generated by Spec as yaml
+func SpecYAML() ([]byte, error) {
+	sw, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(sw)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(data)
+}
+
+func setOperation(item *spec.PathItem, method string, op *spec.Operation) {
+	switch method {
+	case http.MethodGet:
+		item.Get = op
+	case http.MethodPost:
+		item.Post = op
+	case http.MethodPut:
+		item.Put = op
+	case http.MethodPatch:
+		item.Patch = op
+	case http.MethodDelete:
+		item.Delete = op
+	}
+}
+
+func (g *schemaGen) operation(tmpl string, doc apiDoc, queries []string, errorRef *spec.Schema) *spec.Operation {
+	op := spec.NewOperation(doc.id).WithSummary(doc.summary).WithTags(doc.tag)
+	for _, m := range pathParam.FindAllStringSubmatch(tmpl, -1) {
+		op.AddParam(spec.PathParam(m[1]).Typed("string", ""))
+	}
+	names := append([]string{}, doc.query...)
+	if doc.dryRun != nil {
+		names = append(names, "dryRun")
+	}
+	for _, q := range queries {
+		if name := strings.SplitN(q, "=", 2)[0]; !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		op.AddParam(spec.QueryParam(name).Typed("string", ""))
+	}
+	if doc.body != nil {
+		op.AddParam(spec.BodyParam("body", g.schemaOf(reflect.TypeOf(doc.body))).AsRequired())
+	}
+	if len(doc.form) > 0 {
+		op.WithConsumes("multipart/form-data")
+		fields := make([]string, 0, len(doc.form))
+		for name := range doc.form {
+			fields = append(fields, name)
+		}
+		sort.Strings(fields)
+		for _, name := range fields {
+			value := g.schemaOf(reflect.TypeOf(doc.form[name]))
+			p := spec.FormDataParam(name).Typed("string", "").AsRequired().
+				WithDescription("json value of " + value.Ref.String())
+			p.AddExtension(jsonFieldExt, value)
+			op.AddParam(p)
+		}
+		op.AddParam(spec.FileParam("file").WithDescription("files uploaded along with the json fields"))
+	}
+	if doc.produces != "" {
+		op.WithProduces(doc.produces)
+	}
+
+	status := doc.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := spec.NewResponse().WithDescription(http.StatusText(status))
+	if doc.response != nil {
+		resp.WithSchema(g.schemaOf(reflect.TypeOf(doc.response)))
+	}
+	op.RespondsWith(status, resp)
+	if doc.dryRun != nil {
+		op.RespondsWith(http.StatusOK, spec.NewResponse().WithDescription("Plan of a dry run").
+			WithSchema(g.schemaOf(reflect.TypeOf(doc.dryRun))))
+	}
+	op.WithDefaultResponse(spec.NewResponse().WithDescription("Error reply").WithSchema(errorRef))
+	return op
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaGen builds the json schemas of go types the way encoding/json
+// maps them. Named structs become definitions, their fields are closed
+// so that unknown fields are rejected. A field tagged binding:"required"
+// is required.
+type schemaGen struct {
+	defs  spec.Definitions
+	names map[reflect.Type]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{defs: make(spec.Definitions), names: make(map[reflect.Type]string)}
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+func (g *schemaGen) schemaOf(t reflect.Type) *spec.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return spec.DateTimeProperty()
+	case reflect.PtrTo(t).Implements(unmarshalerType):
+		// decoded by the type itself, any value may do
+		return &spec.Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return spec.BoolProperty()
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return spec.Int32Property()
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return spec.Int64Property()
+	case reflect.Float32:
+		return spec.Float32Property()
+	case reflect.Float64:
+		return spec.Float64Property()
+	case reflect.String:
+		return spec.StringProperty()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return spec.StrFmtProperty("byte")
+		}
+		return spec.ArrayProperty(g.schemaOf(t.Elem()))
+	case reflect.Map:
+		return spec.MapProperty(g.schemaOf(t.Elem()))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return spec.RefSchema("#/definitions/" + g.define(t))
+	}
+	return &spec.Schema{}
+}
+
+// define adds the definition of a named struct, the name of the package
+// tells apart the types of the same name
+func (g *schemaGen) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.defs[name]; taken {
+		name = path.Base(t.PkgPath()) + strings.Title(name)
+	}
+	for i := 2; ; i++ {
+		if _, taken := g.defs[name]; !taken {
+			break
+		}
+		name = t.Name() + strconv.Itoa(i)
+	}
+	g.names[t] = name
+	// reserve the name before the fields, which may refer to the type
+	g.defs[name] = spec.Schema{}
+	g.defs[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) *spec.Schema {
+	s := &spec.Schema{}
+	s.Typed("object", "")
+	s.Properties = make(spec.SchemaProperties)
+	s.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.schemaOf(f.Type)
+		if contains(opts[1:], "string") {
+			prop = spec.StringProperty()
+		}
+		s.Properties[name] = *prop
+		if f.Tag.Get("binding") == "required" {
+			s.Required = append(s.Required, name)
+		}
+	}
+	// the fields of the embedded structs are promoted unless shadowed
+	for _, ft := range embedded {
+		promoted := g.structSchema(ft)
+		for name, prop := range promoted.Properties {
+			if _, ok := s.Properties[name]; !ok {
+				s.Properties[name] = prop
+			}
+		}
+		s.Required = append(s.Required, promoted.Required...)
+	}
+	return s
+}
@@ -0,0 +1,644 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"example.com/middleend/authproxy"
+	"example.com/middleend/db"
+	"example.com/middleend/logging"
+	"example.com/middleend/metrics"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const OPERATION_COLLECTION = "operations"
+
+// Operation states
+const (
+	operationPending   = "pending"
+	operationRunning   = "running"
+	operationSucceeded = "succeeded"
+	operationFailed    = "failed"
+)
+
+// Operation step states, in addition to stepSucceeded and stepFailed
+const (
+	stepRunning = "running"
+	stepSkipped = "skipped"
+)
+
+const (
+	defaultOperationWorkers = 4
+	operationQueueSize      = 64
+	// defaultOperationRetentionHours applies when the configuration sets
+	// no operationRetentionHours
+	defaultOperationRetentionHours = 7 * 24
+)
+
+var (
+	errOperationQueueFull = errors.New("too many pending operations")
+	errShuttingDown       = errors.New("middleend is shutting down")
+)
+
+// skipStep ends a step as skipped, the reason is reported as its error
+type skipStep string
+
+func (s skipStep) Error() string {
+	return string(s)
+}
+
+// Operation is a long running request executed by the worker pool. It is
+// persisted on every change so its progress survives the request.
+type Operation struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Method  string `json:"method"`
+	Target  string `json:"target"`
+	Project string `json:"project,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Status  string `json:"status"`
+	Code    int    `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// ErrorDetail is the error reply of a failed operation
+	ErrorDetail *ErrorResponse  `json:"errorDetail,omitempty"`
+	Result      string          `json:"result,omitempty"`
+	Steps       []OperationStep `json:"steps"`
+	Created     time.Time       `json:"created"`
+	Started     time.Time       `json:"started"`
+	Finished    time.Time       `json:"finished"`
+}
+
+type OperationStep struct {
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Took     string    `json:"took,omitempty"`
+}
+
+type OperationKey struct {
+	ID string `json:"operationId"`
+}
+
+func (o *Operation) done() bool {
+	return o.Status == operationSucceeded || o.Status == operationFailed
+}
+
+// operationTracker holds a running operation. Every update is persisted
+// and signalled to the clients watching the operation.
+type operationTracker struct {
+	sync.Mutex
+	op       Operation
+	watchers map[chan struct{}]bool
+	finished chan struct{}
+}
+
+func newOperationTracker(kind string, r *http.Request) *operationTracker {
+	op := Operation{
+		ID:      uuid.New().String(),
+		Kind:    kind,
+		Method:  r.Method,
+		Target:  r.URL.Path,
+		Project: mux.Vars(r)["projectName"],
+		Status:  operationPending,
+		Steps:   []OperationStep{},
+		Created: time.Now(),
+	}
+	if claims, ok := authproxy.ClaimsFromContext(r.Context()); ok {
+		op.Subject = claims.Subject
+	}
+	return &operationTracker{
+		op:       op,
+		watchers: make(map[chan struct{}]bool),
+		finished: make(chan struct{}),
+	}
+}
+
+// snapshot returns a copy of the operation
+func (t *operationTracker) snapshot() Operation {
+	t.Lock()
+	defer t.Unlock()
+	op := t.op
+	op.Steps = append([]OperationStep{}, t.op.Steps...)
+	return op
+}
+
+// update applies fn to the operation, persists it and wakes the watchers.
+// The operation is persisted outside of the transactions, the steps are
+// updated while the transactions of the request run and must be seen
+// before they end.
+func (t *operationTracker) update(fn func(op *Operation)) {
+	t.Lock()
+	defer t.Unlock()
+	fn(&t.op)
+	if err := db.DBconn.Insert(db.WithoutTransaction(context.Background()), OPERATION_COLLECTION, OperationKey{ID: t.op.ID}, nil, "operation", t.op); err != nil {
+		log.WithError(err).Errorf("%s(): Failed to persist operation %s", PrintFunctionName(), t.op.ID)
+	}
+	for c := range t.watchers {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watch returns a channel signalled on every update of the operation
+func (t *operationTracker) watch() (chan struct{}, func()) {
+	c := make(chan struct{}, 1)
+	t.Lock()
+	t.watchers[c] = true
+	t.Unlock()
+	return c, func() {
+		t.Lock()
+		delete(t.watchers, c)
+		t.Unlock()
+	}
+}
+
+func (t *operationTracker) start() {
+	t.update(func(op *Operation) {
+		op.Status = operationRunning
+		op.Started = time.Now()
+	})
+}
+
+// finish completes the operation with the reply of its handler. The
+// operation fails when the handler replied an error or a step failed.
+func (t *operationTracker) finish(code int, body []byte) {
+	t.update(func(op *Operation) {
+		op.Code = code
+		op.Finished = time.Now()
+		op.Status = operationSucceeded
+		if code >= http.StatusBadRequest {
+			op.Status = operationFailed
+			op.Error = string(body)
+			var e ErrorResponse
+			if json.Unmarshal(body, &e) == nil && e.Code != "" {
+				op.Error = e.Message
+				op.ErrorDetail = &e
+			}
+			if op.Error == "" {
+				op.Error = http.StatusText(code)
+			}
+			return
+		}
+		op.Result = string(body)
+		for _, s := range op.Steps {
+			if s.Status == stepFailed {
+				op.Status = operationFailed
+				op.Error = fmt.Sprintf("step %s failed: %s", s.Name, s.Error)
+				return
+			}
+		}
+	})
+	metrics.OperationFinished(t.op.Kind, t.op.Status)
+	close(t.finished)
+}
+
+func (t *operationTracker) beginStep(name string) int {
+	var i int
+	t.update(func(op *Operation) {
+		op.Steps = append(op.Steps, OperationStep{Name: name, Status: stepRunning, Started: time.Now()})
+		i = len(op.Steps) - 1
+	})
+	return i
+}
+
+func (t *operationTracker) endStep(i int, err error) {
+	t.update(func(op *Operation) {
+		s := &op.Steps[i]
+		s.Finished = time.Now()
+		s.Took = s.Finished.Sub(s.Started).String()
+		s.Status = stepSucceeded
+		if _, ok := err.(skipStep); ok {
+			s.Status = stepSkipped
+			s.Error = err.Error()
+		} else if err != nil {
+			s.Status = stepFailed
+			s.Error = err.Error()
+		}
+	})
+}
+
+// opStep records a step of the operation run by h and returns the
+// function ending it. Outside of an operation steps are not recorded.
+func (h *OrchestrationHandler) opStep(name string) func(err error) {
+	if h.operation == nil {
+		return func(err error) {}
+	}
+	i := h.operation.beginStep(name)
+	return func(err error) {
+		h.operation.endStep(i, err)
+	}
+}
+
+// opSkip records a step of the operation which did not need to run
+func (h *OrchestrationHandler) opSkip(name string, reason string) {
+	h.Logger.Infof("Skipping step %s: %s", name, reason)
+	h.opStep(name)(skipStep(reason))
+}
+
+// stepError describes a call which replied code instead of expected
+func stepError(code int, expected int, err error) error {
+	if code == expected {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("replied status %d, expected %d", code, expected)
+}
+
+// recorderError describes a failed call which replied on the recorder
+func recorderError(rw *httptest.ResponseRecorder, msg string) error {
+	if body := bytes.TrimSpace(rw.Body.Bytes()); len(body) > 0 {
+		return fmt.Errorf("%s: %s", msg, body)
+	}
+	return errors.New(msg)
+}
+
+// endRecordedStep ends a step which replied on rw. A failed step is
+// replied on w and false is returned.
+func endRecordedStep(w http.ResponseWriter, rw *httptest.ResponseRecorder, done func(err error), msg string) bool {
+	if rw.Code < http.StatusBadRequest {
+		done(nil)
+		return true
+	}
+	done(recorderError(rw, msg))
+	w.WriteHeader(rw.Code)
+	if _, err := w.Write(rw.Body.Bytes()); err != nil {
+		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	}
+	return false
+}
+
+// operationManager runs the operations on a bounded pool of workers
+type operationManager struct {
+	sync.Mutex
+	jobs   chan func()
+	active map[string]*operationTracker
+	// pending counts the queued and running operations
+	pending sync.WaitGroup
+	closed  bool
+}
+
+func newOperationManager(workers int) *operationManager {
+	if workers <= 0 {
+		workers = defaultOperationWorkers
+	}
+	m := &operationManager{
+		jobs:   make(chan func(), operationQueueSize),
+		active: make(map[string]*operationTracker),
+	}
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+	return m
+}
+
+func (m *operationManager) worker() {
+	for job := range m.jobs {
+		job()
+	}
+}
+
+func (m *operationManager) submit(t *operationTracker, job func()) error {
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return errShuttingDown
+	}
+	m.active[t.op.ID] = t
+	m.pending.Add(1)
+	m.Unlock()
+	select {
+	case m.jobs <- func() {
+		defer m.pending.Done()
+		job()
+	}:
+		return nil
+	default:
+		m.remove(t.op.ID)
+		m.pending.Done()
+		return errOperationQueueFull
+	}
+}
+
+// close refuses the operations submitted from now on
+func (m *operationManager) close() {
+	m.Lock()
+	m.closed = true
+	m.Unlock()
+}
+
+// drain waits for the queued and running operations to finish. When ctx
+// expires first, the operations left are reported and abandoned.
+func (m *operationManager) drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	m.Lock()
+	defer m.Unlock()
+	ids := make([]string, 0, len(m.active))
+	for id := range m.active {
+		ids = append(ids, id)
+	}
+	return fmt.Errorf("%d operation(s) did not finish: %v", len(ids), ids)
+}
+
+func (m *operationManager) get(id string) (*operationTracker, bool) {
+	m.Lock()
+	defer m.Unlock()
+	t, ok := m.active[id]
+	return t, ok
+}
+
+func (m *operationManager) remove(id string) {
+	m.Lock()
+	delete(m.active, id)
+	m.Unlock()
+}
+
+// detachedContext keeps the values of the request context, i.e. the mux
+// variables and the claims, without its cancellation. Operations outlive
+// the request which started them.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// runOperation replies 202 with the operation and runs fn in the worker
+// pool on a copy of the request. The reply of fn becomes the result of
+// the operation.
+func (s *Server) runOperation(kind string, w http.ResponseWriter, r *http.Request, fn handlerFunc) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logging.FromContext(r.Context()).WithError(err).Errorf("%s(): Failed to read request body", PrintFunctionName())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// the operation records the failed calls of its own handler
+	ctx, tracker := withUpstreamTracker(detachedContext{parent: r.Context()})
+	req := r.Clone(ctx)
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t := newOperationTracker(kind, r)
+	id := t.op.ID
+	t.update(func(op *Operation) {})
+	metrics.OperationQueued(kind)
+	err = s.operations.submit(t, func() {
+		defer s.operations.remove(id)
+		h := s.handlerFor(req)
+		h.setLogger(h.Logger.WithField("operation", id))
+		h.operation = t
+		// the span of the request which queued the operation is its parent
+		end := h.startSpan("operation " + kind)
+		rec := httptest.NewRecorder()
+		ew := newErrorWriter(rec, req, tracker)
+		defer func() {
+			if p := recover(); p != nil {
+				h.Logger.Errorf("%s(): Operation %s panicked: %v", PrintFunctionName(), id, p)
+				rec = httptest.NewRecorder()
+				ew = newErrorWriter(rec, req, &upstreamTracker{})
+				ew.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprintf(ew, "operation panicked: %v", p)
+			}
+			ew.finish()
+			if req.MultipartForm != nil {
+				if err := req.MultipartForm.RemoveAll(); err != nil {
+					h.Logger.WithError(err).Warnf("%s(): Failed to remove multipart files", PrintFunctionName())
+				}
+			}
+			var opErr error
+			if rec.Code >= http.StatusBadRequest {
+				opErr = fmt.Errorf("operation %s failed with status %d", id, rec.Code)
+			}
+			end(opErr)
+			t.finish(rec.Code, rec.Body.Bytes())
+			h.Logger.Infof("Operation %s %s finished with status %d", kind, id, rec.Code)
+		}()
+		t.start()
+		fn(h, ew, req)
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).WithError(err).Errorf("%s(): Failed to queue operation %s", PrintFunctionName(), kind)
+		t.finish(http.StatusServiceUnavailable, []byte(err.Error()))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/middleend/operations/"+id)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(t.snapshot()); err != nil {
+		logging.FromContext(r.Context()).WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	}
+}
+
+// getOperation reads an operation, active operations are served from
+// memory, finished ones from the db.
+func (m *operationManager) getOperation(ctx context.Context, id string) (Operation, bool, error) {
+	if t, ok := m.get(id); ok {
+		return t.snapshot(), true, nil
+	}
+	var op Operation
+	values, err := db.DBconn.Find(ctx, OPERATION_COLLECTION, OperationKey{ID: id}, "operation")
+	if err != nil {
+		return op, false, err
+	}
+	if len(values) == 0 {
+		return op, false, nil
+	}
+	if err := db.DBconn.Unmarshal(values[0], &op); err != nil {
+		return op, false, err
+	}
+	return op, true, nil
+}
+
+// ExpireOperations removes, every interval until stop is closed, the
+// operations which finished longer than the retention ago
+func (s *Server) ExpireOperations(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.expireOperations(time.Now())
+		}
+	}
+}
+
+func (s *Server) expireOperations(now time.Time) {
+	hours := s.config().OperationRetentionHours
+	if hours <= 0 {
+		hours = defaultOperationRetentionHours
+	}
+	before := now.Add(-time.Duration(hours) * time.Hour)
+	ctx := context.Background()
+	values, err := db.DBconn.Find(ctx, OPERATION_COLLECTION, OperationKey{}, "operation")
+	if err != nil {
+		log.WithError(err).Warn("Failed to read the operations")
+		return
+	}
+	expired := 0
+	for _, value := range values {
+		var op Operation
+		if err := db.DBconn.Unmarshal(value, &op); err != nil || !op.done() || op.Finished.After(before) {
+			continue
+		}
+		if _, active := s.operations.get(op.ID); active {
+			continue
+		}
+		if err := db.DBconn.Remove(ctx, OPERATION_COLLECTION, OperationKey{ID: op.ID}); err != nil {
+			log.WithError(err).Warnf("Failed to remove operation %s", op.ID)
+			continue
+		}
+		expired++
+	}
+	if expired > 0 {
+		log.Infof("Removed %d operation(s) finished before %s", expired, before.Format(time.RFC3339))
+	}
+}
+
+// visibleTo reports whether the operation may be read by the caller. The
+// operations of a project are restricted to the callers the rbac policy
+// lets access the project, or to its tenant without a policy.
+func (o *Operation) visibleTo(r *http.Request, projects projectAuthorizer) bool {
+	claims, ok := authproxy.ClaimsFromContext(r.Context())
+	if !ok || o.Project == "" {
+		return true
+	}
+	if projects != nil {
+		return projects.ProjectAllowed(claims, o.Project)
+	}
+	return claims.Tenant == "" || claims.Tenant == o.Project
+}
+
+// operationRequest is the request of GetOperation
+type operationRequest struct {
+	ID string
+	// Watch streams the progress of the operation until it finishes
+	Watch bool
+}
+
+func newOperationRequest(r *http.Request) operationRequest {
+	return operationRequest{
+		ID:    mux.Vars(r)["operationId"],
+		Watch: r.URL.Query().Get("watch") == "true" || r.Header.Get("Accept") == "text/event-stream",
+	}
+}
+
+// findOperation returns the operation of the request, provided that the
+// caller may read it
+func (h *OrchestrationHandler) findOperation(r *http.Request, req operationRequest) (Operation, error) {
+	op, found, err := h.srv.operations.getOperation(h.ctx, req.ID)
+	if err != nil {
+		h.Logger.WithError(err).Errorf("%s(): Failed to read operation %s", PrintFunctionName(), req.ID)
+		return op, err
+	}
+	if !found || !op.visibleTo(r, h.srv.projects) {
+		return op, &apiError{code: http.StatusNotFound, msg: "operation " + req.ID + " not found"}
+	}
+	return op, nil
+}
+
+// GetOperation replies the operation. With ?watch=true or an Accept header
+// of text/event-stream the progress is streamed as server sent events
+// until the operation finishes.
+func (h *OrchestrationHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	req := newOperationRequest(r)
+	op, err := h.findOperation(r, req)
+	if e, ok := err.(*apiError); ok {
+		http.Error(w, e.msg, e.code)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if req.Watch {
+		h.streamOperation(w, r, op)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	}
+}
+
+func (h *OrchestrationHandler) streamOperation(w http.ResponseWriter, r *http.Request, op Operation) {
+	stream, ok := newEventStream(w)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusNotAcceptable)
+		return
+	}
+
+	t, active := h.srv.operations.get(op.ID)
+	if !active || op.done() {
+		stream.send("operation", op)
+		return
+	}
+	updates, stop := t.watch()
+	defer stop()
+	if !stream.send("operation", t.snapshot()) {
+		return
+	}
+	deadline := time.NewTimer(eventStreamMaxDuration)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-updates:
+			if !stream.send("operation", t.snapshot()) {
+				return
+			}
+		case <-t.finished:
+			stream.send("operation", t.snapshot())
+			return
+		}
+	}
+}
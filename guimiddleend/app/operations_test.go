@@ -0,0 +1,137 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/middleend/authproxy"
+	"example.com/middleend/db"
+)
+
+// projectList allows the projects it lists to every caller
+type projectList []string
+
+func (l projectList) ProjectAllowed(claims *authproxy.Claims, project string) bool {
+	for _, p := range l {
+		if p == project || p == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOperationVisibleTo(t *testing.T) {
+	op := &Operation{Project: "p1"}
+	tests := []struct {
+		name     string
+		claims   *authproxy.Claims
+		projects projectAuthorizer
+		visible  bool
+	}{
+		{"no claims", nil, nil, true},
+		{"tenant of the project", &authproxy.Claims{Tenant: "p1"}, nil, true},
+		{"other tenant", &authproxy.Claims{Tenant: "p2"}, nil, false},
+		{"policy allows the project", &authproxy.Claims{Tenant: "p2", Roles: []string{"admin"}}, projectList{"*"}, true},
+		{"policy denies the project", &authproxy.Claims{Tenant: "p1"}, projectList{"p2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/middleend/operations/id", nil)
+			if tt.claims != nil {
+				r = r.WithContext(authproxy.NewContext(r.Context(), tt.claims))
+			}
+			if got := op.visibleTo(r, tt.projects); got != tt.visible {
+				t.Fatalf("visible %t, want %t", got, tt.visible)
+			}
+		})
+	}
+}
+
+func TestExpireOperations(t *testing.T) {
+	db.DBconn = db.NewMemoryStore()
+	s := &Server{operations: newOperationManager(1), conf: MiddleendConfig{OperationRetentionHours: 24}}
+	defer s.operations.close()
+	ctx := context.Background()
+	now := time.Now()
+	ops := []Operation{
+		{ID: "old", Status: operationSucceeded, Finished: now.Add(-48 * time.Hour)},
+		{ID: "old-failed", Status: operationFailed, Finished: now.Add(-25 * time.Hour)},
+		{ID: "recent", Status: operationSucceeded, Finished: now.Add(-time.Hour)},
+		{ID: "running", Status: operationRunning, Started: now.Add(-48 * time.Hour)},
+	}
+	for _, op := range ops {
+		if err := db.DBconn.Insert(ctx, OPERATION_COLLECTION, OperationKey{ID: op.ID}, nil, "operation", op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.expireOperations(now)
+
+	for _, op := range ops {
+		_, found, err := s.operations.getOperation(ctx, op.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := op.ID == "recent" || op.ID == "running"; found != want {
+			t.Errorf("operation %s kept %t, want %t", op.ID, found, want)
+		}
+	}
+}
+
+func TestOperationStepInTransaction(t *testing.T) {
+	db.DBconn = db.NewMemoryStore()
+	s := &Server{operations: newOperationManager(1)}
+	defer s.operations.close()
+	h := s.newHandler(context.Background())
+	h.operation = newOperationTracker("test", httptest.NewRequest("POST", "/middleend/projects/p1/test", nil))
+
+	// the body waits on a step written from another goroutine, as the
+	// steps of the async APIs are
+	done := make(chan error, 1)
+	go func() {
+		done <- h.inTransaction(func() error {
+			stepped := make(chan struct{})
+			go func() {
+				h.opStep("step")(nil)
+				close(stepped)
+			}()
+			<-stepped
+			return errors.New("failed")
+		})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("the transaction succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the operation step waited for the transaction")
+	}
+
+	// the step is kept though the transaction rolled back
+	op, found, err := s.operations.getOperation(context.Background(), h.operation.op.ID)
+	if err != nil || !found {
+		t.Fatalf("operation not found: %v", err)
+	}
+	if len(op.Steps) != 1 || op.Steps[0].Status != stepSucceeded {
+		t.Fatalf("unexpected steps %+v", op.Steps)
+	}
+}
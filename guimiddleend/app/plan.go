@@ -0,0 +1,314 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+
+	"example.com/middleend/localstore"
+)
+
+// lifecycleActions are the EMCO APIs acting on a DIG, they reply 202
+var lifecycleActions = []string{"approve", "instantiate", "migrate", "update", "rollback", "terminate", "stop"}
+
+// SubmitPlan is what the submit of a checked out DIG would do, computed by
+// a dry run
+type SubmitPlan struct {
+	// Operation is migrate or update, from FromVersion to ToVersion
+	Operation   string `json:"operation"`
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion"`
+	// CreateDig is set when the DIG is created in ToVersion
+	CreateDig bool `json:"createDig"`
+	// Changes turn the intents of the DIG in EMCO into those of the
+	// checkout: the placement clusters, the network interfaces and the
+	// resources with their customizations of each application
+	Changes []IntentChange `json:"changes"`
+	// Calls are the changes the submit sends to EMCO, in order
+	Calls []PlannedCall `json:"calls"`
+	// Blocked tells why the submit would fail, the calls stop there
+	Blocked string `json:"blocked,omitempty"`
+
+	mu sync.Mutex
+	// objects are the urls of the objects created (true) and deleted
+	// (false) by the calls so far, which EMCO does not know of
+	objects map[string]bool
+}
+
+// PlannedCall is a call of a submit to EMCO
+type PlannedCall struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	// Action is create, delete or the lifecycle action of the DIG
+	Action string `json:"action"`
+	// Kind is the EMCO collection of the object, Name the object
+	Kind string      `json:"kind"`
+	Name string      `json:"name,omitempty"`
+	Body interface{} `json:"body,omitempty"`
+	// Files are the files uploaded along with a multipart body
+	Files []string `json:"files,omitempty"`
+}
+
+// record adds the call of req to the plan and replies it the way EMCO
+// would, without sending it. A delete of an object which does not exist
+// is replied 404, a create under a parent which does not exist 404 and
+// of an object which exists 409; exists asks EMCO about the objects the
+// plan did not change.
+func (p *SubmitPlan) record(service string, req *http.Request, exists func(url string) (bool, error)) (*http.Response, error) {
+	call := PlannedCall{Service: service, Method: req.Method, URL: req.URL.String()}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	if err := call.setBody(req.Header.Get("Content-Type"), body); err != nil {
+		return nil, err
+	}
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	last := segments[len(segments)-1]
+	status := http.StatusCreated
+	switch {
+	case req.Method == http.MethodDelete:
+		status = http.StatusNoContent
+		call.Action = "delete"
+		call.Name = last
+		if len(segments) > 1 {
+			call.Kind = segments[len(segments)-2]
+		}
+	case contains(lifecycleActions, last) && len(segments) > 2:
+		status = http.StatusAccepted
+		call.Action = last
+		call.Kind = segments[len(segments)-3]
+		call.Name = segments[len(segments)-2]
+	default:
+		call.Action = "create"
+		call.Kind = last
+		if obj, ok := call.Body.(map[string]interface{}); ok {
+			if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+				call.Name, _ = meta["name"].(string)
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.Calls = append(p.Calls, call)
+	p.mu.Unlock()
+
+	// EMCO replies the object created, which is the one sent
+	reply := body
+	if status != http.StatusCreated || !json.Valid(body) {
+		reply = nil
+	}
+	object := strings.TrimSuffix(req.URL.String(), "/")
+	switch call.Action {
+	case "delete":
+		found, err := p.exists(object, exists)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			status, reply = http.StatusNotFound, []byte(fmt.Sprintf("%s %s not found", call.Kind, call.Name))
+			break
+		}
+		p.changed(object, false)
+	case "create":
+		if call.Name == "" {
+			break
+		}
+		u := *req.URL
+		u.Path = path.Dir(strings.TrimSuffix(u.Path, "/"))
+		parent, err := p.exists(u.String(), exists)
+		if err != nil {
+			return nil, err
+		}
+		object += "/" + call.Name
+		found, err := p.exists(object, exists)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !parent:
+			status, reply = http.StatusNotFound, []byte(fmt.Sprintf("the parent of %s %s not found", call.Kind, call.Name))
+		case found:
+			status, reply = http.StatusConflict, []byte(fmt.Sprintf("%s %s already exists", call.Kind, call.Name))
+		default:
+			p.changed(object, true)
+		}
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(reply)),
+		Request:    req,
+	}, nil
+}
+
+// exists tells whether the object at url exists once the calls so far
+// are made
+func (p *SubmitPlan) exists(url string, exists func(url string) (bool, error)) (bool, error) {
+	p.mu.Lock()
+	created, ok := p.objects[url]
+	p.mu.Unlock()
+	if ok {
+		return created, nil
+	}
+	return exists(url)
+}
+
+func (p *SubmitPlan) changed(url string, created bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.objects == nil {
+		p.objects = map[string]bool{}
+	}
+	p.objects[url] = created
+}
+
+// objectExists asks EMCO whether the object at url exists
+func (h *OrchestrationHandler) objectExists(url string) (bool, error) {
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode < http.StatusBadRequest:
+		return true, nil
+	}
+	return false, fmt.Errorf("reading %s replied status %d", url, resp.StatusCode)
+}
+
+// setBody keeps the json body of a call, or the json fields and the file
+// names of a multipart one
+func (c *PlannedCall) setBody(contentType string, body []byte) error {
+	if len(body) == 0 {
+		return nil
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		var v interface{}
+		if json.Unmarshal(body, &v) == nil {
+			c.Body = v
+		}
+		return nil
+	}
+	fields := map[string]interface{}{}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if part.FileName() != "" {
+			c.Files = append(c.Files, part.FileName())
+			continue
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if json.Unmarshal(data, &v) != nil {
+			v = string(data)
+		}
+		fields[part.FormName()] = v
+	}
+	c.Body = fields
+	if obj, ok := fields["metadata"]; ok && len(fields) == 1 {
+		c.Body = obj
+	}
+	return nil
+}
+
+// PlanDIGSubmit is the dry run of UpgradeDIG. The submit runs with its
+// changes to EMCO recorded in the plan instead of sent, and without the
+// changes to the middleend store, so nothing is changed.
+func (h *OrchestrationHandler) PlanDIGSubmit(r *http.Request) (interface{}, error) {
+	req := newDigRequest(r)
+	h.Vars = req.vars()
+	h.InitializeResponseMap()
+	version := req.Version
+
+	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
+	retValue, err := localDigStore.getDig(req.Project, req.CompositeApp, version, req.Dig)
+	if err != nil {
+		return nil, &apiError{code: http.StatusNotFound,
+			msg: fmt.Sprintf("DIG %s is not checked out in version %s", req.Dig, version)}
+	}
+	checkout := localstore.DeploymentIntentGroup{}
+	if err := json.Unmarshal(retValue, &checkout); err != nil {
+		return nil, err
+	}
+
+	plan := &SubmitPlan{Operation: checkout.MetaData.UserData1, FromVersion: version, ToVersion: version, Calls: []PlannedCall{}}
+	if plan.Operation == "migrate" {
+		plan.FromVersion = checkout.MetaData.UserData2
+	}
+	dStore := &remoteStoreDigHandler{}
+	dStore.orchInstance = h
+	_, err = dStore.getDig(req.Project, req.CompositeApp, version, req.Dig)
+	plan.CreateDig = err != nil
+
+	// The intents of the checkout against those of the DIG in EMCO
+	if err := h.readDIGData(httptest.NewRecorder(), "middleend", []string{}); err != nil {
+		return nil, err
+	}
+	target := h.DigData
+	h.Vars = req.atVersion(plan.FromVersion).vars()
+	source, err := h.currentIntents()
+	h.Vars = req.vars()
+	if err != nil {
+		return nil, err
+	}
+	if plan.Changes, err = diffIntents(source, &target); err != nil {
+		return nil, err
+	}
+
+	h.state.plan = plan
+	defer func() { h.state.plan = nil }()
+	rw := httptest.NewRecorder()
+	h.UpgradeDIG(rw, r)
+	if rw.Code >= http.StatusBadRequest {
+		plan.Blocked = recorderError(rw, fmt.Sprintf("the submit fails with status %d", rw.Code)).Error()
+	}
+	return plan, nil
+}
@@ -0,0 +1,179 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/middleend/authproxy"
+	"example.com/middleend/backend"
+	"example.com/middleend/db"
+	"example.com/middleend/localstore"
+	"github.com/gorilla/mux"
+)
+
+const planDigURL = "/v2/projects/p1/composite-apps/app/v1/deployment-intent-groups/d1"
+
+// fakeEMCO replies the GETs of the objects it has, the middleend does
+// not send it anything else in a dry run
+type fakeEMCO struct {
+	t       *testing.T
+	objects map[string]string
+}
+
+func (f *fakeEMCO) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		f.t.Errorf("the dry run sent %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, ok := f.objects[r.URL.Path]
+	if !ok {
+		f.t.Logf("EMCO has no %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(body))
+}
+
+// newPlanServer returns a server of the EMCO fake, with the DIG d1 of the
+// application app v1 instantiated in EMCO and checked out for an update
+// of the placement of its app a1
+func newPlanServer(t *testing.T, emco *fakeEMCO) *Server {
+	ts := httptest.NewServer(emco)
+	t.Cleanup(ts.Close)
+	addr := strings.TrimPrefix(ts.URL, "http://")
+	conf := MiddleendConfig{OrchService: addr, OvnService: addr, Dcm: addr, Clm: addr, Gac: addr, Dtc: addr}
+	client, err := backend.NewClient(conf.Backend, conf.services())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.DBconn = db.NewMemoryStore()
+	ctx := context.Background()
+	dig := localstore.DeploymentIntentGroup{
+		MetaData: localstore.DepMetaData{Name: "d1", UserData1: "update"},
+		Spec:     localstore.DepSpecData{Version: "r1", LogicalCloud: "lc1"},
+	}
+	if _, err := localstore.NewDeploymentIntentGroupClient(ctx).CreateDeploymentIntentGroup(dig, "p1", "app", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	intents := localstore.Intent{
+		MetaData: localstore.IntentMetaData{Name: "d1_intents"},
+		Spec:     localstore.IntentSpecData{Intent: map[string]string{"genericPlacementIntent": "app_gpint"}},
+	}
+	if _, err := localstore.NewIntentClient(ctx).AddIntent(intents, "p1", "app", "v1", "d1"); err != nil {
+		t.Fatal(err)
+	}
+	gpint := localstore.GenericPlacementIntent{MetaData: localstore.GenIntentMetaData{Name: "app_gpint"}}
+	if _, err := localstore.NewGenericPlacementIntentClient(ctx).CreateGenericPlacementIntent(gpint, "p1", "app", "v1", "d1"); err != nil {
+		t.Fatal(err)
+	}
+	pint := localstore.AppIntent{
+		MetaData: localstore.MetaData{Name: "a1_pint", UserData1: "updated"},
+		Spec: localstore.SpecData{AppName: "a1", Intent: localstore.IntentStruc{
+			AllOfArray: []localstore.AllOf{{ProviderName: "prov", ClusterName: "c2"}},
+		}},
+	}
+	if _, err := localstore.NewAppIntentClient(ctx).CreateAppIntent(pint, "p1", "app", "v1", "app_gpint", "d1"); err != nil {
+		t.Fatal(err)
+	}
+	return &Server{client: client, conf: conf}
+}
+
+// emcoDIG are the objects of the DIG d1 in EMCO, with a1 placed on c1 by
+// the generic placement intent gpint
+func emcoDIG(gpint string) map[string]string {
+	gpintURL := planDigURL + "/generic-placement-intents/" + gpint
+	return map[string]string{
+		"/v2/projects/p1":                                                        `{"metadata":{"name":"p1"}}`,
+		"/v2/projects/p1/composite-apps/app/v1":                                  `{"metadata":{"name":"app"},"spec":{"compositeAppVersion":"v1"}}`,
+		"/v2/projects/p1/composite-apps/app/v1/apps":                             `[{"metadata":{"name":"a1"}}]`,
+		"/v2/projects/p1/composite-apps/app/v1/composite-profiles":               `[{"metadata":{"name":"prof"}}]`,
+		"/v2/projects/p1/composite-apps/app/v1/composite-profiles/prof/profiles": `[]`,
+		planDigURL:              `{"metadata":{"name":"d1"},"spec":{"version":"r1","logicalCloud":"lc1"}}`,
+		planDigURL + "/status":  `{"states":{"actions":[{"state":"Instantiated"}]}}`,
+		planDigURL + "/intents": `{"intent":[{"genericPlacementIntent":"` + gpint + `"}]}`,
+		planDigURL + "/generic-placement-intents":                   `[{"metadata":{"name":"` + gpint + `"}}]`,
+		planDigURL + "/network-controller-intent":                   `[]`,
+		planDigURL + "/generic-k8s-intents/app_genk8sint/resources": `[]`,
+		gpintURL:                          `{"metadata":{"name":"` + gpint + `"}}`,
+		gpintURL + "/app-intents":         `[{"metadata":{"name":"a1_pint"},"spec":{"app":"a1","intent":{"allOf":[{"clusterProvider":"prov","cluster":"c1"}]}}}]`,
+		gpintURL + "/app-intents/a1_pint": `{"metadata":{"name":"a1_pint"},"spec":{"app":"a1","intent":{"allOf":[{"clusterProvider":"prov","cluster":"c1"}]}}}`,
+	}
+}
+
+func TestPlanDIGSubmit(t *testing.T) {
+	tests := []struct {
+		name string
+		// gpint is the placement intent of the DIG in EMCO
+		gpint   string
+		blocked bool
+		calls   []string
+	}{
+		{
+			name:  "intent replaced",
+			gpint: "app_gpint",
+			calls: []string{"delete app-intents a1_pint", "create app-intents a1_pint", "update deployment-intent-groups d1"},
+		},
+		{
+			// the intent is created under the placement intent of the
+			// checkout, which EMCO does not have
+			name:    "placement intent renamed in EMCO",
+			gpint:   "other_gpint",
+			blocked: true,
+			calls:   []string{"delete app-intents a1_pint", "create app-intents a1_pint"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emco := &fakeEMCO{t: t, objects: emcoDIG(tt.gpint)}
+			s := newPlanServer(t, emco)
+			ctx := authproxy.NewContext(context.Background(), &authproxy.Claims{Subject: "alice"})
+			r := httptest.NewRequest(http.MethodGet, "/middleend/projects/p1/composite-apps/app/v1/deployment-intent-groups/d1/checkout/plan", nil)
+			r = mux.SetURLVars(r.WithContext(ctx), map[string]string{
+				"projectName":               "p1",
+				"compositeAppName":          "app",
+				"version":                   "v1",
+				"deploymentIntentGroupName": "d1",
+			})
+
+			result, err := s.newHandler(ctx).PlanDIGSubmit(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan := result.(*SubmitPlan)
+			if len(plan.Changes) != 1 || plan.Changes[0].From != "c1" || plan.Changes[0].To != "c2" {
+				t.Errorf("changes %+v", plan.Changes)
+			}
+			if blocked := plan.Blocked != ""; blocked != tt.blocked {
+				t.Errorf("blocked %q", plan.Blocked)
+			}
+			var calls []string
+			for _, call := range plan.Calls {
+				calls = append(calls, call.Action+" "+call.Kind+" "+call.Name)
+			}
+			if strings.Join(calls, ", ") != strings.Join(tt.calls, ", ") {
+				t.Errorf("calls %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
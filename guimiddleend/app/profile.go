@@ -117,7 +117,7 @@ func (h *ProfileHandler) getEMCOObject() error {
 		compositeAppMetadata := compositeAppValue.Metadata.Metadata
 		CompositeAppSpec := compositeAppValue.Metadata.Spec
 
-		url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 			"/" + CompositeAppSpec.Version + "/composite-profiles"
 		for profileName, profileValue := range compositeAppValue.ProfileDataArray {
@@ -128,16 +128,16 @@ func (h *ProfileHandler) getEMCOObject() error {
 				defer wg.Done()
 				URL := url + "/" + profileName + "/profiles"
 				reply, err := orch.apiGet(URL, compositeAppMetadata.Name+"_getprofiles")
-				log.Infof("Get app profiles status: %d", reply.StatusCode)
+				h.orchInstance.Logger.Infof("Get app profiles status: %d", reply.StatusCode)
 				if err != nil {
 					err = fmt.Errorf("Failed to read profile %s, error %s", profileName, err)
-					log.Errorf(err.Error())
+					h.orchInstance.Logger.Errorf(err.Error())
 					ERR.Error(err)
 					return
 				}
 				var profileList []ProfileMeta
 				if err := json.Unmarshal(reply.Data, &profileList); err != nil {
-					log.Error(err)
+					h.orchInstance.Logger.Error(err)
 					ERR.Error(err)
 					return
 				}
@@ -150,7 +150,7 @@ func (h *ProfileHandler) getEMCOObject() error {
 						profileValue.AppProfiles[appProfileIndex] = appProfile
 						if h.orchInstance.treeFilter.compositeAppMultiPart {
 							URL := URL + "/" + profileName + "/profiles/" + appProfile.Metadata.Name
-							log.Debugf("composite profile object URL multipart: %s", URL)
+							h.orchInstance.Logger.Debugf("composite profile object URL multipart: %s", URL)
 							_, data, _ := h.orchInstance.apiGetMultiPart(URL, "_getAppProfileMultiPart")
 							profileValue.Lock()
 							profileValue.AppProfiles[appProfileIndex].Metadata.ChartContent = base64.StdEncoding.EncodeToString(data)
@@ -181,19 +181,19 @@ func (h *ProfileHandler) getAnchor() error {
 		wg.Add(1)
 		go func(compositeAppMetadata apiMetaData, CompositeAppSpec compositeAppSpec) {
 			defer wg.Done()
-			url := "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+			url := orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 				vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 				"/" + CompositeAppSpec.Version + "/composite-profiles"
 
 			reply, err := orch.apiGet(url, compositeAppMetadata.Name+"_getcprofile")
 			if err != nil {
-				log.Errorf("Failed to get composite profiles\n")
+				h.orchInstance.Logger.Errorf("Failed to get composite profiles\n")
 				ERR.Error(err)
 				return
 			}
 			var profilemetaList []ProfileMeta
 			if err := json.Unmarshal(reply.Data, &profilemetaList); err != nil {
-				log.Error(err)
+				h.orchInstance.Logger.Error(err)
 				ERR.Error(err)
 				return
 			}
@@ -221,14 +221,14 @@ func (h *ProfileHandler) deleteObject() interface{} {
 		}
 		compositeAppMetadata := compositeAppValue.Metadata.Metadata
 		compositeAppSpec := compositeAppValue.Metadata.Spec
-		h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 			"/" + compositeAppSpec.Version + "/composite-profiles/"
 		for profileName, profileValue := range compositeAppValue.ProfileDataArray {
 			for _, appProfileValue := range profileValue.AppProfiles {
 				url := h.orchURL + profileName + "/profiles/" + appProfileValue.Metadata.Name
 
-				log.Infof("Delete app profiles %s", url)
+				h.orchInstance.Logger.Infof("Delete app profiles %s", url)
 				resp, err := orch.apiDel(url, compositeAppMetadata.Name+"_delappProfiles")
 				if err != nil {
 					return err
@@ -236,7 +236,7 @@ func (h *ProfileHandler) deleteObject() interface{} {
 				if resp != http.StatusNoContent {
 					return resp
 				}
-				log.Infof("Delete profiles status: %d", resp)
+				h.orchInstance.Logger.Infof("Delete profiles status: %d", resp)
 			}
 		}
 	}
@@ -253,13 +253,13 @@ func (h *ProfileHandler) deleteAnchor() interface{} {
 		}
 		compositeAppMetadata := compositeAppValue.Metadata.Metadata
 		compositeAppSpec := compositeAppValue.Metadata.Spec
-		h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps/" + compositeAppMetadata.Name +
 			"/" + compositeAppSpec.Version + "/composite-profiles/"
 
 		for profileName := range compositeAppValue.ProfileDataArray {
 			url := h.orchURL + profileName
-			log.Infof("Delete profile %s", url)
+			h.orchInstance.Logger.Infof("Delete profile %s", url)
 			resp, err := orch.apiDel(url, compositeAppMetadata.Name+"_delProfile")
 			if err != nil {
 				return err
@@ -267,7 +267,7 @@ func (h *ProfileHandler) deleteAnchor() interface{} {
 			if resp != http.StatusNoContent {
 				return resp
 			}
-			log.Infof("Delete profile status: %d", resp)
+			h.orchInstance.Logger.Infof("Delete profile status: %d", resp)
 		}
 	}
 	return nil
@@ -286,7 +286,7 @@ func (h *ProfileHandler) createAnchor() interface{} {
 		},
 	}
 	jsonLoad, _ := json.Marshal(profileCreate)
-	h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		vars["projectName"] + "/composite-apps"
 	url := h.orchURL + "/" + vars["compositeAppName"] + "/" + vars["version"] + "/composite-profiles"
 	resp, err := orch.apiPost(jsonLoad, url, vars["compostie-app-name"]+"_profile")
@@ -296,7 +296,7 @@ func (h *ProfileHandler) createAnchor() interface{} {
 	if resp != http.StatusCreated {
 		return resp
 	}
-	log.Infof("ProfileHandler response: %d", resp)
+	h.orchInstance.Logger.Infof("ProfileHandler response: %d", resp)
 
 	return nil
 }
@@ -328,7 +328,7 @@ func (h *ProfileHandler) createObject() interface{} {
 
 		url := h.orchURL + "/" + vars["compositeAppName"] + "/" + vars["version"] + "/" +
 			"composite-profiles" + "/" + compositeProfilename + "/profiles"
-		log.Debugf("profileAdd is: %s", profileAdd)
+		h.orchInstance.Logger.Debugf("profileAdd is: %s", profileAdd)
 		jsonLoad, _ := json.Marshal(profileAdd)
 		orch.response.lastKey = profileName
 		var fileNames []string
@@ -342,7 +342,7 @@ func (h *ProfileHandler) createObject() interface{} {
 		if status != http.StatusCreated {
 			return status
 		}
-		log.Infof("CompositeProfile profile %s status: %d url: %s", profileName, status, url)
+		h.orchInstance.Logger.Infof("CompositeProfile profile %s status: %d url: %s", profileName, status, url)
 	}
 
 	return nil
@@ -18,8 +18,6 @@ package app
 import (
 	"encoding/json"
 	"net/http"
-
-	log "github.com/sirupsen/logrus"
 )
 
 // CompositeApp application structure
@@ -45,7 +43,7 @@ func (h *projectHandler) getMiddleEndObject() error {
 	}
 	caList, err := orch.GetDraftCompositeApplication(key, "depthAll")
 	if err != nil {
-		log.Errorf("Encountered error while fetching composite app from middleend collection: %s", err)
+		h.orchInstance.Logger.Errorf("Encountered error while fetching composite app from middleend collection: %s", err)
 		return err
 	}
 
@@ -84,10 +82,10 @@ func (h *projectHandler) getEMCOObject() error {
 	dataRead.compositeAppMap = make(map[string]*CompositeAppTree)
 	if orch.treeFilter != nil && orch.treeFilter.compositeAppName != "" {
 		temp := CompositeApp{}
-		h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps/" + orch.treeFilter.compositeAppName + "/" +
 			orch.treeFilter.compositeAppVersion
-		log.Debugf("composite app URL project: %s", h.orchURL)
+		h.orchInstance.Logger.Debugf("composite app URL project: %s", h.orchURL)
 		reply, err := orch.apiGet(h.orchURL, vars["projectName"]+"_getcapps")
 		if err != nil {
 			return err
@@ -98,7 +96,7 @@ func (h *projectHandler) getEMCOObject() error {
 		}
 		cappList = append(cappList, temp)
 	} else {
-		h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+		h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 			vars["projectName"] + "/composite-apps"
 		reply, err := orch.apiGet(h.orchURL, vars["projectName"]+"_getcapps")
 		if err != nil {
@@ -110,7 +108,7 @@ func (h *projectHandler) getEMCOObject() error {
 	}
 
 	for k, value := range cappList {
-		log.Infof("Composite app: %+v", cappList[k])
+		h.orchInstance.Logger.Infof("Composite app: %+v", cappList[k])
 		var cappsDataInstance CompositeAppTree
 		cappName := value.Metadata.Name
 		cappVersion := value.Spec.Version
@@ -125,9 +123,9 @@ func (h *projectHandler) getAnchor() error {
 	orch := h.orchInstance
 	vars := orch.Vars
 	dataRead := h.orchInstance.dataRead
-	h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		vars["projectName"]
-	log.Debugf("projectURL: %s", h.orchURL)
+	h.orchInstance.Logger.Debugf("projectURL: %s", h.orchURL)
 	reply, err := orch.apiGet(h.orchURL, vars["projectName"]+"_getProject")
 	if err != nil {
 		return err
@@ -141,13 +139,13 @@ func (h *projectHandler) deleteObject() interface{} {
 	vars := orch.Vars
 	dataRead := h.orchInstance.dataRead
 	cappList := dataRead.compositeAppMap
-	h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" +
+	h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" +
 		vars["projectName"] + "/composite-apps"
 	for compositeAppName, compositeAppValue := range cappList {
 		url := h.orchURL + "/" + compositeAppName + "/" + compositeAppValue.Metadata.Spec.Version
-		log.Debugf("Delete composite app %s", url)
+		h.orchInstance.Logger.Debugf("Delete composite app %s", url)
 		resp, err := orch.apiDel(url, compositeAppName+"_delcapp")
-		log.Debugf("Delete composite app status: %d", resp)
+		h.orchInstance.Logger.Debugf("Delete composite app status: %d", resp)
 		if err != nil {
 			return err
 		}
@@ -161,8 +159,8 @@ func (h *projectHandler) deleteObject() interface{} {
 func (h *projectHandler) deleteAnchor() interface{} {
 	orch := h.orchInstance
 	vars := orch.Vars
-	h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + vars["projectName"]
-	log.Debugf("Delete Project %s", h.orchURL)
+	h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + vars["projectName"]
+	h.orchInstance.Logger.Debugf("Delete Project %s", h.orchURL)
 	resp, err := orch.apiDel(h.orchURL, vars["projectName"]+"_delProject")
 	if err != nil {
 		return err
@@ -170,7 +168,7 @@ func (h *projectHandler) deleteAnchor() interface{} {
 	if resp != http.StatusNoContent {
 		return resp
 	}
-	log.Debugf("Delete Project status: %d", resp)
+	h.orchInstance.Logger.Debugf("Delete Project status: %d", resp)
 	return nil
 }
 
@@ -181,14 +179,14 @@ func (h *projectHandler) createAnchor() interface{} {
 	projectCreate := ProjectMetadata{
 		Metadata: apiMetaData{
 			Name:        vars["projectName"],
-			Description: vars["description"],
+			Description: orch.state.description,
 			UserData1:   "data 1",
 			UserData2:   "data 2",
 		},
 	}
 
 	jsonLoad, _ := json.Marshal(projectCreate)
-	h.orchURL = "http://" + orch.MiddleendConf.OrchService + "/v2/projects/" + vars["projectName"]
+	h.orchURL = orch.MiddleendConf.serviceURL("orchestrator") + "/v2/projects/" + vars["projectName"]
 	resp, err := orch.apiPost(jsonLoad, h.orchURL, vars["projectName"])
 	if err != nil {
 		return err
@@ -197,7 +195,7 @@ func (h *projectHandler) createAnchor() interface{} {
 		return resp
 	}
 	orch.Vars["version"] = "v1"
-	log.Infof("projectHandler response: %d", resp)
+	h.orchInstance.Logger.Infof("projectHandler response: %d", resp)
 
 	return nil
 }
@@ -2,7 +2,6 @@ package app
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
@@ -58,7 +57,7 @@ func (h *OrchestrationHandler) processResourceData(w http.ResponseWriter, r *htt
 				count += 1
 				files := r.MultipartForm.File[tag]
 				if len(files) == 0 {
-					log.Errorf("Unable to fetch file from multipart request key %s", tag)
+					h.Logger.Errorf("Unable to fetch file from multipart request key %s", tag)
 					return false
 				}
 				fileName = files[0].Filename
@@ -66,7 +65,7 @@ func (h *OrchestrationHandler) processResourceData(w http.ResponseWriter, r *htt
 
 				// Validate resourceGVK and fileContent
 				if fileContent == "" || resGVK.APIVersion == "" || resGVK.Kind == "" || resGVK.Name == "" {
-					log.Errorf("Unable to fetch fileContent or APIVersion/Kind/Name from resource object")
+					h.Logger.Errorf("Unable to fetch fileContent or APIVersion/Kind/Name from resource object")
 					return false
 				}
 
@@ -75,12 +74,12 @@ func (h *OrchestrationHandler) processResourceData(w http.ResponseWriter, r *htt
 				h.DigData.Spec.Apps[i].RsInfo[j].ResourceSpec.ResourceGVK.Name = resGVK.Name
 				resObj = h.DigData.Spec.Apps[i].RsInfo[j]
 
-				log.Infof("Resource kind is: %s", resGVK.Kind)
+				h.Logger.Infof("Resource kind is: %s", resGVK.Kind)
 				if strings.ToLower(resGVK.Kind) == "configmap" || strings.ToLower(resGVK.Kind) == "secret" {
 					byteContent, _ := base64.StdEncoding.DecodeString(fileContent)
 					fileNameArray, contentArray, cmEnvContent = h.ProcessConfigMapSecret(byteContent)
-					log.Debugf("fileNameArray: %+v", fileNameArray)
-					log.Debugf("contentArray: %+v", contentArray)
+					h.Logger.Debugf("fileNameArray: %+v", fileNameArray)
+					h.Logger.Debugf("contentArray: %+v", contentArray)
 					if cmEnvContent != "" {
 						contentArray = append(contentArray, cmEnvContent)
 						fileNameArray = append(fileNameArray, fileName)
@@ -97,18 +96,18 @@ func (h *OrchestrationHandler) processResourceData(w http.ResponseWriter, r *htt
 
 			// Validation to ensure cluster info is present when customization spec clusterspecific field is set to true
 			if strings.ToLower(resObj.CustomizationSpec.ClusterSpecific) == "true" && (localstore.ClusterInfo{}) == resObj.CustomizationSpec.ClusterInfo {
-				log.Error(":: ClusterInfo missing when ClusterSpecific is true ::", log.Fields{})
+				h.Logger.Error(":: ClusterInfo missing when ClusterSpecific is true ::", log.Fields{})
 				return false
 			}
 
 			if strings.ToLower(resObj.CustomizationSpec.ClusterSpecific) == "true" && strings.ToLower(resObj.CustomizationSpec.ClusterInfo.Scope) == "label" && resObj.CustomizationSpec.ClusterInfo.ClusterLabel == "" {
-				log.Error(":: ClusterLabel missing when ClusterSpecific is true and  ClusterScope is label::", log.Fields{})
+				h.Logger.Error(":: ClusterLabel missing when ClusterSpecific is true and  ClusterScope is label::", log.Fields{})
 				w.WriteHeader(http.StatusBadRequest)
 				return false
 			}
 
 			if strings.ToLower(resObj.CustomizationSpec.ClusterSpecific) == "true" && strings.ToLower(resObj.CustomizationSpec.ClusterInfo.Scope) == "name" && resObj.CustomizationSpec.ClusterInfo.ClusterName == "" {
-				log.Error(":: ClusterName missing when ClusterSpecific is true and  ClusterScope is name::", log.Fields{})
+				h.Logger.Error(":: ClusterName missing when ClusterSpecific is true and  ClusterScope is name::", log.Fields{})
 				w.WriteHeader(http.StatusBadRequest)
 				return false
 			}
@@ -123,8 +122,9 @@ func (h *OrchestrationHandler) processResourceData(w http.ResponseWriter, r *htt
 	return true
 }
 
-// Creates a GAC resource and apply the required customization
-func (h *OrchestrationHandler) createUpdateK8sResource(w http.ResponseWriter, storeType string) {
+// Creates a GAC resource and apply the required customization, false when
+// it failed and replied the error
+func (h *OrchestrationHandler) createUpdateK8sResource(w http.ResponseWriter, storeType string) bool {
 	if storeType == "emco" {
 		bstore := &remoteStoreIntentHandler{}
 		bstore.orchInstance = h
@@ -146,18 +146,24 @@ func (h *OrchestrationHandler) createUpdateK8sResource(w http.ResponseWriter, st
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
-		// Rollback DIG
-		retCode, _ := h.DeleteDig("remote")
-		if retCode != http.StatusNoContent {
-			log.Errorf("Rollback of DIG failed...")
+		// Rollback DIG, the changes of a checkout are rolled back with the
+		// transaction it is saved in
+		if storeType == "emco" {
+			retCode, _ := h.DeleteDig("remote")
+			if retCode != http.StatusNoContent {
+				h.Logger.Errorf("Rollback of DIG failed...")
+			}
 		}
+		return false
 	}
+	return true
 }
 
-// Fetches all GAC resources belonging to a given composite application
+// K8sResources returns the GAC resources of the DIG with their
+// customizations, by resource
 // GET /projects/{projectName}/composite-apps/{compositeAppName}/{version}/deployment-intent-groups/{deploymentIntentGroupName}/resources
-func (h *OrchestrationHandler) GetK8sResources(w http.ResponseWriter, r *http.Request) {
-	h.Vars = mux.Vars(r)
+func (h *OrchestrationHandler) K8sResources(req digRequest) (map[string][]localstore.Customization, error) {
+	h.Vars = req.vars()
 	bstore := &remoteStoreIntentHandler{}
 	bstore.orchInstance = h
 	h.bstore = bstore
@@ -166,26 +172,13 @@ func (h *OrchestrationHandler) GetK8sResources(w http.ResponseWriter, r *http.Re
 	// Call workflow for fetching genericK8s intent resource and customization EMCO objects
 	gk8sHandler := &genericK8sIntentHandler{}
 	gk8sHandler.orchInstance = h
-	err := getGenericK8sIntent(gk8sHandler)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err := getGenericK8sIntent(gk8sHandler); err != nil {
+		return nil, err
 	}
 
-	compositeAppName := h.Vars["compositeAppName"]
-	genK8sInfo := h.genK8sInfo[compositeAppName+"_genk8sint"]
-	log.Debugf("genK8sInfo: %+v", genK8sInfo)
-
-	var retval []byte
-	retval, err = json.Marshal(genK8sInfo.listGenK8sData.resMap)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(retval); err != nil {
-		log.Error(err, PrintFunctionName())
-	}
+	genK8sInfo := h.genK8sInfo[req.CompositeApp+"_genk8sint"]
+	h.Logger.Debugf("genK8sInfo: %+v", genK8sInfo)
+	return genK8sInfo.listGenK8sData.resMap, nil
 }
 
 // Deletes given GAC resource belonging to a given composite application
@@ -199,7 +192,7 @@ func (h *OrchestrationHandler) DeleteK8sResources(w http.ResponseWriter, r *http
 
 	retcode, _ := h.bstore.deleteResource(h.Vars["resourceName"], h.Vars["projectName"], h.Vars["compositeAppName"],
 		h.Vars["version"], h.Vars["deploymentIntentGroupName"], h.Vars["compositeAppName"]+"_genk8sint")
-	log.Infof("DeleteK8sResources response: %d", retcode.(int))
+	h.Logger.Infof("DeleteK8sResources response: %d", retcode.(int))
 
 	w.WriteHeader(retcode.(int))
 }
@@ -215,7 +208,7 @@ func (h *OrchestrationHandler) DeleteK8sResourceCustomizations(w http.ResponseWr
 
 	retcode, _ := h.bstore.deleteCustomization(h.Vars["customizationName"], h.Vars["projectName"], h.Vars["compositeAppName"],
 		h.Vars["version"], h.Vars["deploymentIntentGroupName"], h.Vars["compositeAppName"]+"_genk8sint", h.Vars["resourceName"])
-	log.Infof("DeleteK8sResourceCustomizations response: %d", retcode.(int))
+	h.Logger.Infof("DeleteK8sResourceCustomizations response: %d", retcode.(int))
 
 	w.WriteHeader(retcode.(int))
 }
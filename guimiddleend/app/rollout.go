@@ -0,0 +1,774 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/middleend/db"
+	"example.com/middleend/localstore"
+	"github.com/gorilla/mux"
+)
+
+// Rollout states
+const (
+	RolloutRunning    = "running"
+	RolloutPaused     = "paused"
+	RolloutCompleted  = "completed"
+	RolloutRolledBack = "rolledBack"
+)
+
+// Wave states
+const (
+	wavePending   = "pending"
+	waveRunning   = "running"
+	waveCompleted = "completed"
+	waveFailed    = "failed"
+)
+
+const (
+	// defaultWaveTimeout is the time in seconds the resources of a wave
+	// have to be applied when the rollout sets no waveTimeout
+	defaultWaveTimeout = 600
+	// rolloutPollInterval is the time between two reads of the status of
+	// the DIG while a wave is watched
+	rolloutPollInterval = 10 * time.Second
+	// remainingWave applies the checkout to the clusters no wave named
+	remainingWave = "remaining"
+	// the deployed status of the resources of a cluster
+	resourceApplied = "Applied"
+	resourceFailed  = "Failed"
+)
+
+// RolloutWave is a stage of a rollout, the clusters of the placement
+// intents it names or which carry one of its labels
+type RolloutWave struct {
+	Name     string   `json:"name" binding:"required"`
+	Clusters []string `json:"clusters,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// RolloutRequest rolls the checkout of a DIG out wave by wave. A wave
+// places the apps of the checkout on its clusters, the clusters of the
+// later waves keep their deployed placement. The network intents and the
+// resources apply to the whole DIG in EMCO, they change with the first
+// wave.
+type RolloutRequest struct {
+	Waves []RolloutWave `json:"waves" binding:"required"`
+	// WaveTimeout is the time in seconds the resources of a wave have to
+	// be applied, 600 by default
+	WaveTimeout int `json:"waveTimeout,omitempty"`
+	// OnFailure is pause, the default, which keeps the waves applied so
+	// far and the checkout to resume from, or rollback, which rolls the
+	// DIG back to its revision before the rollout
+	OnFailure string `json:"onFailure,omitempty"`
+}
+
+func (r *RolloutRequest) validate() error {
+	if len(r.Waves) == 0 {
+		return fmt.Errorf("a rollout needs a wave at least")
+	}
+	names := map[string]bool{remainingWave: true}
+	for _, wave := range r.Waves {
+		if names[wave.Name] || wave.Name == "" {
+			return fmt.Errorf("wave name %q is empty, reserved or used twice", wave.Name)
+		}
+		names[wave.Name] = true
+		if len(wave.Clusters) == 0 && len(wave.Labels) == 0 {
+			return fmt.Errorf("wave %s names no cluster and no label", wave.Name)
+		}
+	}
+	if r.WaveTimeout < 0 {
+		return fmt.Errorf("waveTimeout must be positive")
+	}
+	if r.OnFailure != "" && r.OnFailure != "pause" && r.OnFailure != "rollback" {
+		return fmt.Errorf("onFailure must be pause or rollback, not %s", r.OnFailure)
+	}
+	return nil
+}
+
+func (r *RolloutRequest) timeout() time.Duration {
+	seconds := r.WaveTimeout
+	if seconds == 0 {
+		seconds = defaultWaveTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// WaveStatus is the progress of a wave. Clusters are those watched,
+// NotReady the resources which were not applied when the wave ended.
+type WaveStatus struct {
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Clusters []string  `json:"clusters,omitempty"`
+	NotReady []string  `json:"notReady,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// DigRollout is the rollout of the checkout of a DIG, kept in the DIG info
+// so that a paused one resumes. The intents of the DIG before the rollout
+// and those of the checkout are kept as json, the rollout computes the
+// intents of its waves from them.
+type DigRollout struct {
+	CompositeAppVersion string         `json:"compositeAppVersion"`
+	Request             RolloutRequest `json:"request"`
+	Status              string         `json:"status"`
+	Reason              string         `json:"reason,omitempty"`
+	// Wave is the index of the next wave to apply
+	Wave  int          `json:"wave"`
+	Waves []WaveStatus `json:"waves"`
+	// FromRevision is the revision of the DIG before the rollout, the
+	// one a rollback restores
+	FromRevision int `json:"fromRevision"`
+	// Operation runs the rollout
+	Operation string          `json:"operation,omitempty"`
+	Started   time.Time       `json:"started"`
+	Updated   time.Time       `json:"updated"`
+	Source    json.RawMessage `json:"-"`
+	Target    json.RawMessage `json:"-"`
+}
+
+// waveScope is what the waves up to a wave reach
+type waveScope struct {
+	all      bool
+	clusters map[string]bool
+	labels   map[string]bool
+}
+
+// scope is what the waves up to wave i reach, the remaining wave reaches
+// everything
+func (r *DigRollout) scope(i int) waveScope {
+	s := waveScope{clusters: map[string]bool{}, labels: map[string]bool{}}
+	for j := 0; j <= i && j < len(r.Waves); j++ {
+		if r.Waves[j].Name == remainingWave {
+			s.all = true
+			continue
+		}
+		wave := r.Request.Waves[j]
+		for _, c := range wave.Clusters {
+			s.clusters[c] = true
+		}
+		for _, l := range wave.Labels {
+			s.labels[l] = true
+		}
+	}
+	return s
+}
+
+func (r *DigRollout) intents() (*deployDigData, *deployDigData, error) {
+	source, target := &deployDigData{}, &deployDigData{}
+	if err := json.Unmarshal(r.Source, source); err != nil {
+		return nil, nil, fmt.Errorf("failed to read the intents before the rollout: %s", err)
+	}
+	if err := json.Unmarshal(r.Target, target); err != nil {
+		return nil, nil, fmt.Errorf("failed to read the intents of the checkout: %s", err)
+	}
+	return source, target, nil
+}
+
+// waveIntents are the intents of the checkout with the placement of each
+// app reaching the clusters of the scope, and keeping the deployed one on
+// the others. An app placed on none of the clusters of the scope yet
+// keeps the placement of the checkout, EMCO places every app of a DIG.
+func waveIntents(source, target *deployDigData, scope waveScope) (deployDigData, error) {
+	var wave deployDigData
+	data, err := json.Marshal(target)
+	if err != nil {
+		return wave, err
+	}
+	if err := json.Unmarshal(data, &wave); err != nil {
+		return wave, err
+	}
+	if scope.all {
+		return wave, nil
+	}
+	deployed := map[string][]ClusterInfo{}
+	for _, app := range source.Spec.Apps {
+		deployed[app.Metadata.Name] = app.Clusters
+	}
+	for i := range wave.Spec.Apps {
+		app := &wave.Spec.Apps[i]
+		if placement := mergePlacement(deployed[app.Metadata.Name], app.Clusters, scope); len(placement) > 0 {
+			app.Clusters = placement
+		}
+	}
+	return wave, nil
+}
+
+// mergePlacement takes the clusters and labels of the scope from to, and
+// the others from from
+func mergePlacement(from, to []ClusterInfo, scope waveScope) []ClusterInfo {
+	var providers []string
+	byProvider := map[string]*ClusterInfo{}
+	seen := map[string]bool{}
+	add := func(provider string, cluster *SelectedCluster, label *SelectedLabel) {
+		info, ok := byProvider[provider]
+		if !ok {
+			info = &ClusterInfo{Provider: provider}
+			byProvider[provider] = info
+			providers = append(providers, provider)
+		}
+		switch {
+		case cluster != nil && !seen[provider+"/c/"+cluster.Name]:
+			seen[provider+"/c/"+cluster.Name] = true
+			info.SelectedClusters = append(info.SelectedClusters, *cluster)
+		case label != nil && !seen[provider+"/l/"+label.Name]:
+			seen[provider+"/l/"+label.Name] = true
+			info.SelectedLabels = append(info.SelectedLabels, *label)
+		}
+	}
+	pick := func(placement []ClusterInfo, inScope bool) {
+		for _, p := range placement {
+			for i := range p.SelectedClusters {
+				if scope.clusters[p.SelectedClusters[i].Name] == inScope {
+					add(p.Provider, &p.SelectedClusters[i], nil)
+				}
+			}
+			for i := range p.SelectedLabels {
+				if scope.labels[p.SelectedLabels[i].Name] == inScope {
+					add(p.Provider, nil, &p.SelectedLabels[i])
+				}
+			}
+		}
+	}
+	pick(to, true)
+	pick(from, false)
+
+	merged := make([]ClusterInfo, 0, len(providers))
+	for _, provider := range providers {
+		if info := byProvider[provider]; len(info.SelectedClusters)+len(info.SelectedLabels) > 0 {
+			merged = append(merged, *info)
+		}
+	}
+	return merged
+}
+
+// waveClusters lists the clusters of wave the intents place apps on, the
+// labels of the wave are resolved by clm
+func (h *OrchestrationHandler) waveClusters(wave RolloutWave, intents deployDigData) ([]string, error) {
+	named := map[string]bool{}
+	for _, c := range wave.Clusters {
+		named[c] = true
+	}
+	labels := map[string]bool{}
+	for _, l := range wave.Labels {
+		labels[l] = true
+	}
+	clusters := map[string]bool{}
+	for _, app := range intents.Spec.Apps {
+		for _, p := range app.Clusters {
+			for _, c := range p.SelectedClusters {
+				if named[c.Name] {
+					clusters[c.Name] = true
+				}
+			}
+			for _, l := range p.SelectedLabels {
+				if !labels[l.Name] {
+					continue
+				}
+				names, err := h.labelClusters(p.Provider, l.Name)
+				if err != nil {
+					return nil, err
+				}
+				for _, name := range names {
+					clusters[name] = true
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// labelClusters lists the clusters of the provider carrying label
+func (h *OrchestrationHandler) labelClusters(provider, label string) ([]string, error) {
+	url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" +
+		provider + "/clusters?label=" + label
+	reply, err := h.apiGet(url, provider)
+	if err != nil {
+		return nil, err
+	}
+	if reply.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list the clusters of label %s, status %d", label, reply.StatusCode)
+	}
+	var names []string
+	if err := json.Unmarshal(reply.Data, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// waveHealth reads the deployed status of the DIG and returns the
+// resources of clusters which are not applied yet and those which
+// failed. All the clusters are watched when clusters is nil. The status
+// is not ready before EMCO reached a revision after since.
+func (h *OrchestrationHandler) waveHealth(clusters []string, since int) ([]string, []string, error) {
+	dStore := &remoteStoreDigHandler{}
+	dStore.orchInstance = h
+	status, err := dStore.getDigStatus(h.digURL()+"/status", h.Vars["compositeAppName"]+"_digpStatus",
+		[][]string{{"status", "deployed"}})
+	if err != nil {
+		return nil, nil, err
+	}
+	if revision := currentRevision(status.States.Actions); revision <= since {
+		return []string{fmt.Sprintf("DIG is at revision %d, waiting for the update", revision)}, nil, nil
+	}
+
+	watched := map[string]bool{}
+	for _, c := range clusters {
+		watched[c] = false
+	}
+	var notReady, failed []string
+	for _, app := range status.Apps {
+		for _, cluster := range app.Clusters {
+			if seen, ok := watched[cluster.Cluster]; clusters != nil && !ok {
+				continue
+			} else if !seen {
+				watched[cluster.Cluster] = true
+			}
+			if len(cluster.Resources) == 0 {
+				notReady = append(notReady, fmt.Sprintf("%s/%s: no resources", cluster.Cluster, app.Name))
+			}
+			for _, res := range cluster.Resources {
+				name := fmt.Sprintf("%s/%s/%s/%s: %s", cluster.Cluster, app.Name, res.GVK.Kind, res.Name, res.DeployedStatus)
+				switch res.DeployedStatus {
+				case resourceApplied:
+				case resourceFailed:
+					failed = append(failed, name)
+				default:
+					notReady = append(notReady, name)
+				}
+			}
+		}
+	}
+	for _, c := range clusters {
+		if !watched[c] {
+			notReady = append(notReady, c+": no status")
+		}
+	}
+	return notReady, failed, nil
+}
+
+// watchWave waits for the resources of the clusters to be applied, until
+// the timeout
+func (h *OrchestrationHandler) watchWave(ws *WaveStatus, clusters []string, since int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		time.Sleep(rolloutPollInterval)
+		notReady, failed, err := h.waveHealth(clusters, since)
+		if err != nil {
+			h.Logger.Warnf("Failed to read the status of DIG %s: %s", h.Vars["deploymentIntentGroupName"], err)
+		}
+		ws.NotReady = append(failed, notReady...)
+		switch {
+		case len(failed) > 0:
+			return fmt.Errorf("%d resource(s) failed: %s", len(failed), strings.Join(failed, ", "))
+		case err == nil && len(notReady) == 0:
+			return nil
+		case h.srv != nil && h.srv.isShuttingDown():
+			return fmt.Errorf("the middleend is shutting down")
+		case time.Now().After(deadline):
+			if err != nil {
+				return fmt.Errorf("timed out after %s: %s", timeout, err)
+			}
+			return fmt.Errorf("timed out after %s, %d resource(s) not applied", timeout, len(notReady))
+		}
+	}
+}
+
+// applyWave plays the intents of the wave to EMCO and updates the DIG,
+// then watches the clusters of the wave
+func (h *OrchestrationHandler) applyWave(ws *WaveStatus, wave RolloutWave, intents deployDigData, timeout time.Duration) error {
+	step := func(name string) func(err error) {
+		return h.opStep(ws.Name + ":" + name)
+	}
+
+	var clusters []string
+	if ws.Name != remainingWave {
+		var err error
+		if clusters, err = h.waveClusters(wave, intents); err != nil {
+			return err
+		}
+		ws.Clusters = clusters
+	}
+
+	done := step("saveIntents")
+	err := h.saveCheckoutIntents(intents.Spec.Apps)
+	done(err)
+	if err != nil {
+		return err
+	}
+
+	done = step("updateIntents")
+	rw := httptest.NewRecorder()
+	err = h.UpdateIntents(rw)
+	if err == nil && rw.Code >= http.StatusBadRequest {
+		err = recorderError(rw, "updating the intents failed")
+	}
+	done(err)
+	if err != nil {
+		return err
+	}
+
+	actions, err := h.digRevisionActions()
+	if err != nil {
+		return err
+	}
+	done = step("update")
+	retcode, err := h.apiPost(nil, h.digURL()+"/update", h.Vars["deploymentIntentGroupName"])
+	err = stepError(retcode.(int), http.StatusAccepted, err)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("update failed: %s", err)
+	}
+
+	if clusters != nil && len(clusters) == 0 {
+		h.opSkip(ws.Name+":watch", "the wave reaches no cluster of the placement intents")
+		return nil
+	}
+	done = step("watch")
+	err = h.watchWave(ws, clusters, currentRevision(actions), timeout)
+	done(err)
+	return err
+}
+
+// saveRollout keeps the rollout in the DIG info
+func (h *OrchestrationHandler) saveRollout(rollout *DigRollout) error {
+	rollout.Updated = time.Now().UTC()
+	key := DigInfoKey{DigName: h.Vars["deploymentIntentGroupName"]}
+	return h.inTransaction(func() error {
+		diginfo, err := h.readDIGInfo()
+		if err != nil {
+			return err
+		}
+		if diginfo.DigName == "" {
+			diginfo.DigName = key.DigName
+			diginfo.VersionList = []string{h.Vars["version"]}
+		}
+		diginfo.Rollout = rollout
+		return db.DBconn.Insert(h.ctx, DIG_INFO_COLLECTION, key, nil, "digmeta", diginfo)
+	})
+}
+
+// readRollout returns the rollout of the DIG in the version of the route,
+// nil when there is none
+func (h *OrchestrationHandler) readRollout() (*DigRollout, error) {
+	diginfo, err := h.readDIGInfo()
+	if err != nil || diginfo.Rollout == nil || diginfo.Rollout.CompositeAppVersion != h.Vars["version"] {
+		return nil, err
+	}
+	return diginfo.Rollout, nil
+}
+
+// endRollout ends a paused rollout of the DIG, the DIG changed since
+func (h *OrchestrationHandler) endRollout(status string, reason string) {
+	rollout, err := h.readRollout()
+	if err == nil && rollout != nil && rollout.Status == RolloutPaused {
+		rollout.Status, rollout.Reason = status, reason
+		err = h.saveRollout(rollout)
+	}
+	if err != nil {
+		h.Logger.Warnf("Failed to end the rollout of DIG %s: %s", h.Vars["deploymentIntentGroupName"], err)
+	}
+}
+
+// runRollout applies the waves of the rollout from its next one. Once
+// they are all applied the checkout is deleted, like a submit; a failed
+// wave pauses the rollout or rolls the DIG back. On failure the reply is
+// written on w.
+func (h *OrchestrationHandler) runRollout(w http.ResponseWriter, rollout *DigRollout) {
+	dig := h.Vars["deploymentIntentGroupName"]
+	source, target, err := rollout.intents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rollout.Status, rollout.Reason = RolloutRunning, ""
+	if h.operation != nil {
+		rollout.Operation = h.operation.snapshot().ID
+	}
+	for ; rollout.Wave < len(rollout.Waves); rollout.Wave++ {
+		ws := &rollout.Waves[rollout.Wave]
+		var wave RolloutWave
+		if ws.Name != remainingWave {
+			wave = rollout.Request.Waves[rollout.Wave]
+		}
+		ws.Status, ws.Error, ws.NotReady = waveRunning, "", nil
+		ws.Started, ws.Finished = time.Now().UTC(), time.Time{}
+		if err := h.saveRollout(rollout); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		intents, err := waveIntents(source, target, rollout.scope(rollout.Wave))
+		if err == nil {
+			err = h.applyWave(ws, wave, intents, rollout.Request.timeout())
+		}
+		ws.Finished = time.Now().UTC()
+		if err != nil {
+			ws.Status, ws.Error = waveFailed, err.Error()
+			h.Logger.Errorf("Wave %s of the rollout of DIG %s failed: %s", ws.Name, dig, err)
+			h.failRollout(w, rollout, fmt.Errorf("wave %s failed: %s", ws.Name, err))
+			return
+		}
+		ws.Status, ws.NotReady = waveCompleted, nil
+	}
+
+	// Delete the checkout like a submit
+	done := h.opStep("deleteCheckout")
+	retcode := http.StatusNoContent
+	err = h.inTransaction(func() error {
+		retcode, _ = h.DeleteDig("local")
+		if retcode != http.StatusNoContent {
+			return fmt.Errorf("failed to delete the checked out DIG, status %d", retcode)
+		}
+		return h.removeLease()
+	})
+	done(err)
+	if err != nil {
+		h.failRollout(w, rollout, err)
+		return
+	}
+	rollout.Status = RolloutCompleted
+	if err := h.saveRollout(rollout); err != nil {
+		h.Logger.Warnf("Failed to record the end of the rollout of DIG %s: %s", dig, err)
+	}
+	h.recordRevisionStep(DigRevision{Operation: "update"})
+	w.WriteHeader(http.StatusOK)
+}
+
+// failRollout pauses the rollout, or rolls the DIG back to its revision
+// before the rollout, and replies err
+func (h *OrchestrationHandler) failRollout(w http.ResponseWriter, rollout *DigRollout, err error) {
+	rollout.Status, rollout.Reason = RolloutPaused, err.Error()
+	if rollout.Request.OnFailure == "rollback" {
+		actions, aerr := h.digRevisionActions()
+		switch {
+		case aerr != nil:
+			rollout.Reason += "; not rolled back: " + aerr.Error()
+		case currentRevision(actions) == rollout.FromRevision:
+			rollout.Reason += "; not rolled back, no wave updated the DIG"
+		default:
+			rw := httptest.NewRecorder()
+			if h.rollbackTo(rw, rollout.FromRevision, "Rollback of a failed rollout") {
+				rollout.Status = RolloutRolledBack
+			} else {
+				rollout.Reason += "; " + recorderError(rw, "rollback failed").Error()
+			}
+		}
+	}
+	if rollout.Status == RolloutPaused {
+		// The checkout holds the intents of the failed wave, it gets those
+		// of the rollout back to be resumed or submitted
+		_, target, terr := rollout.intents()
+		if terr == nil {
+			terr = h.saveCheckoutIntents(target.Spec.Apps)
+		}
+		if terr != nil {
+			h.Logger.Warnf("Failed to restore the checkout of DIG %s: %s", h.Vars["deploymentIntentGroupName"], terr)
+		}
+	}
+	if serr := h.saveRollout(rollout); serr != nil {
+		h.Logger.Warnf("Failed to record the failure of the rollout of DIG %s: %s", h.Vars["deploymentIntentGroupName"], serr)
+	}
+	http.Error(w, fmt.Sprintf("rollout %s: %s", rollout.Status, rollout.Reason), http.StatusInternalServerError)
+}
+
+// RolloutDIG rolls the checkout of the DIG out in waves rather than
+// submitting it at once
+func (h *OrchestrationHandler) RolloutDIG(w http.ResponseWriter, r *http.Request) {
+	h.Vars = mux.Vars(r)
+	h.InitializeResponseMap()
+
+	var req RolloutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid rollout request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.startRollout(w, req)
+}
+
+// startRollout checks the checkout can roll out, snapshots the intents of
+// the DIG and of the checkout, and runs the rollout
+func (h *OrchestrationHandler) startRollout(w http.ResponseWriter, req RolloutRequest) {
+	dig := h.Vars["deploymentIntentGroupName"]
+	if err := req.validate(); err != nil {
+		http.Error(w, "invalid rollout request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	done := h.opStep("checkCheckout")
+	localDigStore := localStoreDigHandler{}
+	localDigStore.orchInstance = h
+	retValue, err := localDigStore.getDig(h.Vars["projectName"],
+		h.Vars["compositeAppName"], h.Vars["version"], dig)
+	checkout := localstore.DeploymentIntentGroup{}
+	if err == nil {
+		err = json.Unmarshal(retValue, &checkout)
+	}
+	if err != nil || checkout.MetaData.Name == "" {
+		err = fmt.Errorf("DIG %s is not checked out in version %s", dig, h.Vars["version"])
+		done(err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if checkout.MetaData.UserData1 != "update" {
+		err = fmt.Errorf("only the checkouts for update roll out in waves, submit a %s", checkout.MetaData.UserData1)
+		done(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.checkLeaseOwner(); err != nil {
+		done(err)
+		h.replyLeaseError(w, err)
+		return
+	}
+	current, err := h.readRollout()
+	if err == nil && current != nil && (current.Status == RolloutRunning || current.Status == RolloutPaused) {
+		err = &apiError{code: http.StatusConflict,
+			msg: fmt.Sprintf("a rollout of DIG %s is %s, resume it or roll the DIG back", dig, current.Status)}
+	}
+	if err != nil {
+		done(err)
+		h.replyLeaseError(w, err)
+		return
+	}
+	actions, err := h.digRevisionActions()
+	if err != nil {
+		done(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(actions) == 0 || actions[len(actions)-1].State != localstore.StateEnum.Instantiated {
+		err = fmt.Errorf("DIG %s is not instantiated", dig)
+		done(err)
+		http.Error(w, err.Error(), http.StatusExpectationFailed)
+		return
+	}
+	done(nil)
+
+	// Keep the intents of the current revision, to roll back to
+	h.recordRevisionStep(DigRevision{})
+
+	done = h.opStep("planWaves")
+	rollout := &DigRollout{
+		CompositeAppVersion: h.Vars["version"],
+		Request:             req,
+		FromRevision:        currentRevision(actions),
+		Started:             time.Now().UTC(),
+	}
+	err = h.readDIGData(httptest.NewRecorder(), "middleend", []string{})
+	target := h.DigData
+	var source *deployDigData
+	if err == nil {
+		source, err = h.currentIntents()
+	}
+	if err == nil {
+		rollout.Target, err = json.Marshal(target)
+	}
+	if err == nil {
+		rollout.Source, err = json.Marshal(source)
+	}
+	for _, wave := range req.Waves {
+		rollout.Waves = append(rollout.Waves, WaveStatus{Name: wave.Name, Status: wavePending})
+	}
+	// The clusters no wave names get the checkout last
+	var last deployDigData
+	var changes []IntentChange
+	if err == nil {
+		last, err = waveIntents(source, &target, rollout.scope(len(req.Waves)-1))
+	}
+	if err == nil {
+		changes, err = diffIntents(&last, &target)
+	}
+	if err == nil && len(changes) > 0 {
+		rollout.Waves = append(rollout.Waves, WaveStatus{Name: remainingWave, Status: wavePending})
+	}
+	if err == nil {
+		err = h.saveRollout(rollout)
+	}
+	done(err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.runRollout(w, rollout)
+}
+
+// ResumeRollout applies the waves of a paused rollout from the one which
+// failed. A rollout left running by a middleend which stopped resumes too.
+func (h *OrchestrationHandler) ResumeRollout(w http.ResponseWriter, r *http.Request) {
+	h.Vars = mux.Vars(r)
+	h.InitializeResponseMap()
+	dig := h.Vars["deploymentIntentGroupName"]
+
+	done := h.opStep("checkRollout")
+	rollout, err := h.readRollout()
+	switch {
+	case err != nil:
+	case rollout == nil:
+		err = &apiError{code: http.StatusNotFound, msg: fmt.Sprintf("DIG %s has no rollout in version %s", dig, h.Vars["version"])}
+	case rollout.Status == RolloutRunning && h.operationActive(rollout.Operation):
+		err = &apiError{code: http.StatusConflict, msg: fmt.Sprintf("the rollout of DIG %s is running in operation %s", dig, rollout.Operation)}
+	case rollout.Status != RolloutPaused && rollout.Status != RolloutRunning:
+		err = &apiError{code: http.StatusConflict, msg: fmt.Sprintf("the rollout of DIG %s is %s", dig, rollout.Status)}
+	default:
+		err = h.checkLeaseOwner()
+	}
+	done(err)
+	if err != nil {
+		h.replyLeaseError(w, err)
+		return
+	}
+	h.runRollout(w, rollout)
+}
+
+// operationActive tells whether the operation runs in this middleend
+func (h *OrchestrationHandler) operationActive(id string) bool {
+	if h.srv == nil || id == "" {
+		return false
+	}
+	_, ok := h.srv.operations.get(id)
+	return ok
+}
+
+// GetRollout returns the rollout of the DIG with the progress of its waves
+func (h *OrchestrationHandler) GetRollout(r *http.Request) (interface{}, error) {
+	h.Vars = mux.Vars(r)
+	rollout, err := h.readRollout()
+	if err != nil {
+		return nil, err
+	}
+	if rollout == nil {
+		return nil, &apiError{code: http.StatusNotFound,
+			msg: fmt.Sprintf("DIG %s has no rollout in version %s", h.Vars["deploymentIntentGroupName"], h.Vars["version"])}
+	}
+	return rollout, nil
+}
@@ -0,0 +1,219 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Step states reported in a sagaReport
+const (
+	stepSucceeded          = "succeeded"
+	stepFailed             = "failed"
+	stepCompensated        = "compensated"
+	stepCompensationFailed = "compensationFailed"
+	stepNotRun             = "notRun"
+)
+
+// Saga states reported in a sagaReport
+const (
+	sagaSucceeded      = "succeeded"
+	sagaRolledBack     = "rolledBack"
+	sagaRollbackFailed = "rollbackFailed"
+)
+
+// sagaStep is a single step of a saga. run and compensate follow the
+// orchWorkflow convention: nil on success, the http status code or an
+// error otherwise. Only steps which succeeded are compensated, unless the
+// step is partial, i.e. it may fail after creating some of its objects.
+// compensate is nil when a step creates nothing or when its objects are
+// removed by the compensation of an earlier step.
+type sagaStep struct {
+	name       string
+	run        func() interface{}
+	compensate func() interface{}
+	partial    bool
+	// payloadKey names the response payload holding the EMCO reply of
+	// the step, it is reported when the step fails.
+	payloadKey string
+}
+
+type sagaStepResult struct {
+	Step              string `json:"step"`
+	Status            string `json:"status"`
+	Code              int    `json:"code,omitempty"`
+	Error             string `json:"error,omitempty"`
+	CompensationError string `json:"compensationError,omitempty"`
+	Took              string `json:"took,omitempty"`
+}
+
+// sagaReport is returned to the client when a saga fails
+type sagaReport struct {
+	Name   string           `json:"name"`
+	Status string           `json:"status"`
+	Steps  []sagaStepResult `json:"steps"`
+}
+
+// saga runs its steps in order. When a step fails, the compensations of
+// the steps before it run in reverse order, so the EMCO objects created
+// so far are removed again.
+type saga struct {
+	name  string
+	orch  *OrchestrationHandler
+	steps []sagaStep
+}
+
+func newSaga(name string, orch *OrchestrationHandler) *saga {
+	return &saga{name: name, orch: orch}
+}
+
+func (s *saga) addStep(name string, run func() interface{}, compensate func() interface{}, payloadKey string) *saga {
+	s.steps = append(s.steps, sagaStep{name: name, run: run, compensate: compensate, payloadKey: payloadKey})
+	return s
+}
+
+// addPartialStep adds a step which is compensated even when it fails. The
+// compensation must cope with objects the step did not get to create.
+func (s *saga) addPartialStep(name string, run func() interface{}, compensate func() interface{}, payloadKey string) *saga {
+	s.steps = append(s.steps, sagaStep{name: name, run: run, compensate: compensate, payloadKey: payloadKey, partial: true})
+	return s
+}
+
+// addWorkflow adds the anchor and the objects of a registered orchWorkflow
+// as two steps. compensate removes both, it runs only when the anchor was
+// created by this saga so existing EMCO objects are never deleted.
+func (s *saga) addWorkflow(name string, compensate func() interface{}, payloadKey string) *saga {
+	I := s.orch.newWorkflow(name)
+	s.addStep(name+"/anchor", I.createAnchor, compensate, payloadKey)
+	return s.addStep(name+"/objects", I.createObject, nil, payloadKey)
+}
+
+// statusCode maps a step result on a http status code
+func statusCode(ret interface{}) int {
+	if code, ok := ret.(int); ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *saga) describe(ret interface{}, payloadKey string) string {
+	if err, ok := ret.(error); ok {
+		return err.Error()
+	}
+	s.orch.Lock()
+	defer s.orch.Unlock()
+	if payloadKey == "" {
+		payloadKey = s.orch.response.lastKey
+	}
+	if payload := s.orch.response.payload[payloadKey]; len(payload) > 0 {
+		return string(payload)
+	}
+	return fmt.Sprintf("%v", ret)
+}
+
+// run executes the saga. It returns the report and nil on success, or the
+// report and the result of the failed step.
+func (s *saga) run() (*sagaReport, interface{}) {
+	report := &sagaReport{Name: s.name, Status: sagaSucceeded}
+	results := make([]sagaStepResult, len(s.steps))
+	for i := range s.steps {
+		results[i] = sagaStepResult{Step: s.steps[i].name, Status: stepNotRun}
+	}
+
+	var failure interface{}
+	failed := -1
+	for i, step := range s.steps {
+		start := time.Now()
+		end := s.orch.startSpan("saga " + s.name + " " + step.name)
+		ret := step.run()
+		end(resultError(ret))
+		results[i].Took = time.Since(start).String()
+		if ret == nil {
+			results[i].Status = stepSucceeded
+			continue
+		}
+		results[i].Status = stepFailed
+		results[i].Code = statusCode(ret)
+		results[i].Error = s.describe(ret, step.payloadKey)
+		s.orch.Logger.Errorf("%s(): saga %s failed at step %s: %s", PrintFunctionName(), s.name, step.name, results[i].Error)
+		failure = ret
+		failed = i
+		break
+	}
+
+	if failed >= 0 {
+		report.Status = sagaRolledBack
+		for i := failed; i >= 0; i-- {
+			step := s.steps[i]
+			if step.compensate == nil || (i == failed && !step.partial) {
+				continue
+			}
+			end := s.orch.startSpan("saga " + s.name + " compensate " + step.name)
+			ret := step.compensate()
+			end(resultError(ret))
+			if ret == nil {
+				if i != failed {
+					results[i].Status = stepCompensated
+				}
+				continue
+			}
+			s.orch.Logger.Errorf("%s(): saga %s failed to compensate step %s: %v", PrintFunctionName(), s.name, step.name, ret)
+			results[i].Status = stepCompensationFailed
+			results[i].CompensationError = s.describe(ret, "")
+			report.Status = sagaRollbackFailed
+		}
+		// steps without a compensation of their own were undone along
+		// with an earlier step
+		if report.Status == sagaRolledBack {
+			for i := 0; i < failed; i++ {
+				if results[i].Status == stepSucceeded {
+					results[i].Status = stepCompensated
+				}
+			}
+		}
+	}
+	report.Steps = results
+	return report, failure
+}
+
+// resultError converts the result of a step, nil or an error or a http
+// status code, to an error
+func resultError(ret interface{}) error {
+	switch v := ret.(type) {
+	case nil:
+		return nil
+	case error:
+		return v
+	case int:
+		return fmt.Errorf("status %d", v)
+	}
+	return fmt.Errorf("%v", ret)
+}
+
+// writeSagaFailure replies the failed saga to the client with the status
+// of the failed step and the per step report as body.
+func writeSagaFailure(w http.ResponseWriter, report *sagaReport, failure interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode(failure))
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	}
+}
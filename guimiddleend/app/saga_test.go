@@ -0,0 +1,143 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// sagaRecorder builds the steps of a saga which record the order they run
+// and compensate in
+type sagaRecorder struct {
+	calls []string
+}
+
+func (r *sagaRecorder) step(name string, ret interface{}) func() interface{} {
+	return func() interface{} {
+		r.calls = append(r.calls, name)
+		return ret
+	}
+}
+
+func TestSaga(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name   string
+		build  func(s *saga, r *sagaRecorder)
+		calls  []string
+		status string
+		steps  []string
+		code   int
+	}{
+		{
+			name: "succeeded",
+			build: func(s *saga, r *sagaRecorder) {
+				s.addStep("a", r.step("run a", nil), r.step("undo a", nil), "")
+				s.addStep("b", r.step("run b", nil), r.step("undo b", nil), "")
+			},
+			calls:  []string{"run a", "run b"},
+			status: sagaSucceeded,
+			steps:  []string{stepSucceeded, stepSucceeded},
+		},
+		{
+			name: "compensated in reverse order",
+			build: func(s *saga, r *sagaRecorder) {
+				s.addStep("a", r.step("run a", nil), r.step("undo a", nil), "")
+				s.addStep("b", r.step("run b", nil), nil, "")
+				s.addStep("c", r.step("run c", nil), r.step("undo c", nil), "")
+				s.addStep("d", r.step("run d", http.StatusConflict), r.step("undo d", nil), "")
+				s.addStep("e", r.step("run e", nil), r.step("undo e", nil), "")
+			},
+			calls:  []string{"run a", "run b", "run c", "run d", "undo c", "undo a"},
+			status: sagaRolledBack,
+			steps:  []string{stepCompensated, stepCompensated, stepCompensated, stepFailed, stepNotRun},
+			code:   http.StatusConflict,
+		},
+		{
+			name: "partial step compensated",
+			build: func(s *saga, r *sagaRecorder) {
+				s.addStep("a", r.step("run a", nil), r.step("undo a", nil), "")
+				s.addPartialStep("b", r.step("run b", failed), r.step("undo b", nil), "")
+			},
+			calls:  []string{"run a", "run b", "undo b", "undo a"},
+			status: sagaRolledBack,
+			steps:  []string{stepCompensated, stepFailed},
+			code:   http.StatusInternalServerError,
+		},
+		{
+			name: "compensation failed",
+			build: func(s *saga, r *sagaRecorder) {
+				s.addStep("a", r.step("run a", nil), r.step("undo a", nil), "")
+				s.addStep("b", r.step("run b", nil), nil, "")
+				s.addStep("c", r.step("run c", nil), r.step("undo c", failed), "")
+				s.addStep("d", r.step("run d", failed), nil, "")
+			},
+			calls:  []string{"run a", "run b", "run c", "run d", "undo c", "undo a"},
+			status: sagaRollbackFailed,
+			steps:  []string{stepCompensated, stepSucceeded, stepCompensationFailed, stepFailed},
+			code:   http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := (&Server{}).newHandler(context.Background())
+			h.InitializeResponseMap()
+			r := &sagaRecorder{}
+			s := newSaga("test", h)
+			tt.build(s, r)
+
+			report, failure := s.run()
+			if !reflect.DeepEqual(r.calls, tt.calls) {
+				t.Errorf("calls %v, want %v", r.calls, tt.calls)
+			}
+			if report.Status != tt.status {
+				t.Errorf("status %s, want %s", report.Status, tt.status)
+			}
+			var steps []string
+			for _, step := range report.Steps {
+				steps = append(steps, step.Status)
+			}
+			if !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("steps %v, want %v", steps, tt.steps)
+			}
+			if tt.code == 0 {
+				if failure != nil {
+					t.Errorf("failure %v", failure)
+				}
+				return
+			}
+			if code := statusCode(failure); code != tt.code {
+				t.Errorf("code %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestSagaReportsPayload(t *testing.T) {
+	h := (&Server{}).newHandler(context.Background())
+	h.InitializeResponseMap()
+	h.response.payload["app_gpint"] = []byte("placement intent exists")
+	s := newSaga("test", h).addStep("placement", func() interface{} { return http.StatusConflict }, nil, "app_gpint")
+
+	report, _ := s.run()
+	if step := report.Steps[0]; step.Code != http.StatusConflict || step.Error != "placement intent exists" {
+		t.Fatalf("unexpected step report %+v", step)
+	}
+}
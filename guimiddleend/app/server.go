@@ -0,0 +1,170 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"example.com/middleend/authproxy"
+	"example.com/middleend/backend"
+	"example.com/middleend/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Server holds the dependencies shared by the requests: the live
+// configuration, the client of the EMCO services, the operation workers,
+// the event watchers and the readiness checker. It is created once at
+// startup and injected into the handler of every request.
+type Server struct {
+	// client is shared by all requests so that connections are pooled
+	// and the circuit breakers see the calls of every request
+	client     *backend.Client
+	operations *operationManager
+	events     *eventHub
+	readiness  *readinessChecker
+
+	// conf is the configuration used by new requests, Reconfigure
+	// replaces its log level and service endpoints without a restart
+	confMu sync.RWMutex
+	conf   MiddleendConfig
+
+	// shuttingDown is set by BeginShutdown
+	shuttingDown int32
+
+	// projects authorizes the reads of the operations of a project, the
+	// caller has to be of the tenant of the project without one
+	projects projectAuthorizer
+}
+
+// projectAuthorizer tells whether the caller may access a project, as
+// the rbac enforcer does
+type projectAuthorizer interface {
+	ProjectAllowed(claims *authproxy.Claims, project string) bool
+}
+
+// SetProjectAuthorizer authorizes the reads of the operations of a
+// project with a, the rbac policy
+func (s *Server) SetProjectAuthorizer(a projectAuthorizer) {
+	s.projects = a
+}
+
+// NewServer creates the dependencies of the APIs for the configuration
+func NewServer(conf MiddleendConfig) (*Server, error) {
+	client, err := backend.NewClient(conf.Backend, conf.services())
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		client:     client,
+		operations: newOperationManager(conf.OperationWorkers),
+		readiness:  newReadinessChecker(conf, client),
+		conf:       conf,
+	}
+	s.events = newEventHub(conf.EventsPollInterval, s.newHandler)
+	return s, nil
+}
+
+func (s *Server) config() MiddleendConfig {
+	s.confMu.RLock()
+	defer s.confMu.RUnlock()
+	return s.conf
+}
+
+// newHandler returns the handler of a call made on behalf of ctx
+func (s *Server) newHandler(ctx context.Context) *OrchestrationHandler {
+	return &OrchestrationHandler{
+		srv:    s,
+		client: s.client,
+		// a request sees the same endpoints throughout, even across a reload
+		MiddleendConf: s.config(),
+		ctx:           ctx,
+		Logger:        logging.FromContext(ctx),
+	}
+}
+
+// handlerFor returns the handler of the request. Calls to the EMCO
+// services are cancelled along with the request.
+func (s *Server) handlerFor(r *http.Request) *OrchestrationHandler {
+	h := s.newHandler(r.Context())
+	// the request logger of the logging middleware has the request id
+	fields := logrus.Fields{}
+	if claims, ok := authproxy.ClaimsFromContext(r.Context()); ok {
+		fields["subject"] = claims.Subject
+		fields["tenant"] = claims.Tenant
+		fields["roles"] = claims.Roles
+	}
+	h.setLogger(logging.FromContext(r.Context()).WithFields(fields))
+	return h
+}
+
+// handlerFunc is an API served by a handler of its own
+type handlerFunc func(h *OrchestrationHandler, w http.ResponseWriter, r *http.Request)
+
+// api serves fn with a new handler per request, the handlers are not
+// safe for concurrent use
+func (s *Server) api(fn handlerFunc) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn(s.handlerFor(r), w, r)
+	}
+}
+
+// async serves fn as an asynchronous operation of the given kind
+func (s *Server) async(kind string, fn handlerFunc) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.runOperation(kind, w, r, fn)
+	}
+}
+
+// apiError is the error of an API, replied with its status code
+type apiError struct {
+	code int
+	msg  string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+// jsonAPIFunc is an API parsing a typed request and computing a typed
+// result, which is replied as json
+type jsonAPIFunc func(h *OrchestrationHandler, r *http.Request) (interface{}, error)
+
+// jsonAPI serves fn. An apiError is replied with its code, other errors
+// with 500.
+func (s *Server) jsonAPI(fn jsonAPIFunc) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := s.handlerFor(r)
+		res, err := fn(h, r)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if e, ok := err.(*apiError); ok {
+				code = e.code
+			} else {
+				h.Logger.WithError(err).Errorf("%s(): Request failed", PrintFunctionName())
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+		}
+	}
+}
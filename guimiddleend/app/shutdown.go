@@ -0,0 +1,53 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const defaultShutdownGracePeriod = 30
+
+func (s *Server) isShuttingDown() bool {
+	return atomic.LoadInt32(&s.shuttingDown) == 1
+}
+
+// GracePeriod returns how long the middleend drains the requests and the
+// operations in flight when it stops
+func (c MiddleendConfig) GracePeriod() time.Duration {
+	seconds := c.ShutdownGracePeriod
+	if seconds <= 0 {
+		seconds = defaultShutdownGracePeriod
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// BeginShutdown fails the readiness probe so that no new traffic is
+// routed to the middleend, refuses new operations and ends the event
+// streams, which would otherwise keep the server from draining.
+func (s *Server) BeginShutdown() {
+	atomic.StoreInt32(&s.shuttingDown, 1)
+	s.operations.close()
+	s.events.close()
+}
+
+// Drain waits for the queued and running operations, e.g. DIG upgrades,
+// until ctx expires
+func (s *Server) Drain(ctx context.Context) error {
+	return s.operations.drain(ctx)
+}
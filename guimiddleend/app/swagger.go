@@ -1,5 +1,14 @@
 package app
 
+import _ "embed"
+
+//go:generate go run ../main/swaggergen -o swagger.yaml
+
+// swaggerYAML is the OpenAPI document generated by Spec
+//
+//go:embed swagger.yaml
+var swaggerYAML []byte
+
 // nolint
 // swaggerJsonResponse
 // swagger:response swaggerJsonResponse
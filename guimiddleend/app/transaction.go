@@ -0,0 +1,89 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"example.com/middleend/db"
+)
+
+// errReplyFailed aborts the transaction of a request which replied an error
+var errReplyFailed = errors.New("the request failed, its changes are rolled back")
+
+// inTransaction runs fn in a transaction of the middleend store: the
+// changes fn makes through the store calls of h are kept together, or
+// undone when it fails, and do not interleave with those of another
+// transaction. Without native transactions the changes are seen by the
+// other requests as they are made. fn runs again when the transaction
+// conflicts with another one, the route variables are restored before.
+// fn must not make store calls on behalf of another context, nor wait
+// for the operations. The transaction holds the store while it runs, so
+// what fn needs from EMCO is read before where possible.
+func (h *OrchestrationHandler) inTransaction(fn func() error) error {
+	outer := h.ctx
+	vars := make(map[string]string, len(h.Vars))
+	for k, v := range h.Vars {
+		vars[k] = v
+	}
+	attempt := 0
+	return db.DBconn.WithTransaction(outer, func(ctx context.Context) error {
+		if attempt > 0 {
+			h.Logger.Warnf("Transaction conflicted, retrying, attempt %d", attempt+1)
+			for k := range h.Vars {
+				delete(h.Vars, k)
+			}
+			for k, v := range vars {
+				h.Vars[k] = v
+			}
+		}
+		attempt++
+		h.ctx = ctx
+		defer func() { h.ctx = outer }()
+		return fn()
+	})
+}
+
+// replyInTransaction serves fn in a transaction, a reply of 400 or more
+// rolls its changes back. The reply is passed on to w once the
+// transaction is over, so the client sees a success only once the changes
+// are applied.
+func (h *OrchestrationHandler) replyInTransaction(w http.ResponseWriter, fn func(w http.ResponseWriter)) {
+	var rec *httptest.ResponseRecorder
+	err := h.inTransaction(func() error {
+		rec = httptest.NewRecorder()
+		fn(rec)
+		if rec.Code >= http.StatusBadRequest {
+			return errReplyFailed
+		}
+		return nil
+	})
+	if err != nil && err != errReplyFailed {
+		h.Logger.WithError(err).Errorf("%s(): Transaction failed", PrintFunctionName())
+		http.Error(w, "Failed to save the changes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.Code)
+	if _, err := w.Write(rec.Body.Bytes()); err != nil {
+		h.Logger.WithError(err).Errorf("%s() : Failed to respond client", PrintFunctionName())
+	}
+}
@@ -0,0 +1,276 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxMultipartMemory is the part of a multipart form kept in memory, the
+// rest is spooled to disk; the handlers parse their forms with the same
+const maxMultipartMemory = 16 << 20
+
+// apiSpec is the embedded document, loaded on first use. doc is its
+// generic form, which the validators resolve the references against.
+var apiSpec struct {
+	once sync.Once
+	sw   *spec.Swagger
+	doc  interface{}
+	err  error
+}
+
+func loadSpec() (*spec.Swagger, interface{}, error) {
+	apiSpec.once.Do(func() {
+		data, err := yaml.YAMLToJSON(swaggerYAML)
+		if err != nil {
+			apiSpec.err = err
+			return
+		}
+		sw := &spec.Swagger{}
+		if err := json.Unmarshal(data, sw); err != nil {
+			apiSpec.err = err
+			return
+		}
+		if sw.Paths == nil {
+			apiSpec.err = errors.New("the document has no paths, run go generate")
+			return
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			apiSpec.err = err
+			return
+		}
+		apiSpec.sw, apiSpec.doc = sw, doc
+	})
+	return apiSpec.sw, apiSpec.doc, apiSpec.err
+}
+
+// validateRequests wraps the APIs registered through handle so that their
+// json bodies and the json fields of their multipart forms are checked
+// against the embedded document before the handlers run. A request not
+// matching it is replied with 400, listing every mismatch.
+func validateRequests(handle HandleFunc) HandleFunc {
+	sw, doc, err := loadSpec()
+	if err != nil {
+		log.WithError(err).Error("Failed to load the embedded swagger.yaml, the requests are not validated")
+		return handle
+	}
+	return func(path string, fn func(http.ResponseWriter, *http.Request)) *mux.Route {
+		item, ok := sw.Paths.Paths[path]
+		if !ok {
+			log.Warnf("%s is not in the embedded swagger.yaml, run go generate", path)
+			return handle(path, fn)
+		}
+		return handle(path, func(w http.ResponseWriter, r *http.Request) {
+			if op := operationOf(item, r.Method); op != nil {
+				if err := validateRequest(sw, doc, op, r); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			fn(w, r)
+		})
+	}
+}
+
+func operationOf(item spec.PathItem, method string) *spec.Operation {
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPost:
+		return item.Post
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPatch:
+		return item.Patch
+	case http.MethodDelete:
+		return item.Delete
+	}
+	return nil
+}
+
+// validateRequest checks the body of r against op. The body is read and
+// restored for the handler.
+func validateRequest(sw *spec.Swagger, doc interface{}, op *spec.Operation, r *http.Request) error {
+	var body *spec.Parameter
+	var fields []spec.Parameter
+	for i, p := range op.Parameters {
+		switch p.In {
+		case "body":
+			body = &op.Parameters[i]
+		case "formData":
+			if _, ok := p.Extensions[jsonFieldExt]; ok {
+				fields = append(fields, p)
+			}
+		}
+	}
+	if body == nil && len(fields) == 0 {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read the request body: %v", err)
+	}
+
+	if body != nil {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return errors.New("the request body is required")
+		}
+		return validateJSON(sw, doc, body.Schema, "body", data)
+	}
+
+	form := r.Clone(r.Context())
+	form.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err := form.ParseMultipartForm(maxMultipartMemory); err != nil {
+		return fmt.Errorf("invalid multipart form: %v", err)
+	}
+	defer form.MultipartForm.RemoveAll()
+	var errs []string
+	for _, p := range fields {
+		values := form.MultipartForm.Value[p.Name]
+		if len(values) == 0 {
+			if p.Required {
+				errs = append(errs, p.Name+" is required")
+			}
+			continue
+		}
+		schema, err := fieldSchema(p)
+		if err != nil {
+			return err
+		}
+		if err := validateJSON(sw, doc, schema, p.Name, []byte(values[0])); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// fieldSchema returns the schema of the json value of a multipart field
+func fieldSchema(p spec.Parameter) (*spec.Schema, error) {
+	data, err := json.Marshal(p.Extensions[jsonFieldExt])
+	if err != nil {
+		return nil, err
+	}
+	schema := &spec.Schema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// validateJSON checks the json data named name against schema
+func validateJSON(sw *spec.Swagger, doc interface{}, schema *spec.Schema, name string, data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%s is not valid json: %v", name, err)
+	}
+	value = canonicalize(sw, schema, value)
+
+	// the validator expands the references of the schema in place
+	var s spec.Schema
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return err
+	}
+	res := validate.NewSchemaValidator(&s, doc, name, strfmt.Default).Validate(value)
+	if !res.HasErrors() {
+		return nil
+	}
+	msgs := make([]string, 0, len(res.Errors))
+	for _, err := range res.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// resolve follows the reference of a schema to its definition
+func resolve(sw *spec.Swagger, s *spec.Schema) *spec.Schema {
+	for s != nil && s.Ref.String() != "" {
+		def, ok := sw.Definitions[strings.TrimPrefix(s.Ref.String(), "#/definitions/")]
+		if !ok {
+			return nil
+		}
+		s = &def
+	}
+	return s
+}
+
+// canonicalize renames the keys of the objects of v to the properties of
+// the schema they match case insensitively, and drops their null values,
+// the way encoding/json decodes them into the types of the handlers
+func canonicalize(sw *spec.Swagger, s *spec.Schema, v interface{}) interface{} {
+	s = resolve(sw, s)
+	if s == nil {
+		return v
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if item == nil {
+				continue
+			}
+			name, prop := key, (*spec.Schema)(nil)
+			if p, ok := s.Properties[key]; ok {
+				prop = &p
+			} else {
+				for pname, p := range s.Properties {
+					if strings.EqualFold(pname, key) {
+						name, prop = pname, &p
+						break
+					}
+				}
+			}
+			if prop == nil && s.AdditionalProperties != nil {
+				prop = s.AdditionalProperties.Schema
+			}
+			out[name] = canonicalize(sw, prop, item)
+		}
+		return out
+	case []interface{}:
+		if s.Items == nil || s.Items.Schema == nil {
+			return v
+		}
+		for i, item := range value {
+			value[i] = canonicalize(sw, s.Items.Schema, item)
+		}
+		return value
+	}
+	return v
+}
@@ -32,6 +32,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 
 	"example.com/middleend/localstore"
+	"example.com/middleend/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -124,183 +125,82 @@ func (h *OrchestrationHandler) getData(I orchWorkflow) error {
 	return I.getObject()
 }
 
-func (h *OrchestrationHandler) deleteData(I orchWorkflow) (interface{}, interface{}) {
-	_ = I.deleteObject()
-	_ = I.deleteAnchor()
-	return nil, http.StatusNoContent // FIXME
+// workflowRegistry holds the orchWorkflow implementations by data point
+// name, as used in constructTree, deleteTree and the sagas.
+var workflowRegistry = map[string]func(h *OrchestrationHandler) orchWorkflow{
+	"projectHandler":          func(h *OrchestrationHandler) orchWorkflow { return &projectHandler{orchInstance: h} },
+	"compAppHandler":          func(h *OrchestrationHandler) orchWorkflow { return &compAppHandler{orchInstance: h} },
+	"ProfileHandler":          func(h *OrchestrationHandler) orchWorkflow { return &ProfileHandler{orchInstance: h} },
+	"digpHandler":             func(h *OrchestrationHandler) orchWorkflow { return &digpHandler{orchInstance: h} },
+	"placementIntentHandler":  func(h *OrchestrationHandler) orchWorkflow { return &placementIntentHandler{orchInstance: h} },
+	"networkIntentHandler":    func(h *OrchestrationHandler) orchWorkflow { return &networkIntentHandler{orchInstance: h} },
+	"dtcIntentHandler":        func(h *OrchestrationHandler) orchWorkflow { return &dtcIntentHandler{orchInstance: h} },
+	"genericK8sIntentHandler": func(h *OrchestrationHandler) orchWorkflow { return &genericK8sIntentHandler{orchInstance: h} },
+}
+
+// newWorkflow returns the registered workflow for the data point, or nil
+func (h *OrchestrationHandler) newWorkflow(dataPoint string) orchWorkflow {
+	newFn, ok := workflowRegistry[dataPoint]
+	if !ok {
+		return nil
+	}
+	return newFn(h)
+}
+
+// deleteData deletes the objects and then the anchor of the workflow.
+// Objects which are already gone are not an error, so that the delete can
+// be repeated after a partial failure.
+func (h *OrchestrationHandler) deleteData(I orchWorkflow) interface{} {
+	if ret := I.deleteObject(); !deleted(ret) {
+		return ret
+	}
+	if ret := I.deleteAnchor(); !deleted(ret) {
+		return ret
+	}
+	return nil
+}
+
+func deleted(ret interface{}) bool {
+	if ret == nil {
+		return true
+	}
+	code, ok := ret.(int)
+	return ok && (code == http.StatusNoContent || code == http.StatusNotFound)
 }
 
 func (h *OrchestrationHandler) deleteTree(dataPoints []string) interface{} {
-	// 1. Fetch App data
-	var I orchWorkflow
 	for _, dataPoint := range dataPoints {
-		switch dataPoint {
-		case "projectHandler":
-			temp := &projectHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "compAppHandler":
-			temp := &compAppHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "ProfileHandler":
-			temp := &ProfileHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "digpHandler":
-			temp := &digpHandler{}
-			temp.orchInstance = h
-			I = temp
-			log.Infof("delete digp")
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "placementIntentHandler":
-			temp := &placementIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "networkIntentHandler":
-			temp := &networkIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "dtcIntentHandler":
-			temp := &dtcIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		case "genericK8sIntentHandler":
-			temp := &genericK8sIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			_, retcode := h.deleteData(I)
-			if retcode != http.StatusNoContent {
-				return retcode
-			}
-		default:
-			log.Infof("%s", dataPoint)
+		I := h.newWorkflow(dataPoint)
+		if I == nil {
+			h.Logger.Infof("%s", dataPoint)
+			continue
+		}
+		end := h.startSpan("deleteTree " + dataPoint)
+		ret := h.deleteData(I)
+		end(resultError(ret))
+		if ret != nil {
+			h.Logger.Errorf("%s(): Failed to delete %s: %v", PrintFunctionName(), dataPoint, ret)
+			return ret
 		}
 	}
 	return nil
 }
 
 func (h *OrchestrationHandler) constructTree(dataPoints []string) error {
-	var I orchWorkflow
 	for _, dataPoint := range dataPoints {
-		switch dataPoint {
-		case "projectHandler":
-			start := time.Now()
-			temp := &projectHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("projectHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "compAppHandler":
-			start := time.Now()
-			temp := &compAppHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("compAppHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "ProfileHandler":
-			start := time.Now()
-			temp := &ProfileHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("ProfileHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "digpHandler":
-			start := time.Now()
-			temp := &digpHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("digpHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "placementIntentHandler":
-			start := time.Now()
-			temp := &placementIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("placementIntentHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "dtcIntentHandler":
-			start := time.Now()
-			temp := &dtcIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("dtcIntentHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "networkIntentHandler":
-			start := time.Now()
-			temp := &networkIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("networkIntentHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		case "genericK8sIntentHandler":
-			start := time.Now()
-			temp := &genericK8sIntentHandler{}
-			temp.orchInstance = h
-			I = temp
-			err := h.getData(I)
-			elapsed := time.Since(start)
-			log.Printf("genericK8sIntentHandler took %s", elapsed)
-			if err != nil {
-				return err
-			}
-		default:
-			log.Infof("%s\n", dataPoint)
+		I := h.newWorkflow(dataPoint)
+		if I == nil {
+			h.Logger.Infof("%s\n", dataPoint)
+			continue
+		}
+		start := time.Now()
+		end := h.startSpan("constructTree " + dataPoint)
+		err := h.getData(I)
+		end(err)
+		h.Logger.Printf("%s took %s", dataPoint, time.Since(start))
+		metrics.ObserveTreeStep(dataPoint, time.Since(start))
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -338,7 +238,7 @@ func (h *OrchestrationHandler) copyNwToStatus() {
 			h.DigStatusJSON.Apps[k].Clusters[i].Interfaces = localAppInterfaceMap[v.Name]
 		}
 		h.DigStatusJSON.Apps[k].Description = localAppDescMap[v.Name]
-		log.Infof("App name %s desc %s", v.Description, localAppDescMap[v.Name])
+		h.Logger.Infof("App name %s desc %s", v.Description, localAppDescMap[v.Name])
 	}
 }
 
@@ -447,7 +347,7 @@ func (h *OrchestrationHandler) copyDigTreeNew() error {
 				guiDigViewApp.Description = application.App.Metadata.Description
 				localApps[guiDigViewApp.Name] = &guiDigViewApp
 			}
-			log.Infof("%d Applications in composite application %s\n", len(localApps), value.Metadata.Metadata.Name)
+			h.Logger.Infof("%d Applications in composite application %s\n", len(localApps), value.Metadata.Metadata.Name)
 
 			// Populate the cluster information in the guiDigView.Apps
 			genericPlacementIntents := digValue.GpintMap
@@ -455,7 +355,7 @@ func (h *OrchestrationHandler) copyDigTreeNew() error {
 				for _, appGenericPlacementIntent := range genericPlacementIntent.AppIntentArray {
 					appName := appGenericPlacementIntent.Spec.AppName
 					guiDigViewApp := localApps[appName]
-					log.Infof("Copying the generic placement intent %s application %s\n",
+					h.Logger.Infof("Copying the generic placement intent %s application %s\n",
 						genericPlacementIntentName, appName)
 
 					// Iterate through all the clusters
@@ -481,8 +381,8 @@ func (h *OrchestrationHandler) copyDigTreeNew() error {
 						localApps[appName].PlacementCriterion = "anyOf"
 					}
 
-					log.Debugf("selectedClusterProviders: %+v", selectedClusterProviders)
-					log.Debugf("selectedLabelProviders: %+v", selectedLabelProviders)
+					h.Logger.Debugf("selectedClusterProviders: %+v", selectedClusterProviders)
+					h.Logger.Debugf("selectedLabelProviders: %+v", selectedLabelProviders)
 
 					for clusterProvider, clusterArray := range selectedClusterProviders {
 						clusterIntent := ClustersInPlacementIntent{}
@@ -518,42 +418,42 @@ func (h *OrchestrationHandler) copyDigTreeNew() error {
 			nwhandler := ncmHandler{}
 			nwhandler.orchInstance = h
 			var conStatus ConsolidatedStatus
-			var appName string
+			var appName, clusterProvider, clusterName string
 			for _, app := range apps {
-				log.Debugf("app info: %+v", localApps[app.App.Metadata.Name])
+				h.Logger.Debugf("app info: %+v", localApps[app.App.Metadata.Name])
 				if (len(localApps[app.App.Metadata.Name].Clusters) > 0) && (len(localApps[app.App.Metadata.Name].Clusters[0].ClusterProvider) > 0) &&
 					((len(localApps[app.App.Metadata.Name].Clusters[0].SelectedCluster) > 0) || (len(localApps[app.App.Metadata.Name].Clusters[0].SelectedLabels) > 0)) {
 					appName = app.App.Metadata.Name
 
-					h.Vars["clusterprovider-name"] = localApps[appName].Clusters[0].ClusterProvider
+					clusterProvider = localApps[appName].Clusters[0].ClusterProvider
 					if len(localApps[appName].Clusters[0].SelectedCluster) > 0 {
-						h.Vars["cluster-name"] = localApps[appName].Clusters[0].SelectedCluster[0].Name
+						clusterName = localApps[appName].Clusters[0].SelectedCluster[0].Name
 					} else {
 						var clusterNames []string
 						label := localApps[appName].Clusters[0].SelectedLabels[0].Name
-						url := "http://" + h.MiddleendConf.Clm + "/v2/cluster-providers/" +
-							h.Vars["clusterprovider-name"] + "/clusters?label=" + label
+						url := h.MiddleendConf.serviceURL("clm") + "/v2/cluster-providers/" +
+							clusterProvider + "/clusters?label=" + label
 
-						reply, err := h.apiGet(url, h.Vars["clusterprovider-name"])
+						reply, err := h.apiGet(url, clusterProvider)
 						if err != nil {
-							log.Errorf("Failed to get cluster name for label %s: ", label)
+							h.Logger.Errorf("Failed to get cluster name for label %s: ", label)
 							return err
 						}
-						log.Infof("Get cluster name : %d", reply.StatusCode)
+						h.Logger.Infof("Get cluster name : %d", reply.StatusCode)
 						err = json.Unmarshal(reply.Data, &clusterNames)
 						if err != nil {
-							log.Errorf("Error unmarshalling clusterNames")
+							h.Logger.Errorf("Error unmarshalling clusterNames")
 							return err
 						}
-						h.Vars["cluster-name"] = clusterNames[0]
+						clusterName = clusterNames[0]
 					}
 					break
 				}
 			}
 
-			conStatus, err := nwhandler.getNetworks()
+			conStatus, err := nwhandler.getNetworks(clusterProvider, clusterName)
 			if err != nil {
-				log.Errorf("Failed to get cluster networks : %s", err)
+				h.Logger.Errorf("Failed to get cluster networks : %s", err)
 				return err
 			}
 
@@ -582,7 +482,7 @@ func (h *OrchestrationHandler) copyDigTreeNew() error {
 
 			// Append all the apps to the guiDigView
 			for _, app := range localApps {
-				log.Infof("app %s\n", *app)
+				h.Logger.Infof("app %s\n", *app)
 				localGuiDigView.Apps = append(localGuiDigView.Apps, *app)
 			}
 		}
@@ -629,7 +529,7 @@ func (h *OrchestrationHandler) copyDigTree() {
 			// Pupolate the generic placement intents
 			SourceGpintMap := digValue.GpintMap
 			for t, gpintValue := range SourceGpintMap {
-				log.Infof("gpName value %s", t)
+				h.Logger.Infof("gpName value %s", t)
 				localGpint := DigsGpint{}
 				localGpint.Metadata = gpintValue.Gpint.MetaData
 				// localGpint.Spec.AppIntentArray = gpintValue.AppIntentArray
@@ -693,7 +593,10 @@ func (h *OrchestrationHandler) copyDigTree() {
 func (h *OrchestrationHandler) FetchLatestVersion() (int, string) {
 	var verList []int
 	// Fetch all versions for a given composite application
-	retCode, versionList := h.GetCompAppVersions("")
+	retCode, versionList := h.GetCompAppVersions(compositeAppVersionsRequest{
+		Project:      h.Vars["projectName"],
+		CompositeApp: h.Vars["compositeAppName"],
+	})
 	if retCode != http.StatusOK {
 		return retCode, ""
 	}
@@ -705,7 +608,7 @@ func (h *OrchestrationHandler) FetchLatestVersion() (int, string) {
 
 	sort.Ints(verList[:])
 
-	log.Infof("version list: %d", verList)
+	h.Logger.Infof("version list: %d", verList)
 
 	latestVersion := strconv.Itoa(verList[len(verList)-1])
 
@@ -722,13 +625,13 @@ func (h *OrchestrationHandler) FetchK8sFileContent(files []*multipart.FileHeader
 			_ = file.Close()
 		}()
 		if err != nil {
-			log.Error("Unable to open file", log.Fields{"FileName": files[i].Filename})
+			h.Logger.Error("Unable to open file", log.Fields{"FileName": files[i].Filename})
 			return resGVK, ""
 		}
 
 		bytesRead, err = ioutil.ReadAll(file)
 		if err != nil {
-			log.Error(":: File read failed ::", log.Fields{"Error": err})
+			h.Logger.Error(":: File read failed ::", log.Fields{"Error": err})
 			return resGVK, ""
 		}
 		fileContent := string(bytesRead)
@@ -763,10 +666,10 @@ func (h *OrchestrationHandler) ProcessConfigMapSecret(byteData []byte) ([]string
 	var spec v1.ConfigMap
 	err := yaml.Unmarshal(byteData, &spec)
 	if err != nil {
-		log.Errorf("Unable to Unmarshal ConfigMap: %s", err)
+		h.Logger.Errorf("Unable to Unmarshal ConfigMap: %s", err)
 		return fileNameArray, contentArray, fileContent
 	}
-	log.Infof("ConfigMap/Secret data is: %+v", spec.Data)
+	h.Logger.Infof("ConfigMap/Secret data is: %+v", spec.Data)
 	for key, item := range spec.Data {
 		// Checking file extensions to ensure if this field is a filename
 		isFile := filepath.Ext(key)
@@ -8,12 +8,14 @@ import (
 	"net/url"
 	"strings"
 
+	"example.com/middleend/logging"
 	"github.com/dgrijalva/jwt-go"
 	log "github.com/sirupsen/logrus"
 )
 
 type AuthProxy struct {
 	AuthProxyConf AuthProxyConfig
+	keys          *keyCache
 }
 
 // AuthProxyConfig holds inputs of authproxy
@@ -21,11 +23,15 @@ type AuthProxyConfig struct {
 	Issuer      string `json:"issuer"`
 	RedirectURI string `json:"redirect_uri"`
 	ClientID    string `json:"client_id"`
+	// TenantClaim is the name of the token claim carrying the tenant of the user
+	TenantClaim string `json:"tenant_claim"`
+	// PublicPaths are served without authentication, e.g. health probes
+	PublicPaths []string `json:"public_paths"`
 }
 
 // NewAppHandler interface implementing REST callhandler
 func NewAppHandler() *AuthProxy {
-	return &AuthProxy{}
+	return &AuthProxy{keys: newKeyCache()}
 }
 
 // OpenIDConfiguration struct to map response from OIDC
@@ -48,39 +54,27 @@ type RealmConfig struct {
 	PublicKey string `json:"public_key"`
 }
 
-var openIDConfig *OpenIDConfiguration
-var realmConfig *RealmConfig
-
 // Loads the openIDconfig from the OIDC only once
-func getOpenIDConfig(issuer string) OpenIDConfiguration {
-	if openIDConfig != nil {
-		log.Debug("openidconfig is not null and returning the cached value")
-		return *openIDConfig
-	}
-	log.Debug("openidconfig is null and loading the values")
-	url := issuer + ".well-known/openid-configuration"
-	response, err := http.Get(url)
+func (h AuthProxy) getOpenIDConfig() (OpenIDConfiguration, error) {
+	cfg, err := h.keys.openIDConfig(h.AuthProxyConf.Issuer)
 	if err != nil {
-		log.Error("The openidconfig HTTP request failed with error %s", err)
-		return *openIDConfig
+		log.Errorf("The openidconfig HTTP request failed with error %s", err)
+		return OpenIDConfiguration{}, err
 	}
-
-	defer response.Body.Close()
-	bodyBytes, _ := ioutil.ReadAll(response.Body)
-	json.Unmarshal(bodyBytes, &openIDConfig)
-	return *openIDConfig
+	return *cfg, nil
 }
 
 // LoginHandler redirects to client login page and sets cookie with the original path
 func (h AuthProxy) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	log.Info("LoginHandler start")
+	logger := logging.FromContext(r.Context())
+	logger.Info("LoginHandler start")
 
 	rd := r.FormValue("rd")
 	scope := r.FormValue("scope")
 
-	log.Debug("[LoginHandler] url Param 'rd' is: %s, 'scope' is: %s", string(rd), string(scope))
+	logger.Debugf("[LoginHandler] url Param 'rd' is: %s, 'scope' is: %s", rd, scope)
 	redirect := r.Header.Get("X-Auth-Request-Redirect")
-	log.Info("redirect url from HEADER is: " + redirect)
+	logger.Info("redirect url from HEADER is: " + redirect)
 	if len(redirect) == 0 {
 		redirect = rd
 	}
@@ -102,7 +96,12 @@ func (h AuthProxy) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// get authorization endpoint from function openidconfig
-	authzEndpoint := getOpenIDConfig(h.AuthProxyConf.Issuer).AuthzEndpoint
+	openIDConfig, err := h.getOpenIDConfig()
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	authzEndpoint := openIDConfig.AuthzEndpoint
 
 	// Construct redirect URL with params
 	u, _ := url.Parse(authzEndpoint)
@@ -116,7 +115,7 @@ func (h AuthProxy) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	q.Add("state", state)
 	u.RawQuery = q.Encode()
 
-	log.Println("[LoginHandler] Redireced URL -> " + u.String())
+	logger.Println("[LoginHandler] Redireced URL -> " + u.String())
 	http.Redirect(w, r, u.String(), http.StatusFound)
 }
 
@@ -126,10 +125,16 @@ func (h AuthProxy) LoginHandler(w http.ResponseWriter, r *http.Request) {
  * Redirects to original URL
  */
 func (h AuthProxy) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
 	state := r.FormValue("state")
 	code := r.FormValue("code")
-	tokenEndpoint := getOpenIDConfig(h.AuthProxyConf.Issuer).TokenEndPoint
-	log.Info("[CallbackHandler] state: %s , code: %s , tokenEndpoint: %s", state, code, tokenEndpoint)
+	openIDConfig, err := h.getOpenIDConfig()
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	tokenEndpoint := openIDConfig.TokenEndPoint
+	logger.Infof("[CallbackHandler] state: %s, tokenEndpoint: %s", state, tokenEndpoint)
 
 	client := http.Client{}
 	form := url.Values{}
@@ -143,8 +148,7 @@ func (h AuthProxy) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := client.Do(request)
 	if err != nil {
-		log.Error("[CallbackHandler] HTTP request to %s failed", tokenEndpoint)
-		log.Error(err)
+		logger.WithError(err).Errorf("[CallbackHandler] HTTP request to %s failed", tokenEndpoint)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -152,15 +156,15 @@ func (h AuthProxy) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("[CallbackHandler] Error while reading response from tokenEndpoint")
-		log.Error(err)
+		logger.WithError(err).Error("[CallbackHandler] Error while reading response from tokenEndpoint")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	var tokenConfig TokenConfig
 	json.Unmarshal(body, &tokenConfig)
-	log.Info("[CallbackHandler] access_token: %s \n id_token: %s\n", tokenConfig.ACCESSTOKEN, tokenConfig.IDTOKEN)
+	// the tokens are credentials, only their presence is logged
+	logger.Debugf("[CallbackHandler] received access token: %t, id token: %t", tokenConfig.ACCESSTOKEN != "", tokenConfig.IDTOKEN != "")
 
 	// Construct the original URL with cookie org
 	var orginalURL string
@@ -168,7 +172,7 @@ func (h AuthProxy) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		orginalURL = cookie.Value
 	}
-	log.Info("[CallbackHandler] orginalURL from cookie: " + orginalURL)
+	logger.Info("[CallbackHandler] orginalURL from cookie: " + orginalURL)
 
 	// Create cookies with id_token, access_token
 	idTokenCookie := http.Cookie{
@@ -197,7 +201,8 @@ func (h AuthProxy) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 
 // AuthHandler verifies the token and returns response
 func (h AuthProxy) AuthHandler(w http.ResponseWriter, r *http.Request) {
-	log.Info("[AuthHandler] Authenticating the token")
+	logger := logging.FromContext(r.Context())
+	logger.Info("[AuthHandler] Authenticating the token")
 
 	var idToken string
 	cookie, err := r.Cookie("idtoken")
@@ -207,57 +212,68 @@ func (h AuthProxy) AuthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if idToken == "" {
-		log.Error("[AuthHandler] id token is nil ")
+		logger.Error("[AuthHandler] id token is nil ")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	error := validateToken(h.AuthProxyConf.Issuer, idToken)
-	if error != nil {
-		log.Error("[AuthHandler] Issue with token and returning failed response")
+	if _, err := h.validateToken(idToken); err != nil {
+		logger.Error("[AuthHandler] Issue with token and returning failed response")
 		w.WriteHeader(http.StatusUnauthorized)
 	}
 }
 
 /*
 * Validates JWT token
-* verifies signature, token expiry and invalid check... etc
+* verifies signature against the issuer key set, token expiry,
+* issuer, audience and invalid check... etc and returns the verified claims
  */
-func validateToken(issuer string, reqToken string) error {
-	log.Info("[AuthHandler] Validating JWT token: \n%s\n", reqToken)
-
-	//load realm public key only once
-	if realmConfig == nil {
-		log.Debug("[AuthHandler] realmconfig is null and loading the value")
-		response, err := http.Get(issuer)
-		if err != nil {
-			log.Error("[AuthHandler] Error while retreiving issuer details : %s\n", err)
-			return err
-		}
-		defer response.Body.Close()
-		bodyBytes, _ := ioutil.ReadAll(response.Body)
-		json.Unmarshal(bodyBytes, &realmConfig)
-	}
-	SecretKey := "-----BEGIN CERTIFICATE-----\n" + realmConfig.PublicKey + "\n-----END CERTIFICATE-----"
-	key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(SecretKey))
-	if err != nil {
-		log.Errorf("[AuthHandler] Error occured while parsing public key: %s", err)
-		return err
-	}
-
+func (h AuthProxy) validateToken(reqToken string) (*Claims, error) {
+	issuer := h.AuthProxyConf.Issuer
 	token, err := jwt.Parse(reqToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			log.Errorf("[AuthHandler] Unexpected signing method: %v", token.Header["alg"])
 			return nil, fmt.Errorf("[AuthHandler] Unexpected signing method: %v", token.Header["alg"])
 		}
-		return key, nil
+		kid, _ := token.Header["kid"].(string)
+		return h.keys.keyFor(issuer, kid)
 	})
-
 	if err != nil {
 		log.Errorf("[AuthHandler] Error while parsing token: %s", err)
-		return err
+		return nil, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("[AuthHandler] Token is not valid")
 	}
-	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		log.Info("[AuthHandler] Token is valid")
+	if iss, _ := mapClaims["iss"].(string); issuerURL(iss) != issuerURL(issuer) {
+		log.Errorf("[AuthHandler] Token issued by %q, expected %q", iss, issuer)
+		return nil, fmt.Errorf("[AuthHandler] Unexpected token issuer: %s", iss)
+	}
+	if clientID := h.AuthProxyConf.ClientID; clientID != "" && !issuedFor(mapClaims, clientID) {
+		log.Errorf("[AuthHandler] Token issued for %v, expected client %q", mapClaims["aud"], clientID)
+		return nil, fmt.Errorf("[AuthHandler] Token not issued for client %s", clientID)
+	}
+	log.Debug("[AuthHandler] Token is valid")
+	return newClaims(mapClaims, h.AuthProxyConf.TenantClaim), nil
+}
+
+// issuedFor reports whether the token was issued for the client: its aud
+// claim, a string or a list, holds the client id, or its azp claim is the
+// client id as in the access tokens Keycloak issues with aud "account".
+func issuedFor(mapClaims jwt.MapClaims, clientID string) bool {
+	if azp, _ := mapClaims["azp"].(string); azp == clientID {
+		return true
+	}
+	switch aud := mapClaims["aud"].(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if s, _ := a.(string); s == clientID {
+				return true
+			}
+		}
 	}
-	return nil
+	return false
 }
@@ -0,0 +1,161 @@
+package authproxy
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/middleend/apierror"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const testClientID = "middleend"
+
+// fakeIssuer serves the discovery document and the key set of an issuer
+// signing with a single RSA key
+type fakeIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	kid string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{key: key, kid: "key-1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realms/test/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(OpenIDConfiguration{
+			Issuer:  f.issuer(),
+			JWKSURI: f.URL + "/realms/test/certs",
+		})
+	})
+	mux.HandleFunc("/realms/test/certs", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: []JSONWebKey{{
+			Kid: f.kid,
+			Kty: "RSA",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeIssuer) issuer() string {
+	return f.URL + "/realms/test"
+}
+
+// token signs claims completed with the defaults of a valid token
+func (f *fakeIssuer) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	defaults := jwt.MapClaims{
+		"iss":                f.issuer(),
+		"aud":                testClientID,
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"tenant":             "project-a",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"realm_access":       map[string]interface{}{"roles": []string{"operator"}},
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(defaults, k)
+			continue
+		}
+		defaults[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, defaults)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestMiddleware(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	proxy := NewAppHandler()
+	proxy.AuthProxyConf = AuthProxyConfig{Issuer: issuer.issuer(), ClientID: testClientID}
+
+	var claims *Claims
+	handler := proxy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = ClaimsFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		kid    string
+		claims jwt.MapClaims
+		cookie bool
+		path   string
+		status int
+	}{
+		{name: "valid bearer", status: http.StatusOK},
+		{name: "valid cookie", cookie: true, status: http.StatusOK},
+		{name: "audience list", claims: jwt.MapClaims{"aud": []string{"account", testClientID}}, status: http.StatusOK},
+		{name: "authorized party", claims: jwt.MapClaims{"aud": "account", "azp": testClientID}, status: http.StatusOK},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, status: http.StatusUnauthorized},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://other.example.com/realms/test"}, status: http.StatusUnauthorized},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "other-client", "azp": "other-client"}, status: http.StatusUnauthorized},
+		{name: "no audience", claims: jwt.MapClaims{"aud": nil}, status: http.StatusUnauthorized},
+		{name: "unknown kid", kid: "key-2", status: http.StatusUnauthorized},
+		{name: "no token", kid: "-", status: http.StatusUnauthorized},
+		{name: "public path", kid: "-", path: "/middleend/healthz", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/middleend/projects"
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.kid != "-" {
+				kid := tt.kid
+				if kid == "" {
+					kid = issuer.kid
+				}
+				token := issuer.token(t, kid, tt.claims)
+				if tt.cookie {
+					req.AddCookie(&http.Cookie{Name: "idtoken", Value: token})
+				} else {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+
+			claims = nil
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				var e apierror.ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil || e.Code != apierror.ErrUnauthorized || e.Status != tt.status {
+					t.Fatalf("reply %q is not an unauthorized ErrorResponse", rec.Body.String())
+				}
+				return
+			}
+			if tt.path != "" {
+				return
+			}
+			if claims == nil {
+				t.Fatal("no claims in the request context")
+			}
+			if claims.Subject != "user-1" || claims.Tenant != "project-a" || !claims.HasRole("operator") {
+				t.Fatalf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package authproxy
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// minRefreshInterval bounds how often an unknown key id may force the
+// key set to be fetched again from the issuer.
+const minRefreshInterval = 30 * time.Second
+
+// JSONWebKey is a single RSA key as published on the issuer jwks_uri
+type JSONWebKey struct {
+	Kid string   `json:"kid"`
+	Kty string   `json:"kty"`
+	Alg string   `json:"alg"`
+	Use string   `json:"use"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	X5c []string `json:"x5c"`
+}
+
+// JSONWebKeySet is the document served on the issuer jwks_uri
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// keyCache holds the issuer discovery document and its signing keys.
+// Keys are looked up by kid and the set is refreshed when a token is
+// signed with a key that is not known yet, so issuer key rotation does
+// not require a restart of the middleend.
+type keyCache struct {
+	sync.RWMutex
+	client      *http.Client
+	openID      *OpenIDConfiguration
+	keys        map[string]*rsa.PublicKey
+	realmKey    *rsa.PublicKey
+	lastRefresh time.Time
+}
+
+func newKeyCache() *keyCache {
+	return &keyCache{
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+func (c *keyCache) getJSON(url string, out interface{}) error {
+	response, err := c.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, response.Status)
+	}
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bodyBytes, out)
+}
+
+// openIDConfig loads the discovery document of the issuer only once
+func (c *keyCache) openIDConfig(issuer string) (*OpenIDConfiguration, error) {
+	c.RLock()
+	cfg := c.openID
+	c.RUnlock()
+	if cfg != nil {
+		return cfg, nil
+	}
+
+	cfg = &OpenIDConfiguration{}
+	if err := c.getJSON(issuerURL(issuer)+".well-known/openid-configuration", cfg); err != nil {
+		return nil, err
+	}
+	c.Lock()
+	c.openID = cfg
+	c.Unlock()
+	return cfg, nil
+}
+
+// refresh fetches the key set from the issuer jwks_uri. When the issuer
+// does not publish a key set, the realm public_key is used instead.
+func (c *keyCache) refresh(issuer string) error {
+	c.Lock()
+	if time.Since(c.lastRefresh) < minRefreshInterval && (len(c.keys) > 0 || c.realmKey != nil) {
+		c.Unlock()
+		return nil
+	}
+	c.lastRefresh = time.Now()
+	c.Unlock()
+
+	cfg, err := c.openIDConfig(issuer)
+	if err != nil {
+		log.WithError(err).Warn("[AuthHandler] Failed to read openid configuration")
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	if cfg != nil && cfg.JWKSURI != "" {
+		var set JSONWebKeySet
+		if err := c.getJSON(cfg.JWKSURI, &set); err != nil {
+			return err
+		}
+		for _, k := range set.Keys {
+			if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+				continue
+			}
+			key, err := k.rsaPublicKey()
+			if err != nil {
+				log.WithError(err).Warnf("[AuthHandler] Skipping key %s in jwks", k.Kid)
+				continue
+			}
+			keys[k.Kid] = key
+		}
+		log.Debugf("[AuthHandler] Loaded %d signing keys from %s", len(keys), cfg.JWKSURI)
+	}
+
+	var realmKey *rsa.PublicKey
+	if len(keys) == 0 {
+		var realm RealmConfig
+		if err := c.getJSON(issuer, &realm); err != nil {
+			return err
+		}
+		realmKey, err = parseRealmKey(realm.PublicKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	c.keys = keys
+	c.realmKey = realmKey
+	c.Unlock()
+	return nil
+}
+
+// keyFor returns the verification key for the given key id, refreshing
+// the cached key set once if the key id is unknown.
+func (c *keyCache) keyFor(issuer string, kid string) (*rsa.PublicKey, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		c.RLock()
+		key, ok := c.keys[kid]
+		if !ok && kid == "" && len(c.keys) == 1 {
+			for _, k := range c.keys {
+				key, ok = k, true
+			}
+		}
+		realmKey := c.realmKey
+		c.RUnlock()
+		if ok {
+			return key, nil
+		}
+		if realmKey != nil {
+			return realmKey, nil
+		}
+		if attempt == 0 {
+			if err := c.refresh(issuer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("no signing key found for kid %q", kid)
+}
+
+func (k JSONWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.N != "" && k.E != "" {
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %s", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %s", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+	if len(k.X5c) > 0 {
+		der, err := base64.StdEncoding.DecodeString(k.X5c[0])
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("certificate does not hold an RSA key")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("key has neither n/e nor x5c")
+}
+
+// parseRealmKey parses the base64 DER public_key published on a Keycloak realm
+func parseRealmKey(publicKey string) (*rsa.PublicKey, error) {
+	if publicKey == "" {
+		return nil, fmt.Errorf("issuer publishes neither jwks_uri nor public_key")
+	}
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid realm public_key: %s", err)
+	}
+	return jwt.ParseRSAPublicKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// issuerURL returns the issuer with a trailing slash, as expected when
+// building the well-known endpoint.
+func issuerURL(issuer string) string {
+	if len(issuer) > 0 && issuer[len(issuer)-1] != '/' {
+		return issuer + "/"
+	}
+	return issuer
+}
@@ -0,0 +1,146 @@
+package authproxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"example.com/middleend/apierror"
+	"example.com/middleend/logging"
+	"github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultTenantClaim is the token claim read for the tenant when the
+// authproxy configuration does not name one.
+const defaultTenantClaim = "tenant"
+
+// defaultPublicPaths are served without a token when public_paths is not
+// set in the configuration, so that probes and metric scrapers keep working.
+var defaultPublicPaths = []string{"/middleend/healthcheck", "/middleend/healthz", "/middleend/readyz", "/middleend/metrics"}
+
+// Claims holds the verified identity of the caller
+type Claims struct {
+	Subject  string   `json:"sub"`
+	Username string   `json:"preferred_username"`
+	Tenant   string   `json:"tenant"`
+	Roles    []string `json:"roles"`
+}
+
+// HasRole reports whether the caller was granted the given role
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx carrying the verified claims
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims attached by Middleware, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// newClaims maps the token claims on Claims. Roles are collected from the
+// Keycloak realm_access section as well as from a top level roles claim.
+func newClaims(mapClaims jwt.MapClaims, tenantClaim string) *Claims {
+	if tenantClaim == "" {
+		tenantClaim = defaultTenantClaim
+	}
+	claims := &Claims{}
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Username, _ = mapClaims["preferred_username"].(string)
+	claims.Tenant, _ = mapClaims[tenantClaim].(string)
+
+	if realmAccess, ok := mapClaims["realm_access"].(map[string]interface{}); ok {
+		claims.Roles = appendRoles(claims.Roles, realmAccess["roles"])
+	}
+	claims.Roles = appendRoles(claims.Roles, mapClaims["roles"])
+	return claims
+}
+
+func appendRoles(roles []string, value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return roles
+	}
+	for _, v := range list {
+		if role, ok := v.(string); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// tokenFromRequest reads the bearer token from the Authorization header,
+// falling back to the idtoken cookie set by CallbackHandler.
+func tokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		parts := strings.SplitN(auth, " ", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	if cookie, err := r.Cookie("idtoken"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func (h AuthProxy) isPublic(path string) bool {
+	publicPaths := h.AuthProxyConf.PublicPaths
+	if len(publicPaths) == 0 {
+		publicPaths = defaultPublicPaths
+	}
+	for _, p := range publicPaths {
+		if p == path || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware is a mux middleware that authenticates every request routed
+// through the middleend router. The token is verified against the issuer
+// key set and the resulting claims are attached to the request context.
+// When no issuer is configured authentication is disabled.
+func (h AuthProxy) Middleware(next http.Handler) http.Handler {
+	if h.AuthProxyConf.Issuer == "" {
+		log.Warn("[AuthHandler] No issuer configured, middleend APIs are not authenticated")
+		return next
+	}
+	if h.keys == nil {
+		h.keys = newKeyCache()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqToken := tokenFromRequest(r)
+		if reqToken == "" {
+			logging.FromContext(r.Context()).Debugf("[AuthHandler] No token in request %s %s", r.Method, r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="middleend"`)
+			apierror.Write(w, r, http.StatusUnauthorized, "no token in the request")
+			return
+		}
+
+		claims, err := h.validateToken(reqToken)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="middleend", error="invalid_token"`)
+			apierror.Write(w, r, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
@@ -0,0 +1,114 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+// breakerStep is an action on the breaker and the state expected after it
+type breakerStep struct {
+	// action is allow, succeed, fail, release or wait for the cooldown
+	action string
+	// allowed is the expected reply of allow
+	allowed bool
+	state   breakerState
+}
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name: "opens after the threshold",
+			steps: []breakerStep{
+				{action: "fail", state: breakerClosed},
+				{action: "allow", allowed: true, state: breakerClosed},
+				{action: "fail", state: breakerOpen},
+				{action: "allow", allowed: false, state: breakerOpen},
+			},
+		},
+		{
+			name: "a success resets the failures",
+			steps: []breakerStep{
+				{action: "fail", state: breakerClosed},
+				{action: "succeed", state: breakerClosed},
+				{action: "fail", state: breakerClosed},
+			},
+		},
+		{
+			name: "probe closes",
+			steps: []breakerStep{
+				{action: "fail", state: breakerClosed},
+				{action: "fail", state: breakerOpen},
+				{action: "wait", state: breakerOpen},
+				{action: "allow", allowed: true, state: breakerHalfOpen},
+				{action: "allow", allowed: false, state: breakerHalfOpen},
+				{action: "succeed", state: breakerClosed},
+				{action: "allow", allowed: true, state: breakerClosed},
+			},
+		},
+		{
+			name: "failed probe opens again",
+			steps: []breakerStep{
+				{action: "fail", state: breakerClosed},
+				{action: "fail", state: breakerOpen},
+				{action: "wait", state: breakerOpen},
+				{action: "allow", allowed: true, state: breakerHalfOpen},
+				{action: "fail", state: breakerOpen},
+				{action: "allow", allowed: false, state: breakerOpen},
+			},
+		},
+		{
+			name: "released probe lets another through",
+			steps: []breakerStep{
+				{action: "fail", state: breakerClosed},
+				{action: "fail", state: breakerOpen},
+				{action: "wait", state: breakerOpen},
+				{action: "allow", allowed: true, state: breakerHalfOpen},
+				{action: "release", state: breakerHalfOpen},
+				{action: "allow", allowed: true, state: breakerHalfOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(2, cooldown)
+			for i, step := range tt.steps {
+				switch step.action {
+				case "allow":
+					if allowed := b.allow(); allowed != step.allowed {
+						t.Fatalf("step %d: allowed %t, want %t", i, allowed, step.allowed)
+					}
+				case "succeed":
+					b.record(true)
+				case "fail":
+					b.record(false)
+				case "release":
+					b.release()
+				case "wait":
+					time.Sleep(cooldown)
+				}
+				if b.state != step.state {
+					t.Fatalf("step %d (%s): state %s, want %s", i, step.action, b.state, step.state)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package backend implements the http client used by the middleend to
+// call the EMCO services. A single client is shared by all requests, it
+// pools connections, bounds every call with a timeout, retries idempotent
+// calls with jittered backoff and trips a circuit breaker per service.
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/middleend/logging"
+	"example.com/middleend/metrics"
+	"example.com/middleend/tlsutil"
+	"example.com/middleend/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned without calling the service while its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Config holds the tunables of the backend client. Zero values are
+// replaced by the defaults.
+type Config struct {
+	// Timeout of a single attempt in seconds, including reading the body
+	Timeout int `json:"timeout"`
+	// Retries is the number of additional attempts of idempotent calls, negative disables them
+	Retries int `json:"retries"`
+	// BackoffMs is the base delay between attempts in milliseconds
+	BackoffMs int `json:"backoffMs"`
+	// MaxBackoffMs caps the delay between attempts in milliseconds
+	MaxBackoffMs int `json:"maxBackoffMs"`
+	// BreakerThreshold is the number of consecutive failures opening the breaker
+	BreakerThreshold int `json:"breakerThreshold"`
+	// BreakerCooldown is the time in seconds before an open breaker is probed
+	BreakerCooldown int `json:"breakerCooldown"`
+	// MaxIdleConnsPerHost bounds the pooled connections per service
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = 15
+	}
+	if c.Retries < 0 {
+		c.Retries = 0
+	} else if c.Retries == 0 {
+		c.Retries = 2
+	}
+	if c.BackoffMs <= 0 {
+		c.BackoffMs = 200
+	}
+	if c.MaxBackoffMs <= 0 {
+		c.MaxBackoffMs = 2000
+	}
+	if c.BreakerThreshold <= 0 {
+		c.BreakerThreshold = 5
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = 15
+	}
+	if c.MaxIdleConnsPerHost <= 0 {
+		c.MaxIdleConnsPerHost = 16
+	}
+	return c
+}
+
+// Service is an EMCO service as found in the middleend configuration
+type Service struct {
+	// Address is the host:port of the service
+	Address string
+	// TLS secures the calls to the service, which is called over plain
+	// http when nil
+	TLS *tlsutil.ClientConfig
+}
+
+// URL returns the base url of the service. Every url of an EMCO service
+// starts with it.
+func (s Service) URL() string {
+	if s.TLS != nil {
+		return "https://" + s.Address
+	}
+	return "http://" + s.Address
+}
+
+// Client is the shared client for the EMCO services
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	smu      sync.RWMutex
+	services map[string]string       // address -> service name
+	baseURLs map[string]string       // service name -> base url
+	clients  map[string]*http.Client // service name -> client of the tls services
+	tlsConfs map[string]tlsutil.ClientConfig
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewClient creates a client for the services, given by name as found in
+// the middleend configuration.
+func NewClient(cfg Config, services map[string]Service) (*Client, error) {
+	cfg = cfg.withDefaults()
+	c := &Client{
+		cfg:      cfg,
+		http:     newHTTPClient(cfg, nil),
+		breakers: make(map[string]*breaker),
+	}
+	if err := c.SetServices(services); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newHTTPClient creates a pooling client, using tlsConf for https
+func newHTTPClient(cfg Config, tlsConf *tls.Config) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          cfg.MaxIdleConnsPerHost * 4,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConf,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+	}
+}
+
+// SetServices replaces the addresses and tls settings of the services,
+// e.g. when the configuration is reloaded. The circuit breakers of the
+// services are kept, so are the connections of unchanged tls settings.
+// Nothing changes when the tls files of a service fail to load.
+func (c *Client) SetServices(services map[string]Service) error {
+	byAddr := make(map[string]string, len(services))
+	baseURLs := make(map[string]string, len(services))
+	clients := make(map[string]*http.Client)
+	tlsConfs := make(map[string]tlsutil.ClientConfig)
+	c.smu.RLock()
+	prevClients, prevConfs := c.clients, c.tlsConfs
+	c.smu.RUnlock()
+	for name, service := range services {
+		if service.Address == "" {
+			continue
+		}
+		byAddr[service.Address] = name
+		baseURLs[name] = service.URL()
+		if service.TLS == nil {
+			continue
+		}
+		tlsConfs[name] = *service.TLS
+		if prev, ok := prevClients[name]; ok && prevConfs[name] == *service.TLS {
+			clients[name] = prev
+			continue
+		}
+		tlsConf, err := service.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("tls of service %s: %v", name, err)
+		}
+		clients[name] = newHTTPClient(c.cfg, tlsConf)
+	}
+	c.smu.Lock()
+	c.services = byAddr
+	c.baseURLs = baseURLs
+	c.clients = clients
+	c.tlsConfs = tlsConfs
+	c.smu.Unlock()
+	return nil
+}
+
+// httpFor returns the client calling the named service
+func (c *Client) httpFor(service string) *http.Client {
+	c.smu.RLock()
+	defer c.smu.RUnlock()
+	if client, ok := c.clients[service]; ok {
+		return client
+	}
+	return c.http
+}
+
+// BaseURL returns the base url of the named service
+func (c *Client) BaseURL(service string) string {
+	c.smu.RLock()
+	defer c.smu.RUnlock()
+	return c.baseURLs[service]
+}
+
+// Services returns the names of the configured services, sorted
+func (c *Client) Services() []string {
+	c.smu.RLock()
+	defer c.smu.RUnlock()
+	names := make([]string, 0, len(c.baseURLs))
+	for name := range c.baseURLs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ping checks that the named service answers http requests. Any reply,
+// whatever its status, counts; only transport errors and timeouts fail.
+// It bypasses the retries and the circuit breaker, which would hide the
+// actual state of the service.
+func (c *Client) Ping(ctx context.Context, service string) error {
+	c.smu.RLock()
+	base, ok := c.baseURLs[service]
+	c.smu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown service %s", service)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpFor(service).Do(req)
+	if err != nil {
+		return err
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// ServiceOf names the service of a request, falling back to its host for
+// addresses which are not part of the configuration.
+func (c *Client) ServiceOf(req *http.Request) string {
+	c.smu.RLock()
+	defer c.smu.RUnlock()
+	if name, ok := c.services[req.URL.Host]; ok {
+		return name
+	}
+	return req.URL.Host
+}
+
+func (c *Client) breakerFor(service string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[service]
+	if !ok {
+		b = newBreaker(c.cfg.BreakerThreshold, time.Duration(c.cfg.BreakerCooldown)*time.Second)
+		c.breakers[service] = b
+	}
+	return b
+}
+
+// idempotent reports whether the request may be sent more than once
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.GetBody != nil
+	}
+	return false
+}
+
+// retryable reports whether the status code signals a transient failure
+func retryable(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := time.Duration(c.cfg.BackoffMs) * time.Millisecond << uint(attempt)
+	if max := time.Duration(c.cfg.MaxBackoffMs) * time.Millisecond; d > max {
+		d = max
+	}
+	// full jitter spreads the retries of concurrent requests
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// Do sends the request. Transport errors and 502/503/504 replies count as
+// failures of the service; idempotent requests are retried on them while
+// the request context is alive. The call is traced as a client span whose
+// context is sent to the service in the traceparent header.
+func (c *Client) Do(req *http.Request) (resp *http.Response, err error) {
+	service := c.ServiceOf(req)
+	ctx, span := tracing.Start(req.Context(), req.Method+" "+service, tracing.KindClient)
+	tried := 0
+	defer func() {
+		span.SetAttribute("http.attempts", tried)
+		if resp != nil {
+			span.SetAttribute("http.status_code", resp.StatusCode)
+			if resp.StatusCode >= http.StatusInternalServerError {
+				span.SetStatus(tracing.StatusError, resp.Status)
+			}
+		}
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("peer.service", service)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	req = req.WithContext(ctx)
+	tracing.Inject(ctx, req.Header)
+	if id := logging.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+
+	b := c.breakerFor(service)
+	client := c.httpFor(service)
+	attempts := 1
+	if idempotent(req) {
+		attempts += c.cfg.Retries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(req.Context(), c.backoff(attempt-1)); err != nil {
+				return nil, err
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+		if !b.allow() {
+			return nil, fmt.Errorf("%s %s: %s: %w", req.Method, req.URL, service, ErrCircuitOpen)
+		}
+
+		tried++
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil && req.Context().Err() != nil {
+			// cancelled by the caller, this says nothing about the service
+			b.release()
+			return nil, err
+		}
+		code := 0
+		if resp != nil {
+			code = resp.StatusCode
+		}
+		metrics.ObserveBackend(service, req.Method, code, err, time.Since(start))
+		failed := err != nil || retryable(resp.StatusCode)
+		c.recordOutcome(b, service, !failed)
+		logging.FromContext(ctx).WithFields(log.Fields{
+			"service": service,
+			"method":  req.Method,
+			"url":     req.URL.String(),
+			"attempt": attempt + 1,
+			"took":    time.Since(start).String(),
+		}).Debug("backend request")
+
+		if !failed {
+			return resp, nil
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if attempt == attempts-1 {
+			return resp, nil
+		}
+		// drain the body so the connection can be reused
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		lastErr = fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	return nil, lastErr
+}
+
+func (c *Client) recordOutcome(b *breaker, service string, success bool) {
+	prev, cur := b.record(success)
+	if prev != cur {
+		log.Warnf("Circuit breaker of service %s changed from %s to %s", service, prev, cur)
+		metrics.SetCircuitOpen(service, cur != breakerClosed)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,230 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package backend
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeService replies the codes in turn, repeating the last one, and
+// counts the requests it gets
+type fakeService struct {
+	mu    sync.Mutex
+	codes []int
+	calls int
+	// bodies are the bodies of the requests, to check those resent
+	bodies []string
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	f.mu.Lock()
+	code := f.codes[len(f.codes)-1]
+	if f.calls < len(f.codes) {
+		code = f.codes[f.calls]
+	}
+	f.calls++
+	f.bodies = append(f.bodies, string(body))
+	f.mu.Unlock()
+	w.WriteHeader(code)
+}
+
+func (f *fakeService) replies(codes ...int) {
+	f.mu.Lock()
+	f.codes = codes
+	f.mu.Unlock()
+}
+
+// newTestClient returns a client of the service orchestrator served by h
+func newTestClient(t *testing.T, cfg Config, h http.Handler) (*Client, string) {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	addr := strings.TrimPrefix(ts.URL, "http://")
+	c, err := NewClient(cfg, map[string]Service{"orchestrator": {Address: addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ts.URL
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		codes  []int
+		calls  int
+		code   int
+	}{
+		{"GET retried on 503", http.MethodGet, "", []int{503, 200}, 2, 200},
+		{"GET retried on 502 and 504", http.MethodGet, "", []int{502, 504, 200}, 3, 200},
+		{"GET replies the last failure", http.MethodGet, "", []int{503}, 3, 503},
+		{"GET not retried on 500", http.MethodGet, "", []int{500, 200}, 1, 500},
+		{"GET not retried on 404", http.MethodGet, "", []int{404, 200}, 1, 404},
+		{"PUT retried with its body", http.MethodPut, "spec", []int{503, 200}, 2, 200},
+		{"DELETE retried", http.MethodDelete, "", []int{503, 204}, 2, 204},
+		{"POST not retried", http.MethodPost, "spec", []int{503, 200}, 1, 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{codes: tt.codes}
+			c, url := newTestClient(t, Config{Retries: 2, BackoffMs: 1, MaxBackoffMs: 2}, svc)
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, url+"/v2/projects", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("code %d, want %d", resp.StatusCode, tt.code)
+			}
+			if svc.calls != tt.calls {
+				t.Errorf("%d calls, want %d", svc.calls, tt.calls)
+			}
+			for i, got := range svc.bodies {
+				if got != tt.body {
+					t.Errorf("call %d sent the body %q, want %q", i+1, got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	svc := &fakeService{codes: []int{503}}
+	c, url := newTestClient(t, Config{Retries: -1, BreakerThreshold: 2}, svc)
+	c.breakers["orchestrator"] = newBreaker(2, cooldown)
+	get := func() (int, error) {
+		req, _ := http.NewRequest(http.MethodGet, url+"/v2/projects", nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	tests := []struct {
+		name  string
+		wait  bool
+		codes []int
+		code  int
+		err   error
+		calls int
+		state breakerState
+	}{
+		{name: "first failure", code: 503, calls: 1, state: breakerClosed},
+		{name: "threshold reached", code: 503, calls: 2, state: breakerOpen},
+		{name: "open", err: ErrCircuitOpen, calls: 2, state: breakerOpen},
+		{name: "failed probe", wait: true, code: 503, calls: 3, state: breakerOpen},
+		{name: "open again", err: ErrCircuitOpen, calls: 3, state: breakerOpen},
+		{name: "probe", wait: true, codes: []int{200}, code: 200, calls: 4, state: breakerClosed},
+		{name: "closed", code: 200, calls: 5, state: breakerClosed},
+	}
+	for _, tt := range tests {
+		if tt.wait {
+			time.Sleep(cooldown)
+		}
+		if tt.codes != nil {
+			svc.replies(tt.codes...)
+		}
+		code, err := get()
+		if !errors.Is(err, tt.err) || code != tt.code {
+			t.Fatalf("%s: replied %d, %v, want %d, %v", tt.name, code, err, tt.code, tt.err)
+		}
+		if svc.calls != tt.calls {
+			t.Fatalf("%s: %d calls, want %d", tt.name, svc.calls, tt.calls)
+		}
+		if state := c.breakerFor("orchestrator").state; state != tt.state {
+			t.Fatalf("%s: breaker %s, want %s", tt.name, state, tt.state)
+		}
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	done := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	})
+	c, url := newTestClient(t, Config{Timeout: 1, Retries: -1}, slow)
+	defer close(done)
+
+	req, _ := http.NewRequest(http.MethodGet, url+"/v2/projects", nil)
+	start := time.Now()
+	_, err := c.Do(req)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("replied %v, want a timeout", err)
+	}
+	if took := time.Since(start); took > 3*time.Second {
+		t.Fatalf("the call took %s with a timeout of 1s", took)
+	}
+}
+
+func TestClientBackoff(t *testing.T) {
+	c, err := NewClient(Config{BackoffMs: 10, MaxBackoffMs: 50}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{1, 20 * time.Millisecond},
+		{2, 40 * time.Millisecond},
+		{3, 50 * time.Millisecond},
+		{8, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		// full jitter draws the delay anywhere up to the capped backoff
+		seen := map[time.Duration]bool{}
+		var low bool
+		for i := 0; i < 200; i++ {
+			d := c.backoff(tt.attempt)
+			if d < 0 || d > tt.max {
+				t.Fatalf("attempt %d: backoff %s out of [0, %s]", tt.attempt, d, tt.max)
+			}
+			seen[d] = true
+			low = low || d < tt.max/2
+		}
+		if len(seen) < 2 || !low {
+			t.Errorf("attempt %d: the backoff is not jittered over [0, %s]", tt.attempt, tt.max)
+		}
+	}
+}
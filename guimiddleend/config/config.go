@@ -0,0 +1,193 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package config loads the middleend configuration file. The values of
+// the file are overridden by environment variables named after the json
+// keys, e.g. MIDDLEEND_LOG_LEVEL for logLevel and MIDDLEEND_BACKEND_TIMEOUT
+// for the timeout of the backend section.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultPath is the configuration file of the middleend container
+	DefaultPath = "/opt/emco/config/middleend.conf"
+	// PathEnv names the environment variable holding the configuration path
+	PathEnv = "MIDDLEEND_CONFIG"
+	// EnvPrefix prefixes the environment variables overriding the configuration
+	EnvPrefix = "MIDDLEEND"
+)
+
+// ResolvePath returns the configuration path given on the command line,
+// else the one of the environment, else the default one.
+func ResolvePath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if path := os.Getenv(PathEnv); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// Load reads the json file at path into each of targets, which are
+// pointers to structs, and applies the environment overrides to them.
+func Load(path string, targets ...interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := json.Unmarshal(data, target); err != nil {
+			return fmt.Errorf("%s: %s", path, describe(data, err))
+		}
+		if err := ApplyEnv(EnvPrefix, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describe locates json syntax and type errors in the file
+func describe(data []byte, err error) string {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+		if e.Field != "" {
+			return fmt.Sprintf("line %d: %s must be a %s, not a %s", line(data, offset), e.Field, e.Type, e.Value)
+		}
+	default:
+		return err.Error()
+	}
+	return fmt.Sprintf("line %d: %s", line(data, offset), err)
+}
+
+func line(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// ApplyEnv overrides the fields of the struct pointed to by v with the
+// environment variables prefix_KEY, where KEY is the json key of the field
+// in upper snake case. Nested structs extend the prefix with their key.
+func ApplyEnv(prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T is not a pointer to a struct", v)
+	}
+	return applyEnv(prefix, rv.Elem())
+}
+
+func applyEnv(prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		name := prefix + "_" + EnvName(key)
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(name, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := set(fv, value); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func set(fv reflect.Value, value string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		fv.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// EnvName converts a json key to its environment variable form, e.g.
+// logLevel to LOG_LEVEL and redirect_uri to REDIRECT_URI.
+func EnvName(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && runes[i-1] != '_' &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
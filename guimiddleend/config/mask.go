@@ -0,0 +1,92 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const masked = "******"
+
+// secretKey matches the json keys whose values are credentials
+var secretKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|private|apikey|api_key)`)
+
+// Masked returns the configurations merged into a single map as found in
+// the file, with the credentials masked so that it can be printed.
+func Masked(confs ...interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, conf := range confs {
+		data, err := json.Marshal(conf)
+		if err != nil {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		for k, v := range m {
+			out[k] = maskValue(k, v)
+		}
+	}
+	return out
+}
+
+func maskValue(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = maskValue(k, item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = maskValue(key, item)
+		}
+		return value
+	case string:
+		if value != "" && secretKey.MatchString(key) {
+			return masked
+		}
+		return maskUserinfo(value)
+	}
+	return v
+}
+
+// maskUserinfo hides the password of addresses like user:pass@host:port
+func maskUserinfo(s string) string {
+	at := strings.LastIndex(s, "@")
+	if at < 0 {
+		return s
+	}
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || u.User == nil {
+			return s
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), masked)
+		}
+		return u.String()
+	}
+	prefix := s[:at]
+	if colon := strings.Index(prefix, ":"); colon >= 0 {
+		return prefix[:colon+1] + masked + s[at:]
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Watch calls reload on SIGHUP and whenever the file at path changes,
+// checking it once per interval, until stop is closed. Kubernetes replaces
+// the files of a mounted configmap, which changes their modification time.
+func Watch(path string, interval time.Duration, stop <-chan struct{}, reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.WithError(err).Warnf("Failed to stat configuration %s", path)
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+	last := modTime()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-hup:
+			log.Infof("Received SIGHUP, reloading configuration %s", path)
+			last = modTime()
+			reload()
+		case <-ticker.C:
+			cur := modTime()
+			if cur.IsZero() || cur.Equal(last) {
+				continue
+			}
+			last = cur
+			log.Infof("Configuration %s changed, reloading it", path)
+			reload()
+		}
+	}
+}
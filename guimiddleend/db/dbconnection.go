@@ -16,22 +16,58 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"example.com/middleend/logging"
 	pkgerrors "github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/net/context"
+)
+
+const (
+	// healthCheckTimeout bounds HealthCheck, the driver would otherwise wait
+	// for the server selection timeout while mongo is down
+	healthCheckTimeout = 5 * time.Second
+	// operationTimeout bounds the operations whose context has no deadline
+	operationTimeout = 30 * time.Second
 )
 
 // MongoStore is the interface which implements the db.Store interface
 type MongoStore struct {
 	db *mongo.Database
+
+	// transactions tells whether the deployment supports transactions,
+	// which need a replica set. Without them txMu serializes the
+	// transactions of the middleend and the writes, and undo has the
+	// documents changed by the transaction running.
+	txCheck      sync.Mutex
+	txChecked    bool
+	transactions bool
+	txMu         sync.Mutex
+	undo         *mongoUndo
+}
+
+// logger returns the logger of the request of ctx, if any
+func (m *MongoStore) logger(ctx context.Context) *log.Entry {
+	return logging.FromContext(ctx)
+}
+
+// opContext bounds an operation by operationTimeout, unless ctx has a
+// deadline already
+func opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, operationTimeout)
 }
 
 // Key interface
@@ -40,19 +76,39 @@ type Key interface{}
 // DBconn variable of type Store
 var DBconn Store
 
-// Store Interface which implements the data store functions
+// Store Interface which implements the data store functions. The
+// operations are bound by the deadline of their context and log through
+// the logger of the request it carries.
 type Store interface {
-	HealthCheck() error
-	Find(coll string, key Key, tag string) ([][]byte, error)
-	Insert(coll string, key Key, query interface{}, tag string, data interface{}) error
+	HealthCheck(ctx context.Context) error
+	Find(ctx context.Context, coll string, key Key, tag string) ([][]byte, error)
+	Insert(ctx context.Context, coll string, key Key, query interface{}, tag string, data interface{}) error
 	Unmarshal(inp []byte, out interface{}) error
-	CheckCollectionExists(coll string) bool
+	CheckCollectionExists(ctx context.Context, coll string) bool
 	// Update(coll string, query interface{}, data interface{}) error
-	Update(coll string, operation string,
+	Update(ctx context.Context, coll string, operation string,
 		vars map[string]string, appName string, data interface{}) error
-	Delete(coll string, vars map[string]string) error
-	Remove(coll string, key Key) error
-	RemoveAll(coll string, key Key) error
+	Delete(ctx context.Context, coll string, vars map[string]string) error
+	Remove(ctx context.Context, coll string, key Key) error
+	RemoveAll(ctx context.Context, coll string, key Key) error
+	// EnsureIndexes creates the indexes missing from their collections
+	EnsureIndexes(ctx context.Context, indexes []Index) error
+	// Documents returns the whole documents of coll which have the field
+	// tag, all of them when tag is empty
+	Documents(ctx context.Context, coll string, tag string) ([]bson.Raw, error)
+	// ReplaceDocument replaces the document of coll with the _id of doc
+	ReplaceDocument(ctx context.Context, coll string, doc bson.Raw) error
+	// WithTransaction runs fn in a transaction: the operations fn makes
+	// with the context it is given are kept when fn succeeds, and undone
+	// when it fails. A store without native transactions, as a
+	// standalone mongo, applies them as they come, so that other reads
+	// may see them before fn ends, and runs its transactions one at a
+	// time with the writes made outside of them. fn may run more than
+	// once when the transaction conflicts with another one.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// Close releases the connections of the store, pending operations
+	// get until the deadline of ctx
+	Close(ctx context.Context) error
 }
 
 // NewMongoStore Return mongo client
@@ -160,8 +216,9 @@ var decodeBytes = func(sr *mongo.SingleResult) (bson.Raw, error) {
 	return sr.DecodeBytes()
 }
 
+// updateFilter sets the json fields of key, which keep their json types
 func (m *MongoStore) updateFilter(key interface{}) (primitive.M, error) {
-	var n map[string]string
+	var n map[string]interface{}
 	st, err := json.Marshal(key)
 	if err != nil {
 		return primitive.M{}, pkgerrors.Errorf("Error Marshalling key: %s", err.Error())
@@ -198,25 +255,21 @@ func (m *MongoStore) validateParams(args ...interface{}) bool {
 	return true
 }
 
-// CreateDBClient creates the DB client. currently only mongo
-func CreateDBClient(dbType string, dbName string, svcEp string) error {
-	var err error
-	switch dbType {
-	case "mongo":
-		DBconn, err = NewMongoStore(dbName, nil, svcEp)
-	default:
-		log.Error(dbType + "DB not supported")
+// HealthCheck verifies the database connection. ping needs no privilege,
+// unlike serverStatus, so it works with the restricted middleend user.
+func (m *MongoStore) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	_, err := (*mongo.SingleResult).DecodeBytes(m.db.RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}))
+	if err != nil {
+		m.logger(ctx).Errorf("Error pinging the DB: err %s", err)
 	}
 	return err
 }
 
-// HealthCheck verifies the database connection
-func (m *MongoStore) HealthCheck() error {
-	_, err := (*mongo.SingleResult).DecodeBytes(m.db.RunCommand(context.Background(), bson.D{{"serverStatus", 1}}))
-	if err != nil {
-		log.Error("Error getting DB server status: err %s", err)
-	}
-	return nil
+// Close disconnects the mongo client of the store
+func (m *MongoStore) Close(ctx context.Context) error {
+	return m.db.Client().Disconnect(ctx)
 }
 
 func (m *MongoStore) Unmarshal(inp []byte, out interface{}) error {
@@ -229,16 +282,27 @@ func (m *MongoStore) Unmarshal(inp []byte, out interface{}) error {
 }
 
 // Check if given collection exists in database
-func (m *MongoStore) CheckCollectionExists(coll string) bool {
-	names, err := m.db.ListCollectionNames(context.Background(), bson.D{})
+func (m *MongoStore) CheckCollectionExists(ctx context.Context, coll string) bool {
+	ctx, cancel := opContext(ctx)
+	defer cancel()
+	listCtx := ctx
+	if mongo.SessionFromContext(ctx) != nil {
+		// listCollections is not allowed in a transaction, it runs
+		// outside of it within the same deadline
+		deadline, _ := ctx.Deadline()
+		var cancelList context.CancelFunc
+		listCtx, cancelList = context.WithDeadline(context.Background(), deadline)
+		defer cancelList()
+	}
+	names, err := m.db.ListCollectionNames(listCtx, bson.D{})
 	if err != nil {
-		log.Error("Failed to fetch collection names: %s", err)
+		m.logger(ctx).Errorf("Failed to fetch collection names: %s", err)
 		return false
 	}
 
 	for _, name := range names {
 		if name == coll {
-			log.Infof("Collection %s exists", coll)
+			m.logger(ctx).Infof("Collection %s exists", coll)
 			return true
 		}
 	}
@@ -246,13 +310,14 @@ func (m *MongoStore) CheckCollectionExists(coll string) bool {
 }
 
 // Insert is used to insert/add element to a document
-func (m *MongoStore) Insert(coll string, key Key, query interface{}, tag string, data interface{}) error {
+func (m *MongoStore) Insert(ctx context.Context, coll string, key Key, query interface{}, tag string, data interface{}) error {
 	if data == nil || !m.validateParams(coll, key, tag) {
 		return pkgerrors.New("No Data to store")
 	}
 
 	c := m.db.Collection(coll)
-	ctx := context.Background()
+	ctx, cancel := opContext(ctx)
+	defer cancel()
 
 	filter, err := m.findFilter(key)
 	if err != nil {
@@ -263,14 +328,19 @@ func (m *MongoStore) Insert(coll string, key Key, query interface{}, tag string,
 	if err != nil {
 		return err
 	}
+	done, err := m.writing(ctx, coll, filter)
+	if err != nil {
+		return err
+	}
+	defer done()
 	_, err = decodeBytes(
 		c.FindOneAndUpdate(
 			ctx,
 			filter,
 			bson.D{
-				{"$set", bson.D{
-					{tag, data},
-					{"key", s},
+				{Key: "$set", Value: bson.D{
+					{Key: tag, Value: data},
+					{Key: "key", Value: s},
 				}},
 			},
 			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)))
@@ -299,7 +369,7 @@ func (m *MongoStore) Insert(coll string, key Key, query interface{}, tag string,
 }
 
 // Find a document
-func (m *MongoStore) Find(coll string, key Key, tag string) ([][]byte, error) {
+func (m *MongoStore) Find(ctx context.Context, coll string, key Key, tag string) ([][]byte, error) {
 	// result, err := m.findInternal(coll, key, tag, "")
 	// return result, err
 	if !m.validateParams(coll, key, tag) {
@@ -311,24 +381,26 @@ func (m *MongoStore) Find(coll string, key Key, tag string) ([][]byte, error) {
 		return nil, err
 	}
 
-	log.Infof("mongo filter %+v : tag %s", filter, tag)
+	m.logger(ctx).Infof("mongo filter %+v : tag %s", filter, tag)
 	projection := bson.D{
-		{tag, 1},
-		{"_id", 0},
+		{Key: tag, Value: 1},
+		{Key: "_id", Value: 0},
 	}
 
 	c := m.db.Collection(coll)
+	ctx, cancel := opContext(ctx)
+	defer cancel()
 
-	cursor, err := c.Find(context.Background(), filter, options.Find().SetProjection(projection))
+	cursor, err := c.Find(ctx, filter, options.Find().SetProjection(projection))
 	if err != nil {
-		log.Errorf("Failed to find the document: %s", err)
+		m.logger(ctx).Errorf("Failed to find the document: %s", err)
 		return nil, err
 	}
 
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 	var data []byte
 	var result [][]byte
-	for cursor.Next(context.Background()) {
+	for cursor.Next(ctx) {
 		d := cursor.Current
 		switch d.Lookup(tag).Type {
 		case bson.TypeString:
@@ -336,17 +408,21 @@ func (m *MongoStore) Find(coll string, key Key, tag string) ([][]byte, error) {
 		default:
 			r, err := d.LookupErr(tag)
 			if err != nil {
-				log.Errorf("Unable to read data: %s err: %s", string(r.Value), err)
+				m.logger(ctx).Errorf("Unable to read data: %s err: %s", string(r.Value), err)
 			}
 			data = r.Value
 		}
 		result = append(result, data)
 	}
+	if err := cursor.Err(); err != nil {
+		m.logger(ctx).Errorf("Failed to read the documents: %s", err)
+		return nil, err
+	}
 	return result, nil
 }
 
 // Update is used to add element to a document
-func (m *MongoStore) Update(coll string, operation string,
+func (m *MongoStore) Update(ctx context.Context, coll string, operation string,
 	vars map[string]string, appName string, data interface{},
 ) error {
 	c := m.db.Collection(coll)
@@ -392,41 +468,64 @@ func (m *MongoStore) Update(coll string, operation string,
 		dbUpdateContent = bson.M{"$pull": bson.M{"appmetadata.spec.compositeProfiles.0.spec.profile": bson.M{"spec.appname": vars["appName"]}}}
 	}
 
-	updatedResult, err := c.UpdateOne(context.Background(), query, dbUpdateContent)
+	if query == nil {
+		return pkgerrors.Errorf("Unknown update operation %s", operation)
+	}
+	ctx, cancel := opContext(ctx)
+	defer cancel()
+	done, err := m.writing(ctx, coll, query)
 	if err != nil {
-		log.Errorf("Encountered error while update of document: %s", err)
 		return err
 	}
-	log.Infof("Updated document is: %s", updatedResult)
+	defer done()
+	updatedResult, err := c.UpdateOne(ctx, query, dbUpdateContent)
+	if err != nil {
+		m.logger(ctx).Errorf("Encountered error while update of document: %s", err)
+		return err
+	}
+	m.logger(ctx).Infof("Updated %d document(s)", updatedResult.ModifiedCount)
 	return nil
 }
 
 // Delete is used to delete the document
-func (m *MongoStore) Delete(coll string, vars map[string]string) error {
+func (m *MongoStore) Delete(ctx context.Context, coll string, vars map[string]string) error {
 	c := m.db.Collection(coll)
 	query := bson.M{
 		"project": vars["projectName"], "compositeapp": vars["compositeAppName"],
 		"compositeappversion": vars["version"],
 	}
-	_, err := c.DeleteOne(context.Background(), query)
+	ctx, cancel := opContext(ctx)
+	defer cancel()
+	done, err := m.writing(ctx, coll, query)
+	if err != nil {
+		return err
+	}
+	defer done()
+	_, err = c.DeleteOne(ctx, query)
 	if err != nil {
-		log.Errorf("Encountered error while removing the document: %s", err)
+		m.logger(ctx).Errorf("Encountered error while removing the document: %s", err)
 		return err
 	}
 	return nil
 }
 
 // RemoveAll method to removes all the documet matching key
-func (m *MongoStore) RemoveAll(coll string, key Key) error {
+func (m *MongoStore) RemoveAll(ctx context.Context, coll string, key Key) error {
 	if !m.validateParams(coll, key) {
 		return pkgerrors.New("Mandatory fields are missing")
 	}
 	c := m.db.Collection(coll)
-	ctx := context.Background()
+	ctx, cancel := opContext(ctx)
+	defer cancel()
 	filter, err := m.findFilter(key)
 	if err != nil {
 		return err
 	}
+	done, err := m.writing(ctx, coll, filter)
+	if err != nil {
+		return err
+	}
+	defer done()
 	_, err = c.DeleteMany(ctx, filter)
 	if err != nil {
 		return pkgerrors.Errorf("Error Deleting from database: %s", err.Error())
@@ -434,17 +533,23 @@ func (m *MongoStore) RemoveAll(coll string, key Key) error {
 	return nil
 }
 
-func (m *MongoStore) Remove(coll string, key Key) error {
+func (m *MongoStore) Remove(ctx context.Context, coll string, key Key) error {
 	if !m.validateParams(coll, key) {
 		return pkgerrors.New("Mandatory fields are missing")
 	}
 	c := m.db.Collection(coll)
-	ctx := context.Background()
+	ctx, cancel := opContext(ctx)
+	defer cancel()
 	filter, err := m.findFilter(key)
 	if err != nil {
 		return err
 	}
-	count, err := c.CountDocuments(context.Background(), filter)
+	done, err := m.writing(ctx, coll, filter)
+	if err != nil {
+		return err
+	}
+	defer done()
+	count, err := c.CountDocuments(ctx, filter)
 	if err != nil {
 		return pkgerrors.Errorf("Error finding: %s", err.Error())
 	}
@@ -460,3 +565,139 @@ func (m *MongoStore) Remove(coll string, key Key) error {
 	}
 	return nil
 }
+
+// EnsureIndexes creates the indexes in mongo, an index which exists
+// already is left as is
+func (m *MongoStore) EnsureIndexes(ctx context.Context, indexes []Index) error {
+	var errs []string
+	for _, index := range indexes {
+		keys := bson.D{}
+		for _, k := range index.Keys {
+			keys = append(keys, bson.E{Key: k, Value: 1})
+		}
+		opCtx, cancel := opContext(ctx)
+		name, err := m.db.Collection(index.Collection).Indexes().CreateOne(opCtx, mongo.IndexModel{Keys: keys})
+		cancel()
+		if err != nil {
+			errs = append(errs, index.Collection+": "+err.Error())
+			continue
+		}
+		m.logger(ctx).Debugf("Index %s of %s is ready", name, index.Collection)
+	}
+	if len(errs) > 0 {
+		return pkgerrors.Errorf("Error creating indexes: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Documents returns the documents of coll having the field tag
+func (m *MongoStore) Documents(ctx context.Context, coll string, tag string) ([]bson.Raw, error) {
+	if !m.validateParams(coll) {
+		return nil, pkgerrors.New("Mandatory fields are missing")
+	}
+	filter := bson.D{}
+	if tag != "" {
+		filter = bson.D{{Key: tag, Value: bson.D{{Key: "$exists", Value: true}}}}
+	}
+	c := m.db.Collection(coll)
+	ctx, cancel := opContext(ctx)
+	defer cancel()
+
+	cursor, err := c.Find(ctx, filter)
+	if err != nil {
+		m.logger(ctx).Errorf("Failed to find the documents: %s", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var docs []bson.Raw
+	for cursor.Next(ctx) {
+		// the cursor reuses its buffer
+		docs = append(docs, append(bson.Raw(nil), cursor.Current...))
+	}
+	if err := cursor.Err(); err != nil {
+		m.logger(ctx).Errorf("Failed to read the documents: %s", err)
+		return nil, err
+	}
+	return docs, nil
+}
+
+// ReplaceDocument replaces the document of coll with the _id of doc
+func (m *MongoStore) ReplaceDocument(ctx context.Context, coll string, doc bson.Raw) error {
+	if !m.validateParams(coll) {
+		return pkgerrors.New("Mandatory fields are missing")
+	}
+	id, err := doc.LookupErr("_id")
+	if err != nil {
+		return pkgerrors.New("The document has no _id")
+	}
+	c := m.db.Collection(coll)
+	ctx, cancel := opContext(ctx)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	done, err := m.writing(ctx, coll, filter)
+	if err != nil {
+		return err
+	}
+	defer done()
+	res, err := c.ReplaceOne(ctx, filter, doc)
+	if err != nil {
+		return pkgerrors.Errorf("Error replacing the document: %s", err.Error())
+	}
+	if res.MatchedCount == 0 {
+		return pkgerrors.Errorf("key not found")
+	}
+	return nil
+}
+
+// WithTransaction runs fn in a mongo transaction, which fn joins when ctx
+// is in one already. A standalone mongo has no transactions: the
+// transactions of the middleend are serialized with its writes instead,
+// and the documents fn changed are restored when it fails.
+func (m *MongoStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil || ctx.Value(txKey{}) == m {
+		return fn(ctx)
+	}
+	supported, err := m.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return m.standaloneTransaction(ctx, fn)
+	}
+
+	session, err := m.db.Client().StartSession()
+	if err != nil {
+		return pkgerrors.Errorf("Error starting a session: %s", err.Error())
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// supportsTransactions tells whether mongo is a replica set or a sharded
+// cluster, the deployments with transactions
+func (m *MongoStore) supportsTransactions(ctx context.Context) (bool, error) {
+	m.txCheck.Lock()
+	defer m.txCheck.Unlock()
+	if m.txChecked {
+		return m.transactions, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	var reply struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := m.db.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&reply); err != nil {
+		return false, pkgerrors.Errorf("Error checking the mongo deployment: %s", err.Error())
+	}
+	m.transactions = reply.SetName != "" || reply.Msg == "isdbgrid"
+	m.txChecked = true
+	if !m.transactions {
+		m.logger(ctx).Warn("Mongo is standalone, the transactions of the middleend are serialized and undone by the middleend")
+	}
+	return m.transactions, nil
+}
@@ -0,0 +1,118 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// collectionExt is the extension of the collection files
+const collectionExt = ".bson"
+
+// NewFileStore returns a MemoryStore kept in the directory dir, which is
+// created when missing. Each collection is saved in a file of its own as
+// the concatenation of its documents, the format of mongodump, so the
+// files can be loaded into mongo with mongorestore. A collection is
+// rewritten on each of its writes, the store suits small databases only.
+func NewFileStore(dir string) (*MemoryStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMemoryStore()
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != collectionExt {
+			continue
+		}
+		docs, err := readCollection(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m.colls[strings.TrimSuffix(f.Name(), collectionExt)] = docs
+	}
+	m.save = func(coll string, docs []bson.Raw) error {
+		return writeCollection(dir, coll, docs)
+	}
+	return m, nil
+}
+
+func readCollection(path string) ([]bson.Raw, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	docs := []bson.Raw{}
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, pkgerrors.Errorf("%s: truncated document", path)
+		}
+		n := int(binary.LittleEndian.Uint32(data))
+		if n < 5 || n > len(data) {
+			return nil, pkgerrors.Errorf("%s: truncated document", path)
+		}
+		doc := bson.Raw(data[:n])
+		if err := doc.Validate(); err != nil {
+			return nil, pkgerrors.Errorf("%s: %s", path, err.Error())
+		}
+		docs = append(docs, doc)
+		data = data[n:]
+	}
+	return docs, nil
+}
+
+// writeCollection replaces the file of coll in dir, through a temporary
+// file so that a crash leaves either version. nil docs removes the file.
+func writeCollection(dir string, coll string, docs []bson.Raw) error {
+	if strings.ContainsAny(coll, `/\`) {
+		return pkgerrors.Errorf("invalid collection name %q", coll)
+	}
+	if docs == nil {
+		err := os.Remove(filepath.Join(dir, coll+collectionExt))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "."+coll+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	for _, doc := range docs {
+		if _, err := f.Write(doc); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, coll+collectionExt))
+}
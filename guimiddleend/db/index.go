@@ -0,0 +1,50 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import "context"
+
+// Index is an ascending index of the collection on the fields Keys. The
+// key field, which the lookups with empty key fields filter on, usually
+// follows the fields every lookup of the collection sets.
+type Index struct {
+	Collection string
+	Keys       []string
+}
+
+// txKey marks the context of a transaction run by a store without native
+// transactions, its value is the store
+type txKey struct{}
+
+// noTxKey marks the context of the writes kept out of the transactions
+type noTxKey struct{}
+
+// WithoutTransaction returns a context whose writes are kept out of the
+// transactions of the stores: they neither wait for the transaction
+// running nor are undone when it fails. It is meant for the records of
+// the progress of a request, written while the request runs its
+// transactions. ctx must not be the context of a transaction, and the
+// collections written with it must not be written by the transactions:
+// the memory store undoes a transaction by collection.
+func WithoutTransaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTxKey{}, true)
+}
+
+// outsideTransactions tells whether the writes of ctx are kept out of the
+// transactions
+func outsideTransactions(ctx context.Context) bool {
+	return ctx.Value(noTxKey{}) != nil
+}
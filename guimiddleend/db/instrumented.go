@@ -0,0 +1,140 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"example.com/middleend/metrics"
+	"example.com/middleend/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// instrumentedStore records the latency and the errors of the operations
+// of the wrapped Store, and traces each of them as a child of the span of
+// their context
+type instrumentedStore struct {
+	Store
+}
+
+func newInstrumentedStore(s Store) Store {
+	return &instrumentedStore{Store: s}
+}
+
+// begin starts the span of an operation, the returned context carries it
+// and the returned function ends it and records its metrics
+func (s *instrumentedStore) begin(ctx context.Context, operation string, coll string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "mongo "+operation, tracing.KindClient)
+	span.SetAttribute("db.system", "mongodb")
+	span.SetAttribute("db.operation", operation)
+	if coll != "" {
+		span.SetAttribute("db.mongodb.collection", coll)
+	}
+	start := time.Now()
+	return ctx, func(err error) {
+		metrics.ObserveMongo(operation, coll, err, time.Since(start))
+		span.RecordError(err)
+		span.End()
+	}
+}
+
+func (s *instrumentedStore) HealthCheck(ctx context.Context) error {
+	ctx, done := s.begin(ctx, "healthCheck", "")
+	err := s.Store.HealthCheck(ctx)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Find(ctx context.Context, coll string, key Key, tag string) ([][]byte, error) {
+	ctx, done := s.begin(ctx, "find", coll)
+	values, err := s.Store.Find(ctx, coll, key, tag)
+	done(err)
+	return values, err
+}
+
+func (s *instrumentedStore) Insert(ctx context.Context, coll string, key Key, query interface{}, tag string, data interface{}) error {
+	ctx, done := s.begin(ctx, "insert", coll)
+	err := s.Store.Insert(ctx, coll, key, query, tag, data)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) CheckCollectionExists(ctx context.Context, coll string) bool {
+	ctx, done := s.begin(ctx, "checkCollectionExists", coll)
+	exists := s.Store.CheckCollectionExists(ctx, coll)
+	done(nil)
+	return exists
+}
+
+func (s *instrumentedStore) Update(ctx context.Context, coll string, operation string,
+	vars map[string]string, appName string, data interface{}) error {
+	ctx, done := s.begin(ctx, "update", coll)
+	err := s.Store.Update(ctx, coll, operation, vars, appName, data)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Delete(ctx context.Context, coll string, vars map[string]string) error {
+	ctx, done := s.begin(ctx, "delete", coll)
+	err := s.Store.Delete(ctx, coll, vars)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Remove(ctx context.Context, coll string, key Key) error {
+	ctx, done := s.begin(ctx, "remove", coll)
+	err := s.Store.Remove(ctx, coll, key)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) RemoveAll(ctx context.Context, coll string, key Key) error {
+	ctx, done := s.begin(ctx, "removeAll", coll)
+	err := s.Store.RemoveAll(ctx, coll, key)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) EnsureIndexes(ctx context.Context, indexes []Index) error {
+	ctx, done := s.begin(ctx, "ensureIndexes", "")
+	err := s.Store.EnsureIndexes(ctx, indexes)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Documents(ctx context.Context, coll string, tag string) ([]bson.Raw, error) {
+	ctx, done := s.begin(ctx, "documents", coll)
+	docs, err := s.Store.Documents(ctx, coll, tag)
+	done(err)
+	return docs, err
+}
+
+func (s *instrumentedStore) ReplaceDocument(ctx context.Context, coll string, doc bson.Raw) error {
+	ctx, done := s.begin(ctx, "replaceDocument", coll)
+	err := s.Store.ReplaceDocument(ctx, coll, doc)
+	done(err)
+	return err
+}
+
+// WithTransaction traces the transaction as a whole, the operations of fn
+// are its children
+func (s *instrumentedStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, done := s.begin(ctx, "transaction", "")
+	err := s.Store.WithTransaction(ctx, fn)
+	done(err)
+	return err
+}
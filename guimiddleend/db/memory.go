@@ -0,0 +1,627 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"example.com/middleend/logging"
+	pkgerrors "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errNoElement is returned by the array updates when no element matches
+var errNoElement = pkgerrors.New("no matching element")
+
+// MemoryStore is a Store keeping its collections in memory. It matches
+// the semantics of MongoStore: the documents are stored in bson the way
+// the driver encodes them, so Find and Unmarshal return the same values
+// as with mongo. The transactions run one at a time and hold off the
+// writes made outside of them; the reads see their changes as they come.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	colls map[string][]bson.Raw
+	// save, when set, persists a collection before a write is applied. A
+	// nil docs removes the collection.
+	save func(coll string, docs []bson.Raw) error
+
+	// tx is held by the transaction running, undo has the collections it
+	// changed as they were before it
+	tx   sync.Mutex
+	undo map[string][]bson.Raw
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{colls: map[string][]bson.Raw{}}
+}
+
+// memFilter matches the documents whose fields, by dotted path, equal its
+// values
+type memFilter map[string]interface{}
+
+func (f memFilter) matches(doc bson.Raw) bool {
+	for path, value := range f {
+		rv, err := doc.LookupErr(strings.Split(path, ".")...)
+		if err != nil {
+			return false
+		}
+		t, data, err := bson.MarshalValue(value)
+		if err != nil || !valuesEqual(rv, bson.RawValue{Type: t, Value: data}) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares two bson values like mongo does: the numbers by
+// value whatever their types, e.g. a float64 decoded from json equals the
+// int32 stored, the other values by type and content
+func valuesEqual(a, b bson.RawValue) bool {
+	if x, ok := a.AsInt64OK(); ok && a.Type != bson.TypeDouble {
+		if y, ok := b.AsInt64OK(); ok && b.Type != bson.TypeDouble {
+			return x == y
+		}
+	}
+	if x, ok := bsonNumber(a); ok {
+		y, ok := bsonNumber(b)
+		return ok && x == y
+	}
+	return a.Type == b.Type && bytes.Equal(a.Value, b.Value)
+}
+
+// bsonNumber returns the value of a bson double, int32 or int64
+func bsonNumber(v bson.RawValue) (float64, bool) {
+	switch v.Type {
+	case bson.TypeDouble:
+		return v.DoubleOK()
+	case bson.TypeInt32, bson.TypeInt64:
+		i, ok := v.AsInt64OK()
+		return float64(i), ok
+	}
+	return 0, false
+}
+
+// keyFields returns the fields of key, which is marshalled to json like
+// MongoStore does
+func keyFields(key Key) (map[string]interface{}, error) {
+	st, err := json.Marshal(key)
+	if err != nil {
+		return nil, pkgerrors.Errorf("Error Marshalling key: %s", err.Error())
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(st, &fields); err != nil {
+		return nil, pkgerrors.Errorf("Error Unmarshalling key to Bson Map: %s", err.Error())
+	}
+	return fields, nil
+}
+
+func sortedNames(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// keyType is the key field of the documents, their sorted key names
+func keyType(fields map[string]interface{}) string {
+	return "{" + strings.Join(sortedNames(fields), ",") + ",}"
+}
+
+// keyFilter matches the documents of key, like MongoStore.findFilter
+func keyFilter(key Key) (memFilter, error) {
+	fields, err := keyFields(key)
+	if err != nil {
+		return nil, err
+	}
+	return memFilter(fields), nil
+}
+
+// partialKeyFilter is keyFilter where the empty fields of key match any
+// document of the same key type, like MongoStore.findFilterWithKey
+func partialKeyFilter(key Key) (memFilter, error) {
+	fields, err := keyFields(key)
+	if err != nil {
+		return nil, err
+	}
+	f := memFilter{}
+	for k, v := range fields {
+		if v == "" {
+			f["key"] = keyType(fields)
+		} else {
+			f[k] = v
+		}
+	}
+	return f, nil
+}
+
+func validParams(args ...interface{}) bool {
+	for _, v := range args {
+		if s, ok := v.(string); (ok && s == "") || v == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// write replaces the documents of coll by the ones returned by fn, once
+// they are saved. Outside of a transaction it waits for the one running,
+// unless ctx is kept out of the transactions.
+func (m *MemoryStore) write(ctx context.Context, coll string, fn func(docs []bson.Raw) ([]bson.Raw, error)) error {
+	free := outsideTransactions(ctx)
+	inTx := !free && ctx.Value(txKey{}) == m
+	if !inTx && !free {
+		m.tx.Lock()
+		defer m.tx.Unlock()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur := m.colls[coll]
+	docs, err := fn(append(make([]bson.Raw, 0, len(cur)), cur...))
+	if err != nil {
+		return err
+	}
+	if err := m.store(coll, docs); err != nil {
+		return err
+	}
+	if _, ok := m.undo[coll]; inTx && !ok {
+		// nil when coll did not exist, the rollback removes it then
+		m.undo[coll] = cur
+	}
+	return nil
+}
+
+// store saves and sets the documents of coll, nil docs removes it
+func (m *MemoryStore) store(coll string, docs []bson.Raw) error {
+	if m.save != nil {
+		if err := m.save(coll, docs); err != nil {
+			return pkgerrors.Errorf("Error saving %s: %s", coll, err.Error())
+		}
+	}
+	if docs == nil {
+		delete(m.colls, coll)
+	} else {
+		m.colls[coll] = docs
+	}
+	return nil
+}
+
+// EnsureIndexes does nothing, the collections are scanned
+func (m *MemoryStore) EnsureIndexes(ctx context.Context, indexes []Index) error {
+	return nil
+}
+
+// WithTransaction runs fn, undoing its writes when it fails. fn joins the
+// transaction of ctx, if any.
+func (m *MemoryStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == m {
+		return fn(ctx)
+	}
+	m.tx.Lock()
+	defer m.tx.Unlock()
+	m.undo = map[string][]bson.Raw{}
+	defer func() { m.undo = nil }()
+
+	err := fn(context.WithValue(ctx, txKey{}, m))
+	if err == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for coll, docs := range m.undo {
+		if rerr := m.store(coll, docs); rerr != nil {
+			logging.FromContext(ctx).Errorf("Failed to roll back %s: %s", coll, rerr)
+		}
+	}
+	return err
+}
+
+// HealthCheck always succeeds, the store is in the process
+func (m *MemoryStore) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing, the writes are applied as they come
+func (m *MemoryStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// Unmarshal decodes a value returned by Find
+func (m *MemoryStore) Unmarshal(inp []byte, out interface{}) error {
+	err := bson.Unmarshal(inp, out)
+	if err != nil {
+		log.Error("Failed to unmarshall bson")
+		return err
+	}
+	return nil
+}
+
+// CheckCollectionExists reports whether anything was stored in coll
+func (m *MemoryStore) CheckCollectionExists(ctx context.Context, coll string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.colls[coll]
+	return ok
+}
+
+// Insert sets the tag and the query fields of the document of key, which
+// is created when missing
+func (m *MemoryStore) Insert(ctx context.Context, coll string, key Key, query interface{}, tag string, data interface{}) error {
+	if data == nil || !validParams(coll, key, tag) {
+		return pkgerrors.New("No Data to store")
+	}
+	fields, err := keyFields(key)
+	if err != nil {
+		return err
+	}
+	set := bson.D{{Key: tag, Value: data}, {Key: "key", Value: keyType(fields)}}
+	if query != nil {
+		q, err := keyFields(query)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedNames(q) {
+			set = append(set, bson.E{Key: name, Value: q[name]})
+		}
+	}
+
+	filter := memFilter(fields)
+	return m.write(ctx, coll, func(docs []bson.Raw) ([]bson.Raw, error) {
+		i := 0
+		for i < len(docs) && !filter.matches(docs[i]) {
+			i++
+		}
+		var doc primitive.D
+		if i < len(docs) {
+			if err := bson.Unmarshal(docs[i], &doc); err != nil {
+				return nil, err
+			}
+		} else {
+			// an upsert starts from the fields of the filter
+			doc = primitive.D{{Key: "_id", Value: primitive.NewObjectID()}}
+			for _, name := range sortedNames(fields) {
+				doc = append(doc, primitive.E{Key: name, Value: fields[name]})
+			}
+			docs = append(docs, nil)
+		}
+		for _, e := range set {
+			v, err := updatePath(doc, strings.Split(e.Key, "."), func(interface{}, bool) (interface{}, error) {
+				return e.Value, nil
+			})
+			if err != nil {
+				return nil, pkgerrors.Errorf("Error updating master table: %s", err.Error())
+			}
+			doc = v.(primitive.D)
+		}
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, pkgerrors.Errorf("Error updating master table: %s", err.Error())
+		}
+		docs[i] = raw
+		return docs, nil
+	})
+}
+
+// Find returns the tag of the documents of key, the empty fields of key
+// match any value
+func (m *MemoryStore) Find(ctx context.Context, coll string, key Key, tag string) ([][]byte, error) {
+	if !validParams(coll, key, tag) {
+		return nil, pkgerrors.New("Mandatory fields are missing")
+	}
+	filter, err := partialKeyFilter(key)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result [][]byte
+	for _, doc := range m.colls[coll] {
+		if !filter.matches(doc) {
+			continue
+		}
+		// like mongo, a document without the tag gives an empty value
+		var data []byte
+		r, err := doc.LookupErr(tag)
+		switch {
+		case err != nil:
+			logging.FromContext(ctx).Errorf("Unable to read data: %s err: %s", tag, err)
+		case r.Type == bson.TypeString:
+			data = []byte(r.StringValue())
+		default:
+			data = append([]byte(nil), r.Value...)
+		}
+		result = append(result, data)
+	}
+	return result, nil
+}
+
+// Update applies operation to the composite app document of vars
+func (m *MemoryStore) Update(ctx context.Context, coll string, operation string,
+	vars map[string]string, appName string, data interface{},
+) error {
+	const (
+		apps     = "appmetadata.spec.apps"
+		profiles = "appmetadata.spec.compositeProfiles.0.spec.profile"
+	)
+	var path, field, value string
+	var update func(elems primitive.A) (primitive.A, error)
+	switch operation {
+	case "UpdateApplication", "UpdateProfile":
+		path, field, value = apps, "metadata.name", appName
+		if operation == "UpdateProfile" {
+			path, field = profiles, "spec.appname"
+		}
+		update = func(elems primitive.A) (primitive.A, error) {
+			for i, elem := range elems {
+				if elemMatches(elem, field, value) {
+					elems[i] = data
+					return elems, nil
+				}
+			}
+			return nil, errNoElement
+		}
+	case "AddApplication", "AddProfile":
+		path = apps
+		if operation == "AddProfile" {
+			path = profiles
+		}
+		update = func(elems primitive.A) (primitive.A, error) {
+			return append(elems, data), nil
+		}
+	case "DeleteApplication", "DeleteProfile":
+		path, field, value = apps, "metadata.name", vars["appName"]
+		if operation == "DeleteProfile" {
+			path, field = profiles, "spec.appname"
+		}
+		update = func(elems primitive.A) (primitive.A, error) {
+			kept := primitive.A{}
+			for _, elem := range elems {
+				if !elemMatches(elem, field, value) {
+					kept = append(kept, elem)
+				}
+			}
+			return kept, nil
+		}
+	default:
+		return pkgerrors.Errorf("Unknown update operation %s", operation)
+	}
+
+	filter := compositeAppFilter(vars)
+	updated := 0
+	err := m.write(ctx, coll, func(docs []bson.Raw) ([]bson.Raw, error) {
+		for i, raw := range docs {
+			if !filter.matches(raw) {
+				continue
+			}
+			var doc primitive.D
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return nil, err
+			}
+			v, err := updatePath(doc, strings.Split(path, "."), func(old interface{}, ok bool) (interface{}, error) {
+				elems, isArray := old.(primitive.A)
+				if ok && !isArray {
+					return nil, pkgerrors.Errorf("%s is not an array", path)
+				}
+				return update(elems)
+			})
+			if err == errNoElement {
+				continue
+			}
+			if err != nil {
+				logging.FromContext(ctx).Errorf("Encountered error while update of document: %s", err)
+				return nil, err
+			}
+			if docs[i], err = bson.Marshal(v); err != nil {
+				return nil, err
+			}
+			updated = 1
+			return docs, nil
+		}
+		return docs, nil
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Infof("Updated %d document(s)", updated)
+	return nil
+}
+
+func compositeAppFilter(vars map[string]string) memFilter {
+	return memFilter{
+		"project": vars["projectName"], "compositeapp": vars["compositeAppName"],
+		"compositeappversion": vars["version"],
+	}
+}
+
+// elemMatches reports whether the array element elem is a document whose
+// field, by dotted path, is value
+func elemMatches(elem interface{}, field string, value string) bool {
+	v := elem
+	for _, name := range strings.Split(field, ".") {
+		doc, ok := v.(primitive.D)
+		if !ok {
+			return false
+		}
+		v = nil
+		for _, e := range doc {
+			if e.Key == name {
+				v = e.Value
+				break
+			}
+		}
+	}
+	s, ok := v.(string)
+	return ok && s == value
+}
+
+// updatePath replaces the value at path in v by the one returned by fn,
+// which is given the current value and whether there is one. The missing
+// documents on the way are created, as mongo does.
+func updatePath(v interface{}, path []string, fn func(old interface{}, ok bool) (interface{}, error)) (interface{}, error) {
+	next := func(old interface{}, ok bool) (interface{}, error) {
+		if len(path) == 1 {
+			return fn(old, ok)
+		}
+		if !ok {
+			old = primitive.D{}
+		}
+		return updatePath(old, path[1:], fn)
+	}
+	switch d := v.(type) {
+	case primitive.D:
+		for i, e := range d {
+			if e.Key == path[0] {
+				nv, err := next(e.Value, true)
+				if err != nil {
+					return nil, err
+				}
+				d[i].Value = nv
+				return d, nil
+			}
+		}
+		nv, err := next(nil, false)
+		if err != nil {
+			return nil, err
+		}
+		return append(d, primitive.E{Key: path[0], Value: nv}), nil
+	case primitive.A:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(d) {
+			return nil, pkgerrors.Errorf("cannot use the part (%s) to traverse the array", path[0])
+		}
+		nv, err := next(d[i], true)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = nv
+		return d, nil
+	}
+	return nil, pkgerrors.Errorf("cannot create field %s in a %T", path[0], v)
+}
+
+// Delete removes the composite app document of vars
+func (m *MemoryStore) Delete(ctx context.Context, coll string, vars map[string]string) error {
+	filter := compositeAppFilter(vars)
+	return m.write(ctx, coll, func(docs []bson.Raw) ([]bson.Raw, error) {
+		for i, doc := range docs {
+			if filter.matches(doc) {
+				return append(docs[:i], docs[i+1:]...), nil
+			}
+		}
+		return docs, nil
+	})
+}
+
+// RemoveAll removes all the documents of key
+func (m *MemoryStore) RemoveAll(ctx context.Context, coll string, key Key) error {
+	if !validParams(coll, key) {
+		return pkgerrors.New("Mandatory fields are missing")
+	}
+	filter, err := keyFilter(key)
+	if err != nil {
+		return err
+	}
+	return m.write(ctx, coll, func(docs []bson.Raw) ([]bson.Raw, error) {
+		kept := docs[:0]
+		for _, doc := range docs {
+			if !filter.matches(doc) {
+				kept = append(kept, doc)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// Remove removes the document of key, which must be the only one matching
+// it: the documents of its children match the key of their parent
+func (m *MemoryStore) Remove(ctx context.Context, coll string, key Key) error {
+	if !validParams(coll, key) {
+		return pkgerrors.New("Mandatory fields are missing")
+	}
+	filter, err := keyFilter(key)
+	if err != nil {
+		return err
+	}
+	return m.write(ctx, coll, func(docs []bson.Raw) ([]bson.Raw, error) {
+		found := -1
+		for i, doc := range docs {
+			if !filter.matches(doc) {
+				continue
+			}
+			if found >= 0 {
+				return nil, pkgerrors.Errorf("Can't delete parent without deleting child references first")
+			}
+			found = i
+		}
+		if found < 0 {
+			return nil, pkgerrors.Errorf("key not found")
+		}
+		return append(docs[:found], docs[found+1:]...), nil
+	})
+}
+
+// Documents returns the documents of coll having the field tag
+func (m *MemoryStore) Documents(ctx context.Context, coll string, tag string) ([]bson.Raw, error) {
+	if !validParams(coll) {
+		return nil, pkgerrors.New("Mandatory fields are missing")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var docs []bson.Raw
+	for _, doc := range m.colls[coll] {
+		if tag != "" {
+			if _, err := doc.LookupErr(tag); err != nil {
+				continue
+			}
+		}
+		docs = append(docs, append(bson.Raw(nil), doc...))
+	}
+	return docs, nil
+}
+
+// ReplaceDocument replaces the document of coll with the _id of doc
+func (m *MemoryStore) ReplaceDocument(ctx context.Context, coll string, doc bson.Raw) error {
+	if !validParams(coll) {
+		return pkgerrors.New("Mandatory fields are missing")
+	}
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+	id, err := doc.LookupErr("_id")
+	if err != nil {
+		return pkgerrors.New("The document has no _id")
+	}
+	return m.write(ctx, coll, func(docs []bson.Raw) ([]bson.Raw, error) {
+		for i, cur := range docs {
+			if rv, err := cur.LookupErr("_id"); err == nil && rv.Type == id.Type && bytes.Equal(rv.Value, id.Value) {
+				docs[i] = append(bson.Raw(nil), doc...)
+				return docs, nil
+			}
+		}
+		return nil, pkgerrors.Errorf("key not found")
+	})
+}
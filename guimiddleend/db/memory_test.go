@@ -0,0 +1,247 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type appKey struct {
+	Project string `json:"project"`
+	App     string `json:"app"`
+}
+
+type revisionKey struct {
+	Project  string `json:"project"`
+	Revision int    `json:"revision"`
+}
+
+type appData struct {
+	Name  string `bson:"name"`
+	Count int    `bson:"count"`
+}
+
+func findApps(t *testing.T, m *MemoryStore, key Key) []appData {
+	t.Helper()
+	values, err := m.Find(context.Background(), "apps", key, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apps []appData
+	for _, v := range values {
+		var a appData
+		if err := m.Unmarshal(v, &a); err != nil {
+			t.Fatal(err)
+		}
+		apps = append(apps, a)
+	}
+	return apps
+}
+
+func TestMemFilterNumbers(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "int32", Value: int32(3)},
+		{Key: "int64", Value: int64(5)},
+		{Key: "double", Value: 2.5},
+		{Key: "string", Value: "3"},
+		{Key: "nested", Value: bson.D{{Key: "n", Value: int32(7)}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		filter memFilter
+		match  bool
+	}{
+		{"json number on int32", memFilter{"int32": float64(3)}, true},
+		{"int64 on int32", memFilter{"int32": int64(3)}, true},
+		{"json number on int64", memFilter{"int64": float64(5)}, true},
+		{"int on double", memFilter{"double": 2}, false},
+		{"json number on double", memFilter{"double": 2.5}, true},
+		{"other number", memFilter{"int32": 3.5}, false},
+		{"number on string", memFilter{"string": float64(3)}, false},
+		{"string on number", memFilter{"int32": "3"}, false},
+		{"dotted path", memFilter{"nested.n": float64(7)}, true},
+		{"missing field", memFilter{"missing": float64(3)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(doc); got != tt.match {
+				t.Fatalf("matches %t, want %t", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreFind(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	for _, k := range []appKey{{"p1", "a1"}, {"p1", "a2"}, {"p2", "a1"}} {
+		if err := m.Insert(ctx, "apps", k, nil, "data", appData{Name: k.App}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a document of another key type is never found by an app key
+	if err := m.Insert(ctx, "apps", revisionKey{"p1", 1}, nil, "data", appData{Name: "revision"}); err != nil {
+		t.Fatal(err)
+	}
+	// the insert of an existing key updates its document
+	if err := m.Insert(ctx, "apps", appKey{"p1", "a1"}, nil, "data", appData{Name: "a1", Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if apps := findApps(t, m, appKey{"p1", "a1"}); len(apps) != 1 || apps[0].Count != 2 {
+		t.Fatalf("full key found %+v", apps)
+	}
+	if apps := findApps(t, m, appKey{Project: "p1"}); len(apps) != 2 {
+		t.Fatalf("partial key found %+v", apps)
+	}
+	if apps := findApps(t, m, appKey{}); len(apps) != 3 {
+		t.Fatalf("empty key found %+v", apps)
+	}
+	if apps := findApps(t, m, revisionKey{Project: "p1", Revision: 1}); len(apps) != 1 || apps[0].Name != "revision" {
+		t.Fatalf("numeric key found %+v", apps)
+	}
+
+	// a document without the tag gives an empty value, like mongo
+	values, err := m.Find(ctx, "apps", appKey{"p1", "a1"}, "other")
+	if err != nil || len(values) != 1 || len(values[0]) != 0 {
+		t.Fatalf("missing tag found %q, %v", values, err)
+	}
+
+	if err := m.Remove(ctx, "apps", appKey{"p3", "a1"}); err == nil {
+		t.Fatal("removed a missing key")
+	}
+	if err := m.Remove(ctx, "apps", appKey{"p1", "a2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveAll(ctx, "apps", appKey{"p2", "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	if apps := findApps(t, m, appKey{}); len(apps) != 1 {
+		t.Fatalf("found %+v after the removals", apps)
+	}
+}
+
+// TestMemoryStoreNumericKey finds, by a key decoded from json, a document
+// whose number was written as int32, as a migration does
+func TestMemoryStoreNumericKey(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	if err := m.Insert(ctx, "apps", revisionKey{"p1", 4}, nil, "data", appData{Name: "r4"}); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := m.Documents(ctx, "apps", "data")
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("documents %v, %v", docs, err)
+	}
+	var doc primitive.D
+	if err := bson.Unmarshal(docs[0], &doc); err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range doc {
+		if e.Key == "revision" {
+			doc[i].Value = int32(4)
+		}
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ReplaceDocument(ctx, "apps", raw); err != nil {
+		t.Fatal(err)
+	}
+	if apps := findApps(t, m, revisionKey{"p1", 4}); len(apps) != 1 {
+		t.Fatalf("found %+v", apps)
+	}
+}
+
+func TestMemoryStoreTransaction(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	if err := m.Insert(ctx, "apps", appKey{"p1", "a1"}, nil, "data", appData{Name: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := m.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := m.Insert(ctx, "apps", appKey{"p1", "a1"}, nil, "data", appData{Name: "a1", Count: 1}); err != nil {
+			return err
+		}
+		if err := m.Insert(ctx, "apps", appKey{"p1", "a2"}, nil, "data", appData{Name: "a2"}); err != nil {
+			return err
+		}
+		if err := m.Insert(ctx, "other", appKey{"p1", "a1"}, nil, "data", appData{Name: "other"}); err != nil {
+			return err
+		}
+		// the transaction reads its own changes, and a nested one joins it
+		if apps := findApps(t, m, appKey{Project: "p1"}); len(apps) != 2 {
+			t.Errorf("transaction found %+v", apps)
+		}
+		return m.WithTransaction(ctx, func(ctx context.Context) error {
+			return failed
+		})
+	})
+	if err != failed {
+		t.Fatalf("transaction returned %v", err)
+	}
+	if apps := findApps(t, m, appKey{}); len(apps) != 1 || apps[0].Count != 0 {
+		t.Fatalf("found %+v after the rollback", apps)
+	}
+	if docs, _ := m.Documents(ctx, "other", ""); len(docs) != 0 {
+		t.Fatalf("the collection created by the transaction is kept: %v", docs)
+	}
+
+	err = m.WithTransaction(ctx, func(ctx context.Context) error {
+		return m.Insert(ctx, "apps", appKey{"p1", "a2"}, nil, "data", appData{Name: "a2"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apps := findApps(t, m, appKey{}); len(apps) != 2 {
+		t.Fatalf("found %+v after the commit", apps)
+	}
+}
+
+func TestMemoryStoreWithoutTransaction(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	err := m.WithTransaction(ctx, func(txCtx context.Context) error {
+		// the write would wait for the transaction with a plain context
+		if err := m.Insert(WithoutTransaction(ctx), "progress", appKey{"p1", "a1"}, nil, "data", appData{Name: "progress"}); err != nil {
+			return err
+		}
+		if err := m.Insert(txCtx, "apps", appKey{"p1", "a1"}, nil, "data", appData{Name: "a1"}); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("the transaction succeeded")
+	}
+	if apps := findApps(t, m, appKey{}); len(apps) != 0 {
+		t.Fatalf("found %+v after the rollback", apps)
+	}
+	if docs, _ := m.Documents(ctx, "progress", "data"); len(docs) != 1 {
+		t.Fatalf("the write outside of the transaction is undone: %v", docs)
+	}
+}
@@ -0,0 +1,158 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import (
+	"context"
+	"sync"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// undoImage is a document changed by a transaction on a standalone mongo,
+// as it was before the transaction. doc is nil when the document did not
+// exist.
+type undoImage struct {
+	id  bson.RawValue
+	doc bson.Raw
+}
+
+// mongoUndo has the documents a transaction on a standalone mongo
+// changed, by collection and _id, to restore them when it fails
+type mongoUndo struct {
+	sync.Mutex
+	colls map[string]map[string]undoImage
+}
+
+// record keeps the image of the documents of coll matching filter which
+// have none yet. existed tells whether the documents are read before the
+// write, or after it and were created by it.
+func (u *mongoUndo) record(ctx context.Context, c *mongo.Collection, filter interface{}, existed bool) error {
+	cursor, err := c.Find(ctx, filter)
+	if err != nil {
+		return pkgerrors.Errorf("Error reading the documents to undo: %s", err.Error())
+	}
+	defer cursor.Close(ctx)
+	u.Lock()
+	defer u.Unlock()
+	images := u.colls[c.Name()]
+	if images == nil {
+		images = map[string]undoImage{}
+		u.colls[c.Name()] = images
+	}
+	for cursor.Next(ctx) {
+		id, err := cursor.Current.LookupErr("_id")
+		if err != nil {
+			continue
+		}
+		key := id.String()
+		if _, ok := images[key]; ok {
+			continue
+		}
+		image := undoImage{id: id}
+		if existed {
+			// the cursor reuses its buffer
+			image.doc = append(bson.Raw(nil), cursor.Current...)
+			image.id, _ = image.doc.LookupErr("_id")
+		} else {
+			image.id.Value = append([]byte(nil), id.Value...)
+		}
+		images[key] = image
+	}
+	return cursor.Err()
+}
+
+// rollback restores the documents as they were before the transaction,
+// the documents it created are removed first
+func (u *mongoUndo) rollback(ctx context.Context, db *mongo.Database) error {
+	u.Lock()
+	defer u.Unlock()
+	var failed error
+	for coll, images := range u.colls {
+		c := db.Collection(coll)
+		for _, image := range images {
+			if image.doc != nil {
+				continue
+			}
+			if _, err := c.DeleteOne(ctx, bson.D{{Key: "_id", Value: image.id}}); err != nil {
+				failed = pkgerrors.Errorf("Error removing a document of %s: %s", coll, err.Error())
+			}
+		}
+		for _, image := range images {
+			if image.doc == nil {
+				continue
+			}
+			if _, err := c.ReplaceOne(ctx, bson.D{{Key: "_id", Value: image.id}}, image.doc,
+				options.Replace().SetUpsert(true)); err != nil {
+				failed = pkgerrors.Errorf("Error restoring a document of %s: %s", coll, err.Error())
+			}
+		}
+	}
+	return failed
+}
+
+// writing prepares a write of the documents of coll matching filter, the
+// returned function ends it. On a standalone mongo a write within a
+// transaction records the documents it changes to undo them, and one
+// outside of a transaction waits for the transaction running.
+func (m *MongoStore) writing(ctx context.Context, coll string, filter interface{}) (func(), error) {
+	if outsideTransactions(ctx) || mongo.SessionFromContext(ctx) != nil {
+		return func() {}, nil
+	}
+	if ctx.Value(txKey{}) != m {
+		supported, err := m.supportsTransactions(ctx)
+		if err != nil || supported {
+			return func() {}, err
+		}
+		m.txMu.Lock()
+		return m.txMu.Unlock, nil
+	}
+	c := m.db.Collection(coll)
+	if err := m.undo.record(ctx, c, filter, true); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := m.undo.record(ctx, c, filter, false); err != nil {
+			m.logger(ctx).Errorf("Failed to record the documents created in %s: %s", coll, err)
+		}
+	}, nil
+}
+
+// standaloneTransaction runs fn in a transaction of a standalone mongo:
+// the transactions and the writes outside of them run one at a time, and
+// the documents fn changed are restored when it fails. The reads see the
+// changes of the transaction as they come.
+func (m *MongoStore) standaloneTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	m.undo = &mongoUndo{colls: map[string]map[string]undoImage{}}
+	defer func() { m.undo = nil }()
+
+	err := fn(context.WithValue(ctx, txKey{}, m))
+	if err == nil {
+		return nil
+	}
+	// the rollback runs even when the request was cancelled
+	rctx, cancel := opContext(context.Background())
+	defer cancel()
+	if rerr := m.undo.rollback(rctx, m.db); rerr != nil {
+		m.logger(ctx).Errorf("Failed to roll back the transaction: %s", rerr)
+	}
+	return err
+}
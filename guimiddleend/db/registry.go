@@ -0,0 +1,98 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package db
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Factory creates a Store for the database dbName. svcEp locates the
+// database, it is the address of a server or a directory depending on
+// the type of the store.
+type Factory func(dbName string, svcEp string) (Store, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{factories: map[string]Factory{}}
+
+// Register makes a type of Store available to CreateDBClient under the
+// name dbType. It panics when the name is taken.
+func Register(dbType string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.factories[dbType]; ok {
+		panic("db: store " + dbType + " registered twice")
+	}
+	registry.factories[dbType] = factory
+}
+
+// Registered reports whether a Store of type dbType is registered
+func Registered(dbType string) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	_, ok := registry.factories[dbType]
+	return ok
+}
+
+// Types returns the names of the registered Stores, sorted
+func Types() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("mongo", func(dbName string, svcEp string) (Store, error) {
+		return NewMongoStore(dbName, nil, svcEp)
+	})
+	Register("memory", func(string, string) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+	Register("file", func(dbName string, svcEp string) (Store, error) {
+		if svcEp == "" {
+			return nil, pkgerrors.New("the file store needs a directory")
+		}
+		return NewFileStore(filepath.Join(svcEp, dbName))
+	})
+}
+
+// CreateDBClient connects DBconn to the database dbName of the Store
+// registered as dbType
+func CreateDBClient(dbType string, dbName string, svcEp string) error {
+	registry.RLock()
+	factory, ok := registry.factories[dbType]
+	registry.RUnlock()
+	if !ok {
+		return pkgerrors.Errorf("%s DB not supported, use one of %s", dbType, strings.Join(Types(), ", "))
+	}
+	store, err := factory(dbName, svcEp)
+	if err != nil {
+		return err
+	}
+	DBconn = newInstrumentedStore(store)
+	return nil
+}
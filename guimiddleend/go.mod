@@ -1,11 +1,14 @@
 module example.com/middleend
 
-go 1.14
+go 1.16
 
 require (
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/ghodss/yaml v0.0.0-20150909031657-73d445a93680
 	github.com/go-openapi/runtime v0.24.1
+	github.com/go-openapi/spec v0.20.4
+	github.com/go-openapi/strfmt v0.21.2
+	github.com/go-openapi/validate v0.21.0
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/handlers v1.5.0
 	github.com/gorilla/mux v1.8.0
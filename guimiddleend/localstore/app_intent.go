@@ -21,6 +21,7 @@ package localstore
 */
 
 import (
+	"context"
 	"encoding/json"
 	//"reflect"
 	"strings"
@@ -89,7 +90,7 @@ type ClusterList struct {
 	OptionalClusters  []ClusterGroup
 }
 
-//ClusterGroup consists of Clusters and GroupNumber. All the clusters under the same clusterGroup belong to the same groupNumber
+// ClusterGroup consists of Clusters and GroupNumber. All the clusters under the same clusterGroup belong to the same groupNumber
 type ClusterGroup struct {
 	Clusters    []ClusterWithName
 	GroupNumber string
@@ -138,7 +139,7 @@ type AppIntentManager interface {
 	DeleteAppIntent(ai string, p string, ca string, v string, i string, digName string) error
 }
 
-//AppIntentQueryKey required for query
+// AppIntentQueryKey required for query
 type AppIntentQueryKey struct {
 	AppName string `json:"app-name"`
 }
@@ -190,12 +191,13 @@ func (ak AppIntentKey) String() string {
 type AppIntentClient struct {
 	storeName   string
 	tagMetaData string
+	ctx         context.Context
 }
 
-
 // NewAppIntentClient returns an instance of AppIntentClient
-func NewAppIntentClient() *AppIntentClient {
+func NewAppIntentClient(ctx context.Context) *AppIntentClient {
 	return &AppIntentClient{
+		ctx:         ctx,
 		storeName:   "resources",
 		tagMetaData: "appintentmetadata",
 	}
@@ -250,7 +252,7 @@ func (c *AppIntentClient) CreateAppIntent(a AppIntent, p string, ca string, v st
 		AppName: a.Spec.AppName,
 	}
 
-	err := db.DBconn.Insert(c.storeName, akey, qkey, c.tagMetaData, a)
+	err := db.DBconn.Insert(c.ctx, c.storeName, akey, qkey, c.tagMetaData, a)
 	if err != nil {
 		return AppIntent{}, pkgerrors.Wrap(err, "Create DB entry error")
 	}
@@ -270,7 +272,7 @@ func (c *AppIntentClient) GetAppIntent(ai string, p string, ca string, v string,
 		DeploymentIntentGroupName: digName,
 	}
 
-	result, err := db.DBconn.Find(c.storeName, k, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, k, c.tagMetaData)
 	if err != nil {
 		return AppIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -301,7 +303,7 @@ func (c *AppIntentClient) GetAllIntentsByApp(aN, p, ca, v, i, digName string) (S
 		DeploymentIntentGroupName: digName,
 		AppName:                   aN,
 	}
-	result, err := db.DBconn.Find(c.storeName, k, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, k, c.tagMetaData)
 	if err != nil {
 		return SpecData{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -331,7 +333,7 @@ func (c *AppIntentClient) GetAllAppIntents(p, ca, v, i, digName string) ([]AppIn
 		Intent:                    i,
 		DeploymentIntentGroupName: digName,
 	}
-	result, err := db.DBconn.Find(c.storeName, k, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, k, c.tagMetaData)
 	if err != nil {
 		return []AppIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -363,7 +365,7 @@ func (c *AppIntentClient) DeleteAppIntent(ai string, p string, ca string, v stri
 		DeploymentIntentGroupName: digName,
 	}
 
-	err := db.DBconn.Remove(c.storeName, k)
+	err := db.DBconn.Remove(c.ctx, c.storeName, k)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -391,11 +393,13 @@ func (gk GenericPlacementIntentKey) String() string {
 type GenericPlacementIntentClient struct {
 	storeName   string
 	tagMetaData string
+	ctx         context.Context
 }
 
 // NewGenericPlacementIntentClient return an instance of GenericPlacementIntentClient which implements GenericPlacementIntentManager
-func NewGenericPlacementIntentClient() *GenericPlacementIntentClient {
+func NewGenericPlacementIntentClient(ctx context.Context) *GenericPlacementIntentClient {
 	return &GenericPlacementIntentClient{
+		ctx:         ctx,
 		storeName:   "resources",
 		tagMetaData: "genericplacementintentmetadata",
 	}
@@ -439,7 +443,7 @@ func (c *GenericPlacementIntentClient) CreateGenericPlacementIntent(g GenericPla
 		DigName:      digName,
 	}
 
-	err := db.DBconn.Insert(c.storeName, gkey, nil, c.tagMetaData, g)
+	err := db.DBconn.Insert(c.ctx, c.storeName, gkey, nil, c.tagMetaData, g)
 	if err != nil {
 		return GenericPlacementIntent{}, pkgerrors.Wrap(err, "Create DB entry error")
 	}
@@ -457,7 +461,7 @@ func (c *GenericPlacementIntentClient) GetGenericPlacementIntent(i string, p str
 		DigName:      digName,
 	}
 
-	result, err := db.DBconn.Find(c.storeName, key, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, key, c.tagMetaData)
 	if err != nil {
 		return GenericPlacementIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -501,7 +505,7 @@ func (c *GenericPlacementIntentClient) GetAllGenericPlacementIntents(p string, c
 	}
 
 	var gpList []GenericPlacementIntent
-	values, err := db.DBconn.Find(c.storeName, key, c.tagMetaData)
+	values, err := db.DBconn.Find(c.ctx, c.storeName, key, c.tagMetaData)
 	if err != nil {
 		return []GenericPlacementIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -529,7 +533,7 @@ func (c *GenericPlacementIntentClient) DeleteGenericPlacementIntent(i string, p
 		DigName:      digName,
 	}
 
-	err := db.DBconn.Remove(c.storeName, key)
+	err := db.DBconn.Remove(c.ctx, c.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -1,6 +1,7 @@
 package localstore
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 
@@ -8,7 +9,6 @@ import (
 	pkgerrors "github.com/pkg/errors"
 )
 
-
 // Customization consists of metadata and Spec
 type Customization struct {
 	Metadata Metadata      `json:"metadata"`
@@ -49,7 +49,6 @@ type CustomizationKey struct {
 	Resource            string `json:"genericResource"`
 }
 
-
 // CustomizationManager exposes all the functionalities of customization
 type CustomizationManager interface {
 	CreateCustomization(c Customization, t SpecFileContent, p, ca, cv, dig, gi, rs string, exists bool) (Customization, error)
@@ -69,7 +68,8 @@ type CustomizationClientDbInfo struct {
 
 // CustomizationClient consists of CustomizationClientDbInfo
 type CustomizationClient struct {
-	db CustomizationClientDbInfo
+	db  CustomizationClientDbInfo
+	ctx context.Context
 }
 
 // We will use json marshalling to convert to string to
@@ -83,8 +83,9 @@ func (ck CustomizationKey) String() string {
 }
 
 // NewCustomizationClient returns an instance of the CustomizationClient
-func NewCustomizationClient() *CustomizationClient {
+func NewCustomizationClient(ctx context.Context) *CustomizationClient {
 	return &CustomizationClient{
+		ctx: ctx,
 		db: CustomizationClientDbInfo{
 			storeName:  "resources",
 			tagMeta:    "data",
@@ -112,12 +113,12 @@ func (cc *CustomizationClient) CreateCustomization(c Customization, t SpecFileCo
 		return Customization{}, pkgerrors.New("Customization already exists")
 	}
 
-	err = db.DBconn.Insert(cc.db.storeName, key, nil, cc.db.tagMeta, c)
+	err = db.DBconn.Insert(cc.ctx, cc.db.storeName, key, nil, cc.db.tagMeta, c)
 	if err != nil {
 		return Customization{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
 
-	err = db.DBconn.Insert(cc.db.storeName, key, nil, cc.db.tagContent, t)
+	err = db.DBconn.Insert(cc.ctx, cc.db.storeName, key, nil, cc.db.tagContent, t)
 	if err != nil {
 		return Customization{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -139,7 +140,7 @@ func (cc *CustomizationClient) GetCustomization(c, p, ca, cv, dig, gi, rs string
 		Resource:            rs,
 	}
 
-	value, err := db.DBconn.Find(cc.db.storeName, key, cc.db.tagMeta)
+	value, err := db.DBconn.Find(cc.ctx, cc.db.storeName, key, cc.db.tagMeta)
 	if err != nil {
 		return Customization{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -172,7 +173,7 @@ func (cc *CustomizationClient) GetAllCustomization(p, ca, cv, dig, gi, rs string
 	}
 
 	var czs []Customization
-	values, err := db.DBconn.Find(cc.db.storeName, key, cc.db.tagMeta)
+	values, err := db.DBconn.Find(cc.ctx, cc.db.storeName, key, cc.db.tagMeta)
 	if err != nil {
 		return []Customization{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -201,7 +202,7 @@ func (cc *CustomizationClient) GetCustomizationContent(c, p, ca, cv, dig, gi, rs
 		Resource:            rs,
 	}
 
-	value, err := db.DBconn.Find(cc.db.storeName, key, cc.db.tagContent)
+	value, err := db.DBconn.Find(cc.ctx, cc.db.storeName, key, cc.db.tagContent)
 	if err != nil {
 		return SpecFileContent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -232,7 +233,7 @@ func (cc *CustomizationClient) DeleteCustomization(c, p, ca, cv, dig, gi, rs str
 		Resource:            rs,
 	}
 
-	err := db.DBconn.Remove(cc.db.storeName, key)
+	err := db.DBconn.Remove(cc.ctx, cc.db.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -244,4 +245,3 @@ func (cc *CustomizationClient) DeleteCustomization(c, p, ca, cv, dig, gi, rs str
 	}
 	return nil
 }
-
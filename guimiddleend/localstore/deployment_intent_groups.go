@@ -16,16 +16,17 @@
 package localstore
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"strings"
 	"time"
 
 	"example.com/middleend/db"
+	"example.com/middleend/logging"
 
 	//	"github.com/open-ness/EMCO/src/orchestrator/pkg/state"
 	pkgerrors "github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 // StateInfo struct is used to maintain the values for state, contextid, (and other)
@@ -161,11 +162,13 @@ type DeploymentIntentGroupClient struct {
 	storeName   string
 	tagMetaData string
 	tagState    string
+	ctx         context.Context
 }
 
 // NewDeploymentIntentGroupClient return an instance of DeploymentIntentGroupClient which implements DeploymentIntentGroupManager
-func NewDeploymentIntentGroupClient() *DeploymentIntentGroupClient {
+func NewDeploymentIntentGroupClient(ctx context.Context) *DeploymentIntentGroupClient {
 	return &DeploymentIntentGroupClient{
+		ctx:         ctx,
 		storeName:   "resources",
 		tagMetaData: "deploymentintentgroupmetadata",
 		tagState:    "stateInfo",
@@ -202,7 +205,7 @@ func (c *DeploymentIntentGroupClient) CreateDeploymentIntentGroup(d DeploymentIn
 		Version:      v,
 	}
 
-	err := db.DBconn.Insert(c.storeName, gkey, nil, c.tagMetaData, d)
+	err := db.DBconn.Insert(c.ctx, c.storeName, gkey, nil, c.tagMetaData, d)
 	if err != nil {
 		return DeploymentIntentGroup{}, pkgerrors.Wrap(err, "Create DB entry error")
 	}
@@ -216,7 +219,7 @@ func (c *DeploymentIntentGroupClient) CreateDeploymentIntentGroup(d DeploymentIn
 	}
 	s.Actions = append(s.Actions, a)
 
-	err = db.DBconn.Insert(c.storeName, gkey, nil, c.tagState, s)
+	err = db.DBconn.Insert(c.ctx, c.storeName, gkey, nil, c.tagState, s)
 	if err != nil {
 		return DeploymentIntentGroup{}, pkgerrors.Wrap(err, "Error updating the stateInfo of the DeploymentIntentGroup: "+d.MetaData.Name)
 	}
@@ -233,8 +236,8 @@ func (c *DeploymentIntentGroupClient) GetDeploymentIntentGroup(di string, p stri
 		Version:      v,
 	}
 
-	log.Infof("GetDeploymentIntentGroup DB key %s", key)
-	result, err := db.DBconn.Find(c.storeName, key, c.tagMetaData)
+	logging.FromContext(c.ctx).Infof("GetDeploymentIntentGroup DB key %s", key)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, key, c.tagMetaData)
 	if err != nil {
 		return DeploymentIntentGroup{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -274,9 +277,9 @@ func (c *DeploymentIntentGroupClient) GetAllDeploymentIntentGroups(p string, ca
 			return []DeploymentIntentGroup{}, pkgerrors.Wrap(err, "Unable to find the composite-app, check CompositeAppName and Version")
 		}
 	*/
-	log.Infof("GetAllDeploymentIntentGroup DB key %s", key)
+	logging.FromContext(c.ctx).Infof("GetAllDeploymentIntentGroup DB key %s", key)
 	var diList []DeploymentIntentGroup
-	result, err := db.DBconn.Find(c.storeName, key, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, key, c.tagMetaData)
 	if err != nil {
 		return []DeploymentIntentGroup{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -302,7 +305,7 @@ func (c *DeploymentIntentGroupClient) GetDeploymentIntentGroupState(di string, p
 		Version:      v,
 	}
 
-	result, err := db.DBconn.Find(c.storeName, key, c.tagState)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, key, c.tagState)
 	if err != nil {
 		return StateInfo{}, pkgerrors.Wrap(err, "Get DeploymentIntentGroup StateInfo error")
 	}
@@ -333,7 +336,7 @@ func (c *DeploymentIntentGroupClient) DeleteDeploymentIntentGroup(di string, p s
 		if err != nil {
 			// If the StateInfo cannot be found, then a proper deployment intent group record is not present.
 			// Call the DB delete to clean up any errant record without a StateInfo element that may exist.
-			err = db.DBconn.Remove(c.storeName, k)
+			err = db.DBconn.Remove(c.ctx, c.storeName, k)
 			if err != nil {
 				return pkgerrors.Wrap(err, "Error deleting DeploymentIntentGroup entry")
 			}
@@ -372,7 +375,7 @@ func (c *DeploymentIntentGroupClient) DeleteDeploymentIntentGroup(di string, p s
 				}
 	*/
 
-	err := db.DBconn.Remove(c.storeName, k)
+	err := db.DBconn.Remove(c.ctx, c.storeName, k)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -442,11 +445,13 @@ func (ik IntentKey) String() string {
 type IntentClient struct {
 	storeName   string
 	tagMetaData string
+	ctx         context.Context
 }
 
 // NewIntentClient returns an instance of AddIntentClient
-func NewIntentClient() *IntentClient {
+func NewIntentClient(ctx context.Context) *IntentClient {
 	return &IntentClient{
+		ctx:         ctx,
 		storeName:   "resources",
 		tagMetaData: "addintent",
 	}
@@ -491,7 +496,7 @@ func (c *IntentClient) AddIntent(a Intent, p string, ca string, v string, di str
 		DeploymentIntentGroup: di,
 	}
 
-	err = db.DBconn.Insert(c.storeName, akey, nil, c.tagMetaData, a)
+	err = db.DBconn.Insert(c.ctx, c.storeName, akey, nil, c.tagMetaData, a)
 	if err != nil {
 		return Intent{}, pkgerrors.Wrap(err, "Create DB entry error")
 	}
@@ -511,7 +516,7 @@ func (c *IntentClient) GetIntent(i string, p string, ca string, v string, di str
 		DeploymentIntentGroup: di,
 	}
 
-	result, err := db.DBconn.Find(c.storeName, k, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, k, c.tagMetaData)
 	if err != nil {
 		return Intent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -540,7 +545,7 @@ func (c IntentClient) GetIntentByName(i string, p string, ca string, v string, d
 		Version:               v,
 		DeploymentIntentGroup: di,
 	}
-	result, err := db.DBconn.Find(c.storeName, k, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, k, c.tagMetaData)
 	if err != nil {
 		return IntentSpecData{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -565,7 +570,7 @@ func (c IntentClient) GetAllIntents(p string, ca string, v string, di string) (L
 		DeploymentIntentGroup: di,
 	}
 
-	result, err := db.DBconn.Find(c.storeName, k, c.tagMetaData)
+	result, err := db.DBconn.Find(c.ctx, c.storeName, k, c.tagMetaData)
 	if err != nil {
 		return ListOfIntents{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -596,7 +601,7 @@ func (c IntentClient) DeleteIntent(i string, p string, ca string, v string, di s
 		DeploymentIntentGroup: di,
 	}
 
-	err := db.DBconn.Remove(c.storeName, k)
+	err := db.DBconn.Remove(c.ctx, c.storeName, k)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -1,10 +1,11 @@
 package localstore
 
 import (
+	"context"
 	"encoding/json"
-	"strings"
 	"example.com/middleend/db"
 	pkgerrors "github.com/pkg/errors"
+	"strings"
 )
 
 // GenericK8sIntent consists of metadata
@@ -31,7 +32,8 @@ type GenericK8sIntentManager interface {
 
 // GenericK8sIntentClient consists of the clientInfo
 type GenericK8sIntentClient struct {
-	db ClientDbInfo
+	db  ClientDbInfo
+	ctx context.Context
 }
 
 // We will use json marshalling to convert to string to
@@ -45,8 +47,9 @@ func (gk GenericK8sIntentKey) String() string {
 }
 
 // NewGenericK8sIntentClient returns an instance of the GenericK8sIntentClient
-func NewGenericK8sIntentClient() *GenericK8sIntentClient {
+func NewGenericK8sIntentClient(ctx context.Context) *GenericK8sIntentClient {
 	return &GenericK8sIntentClient{
+		ctx: ctx,
 		db: ClientDbInfo{
 			storeName: "resources",
 			tagMeta:   "generick8sintentmetadata",
@@ -71,7 +74,7 @@ func (g *GenericK8sIntentClient) CreateGenericK8sIntent(gki GenericK8sIntent, pr
 		return GenericK8sIntent{}, pkgerrors.New("GenericK8sIntent already exists")
 	}
 
-	err = db.DBconn.Insert(g.db.storeName, key, nil, g.db.tagMeta, gki)
+	err = db.DBconn.Insert(g.ctx, g.db.storeName, key, nil, g.db.tagMeta, gki)
 	if err != nil {
 		return GenericK8sIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -91,7 +94,7 @@ func (g *GenericK8sIntentClient) GetGenericK8sIntent(gkiName, project, composite
 		DigName:             dig,
 	}
 
-	value, err := db.DBconn.Find(g.db.storeName, key, g.db.tagMeta)
+	value, err := db.DBconn.Find(g.ctx, g.db.storeName, key, g.db.tagMeta)
 	if err != nil {
 		return GenericK8sIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -122,7 +125,7 @@ func (g *GenericK8sIntentClient) GetAllGenericK8sIntents(project, compositeapp,
 	}
 
 	var resp []GenericK8sIntent
-	values, err := db.DBconn.Find(g.db.storeName, key, g.db.tagMeta)
+	values, err := db.DBconn.Find(g.ctx, g.db.storeName, key, g.db.tagMeta)
 	if err != nil {
 		return []GenericK8sIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -151,7 +154,7 @@ func (g *GenericK8sIntentClient) DeleteGenericK8sIntent(gkiName, project, compos
 		DigName:             dig,
 	}
 
-	err := db.DBconn.Remove(g.db.storeName, key)
+	err := db.DBconn.Remove(g.ctx, g.db.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -163,4 +166,4 @@ func (g *GenericK8sIntentClient) DeleteGenericK8sIntent(gkiName, project, compos
 	}
 
 	return nil
-}
\ No newline at end of file
+}
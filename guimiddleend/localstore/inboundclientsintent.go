@@ -4,6 +4,7 @@
 package localstore
 
 import (
+	"context"
 	"example.com/middleend/db"
 	pkgerrors "github.com/pkg/errors"
 )
@@ -21,9 +22,9 @@ type InboundClientsIntentSpec struct {
 	IpRange     []string `json:"cidrs"`
 }
 
-
 type InboundClientsIntentDbClient struct {
-	db ClientDbInfo
+	db  ClientDbInfo
+	ctx context.Context
 }
 
 // ClientsInboundIntentKey is the key structure that is used in the database
@@ -37,8 +38,9 @@ type InboundClientsIntentKey struct {
 	InboundClientsIntentName  string `json:"inboundClientsIntent"`
 }
 
-func NewClientsInboundIntentClient() *InboundClientsIntentDbClient {
+func NewClientsInboundIntentClient(ctx context.Context) *InboundClientsIntentDbClient {
 	return &InboundClientsIntentDbClient{
+		ctx: ctx,
 		db: ClientDbInfo{
 			storeName: "resources",
 			tagMeta:   "clientsintentmetadata",
@@ -61,7 +63,7 @@ func (v InboundClientsIntentDbClient) CreateClientsInboundIntent(ici InboundClie
 
 	//Check if this InboundClientsIntent already exists
 
-	err := db.DBconn.Insert(v.db.storeName, key, nil, v.db.tagMeta, ici)
+	err := db.DBconn.Insert(v.ctx, v.db.storeName, key, nil, v.db.tagMeta, ici)
 	if err != nil {
 		return InboundClientsIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -84,7 +86,7 @@ func (v *InboundClientsIntentDbClient) GetClientsInboundIntent(name, project, co
 		InboundClientsIntentName:  name,
 	}
 
-	value, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	value, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return InboundClientsIntent{}, err
 	} else if len(value) == 0 {
@@ -119,7 +121,7 @@ func (v *InboundClientsIntentDbClient) GetClientsInboundIntents(project, composi
 	}
 
 	var resp []InboundClientsIntent
-	values, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	values, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return []InboundClientsIntent{}, err
 	}
@@ -151,6 +153,6 @@ func (v *InboundClientsIntentDbClient) DeleteClientsInboundIntent(name, project,
 		InboundClientsIntentName:  name,
 	}
 
-	err := db.DBconn.Remove(v.db.storeName, key)
+	err := db.DBconn.Remove(v.ctx, v.db.storeName, key)
 	return err
 }
@@ -4,6 +4,7 @@
 package localstore
 
 import (
+	"context"
 	"example.com/middleend/db"
 	pkgerrors "github.com/pkg/errors"
 )
@@ -24,21 +25,20 @@ type InbondServerIntentSpec struct {
 	ServiceMesh     string `json:"serviceMesh", default:"none"`
 }
 
-
 type InboundServerIntentSpec struct {
 	AppName         string `json:"app"`
-        AppLabel        string `json:"appLabel"`
-        ServiceName     string `json:"serviceName"`
-        ExternalName    string `json:"externalName", default:""`
-        Port            string    `json:"port"`
-        Protocol        string `json:"protocol"`
-        ExternalSupport bool   `json:"externalSupport", default:false`
-        ServiceMesh     string `json:"serviceMesh", default:"none"`
+	AppLabel        string `json:"appLabel"`
+	ServiceName     string `json:"serviceName"`
+	ExternalName    string `json:"externalName", default:""`
+	Port            string `json:"port"`
+	Protocol        string `json:"protocol"`
+	ExternalSupport bool   `json:"externalSupport", default:false`
+	ServiceMesh     string `json:"serviceMesh", default:"none"`
 }
 
-
 type InboundServerIntentDbClient struct {
-	db ClientDbInfo
+	db  ClientDbInfo
+	ctx context.Context
 }
 
 // ServerInboundIntentKey is the key structure that is used in the database
@@ -51,8 +51,9 @@ type InboundServerIntentKey struct {
 	ServerInboundIntentName   string `json:"inboundServerIntent"`
 }
 
-func NewServerInboundIntentClient() *InboundServerIntentDbClient {
+func NewServerInboundIntentClient(ctx context.Context) *InboundServerIntentDbClient {
 	return &InboundServerIntentDbClient{
+		ctx: ctx,
 		db: ClientDbInfo{
 			storeName: "resources",
 			tagMeta:   "serverintentmetadata",
@@ -72,7 +73,7 @@ func (v InboundServerIntentDbClient) CreateServerInboundIntent(isi InboundServer
 		ServerInboundIntentName:   isi.Metadata.Name,
 	}
 
-	err := db.DBconn.Insert(v.db.storeName, key, nil, v.db.tagMeta, isi)
+	err := db.DBconn.Insert(v.ctx, v.db.storeName, key, nil, v.db.tagMeta, isi)
 	if err != nil {
 		return InboundServerIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -93,7 +94,7 @@ func (v *InboundServerIntentDbClient) GetServerInboundIntent(name, project, comp
 		ServerInboundIntentName:   name,
 	}
 
-	value, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	value, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return InboundServerIntent{}, err
 	} else if len(value) == 0 {
@@ -127,7 +128,7 @@ func (v *InboundServerIntentDbClient) GetServerInboundIntents(project, composite
 	}
 
 	var resp []InboundServerIntent
-	values, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	values, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return []InboundServerIntent{}, err
 	}
@@ -157,6 +158,6 @@ func (v *InboundServerIntentDbClient) DeleteServerInboundIntent(name, project, c
 		ServerInboundIntentName:   name,
 	}
 
-	err := db.DBconn.Remove(v.db.storeName, key)
+	err := db.DBconn.Remove(v.ctx, v.db.storeName, key)
 	return err
 }
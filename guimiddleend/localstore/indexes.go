@@ -0,0 +1,36 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package localstore
+
+import "example.com/middleend/db"
+
+// Indexes are the indexes of the resources collection. Every key of the
+// checked out DIGs and their intents starts with the project, composite
+// app, version and DIG, spelt in one of the casings below, and the lookups
+// with empty fields also filter on the key type.
+func Indexes() []db.Index {
+	scopes := [][]string{
+		{"project", "compositeApp", "compositeAppVersion", "deploymentIntentGroup"},
+		{"project", "compositeapp", "compositeappversion", "deploymentintentgroup"},
+		// the workload intents name the project provider
+		{"provider", "compositeapp", "compositeappversion", "deploymentintentgroup"},
+	}
+	indexes := make([]db.Index, 0, len(scopes))
+	for _, scope := range scopes {
+		indexes = append(indexes, db.Index{Collection: "resources", Keys: append(scope, "key")})
+	}
+	return indexes
+}
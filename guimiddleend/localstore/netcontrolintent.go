@@ -16,6 +16,7 @@
 package localstore
 
 import (
+	"context"
 	"strings"
 
 	"example.com/middleend/db"
@@ -70,13 +71,15 @@ type NetControlIntentManager interface {
 // NetControlIntentClient implements the Manager
 // It will also be used to maintain some localized state
 type NetControlIntentClient struct {
-	db ClientDbInfo
+	db  ClientDbInfo
+	ctx context.Context
 }
 
 // NewNetControlIntentClient returns an instance of the NetControlIntentClient
 // which implements the Manager
-func NewNetControlIntentClient() *NetControlIntentClient {
+func NewNetControlIntentClient(ctx context.Context) *NetControlIntentClient {
 	return &NetControlIntentClient{
+		ctx: ctx,
 		db: ClientDbInfo{
 			storeName: "resources",
 			tagMeta:   "netcontrolintentmetadata",
@@ -102,7 +105,7 @@ func (v *NetControlIntentClient) CreateNetControlIntent(nci NetControlIntent, pr
 		return NetControlIntent{}, pkgerrors.New("NetControlIntent already exists")
 	}
 
-	err = db.DBconn.Insert(v.db.storeName, key, nil, v.db.tagMeta, nci)
+	err = db.DBconn.Insert(v.ctx, v.db.storeName, key, nil, v.db.tagMeta, nci)
 	if err != nil {
 		return NetControlIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -122,7 +125,7 @@ func (v *NetControlIntentClient) GetNetControlIntent(name, project, compositeapp
 		DigName:             dig,
 	}
 
-	value, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	value, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return NetControlIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -153,7 +156,7 @@ func (v *NetControlIntentClient) GetNetControlIntents(project, compositeapp, com
 	}
 
 	var resp []NetControlIntent
-	values, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	values, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return []NetControlIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -203,13 +206,15 @@ type WorkloadIntentManager interface {
 // WorkloadIntentClient implements the Manager
 // It will also be used to maintain some localized state
 type WorkloadIntentClient struct {
-	db ClientDbInfo
+	db  ClientDbInfo
+	ctx context.Context
 }
 
 // NewWorkloadIntentClient returns an instance of the WorkloadIntentClient
 // which implements the Manager
-func NewWorkloadIntentClient() *WorkloadIntentClient {
+func NewWorkloadIntentClient(ctx context.Context) *WorkloadIntentClient {
 	return &WorkloadIntentClient{
+		ctx: ctx,
 		db: ClientDbInfo{
 			storeName: "resources",
 			tagMeta:   "workloadintentmetadata",
@@ -231,7 +236,7 @@ func (v *WorkloadIntentClient) CreateWorkloadIntent(wi WorkloadIntent, project,
 	}
 
 	//Check if the Network Control Intent exists
-	_, err := NewNetControlIntentClient().GetNetControlIntent(netcontrolintent, project, compositeapp, compositeappversion, dig)
+	_, err := NewNetControlIntentClient(v.ctx).GetNetControlIntent(netcontrolintent, project, compositeapp, compositeappversion, dig)
 	if err != nil {
 		return WorkloadIntent{}, pkgerrors.Errorf("Network Control Intent %v does not exist", netcontrolintent)
 	}
@@ -242,7 +247,7 @@ func (v *WorkloadIntentClient) CreateWorkloadIntent(wi WorkloadIntent, project,
 		return WorkloadIntent{}, pkgerrors.New("WorkloadIntent already exists")
 	}
 
-	err = db.DBconn.Insert(v.db.storeName, key, nil, v.db.tagMeta, wi)
+	err = db.DBconn.Insert(v.ctx, v.db.storeName, key, nil, v.db.tagMeta, wi)
 	if err != nil {
 		return WorkloadIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -263,7 +268,7 @@ func (v *WorkloadIntentClient) GetWorkloadIntent(name, project, compositeapp, co
 		WorkloadIntent:      name,
 	}
 
-	value, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	value, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return WorkloadIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -295,7 +300,7 @@ func (v *WorkloadIntentClient) GetWorkloadIntents(project, compositeapp, composi
 	}
 
 	var resp []WorkloadIntent
-	values, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	values, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return []WorkloadIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -325,7 +330,7 @@ func (v *WorkloadIntentClient) DeleteWorkloadIntent(name, project, compositeapp,
 		WorkloadIntent:      name,
 	}
 
-	err := db.DBconn.Remove(v.db.storeName, key)
+	err := db.DBconn.Remove(v.ctx, v.db.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -351,7 +356,7 @@ func (v *NetControlIntentClient) DeleteNetControlIntent(name, project, composite
 		DigName:             dig,
 	}
 
-	err := db.DBconn.Remove(v.db.storeName, key)
+	err := db.DBconn.Remove(v.ctx, v.db.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -401,13 +406,15 @@ type WorkloadIfIntentManager interface {
 // WorkloadIfIntentClient implements the Manager
 // It will also be used to maintain some localized state
 type WorkloadIfIntentClient struct {
-	db ClientDbInfo
+	db  ClientDbInfo
+	ctx context.Context
 }
 
 // NewWorkloadIfIntentClient returns an instance of the WorkloadIfIntentClient
 // which implements the Manager
-func NewWorkloadIfIntentClient() *WorkloadIfIntentClient {
+func NewWorkloadIfIntentClient(ctx context.Context) *WorkloadIfIntentClient {
 	return &WorkloadIfIntentClient{
+		ctx: ctx,
 		db: ClientDbInfo{
 			storeName: "resources",
 			tagMeta:   "workloadifintentmetadata",
@@ -430,7 +437,7 @@ func (v *WorkloadIfIntentClient) CreateWorkloadIfIntent(wif WorkloadIfIntent, pr
 	}
 
 	//Check if the Workload Intent exists
-	_, err := NewWorkloadIntentClient().GetWorkloadIntent(workloadintent, project, compositeapp, compositeappversion, dig, netcontrolintent)
+	_, err := NewWorkloadIntentClient(v.ctx).GetWorkloadIntent(workloadintent, project, compositeapp, compositeappversion, dig, netcontrolintent)
 	if err != nil {
 		return WorkloadIfIntent{}, pkgerrors.Errorf("Workload Intent %v does not exist", workloadintent)
 	}
@@ -441,7 +448,7 @@ func (v *WorkloadIfIntentClient) CreateWorkloadIfIntent(wif WorkloadIfIntent, pr
 		return WorkloadIfIntent{}, pkgerrors.New("WorkloadIfIntent already exists")
 	}
 
-	err = db.DBconn.Insert(v.db.storeName, key, nil, v.db.tagMeta, wif)
+	err = db.DBconn.Insert(v.ctx, v.db.storeName, key, nil, v.db.tagMeta, wif)
 	if err != nil {
 		return WorkloadIfIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -463,7 +470,7 @@ func (v *WorkloadIfIntentClient) GetWorkloadIfIntent(name, project, compositeapp
 		WorkloadIfIntent:    name,
 	}
 
-	value, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	value, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return WorkloadIfIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -496,7 +503,7 @@ func (v *WorkloadIfIntentClient) GetWorkloadIfIntents(project, compositeapp, com
 	}
 
 	var resp []WorkloadIfIntent
-	values, err := db.DBconn.Find(v.db.storeName, key, v.db.tagMeta)
+	values, err := db.DBconn.Find(v.ctx, v.db.storeName, key, v.db.tagMeta)
 	if err != nil {
 		return []WorkloadIfIntent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -527,7 +534,7 @@ func (v *WorkloadIfIntentClient) DeleteWorkloadIfIntent(name, project, composite
 		WorkloadIfIntent:    name,
 	}
 
-	err := db.DBconn.Remove(v.db.storeName, key)
+	err := db.DBconn.Remove(v.ctx, v.db.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
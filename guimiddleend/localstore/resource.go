@@ -1,6 +1,7 @@
 package localstore
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 
@@ -43,7 +44,6 @@ type ResourceKey struct {
 	GenericK8sIntent    string `json:"genericK8sIntent"`
 }
 
-
 // ResourceManager is an interface that exposes resource related functionalities
 type ResourceManager interface {
 	CreateResource(b Resource, t ResourceFileContent, p, ca, cv, dig, gi string, exists bool) (Resource, error)
@@ -61,7 +61,8 @@ type clientDbInfo struct {
 
 // ResourceClient implements the resourceManager
 type ResourceClient struct {
-	db clientDbInfo
+	db  clientDbInfo
+	ctx context.Context
 }
 
 // We will use json marshalling to convert to string to
@@ -76,8 +77,9 @@ func (rk ResourceKey) String() string {
 
 // NewResourceClient returns an instance of the resourceClient
 // which implements the Manager
-func NewResourceClient() *ResourceClient {
+func NewResourceClient(ctx context.Context) *ResourceClient {
 	return &ResourceClient{
+		ctx: ctx,
 		db: clientDbInfo{
 			storeName:  "resources",
 			tagMeta:    "data",
@@ -102,12 +104,12 @@ func (rc *ResourceClient) CreateResource(r Resource, t ResourceFileContent, p, c
 	if err == nil && !exists {
 		return Resource{}, pkgerrors.New("resource already exists")
 	}
-	err = db.DBconn.Insert(rc.db.storeName, key, nil, rc.db.tagMeta, r)
+	err = db.DBconn.Insert(rc.ctx, rc.db.storeName, key, nil, rc.db.tagMeta, r)
 	if err != nil {
 		return Resource{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
 
-	err = db.DBconn.Insert(rc.db.storeName, key, nil, rc.db.tagContent, t)
+	err = db.DBconn.Insert(rc.ctx, rc.db.storeName, key, nil, rc.db.tagContent, t)
 	if err != nil {
 		return Resource{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -126,7 +128,7 @@ func (rc *ResourceClient) GetResource(brName, p, ca, cv, dig, gi string) (Resour
 		GenericK8sIntent:    gi,
 	}
 
-	value, err := db.DBconn.Find(rc.db.storeName, key, rc.db.tagMeta)
+	value, err := db.DBconn.Find(rc.ctx, rc.db.storeName, key, rc.db.tagMeta)
 	if err != nil {
 		return Resource{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -157,7 +159,7 @@ func (rc *ResourceClient) GetAllResources(p, ca, cv, dig, gi string) ([]Resource
 	}
 
 	var brs []Resource
-	values, err := db.DBconn.Find(rc.db.storeName, key, rc.db.tagMeta)
+	values, err := db.DBconn.Find(rc.ctx, rc.db.storeName, key, rc.db.tagMeta)
 	if err != nil {
 		return []Resource{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -185,7 +187,7 @@ func (rc *ResourceClient) GetResourceContent(rName, p, ca, cv, dig, gi string) (
 		GenericK8sIntent:    gi,
 	}
 
-	value, err := db.DBconn.Find(rc.db.storeName, key, rc.db.tagContent)
+	value, err := db.DBconn.Find(rc.ctx, rc.db.storeName, key, rc.db.tagContent)
 	if err != nil {
 		return ResourceFileContent{}, pkgerrors.Wrap(err, "db Find error")
 	}
@@ -213,7 +215,7 @@ func (rc *ResourceClient) DeleteResource(rName, p, ca, cv, dig, gi string) error
 		GenericK8sIntent:    gi,
 	}
 
-	err := db.DBconn.Remove(rc.db.storeName, key)
+	err := db.DBconn.Remove(rc.ctx, rc.db.storeName, key)
 	if err != nil {
 		if strings.Contains(err.Error(), "Error finding:") {
 			return pkgerrors.Wrap(err, "db Remove error - not found")
@@ -9,6 +9,7 @@ package localstore
 */
 
 import (
+	"context"
 
 	//"reflect"
 
@@ -27,10 +28,10 @@ type TrafficGroupMetadata struct {
 	UserData2   string `json:"userData2" yaml:"-"`
 }
 
-
 type TrafficGroupIntentDbClient struct {
 	storeName   string
 	tagMetaData string
+	ctx         context.Context
 }
 
 // TrafficGroupIntentKey is the key structure that is used in the database
@@ -42,8 +43,9 @@ type TrafficGroupIntentKey struct {
 	DeploymentIntentGroupName string `json:"deploymentIntentGroup"`
 }
 
-func NewTrafficGroupIntentClient() *TrafficGroupIntentDbClient {
+func NewTrafficGroupIntentClient(ctx context.Context) *TrafficGroupIntentDbClient {
 	return &TrafficGroupIntentDbClient{
+		ctx: ctx,
 
 		storeName:   "resources",
 		tagMetaData: "traffigroupintentmetadata",
@@ -66,7 +68,7 @@ func (v TrafficGroupIntentDbClient) CreateTrafficGroupIntent(tci TrafficGroupInt
 	//	return TrafficGroupIntent{}, pkgerrors.New("TrafficGroupIntent already exists")
 	//}
 
-	err := db.DBconn.Insert(v.storeName, key, nil, v.tagMetaData, tci)
+	err := db.DBconn.Insert(v.ctx, v.storeName, key, nil, v.tagMetaData, tci)
 	if err != nil {
 		return TrafficGroupIntent{}, pkgerrors.Wrap(err, "Creating DB Entry")
 	}
@@ -86,7 +88,7 @@ func (v *TrafficGroupIntentDbClient) GetTrafficGroupIntent(name, project, compos
 		DeploymentIntentGroupName: dig,
 	}
 
-	value, err := db.DBconn.Find(v.storeName, key, v.tagMetaData)
+	value, err := db.DBconn.Find(v.ctx, v.storeName, key, v.tagMetaData)
 	if err != nil {
 		return TrafficGroupIntent{}, err
 	} else if len(value) == 0 {
@@ -121,7 +123,7 @@ func (v *TrafficGroupIntentDbClient) GetTrafficGroupIntents(project, compositeap
 	}
 
 	var resp []TrafficGroupIntent
-	values, err := db.DBconn.Find(v.storeName, key, v.tagMetaData)
+	values, err := db.DBconn.Find(v.ctx, v.storeName, key, v.tagMetaData)
 	if err != nil {
 		return []TrafficGroupIntent{}, err
 	}
@@ -150,6 +152,6 @@ func (v *TrafficGroupIntentDbClient) DeleteTrafficGroupIntent(name, project, com
 		DeploymentIntentGroupName: dig,
 	}
 
-	err := db.DBconn.Remove(v.storeName, key)
+	err := db.DBconn.Remove(v.ctx, v.storeName, key)
 	return err
 }
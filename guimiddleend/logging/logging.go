@@ -0,0 +1,94 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package logging carries the logger of a request through its context,
+// so that everything logged on behalf of the request, down to the store,
+// has the request id. It also redacts the secrets found in the log lines.
+package logging
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"example.com/middleend/tracing"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is honored on the requests, echoed on the replies and
+// forwarded to the EMCO services
+const RequestIDHeader = "X-Request-ID"
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+)
+
+// NewContext returns a copy of ctx carrying the logger
+func NewContext(ctx context.Context, l *log.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger of ctx, or an entry of the standard
+// logger when ctx carries none
+func FromContext(ctx context.Context) *log.Entry {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey).(*log.Entry); ok {
+			return l
+		}
+	}
+	return log.NewEntry(log.StandardLogger())
+}
+
+// RequestIDFromContext returns the id of the request of ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// validRequestID bounds the ids accepted from the callers, as they end up
+// in every log line of the request
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Middleware takes the request id from the X-Request-ID header or makes a
+// new one, echoes it in the reply and puts a logger with the id and the
+// trace id of the request in the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		fields := log.Fields{
+			"requestId": id,
+			"from":      r.RemoteAddr,
+			"method":    r.Method,
+			"url":       r.URL.String(),
+		}
+		if sc := tracing.SpanContextFromContext(r.Context()); sc.IsValid() {
+			fields["traceId"] = sc.TraceID.String()
+		}
+		tracing.SpanFromContext(r.Context()).SetAttribute("http.request_id", id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = NewContext(ctx, log.WithFields(fields))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
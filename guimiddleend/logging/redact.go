@@ -0,0 +1,66 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package logging
+
+import (
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// The patterns run on the formatted line, so a quote may be escaped when
+// the secret was part of a json encoded field.
+var redactions = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// json web tokens, whatever they are labelled
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]*`), redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + redacted},
+	// gitToken and the like given as {"key": "gitToken", "value": "..."}
+	{regexp.MustCompile(`(?i)(\\*"key\\*"\s*:\s*\\*"(?:gitToken|token|password|secret)\\*"\s*,\s*\\*"value\\*"\s*:\s*\\*")[^"\\]+`), "${1}" + redacted},
+	// key: value, key=value and "key": "value" for the secret keys,
+	// including the credentials of kubeconfigs
+	{regexp.MustCompile(`(?i)((?:access_token|id_token|refresh_token|client_secret|password|gitToken|token|` +
+		`client-key-data|client-certificate-data|certificate-authority-data)\\*"?\s*[:=]\s*\\*"?)[^"\\\s,&}]+`), "${1}" + redacted},
+	// private keys and certificates pasted in full
+	{regexp.MustCompile(`-----BEGIN [A-Z ]+-----[\s\S]*?-----END [A-Z ]+-----`), redacted},
+}
+
+// Redact masks the secrets found in s
+func Redact(s string) string {
+	for _, r := range redactions {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// RedactingFormatter masks the secrets in the lines formatted by the
+// wrapped formatter
+type RedactingFormatter struct {
+	log.Formatter
+}
+
+// Format formats the entry and masks the secrets found in the result
+func (f *RedactingFormatter) Format(e *log.Entry) ([]byte, error) {
+	b, err := f.Formatter.Format(e)
+	if err != nil {
+		return b, err
+	}
+	return []byte(Redact(string(b))), nil
+}
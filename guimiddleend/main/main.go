@@ -18,23 +18,33 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"io/ioutil"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"example.com/middleend/app"
 	"example.com/middleend/authproxy"
+	"example.com/middleend/config"
 	"example.com/middleend/db"
+	"example.com/middleend/logging"
+	"example.com/middleend/metrics"
+	"example.com/middleend/migrate"
+	"example.com/middleend/rbac"
+	"example.com/middleend/tlsutil"
+	"example.com/middleend/tracing"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 )
 
 func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.JSONFormatter{})
+	// Log as JSON instead of the default ASCII formatter, without the
+	// tokens and credentials which may end up in the log lines.
+	log.SetFormatter(&logging.RedactingFormatter{Formatter: &log.JSONFormatter{}})
 
 	// Output to stdout instead of the default stderr
 	log.SetOutput(os.Stdout)
@@ -46,66 +56,89 @@ func init() {
  * subpath /v1.
  */
 func main() {
-	authProxyHandler := authproxy.NewAppHandler()
+	configFlag := flag.String("config", "", "path of the configuration file, $"+config.PathEnv+" or "+config.DefaultPath+" by default")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with the secrets masked and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [migrate [-dry-run]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-	configFile, err := os.Open("/opt/emco/config/middleend.conf")
+	authProxyHandler := authproxy.NewAppHandler()
+	configPath := config.ResolvePath(*configFlag)
+	bootConf, err := loadConfig(configPath, &authProxyHandler.AuthProxyConf)
 	if err != nil {
-		log.WithError(err).Errorf("%s(): Failed to read middleend configuration", app.PrintFunctionName())
+		log.WithError(err).Errorf("%s(): Failed to load middleend configuration", app.PrintFunctionName())
+		os.Exit(1)
+	}
+	effective := config.Masked(bootConf, authProxyHandler.AuthProxyConf)
+	if *printConfig {
+		out, _ := json.MarshalIndent(effective, "", "  ")
+		fmt.Println(string(out))
 		return
 	}
-	defer func(configFile *os.File) {
-		err := configFile.Close()
-		if err != nil {
-			log.WithError(err).Errorf("%s(): Failed to close read file handler", app.PrintFunctionName())
-			return
-		}
-	}(configFile)
+	switch flag.Arg(0) {
+	case "":
+	case "migrate":
+		os.Exit(migrateCommand(bootConf, flag.Args()[1:]))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 
-	// Read the configuration json
-	byteValue, _ := ioutil.ReadAll(configFile)
-	bootConf := &app.MiddleendConfig{}
-	json.Unmarshal(byteValue, bootConf)
-	json.Unmarshal(byteValue, &authProxyHandler.AuthProxyConf)
+	// set global log level
+	log.SetLevel(bootConf.Level())
 
-	// parse string, this is built-in feature of logrus
-	logLevel, err := log.ParseLevel(bootConf.LogLevel)
+	shutdownTracing, err := tracing.Init(bootConf.Tracing)
 	if err != nil {
-		logLevel = log.DebugLevel
+		log.WithError(err).Errorf("%s(): Failed to set up tracing", app.PrintFunctionName())
+		return
 	}
 
-	// set global log level
-	log.SetLevel(logLevel)
-
 	// Connect to the DB
-	err = db.CreateDBClient("mongo", "middleend", bootConf.Mongo)
+	dbType, dbEndpoint := bootConf.Database()
+	err = db.CreateDBClient(dbType, "middleend", dbEndpoint)
 	if err != nil {
-		log.Error("Failed to connect to DB")
+		log.WithError(err).Error("Failed to connect to DB")
 		return
 	}
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), time.Minute)
+	if err := db.DBconn.EnsureIndexes(indexCtx, bootConf.Indexes()); err != nil {
+		log.WithError(err).Warn("Failed to create the DB indexes, the lookups scan the collections")
+	}
+	cancelIndexes()
+	// the handlers expect the documents in the schema of this version
+	report, err := migrate.Run(context.Background(), db.DBconn, bootConf.Migrations(), false)
+	if err != nil {
+		log.WithError(err).Errorf("%s(): Failed to migrate the DB, run the migrate command with -dry-run for the details", app.PrintFunctionName())
+		os.Exit(1)
+	}
+	log.Infof("DB schema version %d", report.To)
 
-	bootConf.StoreName = "middleend"
 	// Get an instance of the OrchestrationHandler, this type implements
 	// the APIs i.e CreateApp, ShowApp, DeleteApp.
 	httpRouter := mux.NewRouter().PathPrefix("/middleend").Subrouter()
+	httpRouter.Use(tracing.Middleware)
+	httpRouter.Use(logging.Middleware)
+	httpRouter.Use(metrics.Middleware)
+	// Every route registered below requires a valid token from the issuer
+	httpRouter.Use(authProxyHandler.Middleware)
+	var projects *rbac.Enforcer
+	if bootConf.RbacPolicy != "" {
+		enforcer, err := rbac.NewEnforcer(bootConf.RbacPolicy, "/middleend", app.RouteTemplates())
+		if err != nil {
+			log.WithError(err).Errorf("%s(): Failed to load rbac policy", app.PrintFunctionName())
+			return
+		}
+		go enforcer.Watch(10*time.Second, nil)
+		httpRouter.Use(enforcer.Middleware)
+		projects = enforcer
+	} else {
+		log.Warn("No rbac policy configured, middleend APIs are not authorized")
+	}
 	loggedRouter := handlers.LoggingHandler(os.Stdout, httpRouter)
 	log.Infof("%s(): Starting middle end service", app.PrintFunctionName())
-	log.WithFields(log.Fields{
-		"ownport":        bootConf.OwnPort,
-		"orchestrator":   bootConf.OrchService,
-		"clm":            bootConf.Clm,
-		"cert":           bootConf.Cert,
-		"dcm":            bootConf.Dcm,
-		"ncm":            bootConf.Ncm,
-		"gac":            bootConf.Gac,
-		"dtc":            bootConf.Dtc,
-		"its":            bootConf.Its,
-		"ovnaction":      bootConf.OvnService,
-		"configSvc":      bootConf.CfgService,
-		"mongo":          bootConf.Mongo,
-		"logLevel":       bootConf.LogLevel,
-		"storeName":      bootConf.StoreName,
-		"appInstantiate": bootConf.AppInstantiate,
-	}).Infof("Middle End Configuration")
+	log.WithField("config", effective).Infof("Middle End Configuration loaded from %s", configPath)
 
 	httpServer := &http.Server{
 		Handler:      loggedRouter,
@@ -114,19 +147,104 @@ func main() {
 		ReadTimeout:  60 * time.Second,
 	}
 
-	// Package level Handlers
-	app.RegisterHandlers(httpRouter.HandleFunc, *bootConf)
+	// The dependencies of the APIs are created once and shared by the
+	// handlers of the requests
+	srv, err := app.NewServer(*bootConf)
+	if err != nil {
+		log.WithError(err).Errorf("%s(): Failed to set up the EMCO services", app.PrintFunctionName())
+		os.Exit(1)
+	}
+	if projects != nil {
+		srv.SetProjectAuthorizer(projects)
+	}
+	srv.RegisterHandlers(httpRouter.HandleFunc)
+	// the checkouts of abandoned sessions are discarded once their lease
+	// expires
+	go srv.ExpireCheckouts(time.Minute, nil)
+	// and the finished operations once their retention is over
+	go srv.ExpireOperations(time.Hour, nil)
+	// the log level and the service endpoints follow the configuration file
+	go config.Watch(configPath, 10*time.Second, nil, func() {
+		conf, err := loadConfig(configPath, &authproxy.AuthProxyConfig{})
+		if err == nil {
+			err = srv.Reconfigure(*conf)
+		}
+		if err != nil {
+			log.WithError(err).Errorf("%s(): Failed to reload middleend configuration, keeping the previous one", app.PrintFunctionName())
+		}
+	})
 	// Start server in a go routine.
-	go func() {
-		log.Fatal(httpServer.ListenAndServe())
-	}()
+	serveErr := make(chan error, 1)
+	if bootConf.TLS.Enabled() {
+		certs, err := tlsutil.NewServerReloader(bootConf.TLS)
+		if err != nil {
+			log.WithError(err).Errorf("%s(): Failed to load the listener certificate", app.PrintFunctionName())
+			os.Exit(1)
+		}
+		go certs.Watch(10*time.Second, nil)
+		httpServer.TLSConfig = certs.TLSConfig()
+		go func() {
+			// the certificate comes from the tls config
+			serveErr <- httpServer.ListenAndServeTLS("", "")
+		}()
+	} else {
+		go func() {
+			serveErr <- httpServer.ListenAndServe()
+		}()
+	}
 
-	// Graceful shutdown of the server,
-	// create a channel and wait for SIGINT
+	// Graceful shutdown of the server on SIGTERM, sent by kubernetes,
+	// or SIGINT
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	log.Info("wait for signal")
-	<-c
+	exitCode := 0
+	select {
+	case sig := <-c:
+		log.Infof("Received %s, shutting down", sig)
+	case err := <-serveErr:
+		log.WithError(err).Error("Middle end server failed, shutting down")
+		exitCode = 1
+	}
+	shutdown(srv, httpServer, bootConf.GracePeriod(), shutdownTracing)
 	log.Info("Bye Bye")
-	httpServer.Shutdown(context.Background())
+	os.Exit(exitCode)
+}
+
+// shutdown stops taking traffic, then drains the requests and operations
+// in flight within the grace period, and finally releases the spans and
+// the mongo connections.
+func shutdown(srv *app.Server, httpServer *http.Server, grace time.Duration, shutdownTracing func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	srv.BeginShutdown()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.WithError(err).Warn("Requests still in flight after the grace period")
+	}
+	if err := srv.Drain(ctx); err != nil {
+		log.WithError(err).Warn("Operations still running after the grace period")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.WithError(err).Warn("Failed to flush the pending spans")
+	}
+	if err := db.DBconn.Close(ctx); err != nil {
+		log.WithError(err).Warn("Failed to disconnect from the DB")
+	}
+}
+
+// loadConfig reads and validates the configuration at path. The authproxy
+// settings live in the same file.
+func loadConfig(path string, authConf *authproxy.AuthProxyConfig) (*app.MiddleendConfig, error) {
+	conf := &app.MiddleendConfig{}
+	if err := config.Load(path, conf, authConf); err != nil {
+		return nil, err
+	}
+	conf.StoreName = "middleend"
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
 }
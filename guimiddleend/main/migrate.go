@@ -0,0 +1,57 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"example.com/middleend/app"
+	"example.com/middleend/db"
+	"example.com/middleend/migrate"
+	log "github.com/sirupsen/logrus"
+)
+
+// migrateCommand runs the schema migrations of the store and prints the
+// report, for "middleend migrate [-dry-run]". It returns the exit code.
+func migrateCommand(conf *app.MiddleendConfig, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report the documents the migrations would change, without changing them")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dbType, dbEndpoint := conf.Database()
+	if err := db.CreateDBClient(dbType, "middleend", dbEndpoint); err != nil {
+		log.WithError(err).Error("Failed to connect to DB")
+		return 1
+	}
+	defer db.DBconn.Close(context.Background())
+
+	report, err := migrate.Run(context.Background(), db.DBconn, conf.Migrations(), *dryRun)
+	if report != nil {
+		if werr := report.Write(os.Stdout); werr != nil {
+			log.WithError(werr).Error("Failed to print the report")
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Schema migration failed: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Command swaggergen writes the OpenAPI document of the middleend APIs,
+// which is embedded in the binary. It runs through go generate in the
+// app package.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"example.com/middleend/app"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	out := flag.String("o", "swagger.yaml", "file the document is written to")
+	flag.Parse()
+
+	data, err := app.SpecYAML()
+	if err != nil {
+		log.Fatalf("Failed to generate the OpenAPI document: %v", err)
+	}
+	if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
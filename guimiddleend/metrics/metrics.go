@@ -0,0 +1,167 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package metrics holds the prometheus metrics of the middleend: its own
+// requests per route template, the calls to the EMCO services, the steps
+// of the composite app tree, the mongo operations and the asynchronous
+// operations. The metrics are served in the prometheus text format.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const namespace = "middleend"
+
+var (
+	latencyBuckets = exponentialBuckets(0.005, 2, 14)
+
+	requests = newCounter("http_requests_total",
+		"Requests served by the middleend per route template, method and status code.",
+		"route", "method", "code")
+	requestDuration = newHistogram("http_request_duration_seconds",
+		"Latency of the requests served by the middleend per route template and method.",
+		latencyBuckets, "route", "method")
+
+	backendRequests = newCounter("backend_requests_total",
+		"Calls to the EMCO services per service, method and status code, transport errors have code error.",
+		"service", "method", "code")
+	backendErrors = newCounter("backend_request_errors_total",
+		"Failed calls to the EMCO services, i.e. transport errors and 5xx replies, per service.",
+		"service")
+	backendDuration = newHistogram("backend_request_duration_seconds",
+		"Latency of the calls to the EMCO services per service and method.",
+		latencyBuckets, "service", "method")
+	backendCircuitOpen = newGauge("backend_circuit_open",
+		"1 while the circuit breaker of the service is open or half-open.",
+		"service")
+
+	treeStepDuration = newHistogram("tree_step_duration_seconds",
+		"Time taken by each data point of constructTree.",
+		latencyBuckets, "step")
+
+	mongoDuration = newHistogram("mongo_operation_duration_seconds",
+		"Latency of the mongo operations per operation and collection.",
+		exponentialBuckets(0.001, 2, 14), "operation", "collection")
+	mongoErrors = newCounter("mongo_operation_errors_total",
+		"Failed mongo operations per operation and collection.",
+		"operation", "collection")
+
+	operationsInFlight = newGauge("operations_in_flight",
+		"Asynchronous operations queued or running per kind.",
+		"kind")
+	operations = newCounter("operations_total",
+		"Finished asynchronous operations per kind and status.",
+		"kind", "status")
+)
+
+// statusWriter records the status code of a reply. It keeps the writer
+// flushable so that event streams work through the middleware.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Middleware counts and times the requests per mux route template, so
+// that the label values stay bounded whatever the path parameters.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(sw, r)
+		if sw.code == 0 {
+			sw.code = http.StatusOK
+		}
+		requestDuration.observe(time.Since(start).Seconds(), route, r.Method)
+		requests.add(1, route, r.Method, strconv.Itoa(sw.code))
+	})
+}
+
+// ObserveBackend records a call to an EMCO service. code is ignored when
+// the call failed with err.
+func ObserveBackend(service string, method string, code int, err error, took time.Duration) {
+	backendDuration.observe(took.Seconds(), service, method)
+	label := "error"
+	if err == nil {
+		label = strconv.Itoa(code)
+	}
+	backendRequests.add(1, service, method, label)
+	if err != nil || code >= http.StatusInternalServerError {
+		backendErrors.add(1, service)
+	}
+}
+
+// SetCircuitOpen records the state of the circuit breaker of a service
+func SetCircuitOpen(service string, open bool) {
+	v := 0.0
+	if open {
+		v = 1
+	}
+	backendCircuitOpen.set(v, service)
+}
+
+// ObserveTreeStep records the time taken by a data point of constructTree
+func ObserveTreeStep(step string, took time.Duration) {
+	treeStepDuration.observe(took.Seconds(), step)
+}
+
+// ObserveMongo records a mongo operation on a collection
+func ObserveMongo(operation string, collection string, err error, took time.Duration) {
+	mongoDuration.observe(took.Seconds(), operation, collection)
+	if err != nil {
+		mongoErrors.add(1, operation, collection)
+	}
+}
+
+// OperationQueued records an asynchronous operation accepted by the pool
+func OperationQueued(kind string) {
+	operationsInFlight.add(1, kind)
+}
+
+// OperationFinished records the end of an asynchronous operation
+func OperationFinished(kind string, status string) {
+	operationsInFlight.add(-1, kind)
+	operations.add(1, kind, status)
+}
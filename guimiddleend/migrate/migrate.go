@@ -0,0 +1,294 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package migrate upgrades the documents of the middleend store to the
+// schema of the running code. The schema version applied is recorded in
+// the store itself, and the migrations above it run in order, each in a
+// transaction of its own together with the record of its version.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"example.com/middleend/db"
+	"example.com/middleend/logging"
+	pkgerrors "github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Collection holds the schema version of the middleend store
+	Collection = "schema"
+	versionTag = "version"
+)
+
+// versionKey selects the schema record
+type versionKey struct {
+	Schema string `json:"schema"`
+}
+
+var schemaKey = versionKey{Schema: "middleend"}
+
+// State is the schema record: the version of the store and the history
+// of the migrations applied to it
+type State struct {
+	Version int
+	Applied []Applied
+}
+
+// Applied records a migration applied to the store
+type Applied struct {
+	Version     int
+	Description string
+	At          time.Time
+	Changed     int
+}
+
+// Migration upgrades the documents of a collection to the schema Version.
+// Apply changes a document in place and reports whether it did; it must
+// leave alone a document which is migrated already, so that the migration
+// can run again after a failure where the store has no transactions.
+type Migration struct {
+	Version     int
+	Description string
+	Collection  string
+	// Tag selects the documents of Collection having the field, all of
+	// them when empty
+	Tag   string
+	Apply func(doc *primitive.D) (bool, error)
+}
+
+// Result is what a migration changed, or would change in a dry run
+type Result struct {
+	Version     int
+	Description string
+	Scanned     int
+	// Changed lists the _id of the documents changed
+	Changed []string
+}
+
+// Report is the outcome of Run
+type Report struct {
+	From    int
+	To      int
+	DryRun  bool
+	Results []Result
+}
+
+// Write prints the report for a person
+func (r *Report) Write(w io.Writer) error {
+	var b strings.Builder
+	switch {
+	case len(r.Results) == 0:
+		fmt.Fprintf(&b, "Schema version %d, up to date\n", r.From)
+	case r.DryRun:
+		fmt.Fprintf(&b, "Schema version %d, would migrate to %d (dry run)\n", r.From, r.To)
+	default:
+		fmt.Fprintf(&b, "Schema version %d, migrated to %d\n", r.From, r.To)
+	}
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "  %d %s: %d of %d documents\n", res.Version, res.Description, len(res.Changed), res.Scanned)
+		for _, id := range res.Changed {
+			fmt.Fprintf(&b, "    %s\n", id)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CurrentVersion returns the schema record of store, which is empty
+// before the first migration
+func CurrentVersion(ctx context.Context, store db.Store) (State, error) {
+	var state State
+	values, err := store.Find(ctx, Collection, schemaKey, versionTag)
+	if err != nil {
+		return state, pkgerrors.Wrap(err, "reading the schema version")
+	}
+	if len(values) == 0 || values[0] == nil {
+		return state, nil
+	}
+	if err := store.Unmarshal(values[0], &state); err != nil {
+		return state, pkgerrors.Wrap(err, "reading the schema version")
+	}
+	return state, nil
+}
+
+// check makes sure the migrations are ordered by version
+func check(migrations []Migration) error {
+	for i, m := range migrations {
+		if m.Version <= 0 || m.Collection == "" || m.Apply == nil {
+			return pkgerrors.Errorf("migration %d (%s) is incomplete", m.Version, m.Description)
+		}
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			return pkgerrors.Errorf("migration %d (%s) is out of order", m.Version, m.Description)
+		}
+	}
+	return nil
+}
+
+// Run applies to store the migrations above its schema version, in order,
+// and stops at the first which fails. With dryRun the documents are only
+// checked: each migration sees them as they are, not as the previous ones
+// would leave them.
+func Run(ctx context.Context, store db.Store, migrations []Migration, dryRun bool) (*Report, error) {
+	if err := check(migrations); err != nil {
+		return nil, err
+	}
+	state, err := CurrentVersion(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{From: state.Version, To: state.Version, DryRun: dryRun}
+	for _, m := range migrations {
+		if m.Version <= report.To {
+			continue
+		}
+		res, applied, err := run(ctx, store, m, dryRun)
+		if err != nil {
+			return report, pkgerrors.Wrapf(err, "migration %d (%s)", m.Version, m.Description)
+		}
+		if !applied {
+			// another instance of the middleend got there first
+			continue
+		}
+		report.Results = append(report.Results, res)
+		report.To = m.Version
+		if !dryRun {
+			logging.FromContext(ctx).Infof("Schema migration %d (%s): %d of %d documents changed", m.Version, m.Description, len(res.Changed), res.Scanned)
+		}
+	}
+	return report, nil
+}
+
+// run applies m in a transaction, unless the store is past its version
+func run(ctx context.Context, store db.Store, m Migration, dryRun bool) (Result, bool, error) {
+	var res Result
+	var applied bool
+	err := store.WithTransaction(ctx, func(ctx context.Context) error {
+		res = Result{Version: m.Version, Description: m.Description}
+		state, err := CurrentVersion(ctx, store)
+		if err != nil {
+			return err
+		}
+		if applied = state.Version < m.Version; !applied {
+			return nil
+		}
+		docs, err := store.Documents(ctx, m.Collection, m.Tag)
+		if err != nil {
+			return err
+		}
+		res.Scanned = len(docs)
+		for _, raw := range docs {
+			id := documentID(raw)
+			var doc primitive.D
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return pkgerrors.Wrapf(err, "document %s", id)
+			}
+			changed, err := m.Apply(&doc)
+			if err != nil {
+				return pkgerrors.Wrapf(err, "document %s", id)
+			}
+			if !changed {
+				continue
+			}
+			res.Changed = append(res.Changed, id)
+			if dryRun {
+				continue
+			}
+			data, err := bson.Marshal(doc)
+			if err != nil {
+				return pkgerrors.Wrapf(err, "document %s", id)
+			}
+			if err := store.ReplaceDocument(ctx, m.Collection, data); err != nil {
+				return pkgerrors.Wrapf(err, "document %s", id)
+			}
+		}
+		if dryRun {
+			return nil
+		}
+		state.Version = m.Version
+		state.Applied = append(state.Applied, Applied{
+			Version:     m.Version,
+			Description: m.Description,
+			At:          time.Now().UTC(),
+			Changed:     len(res.Changed),
+		})
+		return store.Insert(ctx, Collection, schemaKey, nil, versionTag, state)
+	})
+	return res, applied, err
+}
+
+// documentID names a document in the reports
+func documentID(doc bson.Raw) string {
+	rv, err := doc.LookupErr("_id")
+	if err != nil {
+		return "?"
+	}
+	if id, ok := rv.ObjectIDOK(); ok {
+		return id.Hex()
+	}
+	return rv.String()
+}
+
+// Lookup returns the value at the dotted path of doc
+func Lookup(doc primitive.D, path string) (interface{}, bool) {
+	var v interface{} = doc
+	for _, name := range strings.Split(path, ".") {
+		d, ok := v.(primitive.D)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for _, e := range d {
+			if e.Key == name {
+				v, found = e.Value, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Set replaces the value at the dotted path of doc, which must exist
+func Set(doc primitive.D, path string, value interface{}) bool {
+	names := strings.Split(path, ".")
+	d := doc
+	for i, name := range names {
+		found := false
+		for j := range d {
+			if d[j].Key != name {
+				continue
+			}
+			if i == len(names)-1 {
+				d[j].Value = value
+				return true
+			}
+			d, found = d[j].Value.(primitive.D)
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return false
+}
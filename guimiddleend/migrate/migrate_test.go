@@ -0,0 +1,208 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/middleend/db"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type itemKey struct {
+	Item string `json:"item"`
+}
+
+type item struct {
+	Name  string `bson:"name"`
+	Size  string `bson:"size"`
+	Label string `bson:"label"`
+}
+
+func newStore(t *testing.T, items ...item) *db.MemoryStore {
+	t.Helper()
+	store := db.NewMemoryStore()
+	for _, it := range items {
+		if err := store.Insert(context.Background(), "items", itemKey{it.Name}, nil, "item", it); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func readItems(t *testing.T, store db.Store) map[string]primitive.D {
+	t.Helper()
+	docs, err := store.Documents(context.Background(), "items", "item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := map[string]primitive.D{}
+	for _, raw := range docs {
+		var doc struct {
+			Item primitive.D `bson:"item"`
+		}
+		if err := store.Unmarshal(raw, &doc); err != nil {
+			t.Fatal(err)
+		}
+		name, _ := Lookup(doc.Item, "name")
+		items[name.(string)] = doc.Item
+	}
+	return items
+}
+
+// testMigrations label the items, then set the size of the small ones
+func testMigrations(fail string) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "label the items",
+			Collection:  "items",
+			Tag:         "item",
+			Apply: func(doc *primitive.D) (bool, error) {
+				if v, _ := Lookup(*doc, "item.label"); v != "" {
+					return false, nil
+				}
+				return Set(*doc, "item.label", "labelled"), nil
+			},
+		},
+		{
+			Version:     2,
+			Description: "size the small items",
+			Collection:  "items",
+			Tag:         "item",
+			Apply: func(doc *primitive.D) (bool, error) {
+				name, _ := Lookup(*doc, "item.name")
+				if name == fail {
+					return false, errors.New("cannot size")
+				}
+				if v, _ := Lookup(*doc, "item.size"); v != "" {
+					return false, nil
+				}
+				return Set(*doc, "item.size", "small"), nil
+			},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, item{Name: "a"}, item{Name: "b", Size: "large"})
+
+	report, err := Run(ctx, store, testMigrations(""), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.From != 0 || report.To != 2 || len(report.Results) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if changed := len(report.Results[1].Changed); changed != 1 || report.Results[1].Scanned != 2 {
+		t.Fatalf("migration 2 changed %d of %d documents", changed, report.Results[1].Scanned)
+	}
+	items := readItems(t, store)
+	if size, _ := Lookup(items["a"], "size"); size != "small" {
+		t.Fatalf("item a has size %v", size)
+	}
+	if size, _ := Lookup(items["b"], "size"); size != "large" {
+		t.Fatalf("item b has size %v", size)
+	}
+	state, err := CurrentVersion(ctx, store)
+	if err != nil || state.Version != 2 || len(state.Applied) != 2 {
+		t.Fatalf("schema record %+v, %v", state, err)
+	}
+
+	// the migrations applied are not run again
+	report, err = Run(ctx, store, testMigrations(""), false)
+	if err != nil || report.From != 2 || report.To != 2 || len(report.Results) != 0 {
+		t.Fatalf("second run reported %+v, %v", report, err)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, item{Name: "a"}, item{Name: "b", Label: "old"})
+	before := readItems(t, store)
+
+	report, err := Run(ctx, store, testMigrations(""), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.To != 2 || len(report.Results) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if changed := len(report.Results[0].Changed); changed != 1 {
+		t.Fatalf("migration 1 would change %d documents", changed)
+	}
+	if changed := len(report.Results[1].Changed); changed != 2 {
+		t.Fatalf("migration 2 would change %d documents", changed)
+	}
+	after := readItems(t, store)
+	for name, doc := range before {
+		if label, _ := Lookup(after[name], "label"); label != mustLookup(t, doc, "label") {
+			t.Fatalf("the dry run changed item %s", name)
+		}
+	}
+	state, err := CurrentVersion(ctx, store)
+	if err != nil || state.Version != 0 {
+		t.Fatalf("the dry run recorded %+v, %v", state, err)
+	}
+}
+
+// TestRunFailed stops at a migration which fails, its changes are rolled
+// back and it runs again on the next start
+func TestRunFailed(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, item{Name: "a"}, item{Name: "b"})
+
+	report, err := Run(ctx, store, testMigrations("b"), false)
+	if err == nil {
+		t.Fatal("the failed migration succeeded")
+	}
+	if report.To != 1 {
+		t.Fatalf("migrated to %d", report.To)
+	}
+	for name, doc := range readItems(t, store) {
+		if size := mustLookup(t, doc, "size"); size != "" {
+			t.Fatalf("item %s kept the size %v of the failed migration", name, size)
+		}
+	}
+
+	report, err = Run(ctx, store, testMigrations(""), false)
+	if err != nil || report.From != 1 || report.To != 2 {
+		t.Fatalf("the rerun reported %+v, %v", report, err)
+	}
+	if changed := len(report.Results[0].Changed); changed != 2 {
+		t.Fatalf("the rerun changed %d documents", changed)
+	}
+}
+
+func TestRunOutOfOrder(t *testing.T) {
+	migrations := testMigrations("")
+	migrations[0], migrations[1] = migrations[1], migrations[0]
+	if _, err := Run(context.Background(), newStore(t), migrations, false); err == nil {
+		t.Fatal("ran the migrations out of order")
+	}
+}
+
+func mustLookup(t *testing.T, doc primitive.D, path string) interface{} {
+	t.Helper()
+	v, ok := Lookup(doc, path)
+	if !ok {
+		t.Fatalf("no %s in %v", path, doc)
+	}
+	return v
+}
@@ -0,0 +1,180 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package rbac
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/middleend/apierror"
+	"example.com/middleend/authproxy"
+	"example.com/middleend/logging"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// projectVars are the mux variables naming the project of a route
+var projectVars = []string{"projectName", "project"}
+
+// Enforcer authorizes requests against the policy file. The file is
+// reloaded when it changes on disk, so policies can be updated through
+// the config map without restarting the middleend.
+type Enforcer struct {
+	path      string
+	prefix    string
+	templates map[string]string
+	mu        sync.RWMutex
+	policy    Policy
+	modTime   time.Time
+}
+
+// NewEnforcer loads the policy at path. Routes are matched relative to
+// prefix and templates holds the route references usable in the policy.
+func NewEnforcer(path string, prefix string, templates map[string]string) (*Enforcer, error) {
+	e := &Enforcer{path: path, prefix: prefix, templates: templates}
+	if err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Enforcer) reload() error {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	policy, err := loadPolicy(e.path, e.templates)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.policy = policy
+	e.modTime = info.ModTime()
+	e.mu.Unlock()
+	log.Infof("[RBAC] Loaded policy %s with %d roles", e.path, len(policy))
+	return nil
+}
+
+// Watch polls the policy file every interval and reloads it on change.
+// A policy which fails to load is logged and the previous one is kept.
+func (e *Enforcer) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(e.path)
+			if err != nil {
+				log.WithError(err).Warnf("[RBAC] Failed to stat policy %s", e.path)
+				continue
+			}
+			e.mu.RLock()
+			changed := !info.ModTime().Equal(e.modTime)
+			e.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := e.reload(); err != nil {
+				log.WithError(err).Errorf("[RBAC] Failed to reload policy %s, keeping the previous one", e.path)
+			}
+		}
+	}
+}
+
+// Allowed reports whether the caller may invoke method on the route
+// template for the given project.
+func (e *Enforcer) Allowed(claims *authproxy.Claims, method, route, project string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, role := range claims.Roles {
+		rp, ok := e.policy[role]
+		if !ok {
+			continue
+		}
+		if rp.allowsMethod(method) && rp.allowsRoute(route) && rp.allowsProject(project, claims.Tenant) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectAllowed reports whether one of the roles of the caller may
+// access the project, whatever the method and the route. It authorizes
+// the reads of objects which are not under a project route, e.g. the
+// operations started on a project.
+func (e *Enforcer) ProjectAllowed(claims *authproxy.Claims, project string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, role := range claims.Roles {
+		if rp, ok := e.policy[role]; ok && rp.allowsProject(project, claims.Tenant) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects requests not allowed by the policy with a 403
+// ErrorResponse. It has to be installed after the authproxy middleware; requests
+// without claims, i.e. public paths, are passed through.
+func (e *Enforcer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authproxy.ClaimsFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		route = strings.TrimPrefix(route, e.prefix)
+
+		var project string
+		vars := mux.Vars(r)
+		for _, v := range projectVars {
+			if p, ok := vars[v]; ok {
+				project = p
+				break
+			}
+		}
+
+		if e.Allowed(claims, r.Method, route, project) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logging.FromContext(r.Context()).WithFields(log.Fields{
+			"subject": claims.Subject,
+			"roles":   claims.Roles,
+			"tenant":  claims.Tenant,
+			"method":  r.Method,
+			"route":   route,
+			"project": project,
+		}).Warn("[RBAC] Request denied")
+
+		apierror.Write(w, r, http.StatusForbidden,
+			fmt.Sprintf("%s %s is not allowed for roles %v", r.Method, route, claims.Roles))
+	})
+}
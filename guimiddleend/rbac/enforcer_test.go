@@ -0,0 +1,126 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package rbac
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"example.com/middleend/apierror"
+	"example.com/middleend/authproxy"
+	"github.com/gorilla/mux"
+)
+
+const testPolicy = `{
+  "admin":  {"projects": ["*"], "allowMethods": ["*"], "urls": ["*"]},
+  "tenant": {"projects": ["${tenant}"], "allowMethods": ["*"], "urls": ["/projects/{projectName}/*"]},
+  "user":   {"projects": ["${tenant}"], "allowMethods": ["get"], "urls": ["${digUriPattern}/*"]}
+}`
+
+func newTestEnforcer(t *testing.T) *Enforcer {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := ioutil.WriteFile(path, []byte(testPolicy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEnforcer(path, "/middleend", map[string]string{
+		"digUriPattern": "/projects/{projectName}/composite-apps/{compositeAppName}/{version}/deployment-intent-groups/{deploymentIntentGroupName}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestMiddleware(t *testing.T) {
+	e := newTestEnforcer(t)
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := &authproxy.Claims{Subject: "user-1", Tenant: r.Header.Get("X-Tenant"), Roles: r.Header["X-Role"]}
+			next.ServeHTTP(w, r.WithContext(authproxy.NewContext(r.Context(), claims)))
+		})
+	})
+	router.Use(e.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/middleend/projects", ok)
+	router.HandleFunc("/middleend/projects/{projectName}/composite-apps", ok)
+	router.HandleFunc("/middleend/projects/{projectName}/composite-apps/{compositeAppName}/{version}/deployment-intent-groups/{deploymentIntentGroupName}/status", ok)
+
+	dig := "/middleend/projects/p1/composite-apps/ca/v1/deployment-intent-groups/d1/status"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		role   string
+		tenant string
+		status int
+	}{
+		{"admin any project", http.MethodDelete, dig, "admin", "", http.StatusOK},
+		{"tenant own project", http.MethodPost, "/middleend/projects/p1/composite-apps", "tenant", "p1", http.StatusOK},
+		{"tenant other project", http.MethodPost, "/middleend/projects/p2/composite-apps", "tenant", "p1", http.StatusForbidden},
+		{"user read", http.MethodGet, dig, "user", "p1", http.StatusOK},
+		{"user write", http.MethodPost, dig, "user", "p1", http.StatusForbidden},
+		{"user outside urls", http.MethodGet, "/middleend/projects", "user", "p1", http.StatusForbidden},
+		{"unknown role", http.MethodGet, dig, "guest", "p1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-Role", tt.role)
+			req.Header.Set("X-Tenant", tt.tenant)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusForbidden {
+				return
+			}
+			var reply apierror.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply.Status != http.StatusForbidden || reply.Code != apierror.ErrForbidden || reply.Message == "" {
+				t.Fatalf("unexpected reply %+v", reply)
+			}
+		})
+	}
+}
+
+func TestProjectAllowed(t *testing.T) {
+	e := newTestEnforcer(t)
+	tests := []struct {
+		roles   []string
+		tenant  string
+		project string
+		allowed bool
+	}{
+		{[]string{"admin"}, "", "p2", true},
+		{[]string{"tenant"}, "p1", "p1", true},
+		{[]string{"user"}, "p1", "p2", false},
+		{[]string{"guest"}, "p1", "p1", false},
+	}
+	for _, tt := range tests {
+		claims := &authproxy.Claims{Roles: tt.roles, Tenant: tt.tenant}
+		if got := e.ProjectAllowed(claims, tt.project); got != tt.allowed {
+			t.Errorf("roles %v of tenant %s on project %s: allowed %t, want %t", tt.roles, tt.tenant, tt.project, got, tt.allowed)
+		}
+	}
+}
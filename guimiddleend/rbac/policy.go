@@ -0,0 +1,119 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package rbac authorizes middleend calls against a role based policy.
+// The policy maps the roles of the caller to the projects, http methods
+// and route templates the caller may use.
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	wildcard = "*"
+	// tenantRef in the projects of a role is replaced by the tenant of the caller
+	tenantRef = "${tenant}"
+)
+
+// Policy maps a role name to its permissions. It uses the same layout as
+// the roleUrlAccessConfig.json of the authgateway, e.g.
+//
+//	{
+//	  "admin":  {"projects": ["*"], "allowMethods": ["*"], "urls": ["*"]},
+//	  "tenant": {"projects": ["${tenant}"], "allowMethods": ["*"], "urls": ["/projects/{projectName}/*"]},
+//	  "user":   {"projects": ["${tenant}"], "allowMethods": ["GET"], "urls": ["${digUriPattern}/*"]}
+//	}
+type Policy map[string]RolePolicy
+
+// RolePolicy holds the permissions granted to a role
+type RolePolicy struct {
+	// Projects the role may access, "*" for all and "${tenant}" for the tenant of the caller
+	Projects []string `json:"projects"`
+	// AllowMethods lists the http methods, "*" for all
+	AllowMethods []string `json:"allowMethods"`
+	// Urls are mux route templates relative to /middleend. A trailing "*"
+	// matches any route below the prefix and ${name} references a route
+	// template registered with the enforcer, e.g. ${cAppUriPattern}.
+	Urls []string `json:"urls"`
+}
+
+// loadPolicy reads the policy file and expands the route references
+func loadPolicy(path string, templates map[string]string) (Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy := Policy{}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid rbac policy %s: %s", path, err)
+	}
+	for role, rp := range policy {
+		for i, url := range rp.Urls {
+			for name, tmpl := range templates {
+				url = strings.Replace(url, "${"+name+"}", tmpl, -1)
+			}
+			if strings.Contains(url, "${") {
+				return nil, fmt.Errorf("invalid rbac policy %s: unknown reference in url %q of role %s", path, rp.Urls[i], role)
+			}
+			rp.Urls[i] = url
+		}
+		for i, method := range rp.AllowMethods {
+			rp.AllowMethods[i] = strings.ToUpper(method)
+		}
+	}
+	return policy, nil
+}
+
+func (rp RolePolicy) allowsMethod(method string) bool {
+	for _, m := range rp.AllowMethods {
+		if m == wildcard || m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (rp RolePolicy) allowsRoute(route string) bool {
+	for _, u := range rp.Urls {
+		if u == wildcard || u == route {
+			return true
+		}
+		if strings.HasSuffix(u, wildcard) && strings.HasPrefix(route, strings.TrimSuffix(u, wildcard)) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsProject reports whether the role may access the project. Routes
+// which are not scoped to a project are only checked on method and url.
+func (rp RolePolicy) allowsProject(project string, tenant string) bool {
+	if project == "" {
+		return true
+	}
+	for _, p := range rp.Projects {
+		if p == wildcard || p == project {
+			return true
+		}
+		if p == tenantRef && tenant != "" && tenant == project {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package tlsutil builds the tls configurations of the middleend
+// listener and of its calls to the EMCO services.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Client authentication modes of the listener
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+// ServerConfig enables https on the middleend listener
+type ServerConfig struct {
+	// CertFile and KeyFile hold the certificate of the middleend, both
+	// are reloaded when they change
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// ClientCAFile is the CA bundle verifying the client certificates
+	ClientCAFile string `json:"clientCAFile"`
+	// ClientAuth is none, request (verified when given) or require. It
+	// defaults to require when a ClientCAFile is set, none otherwise.
+	ClientAuth string `json:"clientAuth"`
+}
+
+// Enabled reports whether the listener serves https
+func (c ServerConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Validate checks the settings and loads the files once
+func (c ServerConfig) Validate() error {
+	if !c.Enabled() {
+		if c.ClientCAFile != "" {
+			return fmt.Errorf("clientCAFile needs certFile and keyFile")
+		}
+		return nil
+	}
+	if _, err := c.clientAuth(); err != nil {
+		return err
+	}
+	_, err := NewServerReloader(c)
+	return err
+}
+
+func (c ServerConfig) clientAuth() (tls.ClientAuthType, error) {
+	switch c.ClientAuth {
+	case "":
+		if c.ClientCAFile != "" {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		if c.ClientCAFile == "" {
+			return tls.NoClientCert, fmt.Errorf("clientAuth %s needs a clientCAFile", c.ClientAuth)
+		}
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unknown clientAuth %q, expected %s, %s or %s",
+		c.ClientAuth, ClientAuthNone, ClientAuthRequest, ClientAuthRequire)
+}
+
+// ServerReloader serves the current certificate and client CAs of a
+// ServerConfig, reloading them when their files change.
+type ServerReloader struct {
+	cfg        ServerConfig
+	clientAuth tls.ClientAuthType
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// NewServerReloader loads the files of the configuration
+func NewServerReloader(cfg ServerConfig) (*ServerReloader, error) {
+	clientAuth, err := cfg.clientAuth()
+	if err != nil {
+		return nil, err
+	}
+	r := &ServerReloader{cfg: cfg, clientAuth: clientAuth}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the configuration of the listener. Every handshake
+// picks up the files loaded last.
+func (r *ServerReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// net/http requires a certificate source on the config itself
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.pool,
+			}, nil
+		},
+	}
+}
+
+func (r *ServerReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// latestModTime returns the last modification of the files
+func (r *ServerReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *ServerReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		if pool, err = loadPool(r.cfg.ClientCAFile); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the files once they change, checking them once per
+// interval, until stop is closed. A failed reload keeps the previous ones.
+func (r *ServerReloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			modTime, err := r.latestModTime()
+			if err != nil {
+				log.WithError(err).Warn("[TLS] Failed to stat the listener certificate")
+				continue
+			}
+			r.mu.RLock()
+			changed := !modTime.Equal(r.modTime)
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				log.WithError(err).Error("[TLS] Failed to reload the listener certificate, keeping the previous one")
+				continue
+			}
+			log.Info("[TLS] Reloaded the listener certificate")
+		}
+	}
+}
+
+// ClientConfig secures the calls to an EMCO service. A service with a
+// ClientConfig is called over https.
+type ClientConfig struct {
+	// CAFile is the CA bundle verifying the service, the system roots
+	// are used when empty
+	CAFile string `json:"caFile"`
+	// CertFile and KeyFile are the client certificate sent to services
+	// requiring mTLS
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// ServerName overrides the name sent in SNI and verified against the
+	// certificate of the service
+	ServerName string `json:"serverName"`
+	// InsecureSkipVerify disables the verification of the service, for tests only
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+}
+
+// Build loads the files of the configuration
+func (c ClientConfig) Build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("certFile and keyFile go together")
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s holds no PEM certificate", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,313 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	batchSize     = 256
+	queueSize     = 4096
+	flushInterval = 5 * time.Second
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, spans []*SpanData) error
+}
+
+// batcher queues the finished spans and exports them in batches from a
+// single goroutine. Spans are dropped while the queue is full, tracing
+// must never slow the requests down.
+type batcher struct {
+	exp     Exporter
+	queue   chan *SpanData
+	stop    chan struct{}
+	stopped chan struct{}
+
+	mu      sync.Mutex
+	dropped int
+}
+
+func newBatcher(exp Exporter) *batcher {
+	b := &batcher{
+		exp:     exp,
+		queue:   make(chan *SpanData, queueSize),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *batcher) enqueue(s *SpanData) {
+	select {
+	case b.queue <- s:
+	default:
+		b.mu.Lock()
+		b.dropped++
+		b.mu.Unlock()
+	}
+}
+
+func (b *batcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]*SpanData, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := b.exp.Export(ctx, batch); err != nil {
+			log.WithError(err).Warnf("Failed to export %d spans", len(batch))
+		}
+		cancel()
+		batch = make([]*SpanData, 0, batchSize)
+		b.mu.Lock()
+		if b.dropped > 0 {
+			log.Warnf("Dropped %d spans, the export queue was full", b.dropped)
+			b.dropped = 0
+		}
+		b.mu.Unlock()
+	}
+	drain := func() {
+		for {
+			select {
+			case s := <-b.queue:
+				batch = append(batch, s)
+				if len(batch) == batchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case s := <-b.queue:
+			batch = append(batch, s)
+			if len(batch) == batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case <-b.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// shutdown exports the queued spans and stops the batcher
+func (b *batcher) shutdown(ctx context.Context) error {
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+	select {
+	case <-b.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stdoutExporter writes the spans as json lines, for local testing
+type stdoutExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newStdoutExporter() *stdoutExporter {
+	return &stdoutExporter{w: os.Stdout}
+}
+
+func (e *stdoutExporter) Export(ctx context.Context, spans []*SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		attrs := make(map[string]interface{}, len(s.Attributes))
+		for _, a := range s.Attributes {
+			attrs[a.Key] = a.Value
+		}
+		err := enc.Encode(map[string]interface{}{
+			"traceId":       s.TraceID,
+			"spanId":        s.SpanID,
+			"parentSpanId":  s.ParentSpanID,
+			"name":          s.Name,
+			"kind":          s.Kind,
+			"start":         s.Start,
+			"end":           s.End,
+			"took":          s.End.Sub(s.Start).String(),
+			"attributes":    attrs,
+			"status":        s.Status,
+			"statusMessage": s.StatusMessage,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// otlpExporter posts the spans to an OTLP/HTTP collector, using the json
+// encoding of the protocol
+type otlpExporter struct {
+	url     string
+	service string
+	client  *http.Client
+}
+
+func newOTLPExporter(endpoint string, service string) *otlpExporter {
+	return &otlpExporter{
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: service,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpAttribute(key string, value interface{}) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	switch v := value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		kv.Value.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &s
+	case float64:
+		kv.Value.DoubleValue = &v
+	default:
+		s := fmt.Sprint(v)
+		kv.Value.StringValue = &s
+	}
+	return kv
+}
+
+func (e *otlpExporter) Export(ctx context.Context, spans []*SpanData) error {
+	ss := otlpScopeSpans{}
+	ss.Scope.Name = "example.com/middleend"
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentSpanID,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: s.Status, Message: s.StatusMessage},
+		}
+		for _, a := range s.Attributes {
+			span.Attributes = append(span.Attributes, otlpAttribute(a.Key, a.Value))
+		}
+		ss.Spans = append(ss.Spans, span)
+	}
+
+	rs := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{ss}}
+	rs.Resource.Attributes = []otlpKeyValue{otlpAttribute("service.name", e.service)}
+
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp collector %s replied %s", e.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+//=======================================================================
+// Copyright (c) 2017-2020 Aarna Networks, Inc.
+// All rights reserved.
+// ======================================================================
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//           http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// ========================================================================
+
+// Package tracing records OpenTelemetry compatible spans for the requests
+// served by the middleend, the steps of the composite app tree, the calls
+// to the EMCO services and the mongo operations. The trace context is
+// propagated to and from the EMCO services with the W3C traceparent
+// header, and the spans are exported to an OTLP/HTTP collector or to
+// stdout.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+// SpanKind tells the role of a span, the values are the OTLP ones
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode is the status of a span, the values are the OTLP ones
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid reports whether the id is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether the id is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span which is propagated to its children,
+// in process and to the EMCO services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether the span context has a trace and a span id
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Attribute is a key value pair describing a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is an operation being traced. A nil Span is valid and records
+// nothing, so callers need not care whether tracing is enabled.
+type Span struct {
+	tracer *tracer
+	sc     SpanContext
+	parent SpanID
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mu      sync.Mutex
+	end     time.Time
+	attrs   []Attribute
+	status  StatusCode
+	message string
+	ended   bool
+}
+
+// SpanContext returns the span context of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute adds an attribute to the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, Attribute{Key: key, Value: value})
+	s.mu.Unlock()
+}
+
+// SetStatus sets the status of the span
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.status = code
+	s.message = message
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err, a nil err is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End ends the span and hands it to the exporter. Only the first call
+// has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.tracer.batcher.enqueue(s.data())
+	}
+}
+
+// data returns the exported view of the span
+func (s *Span) data() *SpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &SpanData{
+		TraceID:       s.sc.TraceID.String(),
+		SpanID:        s.sc.SpanID.String(),
+		ParentSpanID:  parentString(s.parent),
+		Name:          s.name,
+		Kind:          s.kind,
+		Start:         s.start,
+		End:           s.end,
+		Attributes:    append([]Attribute{}, s.attrs...),
+		Status:        s.status,
+		StatusMessage: s.message,
+	}
+}
+
+func parentString(id SpanID) string {
+	if !id.IsValid() {
+		return ""
+	}
+	return id.String()
+}
+
+// SpanData is a finished span as handed to the exporters
+type SpanData struct {
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	Name          string
+	Kind          SpanKind
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+type ctxKey int
+
+const (
+	spanKey ctxKey = iota
+	remoteKey
+)
+
+// ContextWithSpan returns a copy of ctx carrying the span
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey, s)
+}
+
+// SpanFromContext returns the span of the context, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey).(*Span)
+	return s
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx whose next span is a
+// child of the span context received from a caller
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span of
+// ctx, or the remote one when no span was started yet
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+	sc, _ := ctx.Value(remoteKey).(SpanContext)
+	return sc
+}
+
+type tracer struct {
+	ratio   float64
+	batcher *batcher
+
+	mu  sync.Mutex
+	rnd *mrand.Rand
+}
+
+// active is the tracer set up by Init, nil while tracing is disabled
+var active *tracer
+
+// Start starts a span as a child of the span of ctx, or of the remote
+// span context of ctx, and returns a copy of ctx carrying the new span.
+// The span is nil when tracing is disabled.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := active
+	if t == nil {
+		return ctx, nil
+	}
+	parent := SpanContextFromContext(ctx)
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.sc.Sampled = parent.Sampled
+		s.sc.TraceState = parent.TraceState
+		s.parent = parent.SpanID
+	} else {
+		s.sc.TraceID = t.newTraceID()
+		s.sc.Sampled = t.sample()
+	}
+	s.sc.SpanID = t.newSpanID()
+	return ContextWithSpan(ctx, s), s
+}
+
+func (t *tracer) sample() bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rnd.Float64() < t.ratio
+}
+
+func (t *tracer) newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func (t *tracer) newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// Config holds the tracing settings of the middleend configuration
+type Config struct {
+	// Exporter is otlp, stdout or empty to disable tracing
+	Exporter string `json:"exporter"`
+	// Endpoint is the base url of the OTLP/HTTP collector, e.g. http://otel-collector:4318
+	Endpoint string `json:"endpoint"`
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string `json:"serviceName"`
+	// SampleRatio is the fraction of the new traces which are recorded,
+	// zero records all of them. Traces started by a caller follow its decision.
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Init sets up tracing as configured. The returned function flushes the
+// pending spans and must be called before exiting.
+func Init(cfg Config) (func(context.Context) error, error) {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "middleend"
+	}
+	if cfg.SampleRatio <= 0 {
+		cfg.SampleRatio = 1
+	}
+	var exp Exporter
+	switch cfg.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp = newStdoutExporter()
+	case "otlp":
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("tracing: the otlp exporter requires an endpoint")
+		}
+		exp = newOTLPExporter(cfg.Endpoint, cfg.ServiceName)
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+	t := &tracer{
+		ratio:   cfg.SampleRatio,
+		batcher: newBatcher(exp),
+		rnd:     mrand.New(mrand.NewSource(time.Now().UnixNano())),
+	}
+	active = t
+	return t.batcher.shutdown, nil
+}
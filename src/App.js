@@ -60,8 +60,9 @@ function App() {
         <UserContext.Provider value={{user, setUser}}>
             {user && (
                 <>
-                    <UpdatePassword formOpen={passwordFormOpen} setFormOpen={setPasswordFormOpen}/>
-                    <Router>
+                    <UpdatePassword formOpen={passwordFormOpen || Boolean(user.mustChangePassword)}
+                                    setFormOpen={setPasswordFormOpen}/>
+                    {!user.mustChangePassword && <Router>
                         <Switch>
                             {user.role === "admin" && (
                                 <Route
@@ -115,7 +116,7 @@ function App() {
                                 }}
                             />
                         </Switch>
-                    </Router>
+                    </Router>}
                 </>
             )}
 
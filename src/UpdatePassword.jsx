@@ -32,7 +32,9 @@ const schema = Yup.object({
 });
 
 export default function UpdatePassword({formOpen, setFormOpen}) {
-    const {user} = useContext(UserContext);
+    const {user, setUser} = useContext(UserContext);
+    //the user must change the password before using the app
+    const required = Boolean(user.mustChangePassword);
     const [apiResponseError, setApiResponseError] = useState(null);
     const handleClose = () => {
         setFormOpen(false);
@@ -43,6 +45,9 @@ export default function UpdatePassword({formOpen, setFormOpen}) {
         values.userId = user._id;
         apiService.updateUserPassword(values).then(() => {
             setFormOpen(false);
+            if (required) {
+                setUser({...user, mustChangePassword: false});
+            }
         }).catch(err => {
             if (err.response.data) {
                 setApiResponseError(err.response);
@@ -57,7 +62,7 @@ export default function UpdatePassword({formOpen, setFormOpen}) {
             onClose={handleClose}
             disableBackdropClick
         >
-            <DialogTitle>Change Password</DialogTitle>
+            <DialogTitle>{required ? "Change your password to continue" : "Change Password"}</DialogTitle>
             <Formik
                 initialValues={initialValues}
                 onSubmit={(values, {setSubmitting}) => {
@@ -127,11 +132,11 @@ export default function UpdatePassword({formOpen, setFormOpen}) {
                                         getIn(errors, 'confirmPassword'))}/>
                             </DialogContent>
                             <DialogActions>
-                                <Button autoFocus onClick={handleClose}
+                                {!required && <Button autoFocus onClick={handleClose}
                                         color="secondary"
                                         disabled={isSubmitting}>
                                     Cancel
-                                </Button>
+                                </Button>}
                                 <LoadingButton
                                     type="submit"
                                     buttonLabel="OK"
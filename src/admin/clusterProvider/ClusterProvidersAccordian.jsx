@@ -178,6 +178,8 @@ export default function ControlledAccordions({ data, setData }) {
     apiService
       .addCluster(formData)
       .then((res) => {
+        const failedSteps = res.failedSteps;
+        delete res.failedSteps;
         res.isNew = true;
         //a newly added cluster will have the below values, so we need not call getAllNetworks here
         res.networks = null;
@@ -195,11 +197,21 @@ export default function ControlledAccordions({ data, setData }) {
         }
         setData([...data]);
         setFormOpen(false);
-        setNotificationDetails({
-          show: true,
-          message: `cluster added : ${values.name} `,
-          severity: "success",
-        });
+        if (failedSteps.length > 0) {
+          setNotificationDetails({
+            show: true,
+            message: `cluster added : ${values.name}, but ${failedSteps
+              .map((step) => step.name)
+              .join(", ")} failed`,
+            severity: "warning",
+          });
+        } else {
+          setNotificationDetails({
+            show: true,
+            message: `cluster added : ${values.name} `,
+            severity: "success",
+          });
+        }
       })
       .catch((err) => {
         let notificationMessage;
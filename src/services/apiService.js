@@ -652,13 +652,51 @@ const deleteLogicalCloud = (request) => {
 };
 
 //middleend
+const operationPollInterval = 2000;
+
+// Long running middleend requests reply 202 with an operation, this polls the
+// operation until it finishes and resolves with the reply of the request.
+// A failed request rejects like axios does, with the status and error of the operation.
+const waitForOperation = (res) => {
+    if (res.status !== 202 || !res.data || !res.data.id) {
+        return Promise.resolve(res);
+    }
+    const parse = (value) => {
+        try {
+            return JSON.parse(value);
+        } catch (e) {
+            return value;
+        }
+    };
+    const poll = () =>
+        axios.get(`/middleend/operations/${res.data.id}`).then(({data: op}) => {
+            if (op.status === "pending" || op.status === "running") {
+                return new Promise((resolve) =>
+                    setTimeout(resolve, operationPollInterval)
+                ).then(poll);
+            }
+            if (op.code >= 400) {
+                const err = new Error(op.error);
+                err.response = {status: op.code, data: op.error, operation: op};
+                throw err;
+            }
+            return {status: op.code, data: parse(op.result), operation: op};
+        });
+    return poll();
+};
+
 const addCluster = (request) => {
     return axios
         .post(
             `/middleend/cluster-providers/${request.get("providerName")}/clusters`,
             request
         )
+        .then(waitForOperation)
         .then((res) => {
+            // the cluster is onboarded even when deploying the monitor or istio apps failed
+            res.data.failedSteps = res.operation
+                ? res.operation.steps.filter((step) => step.status === "failed")
+                : [];
             return res.data;
         });
 };
@@ -896,6 +934,7 @@ const submitCheckoutServiceInstance = (request) => {
         .post(
             `/middleend/projects/${request.projectName}/composite-apps/${request.compositeAppName}/${request.compositeAppVersion}/deployment-intent-groups/${request.deploymentIntentGroupName}/checkout/submit`
         )
+        .then(waitForOperation)
         .then((res) => res.data);
 };
 
@@ -912,7 +951,7 @@ const migrateServiceInstance = (request) => {
 };
 const terminateAndDeleteLogicalCloud = (request) => {
     let deleteUrl = `/middleend/projects/${request.projectName}/logical-clouds/${request.logicalCloudName}`;
-    return axios.delete(deleteUrl);
+    return axios.delete(deleteUrl).then(waitForOperation);
 };
 const getUserDetails = () => {
     return axios.get("/api/user/me").then((res) => res.data);